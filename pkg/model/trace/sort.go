@@ -10,32 +10,43 @@ import (
 
 // SortTrace sorts a *tempopb.Trace
 func SortTrace(t *tempopb.Trace) {
-	// Sort bottom up by span start times
+	SortTraceBy(t, SpansByStartTime)
+}
+
+// SortTraceBy sorts the spans of every scope of the trace using less, then the scopes and batches
+// by their first span. less must be a strict weak ordering.
+func SortTraceBy(t *tempopb.Trace, less func(a, b *v1.Span) bool) {
+	// Sort bottom up
 	for _, b := range t.Batches {
 		for _, ils := range b.ScopeSpans {
 			sort.Slice(ils.Spans, func(i, j int) bool {
-				return compareSpans(ils.Spans[i], ils.Spans[j])
+				return less(ils.Spans[i], ils.Spans[j])
 			})
 		}
 		sort.Slice(b.ScopeSpans, func(i, j int) bool {
-			return compareIls(b.ScopeSpans[i], b.ScopeSpans[j])
+			return compareIls(b.ScopeSpans[i], b.ScopeSpans[j], less)
 		})
 	}
 	sort.Slice(t.Batches, func(i, j int) bool {
-		return compareBatches(t.Batches[i], t.Batches[j])
+		return compareBatches(t.Batches[i], t.Batches[j], less)
 	})
 }
 
-func compareBatches(a *v1.ResourceSpans, b *v1.ResourceSpans) bool {
+// SpansByStartTime orders spans by start time, then ID.
+func SpansByStartTime(a, b *v1.Span) bool {
+	return compareSpans(a, b)
+}
+
+func compareBatches(a *v1.ResourceSpans, b *v1.ResourceSpans, less func(a, b *v1.Span) bool) bool {
 	if len(a.ScopeSpans) > 0 && len(b.ScopeSpans) > 0 {
-		return compareIls(a.ScopeSpans[0], b.ScopeSpans[0])
+		return compareIls(a.ScopeSpans[0], b.ScopeSpans[0], less)
 	}
 	return false
 }
 
-func compareIls(a *v1.ScopeSpans, b *v1.ScopeSpans) bool {
+func compareIls(a *v1.ScopeSpans, b *v1.ScopeSpans, less func(a, b *v1.Span) bool) bool {
 	if len(a.Spans) > 0 && len(b.Spans) > 0 {
-		return compareSpans(a.Spans[0], b.Spans[0])
+		return less(a.Spans[0], b.Spans[0])
 	}
 	return false
 }
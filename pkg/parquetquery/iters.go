@@ -255,6 +255,11 @@ type ColumnIterator struct {
 	seekToMtx sync.Mutex
 	seekTo    RowNumber
 
+	// How many pages to read ahead of the page being processed
+	readAhead int
+	// How many pages to decode at once
+	decodeConcurrency int
+
 	quit chan struct{}
 	ch   chan *columnIteratorBuffer
 
@@ -270,7 +275,28 @@ type columnIteratorBuffer struct {
 	values     []pq.Value
 }
 
-func NewColumnIterator(ctx context.Context, rgs []pq.RowGroup, column int, columnName string, readSize int, filter Predicate, selectAs string) *ColumnIterator {
+// ColumnIteratorOption configures optional behavior of a ColumnIterator.
+type ColumnIteratorOption func(*ColumnIterator)
+
+// WithReadAhead makes the iterator read up to the given number of pages in the background while
+// the current page is processed. This hides the latency of slow storage, at the cost of holding
+// more pages in memory. It doesn't change the results.
+func WithReadAhead(pages int) ColumnIteratorOption {
+	return func(c *ColumnIterator) {
+		c.readAhead = pages
+	}
+}
+
+// WithDecodeConcurrency makes the iterator decode the values of up to the given number of pages of
+// a column chunk at once, which spreads the CPU cost of large scans over multiple cores. Pages are
+// still processed in order, so it doesn't change the results. 1 or less decodes pages serially.
+func WithDecodeConcurrency(pages int) ColumnIteratorOption {
+	return func(c *ColumnIterator) {
+		c.decodeConcurrency = pages
+	}
+}
+
+func NewColumnIterator(ctx context.Context, rgs []pq.RowGroup, column int, columnName string, readSize int, filter Predicate, selectAs string, opts ...ColumnIteratorOption) *ColumnIterator {
 	c := &ColumnIterator{
 		rgs:      rgs,
 		col:      column,
@@ -280,6 +306,12 @@ func NewColumnIterator(ctx context.Context, rgs []pq.RowGroup, column int, colum
 		quit:     make(chan struct{}),
 		ch:       make(chan *columnIteratorBuffer, 1),
 		currN:    -1,
+
+		decodeConcurrency: 1,
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
 
 	go c.iterate(ctx, readSize)
@@ -306,17 +338,58 @@ func (c *ColumnIterator) iterate(ctx context.Context, readSize int) {
 	rn := EmptyRowNumber()
 	buffer := make([]pq.Value, readSize)
 
-	keepSeeking := func(numRows int64) bool {
+	// keepSeeking returns true if all numRows rows after from are before the row to seek to.
+	keepSeeking := func(from RowNumber, numRows int64) bool {
 		c.seekToMtx.Lock()
 		seekTo := c.seekTo
 		c.seekToMtx.Unlock()
 
-		rnNext := rn
+		rnNext := from
 		rnNext.Skip(numRows)
 
 		return CompareRowNumbers(0, rnNext, seekTo) == -1
 	}
 
+	// emit assigns row numbers to the values read from a page, filters them and sends the kept
+	// ones. Returns true if the iteration stopped.
+	emit := func(values []pq.Value) (stop bool) {
+		// Assign row numbers, filter values, and collect the results.
+		newBuffer := columnIteratorPoolGet(readSize, 0)
+
+		for _, v := range values {
+			// We have to do this for all values (even if the
+			// value is excluded by the predicate)
+			rn.Next(v.RepetitionLevel(), v.DefinitionLevel())
+
+			if c.filter != nil {
+				if !c.filter.KeepValue(v) {
+					continue
+				}
+			}
+
+			newBuffer.rowNumbers = append(newBuffer.rowNumbers, rn)
+			newBuffer.values = append(newBuffer.values, v.Clone()) // We clone values so they don't reference the page
+		}
+
+		if len(newBuffer.rowNumbers) == 0 {
+			// All values excluded, we go ahead and immediately
+			// return the buffer to the pool.
+			columnIteratorPoolPut(newBuffer)
+			return false
+		}
+
+		select {
+		case c.ch <- newBuffer:
+			return false
+		case <-c.quit:
+			columnIteratorPoolPut(newBuffer)
+			return true
+		case <-ctx.Done():
+			columnIteratorPoolPut(newBuffer)
+			return true
+		}
+	}
+
 	for _, rg := range c.rgs {
 		// bail out if we errored somewhere
 		if c.currErr.Load() != nil {
@@ -325,7 +398,7 @@ func (c *ColumnIterator) iterate(ctx context.Context, readSize int) {
 
 		col := rg.ColumnChunks()[c.col]
 
-		if keepSeeking(rg.NumRows()) {
+		if keepSeeking(rn, rg.NumRows()) {
 			// Skip column chunk
 			rn.Skip(rg.NumRows())
 			continue
@@ -345,8 +418,48 @@ func (c *ColumnIterator) iterate(ctx context.Context, readSize int) {
 					c.storeErr("column iterator pages close", err)
 				}
 			}()
+
+			readPage := pgs.ReadPage
+			owned := false
+			if c.readAhead > 0 {
+				ra := newPageReadAhead(pgs, c.readAhead)
+				// Deferred after pgs.Close so the background reads stop before the pages are closed
+				defer ra.close()
+				readPage = ra.ReadPage
+				owned = true
+			}
+
+			if c.decodeConcurrency > 1 {
+				c.decodePages(readPage, owned, rn, keepSeeking, func(p *decodedPage) (stop bool) {
+					if p.skip {
+						rn.Skip(p.rows)
+						return false
+					}
+
+					for values := p.values; len(values) > 0; {
+						n := len(values)
+						if n > readSize {
+							n = readSize
+						}
+						if emit(values[:n]) {
+							return true
+						}
+						values = values[n:]
+					}
+
+					// Error checks occur after processing the values that were read,
+					// the same as in the serial loop below.
+					if p.err != nil {
+						c.storeErr("column iterator read values", p.err)
+						return true
+					}
+					return false
+				})
+				return
+			}
+
 			for {
-				pg, err := pgs.ReadPage()
+				pg, err := readPage()
 
 				if pg == nil || err == io.EOF {
 					break
@@ -359,7 +472,7 @@ func (c *ColumnIterator) iterate(ctx context.Context, readSize int) {
 				stop := func(pg pq.Page) (stop bool) {
 					defer pq.Release(pg)
 
-					if keepSeeking(pg.NumRows()) {
+					if keepSeeking(rn, pg.NumRows()) {
 						// Skip page
 						rn.Skip(pg.NumRows())
 						return
@@ -376,44 +489,8 @@ func (c *ColumnIterator) iterate(ctx context.Context, readSize int) {
 					vr := pg.Values()
 					for {
 						count, err := vr.ReadValues(buffer)
-						if count > 0 {
-
-							// Assign row numbers, filter values, and collect the results.
-							newBuffer := columnIteratorPoolGet(readSize, 0)
-
-							for i := 0; i < count; i++ {
-
-								v := buffer[i]
-
-								// We have to do this for all values (even if the
-								// value is excluded by the predicate)
-								rn.Next(v.RepetitionLevel(), v.DefinitionLevel())
-
-								if c.filter != nil {
-									if !c.filter.KeepValue(v) {
-										continue
-									}
-								}
-
-								newBuffer.rowNumbers = append(newBuffer.rowNumbers, rn)
-								newBuffer.values = append(newBuffer.values, v.Clone()) // We clone values so they don't reference the page
-							}
-
-							if len(newBuffer.rowNumbers) > 0 {
-								select {
-								case c.ch <- newBuffer:
-								case <-c.quit:
-									columnIteratorPoolPut(newBuffer)
-									return true
-								case <-ctx.Done():
-									columnIteratorPoolPut(newBuffer)
-									return true
-								}
-							} else {
-								// All values excluded, we go ahead and immediately
-								// return the buffer to the pool.
-								columnIteratorPoolPut(newBuffer)
-							}
+						if count > 0 && emit(buffer[:count]) {
+							return true
 						}
 
 						// Error checks MUST occur after processing any returned data
@@ -436,6 +513,171 @@ func (c *ColumnIterator) iterate(ctx context.Context, readSize int) {
 	}
 }
 
+// decodedPage is a page whose values are decoded in the background by decodePages.
+type decodedPage struct {
+	rows int64
+	// skip is set if the page was skipped without decoding. Its rows still need to be counted.
+	skip   bool
+	values []pq.Value
+	err    error
+	done   chan struct{}
+}
+
+// decodePages reads the pages of a column chunk and decodes up to decodeConcurrency of them at
+// once, while consume processes the decoded pages one after the other in the order of the chunk.
+// Pages are skipped the same way as by the serial loop in iterate. Because pages are checked before
+// the pages ahead of them were consumed, the rows they skip are counted from queued, the row
+// number at the end of the pages read so far. A page read by readPage is only valid until the next
+// call, so the pages are cloned before they are decoded, unless owned is set because readPage
+// already returns clones.
+func (c *ColumnIterator) decodePages(readPage func() (pq.Page, error), owned bool, queued RowNumber, keepSeeking func(RowNumber, int64) bool, consume func(*decodedPage) bool) {
+	var pending []*decodedPage
+	defer func() {
+		// Wait for the pages that weren't consumed so they aren't decoded after the chunk is closed
+		for _, p := range pending {
+			<-p.done
+		}
+	}()
+
+	next := func() (stop bool) {
+		p := pending[0]
+		pending = pending[1:]
+		<-p.done
+		return consume(p)
+	}
+
+	for {
+		pg, err := readPage()
+
+		if pg == nil || err == io.EOF {
+			break
+		}
+		if err != nil {
+			c.storeErr("column iterator read page", err)
+			return
+		}
+
+		p := &decodedPage{rows: pg.NumRows(), done: make(chan struct{})}
+		p.skip = keepSeeking(queued, p.rows) || (c.filter != nil && !c.filter.KeepPage(pg))
+		queued.Skip(p.rows)
+
+		if p.skip {
+			pq.Release(pg)
+			close(p.done)
+		} else {
+			if !owned {
+				pg = clonePage(pg)
+			}
+			go func() {
+				defer close(p.done)
+				p.values, p.err = decodePageValues(pg)
+			}()
+		}
+
+		pending = append(pending, p)
+		if len(pending) >= c.decodeConcurrency && next() {
+			return
+		}
+	}
+
+	for len(pending) > 0 {
+		if next() {
+			return
+		}
+	}
+}
+
+// clonePage returns a copy of the page that stays valid after the next page is read, and releases
+// the page.
+func clonePage(pg pq.Page) pq.Page {
+	clone := pg.Clone()
+	pq.Release(pg)
+	return clone
+}
+
+// decodePageValues reads all values of the page. The values reference the page.
+func decodePageValues(pg pq.Page) ([]pq.Value, error) {
+	values := make([]pq.Value, pg.NumValues())
+	vr := pg.Values()
+	read := 0
+	for read < len(values) {
+		n, err := vr.ReadValues(values[read:])
+		read += n
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return values[:read], err
+		}
+	}
+	return values[:read], nil
+}
+
+type pageReadAheadResult struct {
+	pg  pq.Page
+	err error
+}
+
+// pageReadAhead reads pages in the background into a bounded buffer. A page is only valid until the
+// next page is read, so the buffered pages are clones.
+type pageReadAhead struct {
+	ch   chan pageReadAheadResult
+	quit chan struct{}
+	done chan struct{}
+}
+
+func newPageReadAhead(pgs pq.Pages, n int) *pageReadAhead {
+	r := &pageReadAhead{
+		ch:   make(chan pageReadAheadResult, n),
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(r.done)
+		defer close(r.ch)
+
+		for {
+			pg, err := pgs.ReadPage()
+			if pg != nil {
+				pg = clonePage(pg)
+			}
+			select {
+			case r.ch <- pageReadAheadResult{pg: pg, err: err}:
+			case <-r.quit:
+				if pg != nil {
+					pq.Release(pg)
+				}
+				return
+			}
+			if pg == nil || err != nil {
+				return
+			}
+		}
+	}()
+
+	return r
+}
+
+func (r *pageReadAhead) ReadPage() (pq.Page, error) {
+	res, ok := <-r.ch
+	if !ok {
+		return nil, io.EOF
+	}
+	return res.pg, res.err
+}
+
+// close stops reading and releases any pages that were read but not consumed.
+func (r *pageReadAhead) close() {
+	close(r.quit)
+	<-r.done
+	for res := range r.ch {
+		if res.pg != nil {
+			pq.Release(res.pg)
+		}
+	}
+}
+
 // Next returns the next matching value from the iterator.
 // Returns nil when finished.
 func (c *ColumnIterator) Next() (*IteratorResult, error) {
@@ -1042,6 +1284,59 @@ func (u *UnionIterator) Close() {
 	}
 }
 
+// CountingIterator wraps an iterator and counts the results it returned, SeekTo included. The
+// count can be read at any time and is reported to a callback every given number of results and
+// once more when the iterator is closed, which lets long scans report progress or be detected as
+// stalled.
+type CountingIterator struct {
+	iter     Iterator
+	count    int64
+	every    int64
+	progress func(count int64)
+}
+
+var _ Iterator = (*CountingIterator)(nil)
+
+// NewCountingIterator wraps iter. progress is called every `every` results and on Close, it may be
+// nil. An every of 0 or less only reports on Close.
+func NewCountingIterator(iter Iterator, every int64, progress func(count int64)) *CountingIterator {
+	return &CountingIterator{
+		iter:     iter,
+		every:    every,
+		progress: progress,
+	}
+}
+
+func (c *CountingIterator) Next() (*IteratorResult, error) {
+	return c.counted(c.iter.Next())
+}
+
+func (c *CountingIterator) SeekTo(t RowNumber, definitionLevel int) (*IteratorResult, error) {
+	return c.counted(c.iter.SeekTo(t, definitionLevel))
+}
+
+func (c *CountingIterator) counted(res *IteratorResult, err error) (*IteratorResult, error) {
+	if res != nil {
+		c.count++
+		if c.progress != nil && c.every > 0 && c.count%c.every == 0 {
+			c.progress(c.count)
+		}
+	}
+	return res, err
+}
+
+// Count returns the number of results returned so far.
+func (c *CountingIterator) Count() int64 {
+	return c.count
+}
+
+func (c *CountingIterator) Close() {
+	c.iter.Close()
+	if c.progress != nil {
+		c.progress(c.count)
+	}
+}
+
 type GroupPredicate interface {
 	KeepGroup(*IteratorResult) bool
 }
@@ -2,8 +2,12 @@ package parquetquery
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/segmentio/parquet-go"
 	"github.com/stretchr/testify/require"
@@ -109,6 +113,270 @@ func TestColumnIteratorContextCanceled(t *testing.T) {
 	require.Less(t, received, count)
 }
 
+func TestColumnIteratorReadAhead(t *testing.T) {
+	type T struct{ A int }
+
+	rows := []T{}
+	count := 10_000
+	for i := 0; i < count; i++ {
+		rows = append(rows, T{i})
+	}
+
+	f, size := writeFileWithPages(t, rows, 256)
+	pf, err := parquet.OpenFile(f, size)
+	require.NoError(t, err)
+	idx, _ := GetColumnIndexByPath(pf, "A")
+
+	iter := NewColumnIterator(context.TODO(), pf.RowGroups(), idx, "", 100, NewIntBetweenPredicate(10, 9000), "A", WithReadAhead(4))
+	defer iter.Close()
+
+	for i := 10; i <= 9000; i++ {
+		res, err := iter.Next()
+		require.NoError(t, err)
+		require.Equal(t, RowNumber{int64(i), -1, -1, -1, -1, -1}, res.RowNumber)
+		require.Equal(t, int64(i), res.ToMap()["A"][0].Int64())
+
+		if i == 5000 {
+			res, err = iter.SeekTo(RowNumber{8000}, 0)
+			require.NoError(t, err)
+			require.Equal(t, int64(8000), res.ToMap()["A"][0].Int64())
+			i = 8000
+		}
+	}
+
+	res, err := iter.Next()
+	require.NoError(t, err)
+	require.Nil(t, res)
+}
+
+func TestColumnIteratorDecodeConcurrency(t *testing.T) {
+	type T struct{ A []string }
+
+	rows := []T{}
+	count := 10_000
+	for i := 0; i < count; i++ {
+		// a varying number of values per row so pages don't line up with rows
+		row := T{}
+		for j := 0; j < i%4; j++ {
+			row.A = append(row.A, fmt.Sprintf("%d-%d", i, j))
+		}
+		rows = append(rows, row)
+	}
+
+	f, size := writeFileWithPages(t, rows, 512)
+	pf, err := parquet.OpenFile(f, size)
+	require.NoError(t, err)
+	idx, _ := GetColumnIndexByPath(pf, "A")
+	require.NotEqual(t, -1, idx)
+
+	type result struct {
+		rn RowNumber
+		v  string
+	}
+
+	// collect seeks ahead every 1000 rows, so pages are skipped while others are decoded
+	collect := func(filter Predicate, opts ...ColumnIteratorOption) []result {
+		iter := NewColumnIterator(context.TODO(), pf.RowGroups(), idx, "", 100, filter, "A", opts...)
+		defer iter.Close()
+
+		var results []result
+		next := iter.Next
+		for {
+			res, err := next()
+			require.NoError(t, err)
+			if res == nil {
+				return results
+			}
+			results = append(results, result{res.RowNumber, res.ToMap()["A"][0].String()})
+
+			next = iter.Next
+			if res.RowNumber[0]%1000 == 0 {
+				to := RowNumber{res.RowNumber[0] + 500}
+				next = func() (*IteratorResult, error) { return iter.SeekTo(to, 0) }
+			}
+		}
+	}
+
+	regex, err := NewRegexInPredicate([]string{"^[0-9]*5-1$"})
+	require.NoError(t, err)
+
+	for _, filter := range []Predicate{nil, regex} {
+		expected := collect(filter)
+		require.NotEmpty(t, expected)
+
+		for _, concurrency := range []int{2, 3, 8} {
+			require.Equal(t, expected, collect(filter, WithDecodeConcurrency(concurrency)), "concurrency %d", concurrency)
+			require.Equal(t, expected, collect(filter, WithDecodeConcurrency(concurrency), WithReadAhead(2)), "concurrency %d with read-ahead", concurrency)
+		}
+	}
+}
+
+func TestColumnIteratorDecodePagesClonesPages(t *testing.T) {
+	type T struct{ A int }
+
+	rows := []T{}
+	count := 10_000
+	for i := 0; i < count; i++ {
+		rows = append(rows, T{i})
+	}
+
+	f, size := writeFileWithPages(t, rows, 256)
+	pf, err := parquet.OpenFile(f, size)
+	require.NoError(t, err)
+	idx, _ := GetColumnIndexByPath(pf, "A")
+
+	pgs := &invalidatingPages{Pages: pf.RowGroups()[0].ColumnChunks()[idx].Pages()}
+	defer pgs.Close()
+
+	c := &ColumnIterator{decodeConcurrency: 8}
+	keepSeeking := func(RowNumber, int64) bool { return false }
+
+	var values []int64
+	c.decodePages(pgs.ReadPage, false, EmptyRowNumber(), keepSeeking, func(p *decodedPage) bool {
+		require.NoError(t, p.err)
+		for _, v := range p.values {
+			values = append(values, v.Int64())
+		}
+		return false
+	})
+
+	require.Nil(t, c.currErr.Load())
+	require.Len(t, values, count)
+	for i, v := range values {
+		require.Equal(t, int64(i), v)
+	}
+	require.False(t, pgs.misused.Load(), "a page was read after the next page was read")
+}
+
+func TestPageReadAheadClonesPages(t *testing.T) {
+	type T struct{ A int }
+
+	rows := []T{}
+	count := 10_000
+	for i := 0; i < count; i++ {
+		rows = append(rows, T{i})
+	}
+
+	f, size := writeFileWithPages(t, rows, 256)
+	pf, err := parquet.OpenFile(f, size)
+	require.NoError(t, err)
+	idx, _ := GetColumnIndexByPath(pf, "A")
+
+	pgs := &invalidatingPages{Pages: pf.RowGroups()[0].ColumnChunks()[idx].Pages()}
+	defer pgs.Close()
+
+	ra := newPageReadAhead(pgs, 4)
+	defer ra.close()
+
+	// Read all pages before reading any of their values
+	var pages []parquet.Page
+	for {
+		pg, err := ra.ReadPage()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		pages = append(pages, pg)
+	}
+	require.Greater(t, len(pages), 1)
+
+	var values []int64
+	for _, pg := range pages {
+		vs, err := decodePageValues(pg)
+		require.NoError(t, err)
+		for _, v := range vs {
+			values = append(values, v.Int64())
+		}
+	}
+
+	require.Len(t, values, count)
+	for i, v := range values {
+		require.Equal(t, int64(i), v)
+	}
+	require.False(t, pgs.misused.Load(), "a page was read after the next page was read")
+}
+
+func TestColumnIteratorDecodeConcurrencyContextCanceled(t *testing.T) {
+	type T struct{ A int }
+
+	rows := []T{}
+	count := 10_000
+	for i := 0; i < count; i++ {
+		rows = append(rows, T{i})
+	}
+
+	f, size := writeFileWithPages(t, rows, 256)
+	pf, err := parquet.OpenFile(f, size)
+	require.NoError(t, err)
+	idx, _ := GetColumnIndexByPath(pf, "A")
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+
+	iter := NewColumnIterator(ctx, pf.RowGroups(), idx, "", 100, nil, "A", WithDecodeConcurrency(4))
+	defer iter.Close()
+
+	// Verify iterator exits early
+	received := 0
+	for {
+		res, err := iter.Next()
+		require.NoError(t, err)
+		if res == nil {
+			break
+		}
+		received++
+	}
+	require.Less(t, received, count)
+}
+
+func TestCountingIterator(t *testing.T) {
+	type T struct{ A int }
+
+	rows := []T{}
+	count := 1000
+	for i := 0; i < count; i++ {
+		rows = append(rows, T{i})
+	}
+
+	pf := createFileWith(t, rows)
+	idx, _ := GetColumnIndexByPath(pf, "A")
+
+	var reported []int64
+	iter := NewCountingIterator(
+		NewColumnIterator(context.TODO(), pf.RowGroups(), idx, "", 100, NewIntBetweenPredicate(100, 549), "A"),
+		100,
+		func(count int64) { reported = append(reported, count) },
+	)
+
+	results := int64(0)
+	for {
+		res, err := iter.Next()
+		require.NoError(t, err)
+		if res == nil {
+			break
+		}
+		results++
+		require.Equal(t, results, iter.Count())
+
+		if results == 200 {
+			// results of a seek are counted too, the skipped rows aren't
+			res, err = iter.SeekTo(RowNumber{500}, 0)
+			require.NoError(t, err)
+			require.Equal(t, int64(500), res.ToMap()["A"][0].Int64())
+			results++
+		}
+	}
+
+	// 200 results before the seek and 50 after
+	require.Equal(t, int64(250), results)
+	require.Equal(t, results, iter.Count())
+	require.Equal(t, []int64{100, 200}, reported)
+
+	// the final count is reported on close
+	iter.Close()
+	require.Equal(t, []int64{100, 200, 250}, reported)
+}
+
 func BenchmarkColumnIterator(b *testing.B) {
 	type T struct{ A int }
 	rows := []T{}
@@ -135,6 +403,82 @@ func BenchmarkColumnIterator(b *testing.B) {
 	}
 }
 
+// latencyReaderAt simulates the latency of an object store
+type latencyReaderAt struct {
+	r       io.ReaderAt
+	latency time.Duration
+}
+
+func (l *latencyReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	time.Sleep(l.latency)
+	return l.r.ReadAt(p, off)
+}
+
+func BenchmarkColumnIteratorReadAhead(b *testing.B) {
+	type T struct{ A string }
+	rows := []T{}
+	count := 2000
+	for i := 0; i < count; i++ {
+		rows = append(rows, T{fmt.Sprintf("%0100d", i)})
+	}
+
+	// Small pages and read buffers so that every page is a separate read
+	f, size := writeFileWithPages(b, rows, 1024)
+	pf, err := parquet.OpenFile(&latencyReaderAt{r: f, latency: time.Millisecond}, size, parquet.ReadBufferSize(1024))
+	require.NoError(b, err)
+	idx, _ := GetColumnIndexByPath(pf, "A")
+
+	for _, readAhead := range []int{0, 1, 4} {
+		b.Run(fmt.Sprintf("readAhead=%d", readAhead), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				iter := NewColumnIterator(context.TODO(), pf.RowGroups(), idx, "", 10, nil, "A", WithReadAhead(readAhead))
+				for {
+					res, err := iter.Next()
+					require.NoError(b, err)
+					if res == nil {
+						break
+					}
+					// Simulate processing the results
+					if res.RowNumber[0]%80 == 0 {
+						time.Sleep(time.Millisecond)
+					}
+				}
+				iter.Close()
+			}
+		})
+	}
+}
+
+func BenchmarkColumnIteratorDecodeConcurrency(b *testing.B) {
+	type T struct{ A string }
+	rows := []T{}
+	count := 200_000
+	for i := 0; i < count; i++ {
+		rows = append(rows, T{fmt.Sprintf("%050d", i)})
+	}
+
+	// One large row group with many pages
+	pf := createFileWith(b, rows)
+	require.Equal(b, 1, len(pf.RowGroups()))
+	idx, _ := GetColumnIndexByPath(pf, "A")
+
+	for _, concurrency := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				iter := NewColumnIterator(context.TODO(), pf.RowGroups(), idx, "", 1000, nil, "A", WithDecodeConcurrency(concurrency))
+				for {
+					res, err := iter.Next()
+					require.NoError(b, err)
+					if res == nil {
+						break
+					}
+				}
+				iter.Close()
+			}
+		})
+	}
+}
+
 func createFileWith[T any](t testing.TB, rows []T) *parquet.File {
 	f, err := os.CreateTemp(t.TempDir(), "data.parquet")
 	require.NoError(t, err)
@@ -154,3 +498,55 @@ func createFileWith[T any](t testing.TB, rows []T) *parquet.File {
 
 	return pf
 }
+
+// writeFileWithPages writes the rows one at a time so that the writer cuts a new page
+// whenever the page buffer is full.
+func writeFileWithPages[T any](t testing.TB, rows []T, pageBufferSize int) (*os.File, int64) {
+	f, err := os.CreateTemp(t.TempDir(), "data.parquet")
+	require.NoError(t, err)
+
+	w := parquet.NewGenericWriter[T](f, parquet.PageBufferSize(pageBufferSize))
+	for _, r := range rows {
+		_, err := w.Write([]T{r})
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	stat, err := f.Stat()
+	require.NoError(t, err)
+
+	return f, stat.Size()
+}
+
+// invalidatingPages marks each page it returns as invalid once the next page is read, following the
+// contract of parquet.PageReader, and records if an invalid page is read.
+type invalidatingPages struct {
+	parquet.Pages
+	prev    *invalidatedPage
+	misused atomic.Bool
+}
+
+func (p *invalidatingPages) ReadPage() (parquet.Page, error) {
+	if p.prev != nil {
+		p.prev.invalid.Store(true)
+	}
+	pg, err := p.Pages.ReadPage()
+	if pg == nil {
+		return nil, err
+	}
+	p.prev = &invalidatedPage{Page: pg, pages: p}
+	return p.prev, err
+}
+
+type invalidatedPage struct {
+	parquet.Page
+	pages   *invalidatingPages
+	invalid atomic.Bool
+}
+
+func (p *invalidatedPage) Values() parquet.ValueReader {
+	if p.invalid.Load() {
+		p.pages.misused.Store(true)
+	}
+	return p.Page.Values()
+}
@@ -3,6 +3,7 @@ package parquetquery
 import (
 	"bytes"
 	"context"
+	"sort"
 	"testing"
 
 	"github.com/google/uuid"
@@ -45,6 +46,130 @@ func TestSubstringPredicate(t *testing.T) {
 	})
 }
 
+func TestPrefixPredicate(t *testing.T) {
+	type String struct {
+		S string `parquet:","`
+	}
+
+	testPredicate(t, predicateTestCase{
+		predicate:  NewPrefixPredicate([]byte("ab")),
+		keptChunks: 1,
+		keptPages:  1,
+		keptValues: 2,
+		writeData: func(w *parquet.Writer) { //nolint:all
+			require.NoError(t, w.Write(&String{"aaa"})) // skipped
+			require.NoError(t, w.Write(&String{"ab"}))  // kept
+			require.NoError(t, w.Write(&String{"abc"})) // kept
+			require.NoError(t, w.Write(&String{"b"}))   // skipped
+		},
+	})
+
+	// Bounds of the column chunk don't contain the prefix
+	testPredicate(t, predicateTestCase{
+		predicate:  NewPrefixPredicate([]byte("c")),
+		keptChunks: 0,
+		keptPages:  0,
+		keptValues: 0,
+		writeData: func(w *parquet.Writer) { //nolint:all
+			require.NoError(t, w.Write(&String{"aaa"}))
+			require.NoError(t, w.Write(&String{"abc"}))
+			require.NoError(t, w.Write(&String{"bcd"}))
+		},
+	})
+}
+
+func TestStringInPredicate(t *testing.T) {
+	type String struct {
+		S string `parquet:","`
+	}
+
+	testPredicate(t, predicateTestCase{
+		predicate:  NewStringInPredicate([]string{"d", "b", "b", "zz"}),
+		keptChunks: 1,
+		keptPages:  1,
+		keptValues: 2,
+		writeData: func(w *parquet.Writer) { //nolint:all
+			require.NoError(t, w.Write(&String{"a"})) // skipped
+			require.NoError(t, w.Write(&String{"b"})) // kept
+			require.NoError(t, w.Write(&String{"c"})) // skipped
+			require.NoError(t, w.Write(&String{"d"})) // kept
+			require.NoError(t, w.Write(&String{"e"})) // skipped
+		},
+	})
+
+	// None of the strings are within the bounds of the column chunk
+	testPredicate(t, predicateTestCase{
+		predicate:  NewStringInPredicate([]string{"a", "f"}),
+		keptChunks: 0,
+		keptPages:  0,
+		keptValues: 0,
+		writeData: func(w *parquet.Writer) { //nolint:all
+			require.NoError(t, w.Write(&String{"b"}))
+			require.NoError(t, w.Write(&String{"e"}))
+		},
+	})
+
+	var ss []string
+	for i := 0; i < 1000; i++ {
+		ss = append(ss, uuid.New().String())
+	}
+	p := NewStringInPredicate(ss).(*StringInPredicate)
+	for _, s := range ss {
+		require.True(t, p.KeepValue(parquet.ValueOf(s)), s)
+		require.True(t, p.KeepRange(parquet.ValueOf(s), parquet.ValueOf(s)), s)
+	}
+	for i := 0; i < 1000; i++ {
+		s := uuid.New().String()
+		require.False(t, p.KeepValue(parquet.ValueOf(s)), s)
+		require.False(t, p.KeepRange(parquet.ValueOf(s), parquet.ValueOf(s)), s)
+	}
+	require.True(t, p.KeepRange(parquet.ValueOf(""), parquet.ValueOf("\xff")))
+}
+
+func TestStringEqualPredicate(t *testing.T) {
+	type String struct {
+		S string `parquet:","`
+	}
+
+	testPredicate(t, predicateTestCase{
+		predicate:  NewStringEqualPredicate([]byte("b")),
+		keptChunks: 1,
+		keptPages:  1,
+		keptValues: 2,
+		writeData: func(w *parquet.Writer) { //nolint:all
+			require.NoError(t, w.Write(&String{"a"}))  // skipped
+			require.NoError(t, w.Write(&String{"b"}))  // kept
+			require.NoError(t, w.Write(&String{"bb"})) // skipped
+			require.NoError(t, w.Write(&String{"b"}))  // kept
+			require.NoError(t, w.Write(&String{"c"}))  // skipped
+		},
+	})
+
+	// The string isn't within the bounds of the column chunk
+	testPredicate(t, predicateTestCase{
+		predicate:  NewStringEqualPredicate([]byte("f")),
+		keptChunks: 0,
+		keptPages:  0,
+		keptValues: 0,
+		writeData: func(w *parquet.Writer) { //nolint:all
+			require.NoError(t, w.Write(&String{"b"}))
+			require.NoError(t, w.Write(&String{"e"}))
+		},
+	})
+
+	// matches the same values as a set of the single string
+	s := uuid.New().String()
+	eq := NewStringEqualPredicate([]byte(s))
+	in := NewStringInPredicate([]string{s}).(*StringInPredicate)
+	values := []string{s, "", s[:10], s + "x", uuid.New().String()}
+	for _, v := range values {
+		require.Equal(t, in.KeepValue(parquet.ValueOf(v)), eq.KeepValue(parquet.ValueOf(v)), v)
+		for _, max := range values {
+			require.Equal(t, in.KeepRange(parquet.ValueOf(v), parquet.ValueOf(max)), eq.KeepRange(parquet.ValueOf(v), parquet.ValueOf(max)), v, max)
+		}
+	}
+}
+
 type predicateTestCase struct {
 	writeData  func(w *parquet.Writer) //nolint:all
 	keptChunks int
@@ -115,3 +240,71 @@ func BenchmarkStringInPredicate(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkStringInPredicateManyValues(b *testing.B) {
+	ss := make([]string, 1000)
+	for i := range ss {
+		ss[i] = uuid.New().String()
+	}
+	p := NewStringInPredicate(ss)
+
+	// a large column where one in ten values is in the set
+	s := make([]parquet.Value, 100_000)
+	for i := range s {
+		if i%10 == 0 {
+			s[i] = parquet.ValueOf(ss[i%len(ss)])
+			continue
+		}
+		s[i] = parquet.ValueOf(uuid.New().String())
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, ss := range s {
+			p.KeepValue(ss)
+		}
+	}
+}
+
+func BenchmarkStringEqualPredicate(b *testing.B) {
+	type T struct {
+		ID []byte `parquet:","`
+	}
+
+	// a large row group of sorted trace IDs, like the trace ID column of a block
+	rows := make([]T, 100_000)
+	for i := range rows {
+		id := uuid.New()
+		rows[i] = T{ID: id[:]}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return bytes.Compare(rows[i].ID, rows[j].ID) < 0
+	})
+	pf := createFileWith(b, rows)
+	idx, _ := GetColumnIndexByPath(pf, "ID")
+	id := rows[len(rows)/2].ID
+
+	predicates := map[string]Predicate{
+		"equal": NewStringEqualPredicate(id),
+		"in":    NewStringInPredicate([]string{string(id)}),
+	}
+	for name, p := range predicates {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				iter := NewColumnIterator(context.TODO(), pf.RowGroups(), idx, "", 1000, p, "")
+				found := 0
+				for {
+					res, err := iter.Next()
+					require.NoError(b, err)
+					if res == nil {
+						break
+					}
+					found++
+				}
+				iter.Close()
+				require.Equal(b, 1, found)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package parquetquery
 import (
 	"bytes"
 	"regexp"
+	"sort"
 	"strings"
 
 	pq "github.com/segmentio/parquet-go"
@@ -16,34 +17,56 @@ type Predicate interface {
 	KeepValue(pq.Value) bool
 }
 
+// RangePredicate is implemented by predicates that can tell if any value within the
+// inclusive bounds [min, max] could be kept.
+type RangePredicate interface {
+	KeepRange(min, max pq.Value) bool
+}
+
 // StringInPredicate checks for any of the given strings.
 // Case sensitive exact byte matching
 type StringInPredicate struct {
+	// ss is sorted and deduplicated so bounds can be checked with a binary search
 	ss [][]byte
+	// set is used to match values, which is constant time regardless of the number of strings
+	set map[string]struct{}
 }
 
 var _ Predicate = (*StringInPredicate)(nil)
 
 func NewStringInPredicate(ss []string) Predicate {
 	p := &StringInPredicate{
-		ss: make([][]byte, len(ss)),
+		ss:  make([][]byte, 0, len(ss)),
+		set: make(map[string]struct{}, len(ss)),
 	}
-	for i := range ss {
-		p.ss[i] = []byte(ss[i])
+	for _, s := range ss {
+		if _, ok := p.set[s]; ok {
+			continue
+		}
+		p.set[s] = struct{}{}
+		p.ss = append(p.ss, []byte(s))
 	}
+	sort.Slice(p.ss, func(i, j int) bool {
+		return bytes.Compare(p.ss[i], p.ss[j]) < 0
+	})
 	return p
 }
 
+// inRange returns true if any of the strings is between min and max inclusive.
+func (p *StringInPredicate) inRange(min, max []byte) bool {
+	// the smallest string >= min
+	i := sort.Search(len(p.ss), func(i int) bool {
+		return bytes.Compare(p.ss[i], min) >= 0
+	})
+	return i < len(p.ss) && bytes.Compare(p.ss[i], max) <= 0
+}
+
 func (p *StringInPredicate) KeepColumnChunk(cc pq.ColumnChunk) bool {
 	if ci := cc.ColumnIndex(); ci != nil {
-
-		for _, subs := range p.ss {
-			for i := 0; i < ci.NumPages(); i++ {
-				ok := bytes.Compare(ci.MinValue(i).ByteArray(), subs) <= 0 && bytes.Compare(ci.MaxValue(i).ByteArray(), subs) >= 0
-				if ok {
-					// At least one page in this chunk matches
-					return true
-				}
+		for i := 0; i < ci.NumPages(); i++ {
+			if p.inRange(ci.MinValue(i).ByteArray(), ci.MaxValue(i).ByteArray()) {
+				// At least one page in this chunk matches
+				return true
 			}
 		}
 		return false
@@ -52,14 +75,13 @@ func (p *StringInPredicate) KeepColumnChunk(cc pq.ColumnChunk) bool {
 	return true
 }
 
+func (p *StringInPredicate) KeepRange(min, max pq.Value) bool {
+	return p.inRange(min.ByteArray(), max.ByteArray())
+}
+
 func (p *StringInPredicate) KeepValue(v pq.Value) bool {
-	ba := v.ByteArray()
-	for _, ss := range p.ss {
-		if bytes.Equal(ba, ss) {
-			return true
-		}
-	}
-	return false
+	_, ok := p.set[string(v.ByteArray())]
+	return ok
 }
 
 func (p *StringInPredicate) KeepPage(page pq.Page) bool {
@@ -72,21 +94,129 @@ func (p *StringInPredicate) KeepPage(page pq.Page) bool {
 		len := dict.Len()
 
 		for i := 0; i < len; i++ {
-			dictionaryEntry := dict.Index(int32(i)).ByteArray()
-			for _, subs := range p.ss {
-				if bytes.Equal(dictionaryEntry, subs) {
-					// At least 1 string present in this page
-					return true
-				}
+			if _, ok := p.set[string(dict.Index(int32(i)).ByteArray())]; ok {
+				// At least 1 string present in this page
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// StringEqualPredicate checks for a single string. Comparing the bytes is cheaper than the set lookup
+// of StringInPredicate, which matters for lookups of a single trace ID.
+// Case sensitive exact byte matching
+type StringEqualPredicate struct {
+	s []byte
+}
+
+var _ Predicate = (*StringEqualPredicate)(nil)
+
+func NewStringEqualPredicate(s []byte) *StringEqualPredicate {
+	return &StringEqualPredicate{
+		s: s,
+	}
+}
+
+// inRange returns true if the string is between min and max inclusive.
+func (p *StringEqualPredicate) inRange(min, max []byte) bool {
+	return bytes.Compare(min, p.s) <= 0 && bytes.Compare(p.s, max) <= 0
+}
+
+func (p *StringEqualPredicate) KeepColumnChunk(cc pq.ColumnChunk) bool {
+	if ci := cc.ColumnIndex(); ci != nil {
+		for i := 0; i < ci.NumPages(); i++ {
+			if p.inRange(ci.MinValue(i).ByteArray(), ci.MaxValue(i).ByteArray()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+func (p *StringEqualPredicate) KeepRange(min, max pq.Value) bool {
+	return p.inRange(min.ByteArray(), max.ByteArray())
+}
+
+func (p *StringEqualPredicate) KeepValue(v pq.Value) bool {
+	return bytes.Equal(v.ByteArray(), p.s)
+}
+
+func (p *StringEqualPredicate) KeepPage(page pq.Page) bool {
+	// If a dictionary column then ensure the value exists in the dictionary
+	dict := page.Dictionary()
+	if dict != nil && dict.Len() > 0 {
+		for i := 0; i < dict.Len(); i++ {
+			if bytes.Equal(dict.Index(int32(i)).ByteArray(), p.s) {
+				return true
 			}
 		}
+		return false
+	}
+
+	return true
+}
 
+// PrefixPredicate checks for byte arrays starting with the given prefix. Column chunks and
+// pages are skipped when their bounds can't contain a matching value.
+type PrefixPredicate struct {
+	prefix []byte
+}
+
+var _ Predicate = (*PrefixPredicate)(nil)
+
+func NewPrefixPredicate(prefix []byte) *PrefixPredicate {
+	return &PrefixPredicate{
+		prefix: prefix,
+	}
+}
+
+// inRange returns true if a value between min and max inclusive can have the prefix.
+func (p *PrefixPredicate) inRange(min, max []byte) bool {
+	return bytes.Compare(truncate(min, len(p.prefix)), p.prefix) <= 0 &&
+		bytes.Compare(truncate(max, len(p.prefix)), p.prefix) >= 0
+}
+
+func (p *PrefixPredicate) KeepRange(min, max pq.Value) bool {
+	return p.inRange(min.ByteArray(), max.ByteArray())
+}
+
+func (p *PrefixPredicate) KeepColumnChunk(cc pq.ColumnChunk) bool {
+	if ci := cc.ColumnIndex(); ci != nil {
+		for i := 0; i < ci.NumPages(); i++ {
+			if p.inRange(ci.MinValue(i).ByteArray(), ci.MaxValue(i).ByteArray()) {
+				return true
+			}
+		}
 		return false
 	}
 
 	return true
 }
 
+func (p *PrefixPredicate) KeepPage(page pq.Page) bool {
+	if min, max, ok := page.Bounds(); ok {
+		return p.inRange(min.ByteArray(), max.ByteArray())
+	}
+	return true
+}
+
+func (p *PrefixPredicate) KeepValue(v pq.Value) bool {
+	return bytes.HasPrefix(v.ByteArray(), p.prefix)
+}
+
+func truncate(b []byte, n int) []byte {
+	if len(b) > n {
+		return b[:n]
+	}
+	return b
+}
+
 // RegexInPredicate checks for match against any of the given regexs.
 // Memoized and resets on each row group.
 type RegexInPredicate struct {
@@ -129,7 +259,8 @@ func (p *RegexInPredicate) keep(v *pq.Value) bool {
 		}
 	}
 
-	p.matches[s] = matched
+	// s references the page, which is reused once released. The cache needs its own copy.
+	p.matches[strings.Clone(s)] = matched
 	return matched
 }
 
@@ -198,7 +329,7 @@ func (p *SubstringPredicate) KeepValue(v pq.Value) bool {
 	}
 
 	m := strings.Contains(vs, p.substring)
-	p.matches[vs] = m
+	p.matches[strings.Clone(vs)] = m
 	return m
 }
 
@@ -248,6 +379,10 @@ func (p *IntBetweenPredicate) KeepColumnChunk(c pq.ColumnChunk) bool {
 	return true
 }
 
+func (p *IntBetweenPredicate) KeepRange(min, max pq.Value) bool {
+	return p.max >= min.Int64() && p.min <= max.Int64()
+}
+
 func (p *IntBetweenPredicate) KeepValue(v pq.Value) bool {
 	vv := v.Int64()
 	return p.min <= vv && vv <= p.max
@@ -296,6 +431,13 @@ func (p *GenericPredicate[T]) KeepColumnChunk(c pq.ColumnChunk) bool {
 	return true
 }
 
+func (p *GenericPredicate[T]) KeepRange(min, max pq.Value) bool {
+	if p.RangeFn == nil {
+		return true
+	}
+	return p.RangeFn(p.Extract(min), p.Extract(max))
+}
+
 func (p *GenericPredicate[T]) KeepPage(page pq.Page) bool {
 
 	if p.RangeFn != nil {
@@ -374,6 +516,10 @@ func (p *FloatBetweenPredicate) KeepColumnChunk(c pq.ColumnChunk) bool {
 	return true
 }
 
+func (p *FloatBetweenPredicate) KeepRange(min, max pq.Value) bool {
+	return p.max >= min.Double() && p.min <= max.Double()
+}
+
 func (p *FloatBetweenPredicate) KeepValue(v pq.Value) bool {
 	vv := v.Double()
 	return p.min <= vv && vv <= p.max
@@ -1,13 +1,21 @@
 package parquetquery
 
 import (
+	"strconv"
 	"strings"
 
 	pq "github.com/segmentio/parquet-go"
+	"github.com/segmentio/parquet-go/format"
 )
 
+// GetColumnIndexByPath returns the index and depth of the column at the dotted path. Path
+// elements containing dots must be quoted, e.g. `Attrs."http.method"`. Returns -1, -1 if the
+// column doesn't exist.
 func GetColumnIndexByPath(pf *pq.File, s string) (index, depth int) {
-	colSelector := strings.Split(s, ".")
+	colSelector, ok := splitColumnPath(s)
+	if !ok {
+		return -1, -1
+	}
 	n := pf.Root()
 	for len(colSelector) > 0 {
 		n = n.Column(colSelector[0])
@@ -22,7 +30,125 @@ func GetColumnIndexByPath(pf *pq.File, s string) (index, depth int) {
 	return n.Index(), depth
 }
 
+// splitColumnPath splits the path on dots outside of quoted elements.
+func splitColumnPath(s string) ([]string, bool) {
+	if !strings.Contains(s, `"`) {
+		return strings.Split(s, "."), true
+	}
+
+	var elems []string
+	for len(s) > 0 {
+		if s[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return nil, false
+			}
+			elem, err := strconv.Unquote(quoted)
+			if err != nil {
+				return nil, false
+			}
+			elems = append(elems, elem)
+			s = s[len(quoted):]
+			if len(s) > 0 {
+				if s[0] != '.' {
+					return nil, false
+				}
+				s = s[1:]
+			}
+			continue
+		}
+
+		i := strings.IndexByte(s, '.')
+		if i == -1 {
+			elems = append(elems, s)
+			break
+		}
+		elems = append(elems, s[:i])
+		s = s[i+1:]
+	}
+
+	return elems, true
+}
+
 func HasColumn(pf *pq.File, s string) bool {
 	index, _ := GetColumnIndexByPath(pf, s)
 	return index >= 0
 }
+
+// FilterRowGroups returns the row groups of the file that may contain values of the column
+// matching the predicate. The bounds of each row group are taken from the column chunk statistics
+// or, if the writer didn't record them, from the page index. Row groups without bounds and
+// predicates that don't implement RangePredicate always keep the row group.
+func FilterRowGroups(pf *pq.File, colIndex int, pred Predicate) ([]pq.RowGroup, error) {
+	rowGroups := pf.RowGroups()
+
+	rp, ok := pred.(RangePredicate)
+	if !ok || len(rowGroups) == 0 {
+		return rowGroups, nil
+	}
+
+	// The page index is only read if a column chunk is missing statistics
+	columnIndexes := pf.ColumnIndexes()
+	readPageIndex := len(columnIndexes) == 0
+
+	metadata := pf.Metadata()
+	numColumns := len(metadata.RowGroups[0].Columns)
+
+	var kept []pq.RowGroup
+	for i, rg := range rowGroups {
+		typ := rg.ColumnChunks()[colIndex].Type()
+
+		min, max, ok := statisticsBounds(typ, metadata.RowGroups[i].Columns[colIndex].MetaData.Statistics)
+		if !ok {
+			if readPageIndex {
+				var err error
+				columnIndexes, _, err = pf.ReadPageIndex()
+				if err != nil {
+					return nil, err
+				}
+				readPageIndex = false
+			}
+
+			if idx := i*numColumns + colIndex; idx < len(columnIndexes) {
+				min, max, ok = columnIndexBounds(typ, columnIndexes[idx])
+			}
+		}
+
+		if !ok || rp.KeepRange(min, max) {
+			kept = append(kept, rg)
+		}
+	}
+
+	return kept, nil
+}
+
+func statisticsBounds(typ pq.Type, stats format.Statistics) (min, max pq.Value, ok bool) {
+	if len(stats.MinValue) == 0 || len(stats.MaxValue) == 0 {
+		return pq.Value{}, pq.Value{}, false
+	}
+	kind := typ.Kind()
+	return kind.Value(stats.MinValue), kind.Value(stats.MaxValue), true
+}
+
+// columnIndexBounds returns the min and max over all pages that have values.
+func columnIndexBounds(typ pq.Type, ci format.ColumnIndex) (min, max pq.Value, ok bool) {
+	kind := typ.Kind()
+	for i := range ci.NullPages {
+		if ci.NullPages[i] || i >= len(ci.MinValues) || i >= len(ci.MaxValues) {
+			continue
+		}
+
+		pageMin, pageMax := kind.Value(ci.MinValues[i]), kind.Value(ci.MaxValues[i])
+		if !ok {
+			min, max, ok = pageMin, pageMax, true
+			continue
+		}
+		if typ.Compare(pageMin, min) < 0 {
+			min = pageMin
+		}
+		if typ.Compare(pageMax, max) > 0 {
+			max = pageMax
+		}
+	}
+	return min, max, ok
+}
@@ -0,0 +1,109 @@
+package parquetquery
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/segmentio/parquet-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetColumnIndexByPath(t *testing.T) {
+	type attrs struct {
+		Key        string
+		HTTPMethod string `parquet:"http.method"`
+	}
+	type row struct {
+		ID    int64
+		Attrs attrs
+	}
+
+	buf := new(bytes.Buffer)
+	w := parquet.NewWriter(buf)
+	require.NoError(t, w.Write(&row{}))
+	require.NoError(t, w.Close())
+
+	pf, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	tcs := []struct {
+		path         string
+		index, depth int
+	}{
+		{path: "ID", index: 0, depth: 1},
+		{path: "Attrs.Key", index: 1, depth: 2},
+		{path: `Attrs."http.method"`, index: 2, depth: 2},
+		{path: `"Attrs"."http.method"`, index: 2, depth: 2},
+		{path: "Attrs.http.method", index: -1, depth: -1},
+		{path: `Attrs."http.method`, index: -1, depth: -1},
+		{path: `Attrs."http".method`, index: -1, depth: -1},
+		{path: "Missing", index: -1, depth: -1},
+	}
+
+	for _, tc := range tcs {
+		index, depth := GetColumnIndexByPath(pf, tc.path)
+		require.Equal(t, tc.index, index, tc.path)
+		require.Equal(t, tc.depth, depth, tc.path)
+	}
+}
+
+func TestFilterRowGroups(t *testing.T) {
+	type row struct {
+		Duration int64
+	}
+
+	// Two row groups with durations [1, 3] and [10, 12]
+	buf := new(bytes.Buffer)
+	w := parquet.NewWriter(buf)
+	for _, d := range []int64{1, 2, 3} {
+		require.NoError(t, w.Write(&row{d}))
+	}
+	require.NoError(t, w.Flush())
+	for _, d := range []int64{10, 11, 12} {
+		require.NoError(t, w.Write(&row{d}))
+	}
+	require.NoError(t, w.Close())
+
+	for _, skipPageIndex := range []bool{false, true} {
+		pf, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()), parquet.SkipPageIndex(skipPageIndex))
+		require.NoError(t, err)
+		require.Len(t, pf.RowGroups(), 2)
+
+		tcs := []struct {
+			name     string
+			pred     Predicate
+			expected []parquet.RowGroup
+		}{
+			{
+				name:     "duration > 5",
+				pred:     NewIntPredicate(func(v int64) bool { return v > 5 }, func(min, max int64) bool { return max > 5 }),
+				expected: pf.RowGroups()[1:],
+			},
+			{
+				name:     "duration between 2 and 4",
+				pred:     NewIntBetweenPredicate(2, 4),
+				expected: pf.RowGroups()[:1],
+			},
+			{
+				name: "duration between 5 and 8",
+				pred: NewIntBetweenPredicate(5, 8),
+			},
+			{
+				name:     "no range function",
+				pred:     NewIntPredicate(func(v int64) bool { return v > 5 }, nil),
+				expected: pf.RowGroups(),
+			},
+			{
+				name:     "not a range predicate",
+				pred:     NewSubstringPredicate("x"),
+				expected: pf.RowGroups(),
+			},
+		}
+
+		for _, tc := range tcs {
+			actual, err := FilterRowGroups(pf, 0, tc.pred)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual, "%s skipPageIndex=%v", tc.name, skipPageIndex)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package traceql
+
+import "fmt"
+
+// Warning describes a part of a valid query that is likely a mistake.
+type Warning struct {
+	Element Element
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.Message + ": " + w.Element.String()
+}
+
+// Analyze looks for valid but suspicious parts of the query, e.g. spanset filters that can never
+// match. Unlike validation this is optional and the warnings never make the query fail.
+func Analyze(root *RootExpr) []Warning {
+	var warnings []Warning
+
+	Walk(root, func(e Element) bool {
+		f, ok := e.(SpansetFilter)
+		if !ok {
+			return true
+		}
+
+		if msg, contradicts := contradiction(conjuncts(f.Expression, nil)); contradicts {
+			warnings = append(warnings, Warning{Element: f, Message: msg})
+		}
+		return true
+	})
+
+	return warnings
+}
+
+// literalConstraint is a comparison of an attribute with a static, normalized so the attribute is
+// on the left.
+type literalConstraint struct {
+	op    Operator
+	value Static
+}
+
+// conjuncts appends all expressions that must be true for e to be true.
+func conjuncts(e FieldExpression, out []FieldExpression) []FieldExpression {
+	if o, ok := e.(BinaryOperation); ok && o.Op == OpAnd {
+		out = conjuncts(o.LHS, out)
+		return conjuncts(o.RHS, out)
+	}
+	return append(out, e)
+}
+
+// contradiction checks if there is an attribute with literal constraints that can't all be true.
+func contradiction(exprs []FieldExpression) (string, bool) {
+	constraints := map[Attribute][]literalConstraint{}
+	var attributes []Attribute
+
+	for _, e := range exprs {
+		a, c, ok := asLiteralConstraint(e)
+		if !ok {
+			continue
+		}
+		if _, seen := constraints[a]; !seen {
+			attributes = append(attributes, a)
+		}
+		constraints[a] = append(constraints[a], c)
+	}
+
+	for _, a := range attributes {
+		cs := constraints[a]
+		for i := range cs {
+			for j := i + 1; j < len(cs); j++ {
+				if !satisfiable(cs[i], cs[j]) {
+					return fmt.Sprintf("%s can't be %s %s and %s %s at the same time", a, cs[i].op, cs[i].value, cs[j].op, cs[j].value), true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+func asLiteralConstraint(e FieldExpression) (Attribute, literalConstraint, bool) {
+	o, ok := e.(BinaryOperation)
+	if !ok {
+		return Attribute{}, literalConstraint{}, false
+	}
+
+	switch o.Op {
+	case OpEqual, OpNotEqual, OpGreater, OpGreaterEqual, OpLess, OpLessEqual:
+	default:
+		return Attribute{}, literalConstraint{}, false
+	}
+
+	if a, ok := o.LHS.(Attribute); ok {
+		if s, ok := o.RHS.(Static); ok {
+			return a, literalConstraint{op: o.Op, value: s}, true
+		}
+	}
+	if s, ok := o.LHS.(Static); ok {
+		if a, ok := o.RHS.(Attribute); ok {
+			return a, literalConstraint{op: flipComparison(o.Op), value: s}, true
+		}
+	}
+
+	return Attribute{}, literalConstraint{}, false
+}
+
+// satisfiable returns false only if it is certain that no value meets both constraints. Values
+// that can't be compared are assumed to be satisfiable.
+func satisfiable(a, b literalConstraint) bool {
+	if a.op != OpEqual && b.op == OpEqual {
+		a, b = b, a
+	}
+
+	// x = v and x op w is satisfiable if v op w
+	if a.op == OpEqual {
+		return holds(b.op, a.value, b.value)
+	}
+
+	lower, upper := a, b
+	if isUpperBound(lower.op) {
+		lower, upper = upper, lower
+	}
+	if !isLowerBound(lower.op) || !isUpperBound(upper.op) {
+		return true
+	}
+
+	// lower bound and upper bound must leave a value between them
+	cmp, err := lower.value.Compare(upper.value)
+	if err != nil {
+		return true
+	}
+	if cmp < 0 {
+		return true
+	}
+	return cmp == 0 && lower.op == OpGreaterEqual && upper.op == OpLessEqual
+}
+
+// holds evaluates v op w. Returns true if the values can't be compared.
+func holds(op Operator, v, w Static) bool {
+	switch op {
+	case OpEqual:
+		if !v.Type.isMatchingOperand(w.Type) {
+			return true
+		}
+		return v.Equals(w)
+	case OpNotEqual:
+		if !v.Type.isMatchingOperand(w.Type) {
+			return true
+		}
+		return !v.Equals(w)
+	}
+
+	cmp, err := v.Compare(w)
+	if err != nil {
+		return true
+	}
+
+	switch op {
+	case OpGreater:
+		return cmp > 0
+	case OpGreaterEqual:
+		return cmp >= 0
+	case OpLess:
+		return cmp < 0
+	case OpLessEqual:
+		return cmp <= 0
+	}
+	return true
+}
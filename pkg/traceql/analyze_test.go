@@ -0,0 +1,55 @@
+package traceql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyze(t *testing.T) {
+	contradictions := []struct {
+		query   string
+		message string
+	}{
+		{`{ span.x = 1 && span.x = 2 }`, "span.x can't be = 1 and = 2 at the same time"},
+		{`{ .x = "a" && .y = 1 && .x = "b" }`, ".x can't be = `a` and = `b` at the same time"},
+		{`{ .x = 1 && .x != 1 }`, ".x can't be = 1 and != 1 at the same time"},
+		{`{ duration > 2s && duration < 1s }`, "duration can't be > 2s and < 1s at the same time"},
+		{`{ duration > 1s && duration <= 1s }`, "duration can't be > 1s and <= 1s at the same time"},
+		{`{ .x = 5 && 10 < .x }`, ".x can't be = 5 and > 10 at the same time"},
+		{`{ status = error && status = ok }`, "status can't be = error and = ok at the same time"},
+		{`{ true } | { .x = 1 && (.x = 2 && .y = 3) }`, ".x can't be = 1 and = 2 at the same time"},
+	}
+
+	for _, tc := range contradictions {
+		t.Run(tc.query, func(t *testing.T) {
+			expr, err := Parse(tc.query)
+			require.NoError(t, err)
+			require.NoError(t, expr.validate())
+
+			warnings := Analyze(expr)
+			require.Len(t, warnings, 1)
+			require.Equal(t, tc.message, warnings[0].Message)
+		})
+	}
+
+	satisfiable := []string{
+		`{ span.x = 1 && span.y = 2 }`,
+		`{ span.x = 1 && resource.x = 2 }`,
+		`{ .x = 1 || .x = 2 }`,
+		`{ .x = 1 && .x = 1 }`,
+		`{ .x > 1 && .x < 2 }`,
+		`{ .x >= 1 && .x <= 1 }`,
+		`{ .x = 1 && .x != 2 }`,
+		`{ .x = 1 } && { .x = 2 }`,
+		`{ .x = 1 && .x + 1 = 3 }`,
+	}
+
+	for _, q := range satisfiable {
+		t.Run(q, func(t *testing.T) {
+			expr, err := Parse(q)
+			require.NoError(t, err)
+			require.Empty(t, Analyze(expr))
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package traceql
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var errInvalidArray = errors.New("invalid array encoding")
+
+// NewStaticArray creates a static holding the elements of an array attribute. The elements are
+// encoded into S so the static stays comparable, two arrays are equal if their elements are. Each
+// element is written as its type followed by its value, see appendElement.
+func NewStaticArray(elements []Static) Static {
+	var b []byte
+	for _, e := range elements {
+		b = appendElement(b, e)
+	}
+
+	return Static{
+		Type: TypeArray,
+		S:    string(b),
+	}
+}
+
+// Elements decodes the elements of an array static. It returns nil for any other type. Evaluation
+// decodes arrays through evalContext.arrayElements, which decodes each array once per span.
+func (s Static) Elements() ([]Static, error) {
+	if s.Type != TypeArray {
+		return nil, nil
+	}
+
+	var elements []Static
+	for rest := s.S; len(rest) > 0; {
+		e, n, err := decodeElement(rest)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, e)
+		rest = rest[n:]
+	}
+	return elements, nil
+}
+
+func appendElement(b []byte, e Static) []byte {
+	b = append(b, byte(e.Type))
+	switch e.Type {
+	case TypeInt, TypeTimestamp:
+		b = binary.AppendVarint(b, int64(e.N))
+	case TypeFloat:
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(e.F))
+	case TypeString, TypeArray:
+		b = binary.AppendVarint(b, int64(len(e.S)))
+		b = append(b, e.S...)
+	case TypeBoolean:
+		if e.B {
+			b = append(b, 1)
+		} else {
+			b = append(b, 0)
+		}
+	case TypeDuration:
+		b = binary.AppendVarint(b, int64(e.D))
+	case TypeStatus:
+		b = binary.AppendVarint(b, int64(e.Status))
+	}
+	return b
+}
+
+// decodeElement decodes the first element of the encoding and returns it with the number of bytes
+// it takes up. Strings share the memory of the encoding.
+func decodeElement(s string) (Static, int, error) {
+	if len(s) == 0 {
+		return NewStaticNil(), 0, errInvalidArray
+	}
+	t := StaticType(s[0])
+	n := 1
+
+	next := func() (int64, error) {
+		v, l := readVarint(s[n:])
+		if l <= 0 {
+			return 0, errInvalidArray
+		}
+		n += l
+		return v, nil
+	}
+
+	switch t {
+	case TypeNil:
+		return NewStaticNil(), n, nil
+	case TypeInt, TypeTimestamp, TypeDuration, TypeStatus:
+		v, err := next()
+		if err != nil {
+			return NewStaticNil(), 0, err
+		}
+		switch t {
+		case TypeDuration:
+			return NewStaticDuration(time.Duration(v)), n, nil
+		case TypeStatus:
+			return NewStaticStatus(Status(v)), n, nil
+		}
+		return Static{Type: t, N: int(v)}, n, nil
+	case TypeFloat:
+		if len(s) < n+8 {
+			return NewStaticNil(), 0, errInvalidArray
+		}
+		bits := uint64(0)
+		for i := 7; i >= 0; i-- {
+			bits = bits<<8 | uint64(s[n+i])
+		}
+		return NewStaticFloat(math.Float64frombits(bits)), n + 8, nil
+	case TypeString, TypeArray:
+		l, err := next()
+		if err != nil {
+			return NewStaticNil(), 0, err
+		}
+		if l < 0 || int64(len(s)-n) < l {
+			return NewStaticNil(), 0, errInvalidArray
+		}
+		return Static{Type: t, S: s[n : n+int(l)]}, n + int(l), nil
+	case TypeBoolean:
+		if len(s) < n+1 {
+			return NewStaticNil(), 0, errInvalidArray
+		}
+		return NewStaticBool(s[n] == 1), n + 1, nil
+	}
+
+	return NewStaticNil(), 0, fmt.Errorf("%w: element of type %d", errInvalidArray, t)
+}
+
+// readVarint is binary.Varint for strings, so decoding doesn't copy the encoding.
+func readVarint(s string) (int64, int) {
+	var ux uint64
+	for i := 0; i < len(s) && i < binary.MaxVarintLen64; i++ {
+		b := s[i]
+		if b < 0x80 {
+			if i == binary.MaxVarintLen64-1 && b > 1 {
+				return 0, 0
+			}
+			ux |= uint64(b) << (7 * i)
+			x := int64(ux >> 1)
+			if ux&1 != 0 {
+				x = ^x
+			}
+			return x, i + 1
+		}
+		ux |= uint64(b&0x7f) << (7 * i)
+	}
+	return 0, 0
+}
@@ -1,7 +1,9 @@
 package traceql
 
 import (
+	"container/heap"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -12,7 +14,7 @@ type Element interface {
 
 type pipelineElement interface {
 	Element
-	evaluate([]Spanset) ([]Spanset, error)
+	evaluate(*evalContext, []Spanset) ([]Spanset, error)
 }
 
 type typedExpression interface {
@@ -34,6 +36,31 @@ func newRootExpr(e pipelineElement) *RootExpr {
 	}
 }
 
+// RequiresTraceContext reports whether the query depends on spans of the trace other than the ones
+// it filters on, like the parent or the children of a span. If it doesn't, the fetch layer can read
+// the matching spans only. It's a hint for optimizations and errs on the side of true.
+func (r *RootExpr) RequiresTraceContext() bool {
+	requires := false
+	Walk(r, func(e Element) bool {
+		switch e := e.(type) {
+		case Attribute:
+			requires = e.Parent || traceContextIntrinsics[e.Intrinsic]
+		case SpansetOperation:
+			// structural operators relate the spans of both sides through the spans of the trace
+			requires = e.Op != OpSpansetAnd && e.Op != OpSpansetUnion
+		}
+		return !requires
+	})
+	return requires
+}
+
+// traceContextIntrinsics are the intrinsics derived from other spans of the trace.
+var traceContextIntrinsics = map[Intrinsic]bool{
+	IntrinsicParent:     true,
+	IntrinsicChildCount: true,
+	IntrinsicSelfTime:   true,
+}
+
 // **********************
 // Pipeline
 // **********************
@@ -65,28 +92,37 @@ func (p Pipeline) impliedType() StaticType {
 	}
 
 	finalItem := p.Elements[len(p.Elements)-1]
-	aggregate, ok := finalItem.(Aggregate)
-	if ok {
-		return aggregate.impliedType()
+	switch e := finalItem.(type) {
+	case Aggregate:
+		return e.impliedType()
+	case ScalarOperation:
+		return e.impliedType()
 	}
 
 	return TypeSpanset
 }
 
-func (p Pipeline) evaluate(input []Spanset) (result []Spanset, err error) {
+func (p Pipeline) evaluate(ec *evalContext, input []Spanset) (result []Spanset, err error) {
 	result = input
+	stages := ec.startStages()
 
 	for _, element := range p.Elements {
-		result, err = element.evaluate(result)
+		finish := ec.startSpan(element)
+		result, err = element.evaluate(ec, result)
+		finish()
+		stages.evaluated(element)
 		if err != nil {
+			stages.finish()
 			return nil, err
 		}
 
 		if len(result) == 0 {
+			stages.discard()
 			return []Spanset{}, nil
 		}
 	}
 
+	stages.finish()
 	return result, nil
 }
 
@@ -100,8 +136,51 @@ func newGroupOperation(e FieldExpression) GroupOperation {
 	}
 }
 
-func (GroupOperation) evaluate(ss []Spanset) ([]Spanset, error) {
-	return ss, nil
+// evaluate splits every spanset into one spanset per distinct value of the expression, in the
+// order the values first appear. Spans without a value are grouped under nil and spansets without
+// spans are dropped.
+func (o GroupOperation) evaluate(ec *evalContext, ss []Spanset) ([]Spanset, error) {
+	output := make([]Spanset, 0, len(ss))
+	opts := ec.options()
+
+	for _, s := range ss {
+		ec := ec.forSpanset(s)
+
+		groups := map[Static]int{}
+		for _, span := range s.Spans {
+			if err := ec.nextSpan(); err != nil {
+				return nil, err
+			}
+
+			v, err := o.Expression.execute(ec, span)
+			if err != nil {
+				return nil, err
+			}
+
+			i, ok := groups[v]
+			if !ok {
+				// the limit is checked before a group is created, so it also bounds the memory
+				if opts.MaxGroups > 0 && len(output) >= opts.MaxGroups {
+					if opts.GroupLimitPolicy == GroupLimitDrop {
+						continue
+					}
+					return nil, fmt.Errorf("%w: %s created more than %d groups", ErrTooManyGroups, o.String(), opts.MaxGroups)
+				}
+
+				i = len(output)
+				groups[v] = i
+
+				group := s
+				group.Spans = nil
+				group.group = v
+				group.grouped = true
+				output = append(output, group)
+			}
+			output[i].Spans = append(output[i].Spans, span)
+		}
+	}
+
+	return output, nil
 }
 
 type CoalesceOperation struct {
@@ -111,8 +190,304 @@ func newCoalesceOperation() CoalesceOperation {
 	return CoalesceOperation{}
 }
 
-func (CoalesceOperation) evaluate(ss []Spanset) ([]Spanset, error) {
-	return ss, nil
+// evaluate merges the spansets of each trace into one spanset that contains every span once, which
+// undoes by() and other elements that split spansets. The spansets are returned in the order their
+// traces first appear and the trace level fields are taken from the first spanset of a trace.
+func (CoalesceOperation) evaluate(_ *evalContext, ss []Spanset) ([]Spanset, error) {
+	var output []Spanset
+	traces := map[string]int{}
+	seen := map[string]map[string]struct{}{}
+
+	for _, s := range ss {
+		id := string(s.TraceID)
+		i, ok := traces[id]
+		if !ok {
+			i = len(output)
+			traces[id] = i
+			seen[id] = map[string]struct{}{}
+
+			merged := s
+			merged.Spans = nil
+			merged.group, merged.grouped = Static{}, false
+			output = append(output, merged)
+		}
+
+		output[i].Spans = appendSpans(output[i].Spans, seen[id], []Spanset{s})
+		output[i].Truncated = output[i].Truncated || s.Truncated
+	}
+
+	return output, nil
+}
+
+// FlattenOperation merges all spansets into a single spanset that contains every span once. The
+// trace level fields are taken from the first spanset.
+type FlattenOperation struct {
+}
+
+func newFlattenOperation() FlattenOperation {
+	return FlattenOperation{}
+}
+
+func (FlattenOperation) impliedType() StaticType {
+	return TypeSpanset
+}
+
+func (FlattenOperation) evaluate(_ *evalContext, ss []Spanset) ([]Spanset, error) {
+	if len(ss) == 0 {
+		return ss, nil
+	}
+
+	flattened := ss[0]
+	flattened.Spans = appendSpans(nil, map[string]struct{}{}, ss)
+	flattened.group, flattened.grouped = Static{}, false
+	for _, s := range ss {
+		flattened.Truncated = flattened.Truncated || s.Truncated
+	}
+	return []Spanset{flattened}, nil
+}
+
+// SelectOperation evaluates its expressions for every span and attaches the results to the span.
+// An attribute is attached under its own key, a computed expression under an unscoped attribute
+// named after the expression.
+type SelectOperation struct {
+	Exprs []FieldExpression
+}
+
+func newSelectOperation(exprs []FieldExpression) SelectOperation {
+	return SelectOperation{
+		Exprs: exprs,
+	}
+}
+
+func (SelectOperation) impliedType() StaticType {
+	return TypeSpanset
+}
+
+func (o SelectOperation) evaluate(ec *evalContext, ss []Spanset) ([]Spanset, error) {
+	output := make([]Spanset, 0, len(ss))
+
+	for _, s := range ss {
+		ec := ec.forSpanset(s)
+
+		spans := make([]Span, 0, len(s.Spans))
+		for _, span := range s.Spans {
+			if err := ec.nextSpan(); err != nil {
+				return nil, err
+			}
+
+			// Spans can be shared between spansets, don't modify the input attributes
+			attributes := make(map[Attribute]Static, len(span.Attributes)+len(o.Exprs))
+			for a, v := range span.Attributes {
+				attributes[a] = v
+			}
+			for _, e := range o.Exprs {
+				v, err := e.execute(ec, span)
+				if err != nil {
+					return nil, err
+				}
+				attributes[selectedAttribute(e)] = v
+			}
+
+			span.Attributes = attributes
+			spans = append(spans, span)
+		}
+
+		s.Spans = spans
+		output = append(output, s)
+	}
+
+	return output, nil
+}
+
+// DistinctOperation keeps one span per distinct value of its expression within each spanset. The
+// span that started first is kept and the spans stay in their original order. Spans without a
+// value for the expression share the nil value.
+type DistinctOperation struct {
+	Expression FieldExpression
+}
+
+func newDistinctOperation(e FieldExpression) DistinctOperation {
+	return DistinctOperation{
+		Expression: e,
+	}
+}
+
+func (DistinctOperation) impliedType() StaticType {
+	return TypeSpanset
+}
+
+func (o DistinctOperation) evaluate(ec *evalContext, ss []Spanset) ([]Spanset, error) {
+	output := make([]Spanset, 0, len(ss))
+
+	for _, s := range ss {
+		ec := ec.forSpanset(s)
+
+		// index of the representative in spans for every value
+		kept := map[Static]int{}
+		spans := make([]Span, 0, len(s.Spans))
+		for _, span := range s.Spans {
+			if err := ec.nextSpan(); err != nil {
+				return nil, err
+			}
+
+			v, err := o.Expression.execute(ec, span)
+			if err != nil {
+				return nil, err
+			}
+
+			i, ok := kept[v]
+			if !ok {
+				kept[v] = len(spans)
+				spans = append(spans, span)
+				continue
+			}
+			if span.StartTimeUnixNanos < spans[i].StartTimeUnixNanos {
+				spans[i] = span
+			}
+		}
+
+		s.Spans = spans
+		output = append(output, s)
+	}
+
+	return output, nil
+}
+
+// TopKOperation keeps the K spansets with the largest value of its expression, largest first. The
+// value of a spanset is the largest value of the expression over its spans, like max() computes
+// it. Spansets without a value rank below all others and ties keep the order of the input.
+type TopKOperation struct {
+	Expression FieldExpression
+	K          int
+}
+
+func newTopKOperation(e FieldExpression, k int) TopKOperation {
+	return TopKOperation{
+		Expression: e,
+		K:          k,
+	}
+}
+
+func (TopKOperation) impliedType() StaticType {
+	return TypeSpanset
+}
+
+// evaluate only holds the K best spansets seen so far in a heap instead of sorting the input.
+func (o TopKOperation) evaluate(ec *evalContext, ss []Spanset) ([]Spanset, error) {
+	value := newAggregate(aggregateMax, o.Expression)
+
+	h := make(topKHeap, 0, o.K)
+	for i, s := range ss {
+		v, err := value.compute(ec.forSpanset(s), s)
+		if err != nil {
+			return nil, err
+		}
+
+		r := rankedSpanset{spanset: s, value: v, position: i}
+		if len(h) < o.K {
+			heap.Push(&h, r)
+			continue
+		}
+		if len(h) > 0 && h[0].below(r) {
+			h[0] = r
+			heap.Fix(&h, 0)
+		}
+	}
+
+	output := make([]Spanset, len(h))
+	for i := len(output) - 1; i >= 0; i-- {
+		output[i] = heap.Pop(&h).(rankedSpanset).spanset
+	}
+	return output, nil
+}
+
+type rankedSpanset struct {
+	spanset  Spanset
+	value    Static
+	position int
+}
+
+// below reports whether r ranks below other. Values are numeric or nil, so they always compare.
+func (r rankedSpanset) below(other rankedSpanset) bool {
+	if r.value.Type == TypeNil || other.value.Type == TypeNil {
+		if r.value.Type != other.value.Type {
+			return r.value.Type == TypeNil
+		}
+		return r.position > other.position
+	}
+
+	if c, _ := r.value.Compare(other.value); c != 0 {
+		return c < 0
+	}
+	return r.position > other.position
+}
+
+// topKHeap is a min-heap of spansets, the top is the spanset that ranks lowest.
+type topKHeap []rankedSpanset
+
+func (h topKHeap) Len() int           { return len(h) }
+func (h topKHeap) Less(i, j int) bool { return h[i].below(h[j]) }
+func (h topKHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *topKHeap) Push(x interface{}) {
+	*h = append(*h, x.(rankedSpanset))
+}
+
+func (h *topKHeap) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// WithOperation binds the value of an aggregate over each spanset to a name. Subsequent elements of
+// the pipeline can use the value in field expressions with a Reference. Binding a name again
+// shadows the earlier value.
+type WithOperation struct {
+	Name  string
+	Value Aggregate
+}
+
+func newWithOperation(name string, value Aggregate) WithOperation {
+	return WithOperation{
+		Name:  name,
+		Value: value,
+	}
+}
+
+func (WithOperation) impliedType() StaticType {
+	return TypeSpanset
+}
+
+func (o WithOperation) evaluate(ec *evalContext, ss []Spanset) ([]Spanset, error) {
+	output := make([]Spanset, 0, len(ss))
+
+	for _, s := range ss {
+		v, err := o.Value.compute(ec.forSpanset(s), s)
+		if err != nil {
+			return nil, err
+		}
+
+		// Spansets share the bindings of the spanset they were derived from, don't modify them
+		bindings := make(map[string]Static, len(s.bindings)+1)
+		for name, v := range s.bindings {
+			bindings[name] = v
+		}
+		bindings[o.Name] = v
+
+		s.bindings = bindings
+		output = append(output, s)
+	}
+
+	return output, nil
+}
+
+// selectedAttribute returns the key the result of a selected expression is attached under.
+func selectedAttribute(e FieldExpression) Attribute {
+	if a, ok := e.(Attribute); ok {
+		return a
+	}
+	return NewAttribute(e.String())
 }
 
 // **********************
@@ -157,6 +532,10 @@ func (o ScalarOperation) impliedType() StaticType {
 	return o.RHS.impliedType()
 }
 
+func (ScalarOperation) evaluate(_ *evalContext, ss []Spanset) ([]Spanset, error) {
+	return ss, nil
+}
+
 type Aggregate struct {
 	agg AggregateOp
 	e   FieldExpression
@@ -173,14 +552,14 @@ func newAggregate(agg AggregateOp, e FieldExpression) Aggregate {
 func (Aggregate) __scalarExpression() {}
 
 func (a Aggregate) impliedType() StaticType {
-	if a.agg == aggregateCount || a.e == nil {
+	if a.agg == aggregateCount || a.agg == aggregateCountDistinct || a.e == nil {
 		return TypeInt
 	}
 
 	return a.e.impliedType()
 }
 
-func (Aggregate) evaluate(ss []Spanset) ([]Spanset, error) {
+func (Aggregate) evaluate(_ *evalContext, ss []Spanset) ([]Spanset, error) {
 	return ss, nil
 }
 
@@ -222,7 +601,7 @@ func newSpansetFilter(e FieldExpression) SpansetFilter {
 // nolint: revive
 func (SpansetFilter) __spansetExpression() {}
 
-func (f SpansetFilter) evaluate(input []Spanset) ([]Spanset, error) {
+func (f SpansetFilter) evaluate(ec *evalContext, input []Spanset) ([]Spanset, error) {
 	var output []Spanset
 
 	for _, ss := range input {
@@ -230,9 +609,15 @@ func (f SpansetFilter) evaluate(input []Spanset) ([]Spanset, error) {
 			continue
 		}
 
-		var matchingSpans []Span
+		ec := ec.forSpanset(ss)
+
+		matchingSpans := ec.getSpans()
 		for _, s := range ss.Spans {
-			result, err := f.Expression.execute(s)
+			if err := ec.nextSpan(); err != nil {
+				return nil, err
+			}
+
+			result, err := f.Expression.execute(ec, s)
 			if err != nil {
 				return nil, err
 			}
@@ -249,8 +634,10 @@ func (f SpansetFilter) evaluate(input []Spanset) ([]Spanset, error) {
 		}
 
 		if len(matchingSpans) == 0 {
+			ec.putSpans(matchingSpans)
 			continue
 		}
+		ec.keepSpans(matchingSpans)
 
 		matchingSpanset := ss
 		matchingSpanset.Spans = matchingSpans
@@ -277,8 +664,38 @@ func newScalarFilter(op Operator, lhs ScalarExpression, rhs ScalarExpression) Sc
 // nolint: revive
 func (ScalarFilter) __spansetExpression() {}
 
-func (ScalarFilter) evaluate(ss []Spanset) ([]Spanset, error) {
-	return ss, nil
+// evaluate keeps the spansets for which the comparison is true. Both sides are computed over the
+// spans of each spanset on its own, so after coalesce() aggregates are computed per trace and
+// after by() per group.
+func (f ScalarFilter) evaluate(ec *evalContext, ss []Spanset) ([]Spanset, error) {
+	var output []Spanset
+
+	for _, s := range ss {
+		ec := ec.forSpanset(s)
+		input := []Spanset{s}
+
+		lhs, err := evaluateScalar(ec, f.lhs, input)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := evaluateScalar(ec, f.rhs, input)
+		if err != nil {
+			return nil, err
+		}
+
+		result, ok := compareWithNil(f.op, f.lhs, f.rhs, lhs, rhs)
+		if !ok {
+			result, err = binaryOperation(ec, f.op, lhs, rhs)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if result.Type == TypeBoolean && result.B {
+			output = append(output, s)
+		}
+	}
+
+	return output, nil
 }
 
 // **********************
@@ -293,7 +710,7 @@ type FieldExpression interface {
 	__fieldExpression()
 
 	extractConditions(request *FetchSpansRequest)
-	execute(span Span) (Static, error)
+	execute(ec *evalContext, span Span) (Static, error)
 }
 
 type BinaryOperation struct {
@@ -317,6 +734,9 @@ func (o BinaryOperation) impliedType() StaticType {
 	if o.Op.isBoolean() {
 		return TypeBoolean
 	}
+	if o.Op.isBitwise() {
+		return TypeInt
+	}
 
 	// remaining operators will be based on the operands
 	// opAdd, opSub, opDiv, opMod, opMult
@@ -356,6 +776,139 @@ func (o UnaryOperation) referencesSpan() bool {
 	return o.Expression.referencesSpan()
 }
 
+// SetOperation checks the value of Expression for membership in Values. Op is either OpIn or
+// OpNotIn.
+type SetOperation struct {
+	Op         Operator
+	Expression FieldExpression
+	Values     []Static
+}
+
+func newSetOperation(op Operator, e FieldExpression, values []Static) SetOperation {
+	return SetOperation{
+		Op:         op,
+		Expression: e,
+		Values:     values,
+	}
+}
+
+// nolint: revive
+func (SetOperation) __fieldExpression() {}
+
+func (SetOperation) impliedType() StaticType {
+	return TypeBoolean
+}
+
+func (o SetOperation) referencesSpan() bool {
+	return o.Expression.referencesSpan()
+}
+
+// HasOperation is true if the span has the attribute, regardless of its value. Unlike comparing
+// the attribute to nil this is also true for attributes that are present with a nil value.
+type HasOperation struct {
+	Expression FieldExpression
+}
+
+func newHasOperation(e FieldExpression) HasOperation {
+	return HasOperation{
+		Expression: e,
+	}
+}
+
+// nolint: revive
+func (HasOperation) __fieldExpression() {}
+
+func (HasOperation) impliedType() StaticType {
+	return TypeBoolean
+}
+
+func (o HasOperation) referencesSpan() bool {
+	return o.Expression.referencesSpan()
+}
+
+// ArrayElements compares each element of an array attribute to the other side of a comparison
+// instead of the array as a whole. { span.tags[] = "prod" } matches if any element is "prod" and
+// { all(span.tags[]) = "prod" } only if every element is.
+type ArrayElements struct {
+	Attribute Attribute
+	All       bool
+}
+
+func newArrayElements(a Attribute, all bool) ArrayElements {
+	return ArrayElements{
+		Attribute: a,
+		All:       all,
+	}
+}
+
+// nolint: revive
+func (ArrayElements) __fieldExpression() {}
+
+func (ArrayElements) impliedType() StaticType {
+	// the type of the elements is only known at query time
+	return TypeAttribute
+}
+
+func (ArrayElements) referencesSpan() bool {
+	return true
+}
+
+// FunctionOperation applies a numeric function like abs() or sign() to a field expression.
+type FunctionOperation struct {
+	Op         FunctionOp
+	Expression FieldExpression
+}
+
+func newFunctionOperation(op FunctionOp, e FieldExpression) FunctionOperation {
+	return FunctionOperation{
+		Op:         op,
+		Expression: e,
+	}
+}
+
+// nolint: revive
+func (FunctionOperation) __fieldExpression() {}
+
+func (o FunctionOperation) impliedType() StaticType {
+	if o.Op == functionSign {
+		return TypeInt
+	}
+
+	// abs keeps the type of its operand
+	return o.Expression.impliedType()
+}
+
+func (o FunctionOperation) referencesSpan() bool {
+	return o.Expression.referencesSpan()
+}
+
+// Reference is the value bound to a name by an earlier with() in the pipeline. It is the same for
+// every span of a spanset, so it can be used in span filters as well as in scalar filters.
+type Reference struct {
+	Name string
+}
+
+func newReference(name string) Reference {
+	return Reference{
+		Name: name,
+	}
+}
+
+// nolint: revive
+func (Reference) __fieldExpression() {}
+
+// nolint: revive
+func (Reference) __scalarExpression() {}
+
+func (Reference) impliedType() StaticType {
+	// the type of the bound value is only known during evaluation
+	return TypeAttribute
+}
+
+func (Reference) referencesSpan() bool {
+	return false
+}
+
 // **********************
 // Statics
 // **********************
@@ -383,28 +936,94 @@ func (s Static) impliedType() StaticType {
 	return s.Type
 }
 
+// Equals reports whether two statics are the same value. A status also equals the int of its
+// ordinal and the string of its name, e.g. error, 0 and "error".
 func (s Static) Equals(other Static) bool {
-	eitherIsTypeStatus := (s.Type == TypeStatus && other.Type == TypeInt) || (other.Type == TypeStatus && s.Type == TypeInt)
-	if !eitherIsTypeStatus {
-		return s == other
+	status, v := s, other
+	if v.Type == TypeStatus {
+		status, v = v, status
+	}
+	if status.Type == TypeStatus {
+		switch v.Type {
+		case TypeInt:
+			return status.Status == Status(v.N)
+		case TypeString:
+			return status.Status.String() == v.S
+		}
+	}
+	return s == other
+}
+
+// Compare returns -1, 0 or 1 if s is less than, equal to or greater than other. Numeric types
+// are compared by value regardless of their type, strings lexicographically, statuses by their
+// ordinal and false is less than true. A status and an int are compared the same way Equals
+// treats them. Returns an error if the types can't be compared.
+func (s Static) Compare(other Static) (int, error) {
+	return s.CompareCollated(other, nil)
+}
+
+// CompareCollated is like Compare but orders strings with the collation instead of by their bytes.
+// A nil collation is byte order.
+func (s Static) CompareCollated(other Static, c Collation) (int, error) {
+	switch {
+	case s.Type == TypeInt && other.Type == TypeInt:
+		return compareOrdered(s.N, other.N), nil
+	case s.Type == TypeDuration && other.Type == TypeDuration:
+		return compareOrdered(s.D, other.D), nil
+	case s.Type == TypeTimestamp && other.Type == TypeTimestamp:
+		return compareOrdered(s.N, other.N), nil
+	case s.Type.isNumeric() && other.Type.isNumeric():
+		l, _ := s.asFloat()
+		r, _ := other.asFloat()
+		return compareOrdered(l, r), nil
+	case s.Type == TypeString && other.Type == TypeString:
+		if c != nil {
+			return c.CompareString(s.S, other.S), nil
+		}
+		return strings.Compare(s.S, other.S), nil
+	case s.Type == TypeStatus && other.Type == TypeStatus:
+		return compareOrdered(s.Status, other.Status), nil
+	case s.Type == TypeStatus && other.Type == TypeInt:
+		return compareOrdered(s.Status, Status(other.N)), nil
+	case s.Type == TypeInt && other.Type == TypeStatus:
+		return compareOrdered(Status(s.N), other.Status), nil
+	case s.Type == TypeBoolean && other.Type == TypeBoolean:
+		return compareOrdered(boolOrdinal(s.B), boolOrdinal(other.B)), nil
+	case s.Type == TypeNil && other.Type == TypeNil:
+		return 0, nil
 	}
-	if s.Type == TypeStatus {
-		return s.Status == Status(other.N)
+
+	return 0, fmt.Errorf("cannot compare %v with %v", s.Type, other.Type)
+}
+
+func compareOrdered[T int | float64 | time.Duration | Status](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
 	}
-	return Status(s.N) == other.Status
+	return 0
 }
 
-func (s Static) asFloat() float64 {
+func boolOrdinal(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// asFloat returns the value of a numeric static as a float. ok is false for non-numeric types.
+func (s Static) asFloat() (f float64, ok bool) {
 	switch s.Type {
 	case TypeInt:
-		return float64(s.N)
+		return float64(s.N), true
 	case TypeFloat:
-		return s.F
+		return s.F, true
 	case TypeDuration:
-		return float64(s.D.Nanoseconds())
-	default:
-		panic(fmt.Sprintf("called asfloat on non-numeric Static (type = %v)", s.Type))
+		return float64(s.D.Nanoseconds()), true
 	}
+	return 0, false
 }
 
 func NewStaticInt(n int) Static {
@@ -448,6 +1067,15 @@ func NewStaticDuration(d time.Duration) Static {
 	}
 }
 
+// NewStaticTimestamp creates a static for a point in time. Timestamps are stored with nanosecond
+// precision, so t must be within the range of UnixNano.
+func NewStaticTimestamp(t time.Time) Static {
+	return Static{
+		Type: TypeTimestamp,
+		N:    int(t.UnixNano()),
+	}
+}
+
 func NewStaticStatus(s Status) Static {
 	return Static{
 		Type:   TypeStatus,
@@ -491,6 +1119,13 @@ func (a Attribute) impliedType() StaticType {
 		return TypeStatus
 	case IntrinsicParent:
 		return TypeNil
+	case IntrinsicSelfTime:
+		return TypeDuration
+	case IntrinsicStartTime, IntrinsicEndTime:
+		return TypeTimestamp
+	case IntrinsicLinkTraceID, IntrinsicLinkSpanID:
+		// the type of a single link, see BinaryOperation.execute
+		return TypeString
 	}
 
 	return TypeAttribute
@@ -531,5 +1166,11 @@ var _ pipelineElement = (*Aggregate)(nil)
 var _ pipelineElement = (*SpansetOperation)(nil)
 var _ pipelineElement = (*SpansetFilter)(nil)
 var _ pipelineElement = (*CoalesceOperation)(nil)
+var _ pipelineElement = (*FlattenOperation)(nil)
+var _ pipelineElement = (*SelectOperation)(nil)
+var _ pipelineElement = (*WithOperation)(nil)
+var _ pipelineElement = (*DistinctOperation)(nil)
+var _ pipelineElement = (*TopKOperation)(nil)
 var _ pipelineElement = (*ScalarFilter)(nil)
+var _ pipelineElement = (*ScalarOperation)(nil)
 var _ pipelineElement = (*GroupOperation)(nil)
@@ -1,74 +1,384 @@
 package traceql
 
+import (
+	"math"
+	"time"
+)
+
 func (f SpansetFilter) extractConditions(request *FetchSpansRequest) {
 	f.Expression.extractConditions(request)
 }
 
 func (o BinaryOperation) extractConditions(request *FetchSpansRequest) {
+	// like functions, bitwise operators change the value so their operands can only be fetched
+	if o.Op.isBitwise() {
+		o.LHS.extractConditions(request)
+		o.RHS.extractConditions(request)
+		request.residual = true
+		return
+	}
+
+	// { x > a && x < b } can be fetched as a single range instead of two independent bounds
+	if o.Op == OpAnd {
+		if cond, ok := combineRangeConditions(o.LHS, o.RHS); ok {
+			request.appendCondition(conditionPushdown(cond), cond)
+			return
+		}
+	}
+
+	// { x = a || x = b } can be fetched as a single set of values instead of two independent conditions
+	if o.Op == OpOr {
+		if cond, ok := combineEqualityConditions(o); ok {
+			request.appendCondition(conditionPushdown(cond), cond)
+			return
+		}
+	}
+
 	// TODO we can further optimise this by attempting to execute every FieldExpression, if they only contain statics it should resolve
 	switch o.LHS.(type) {
 	case Attribute:
 		switch o.RHS.(type) {
 		case Static:
-			request.appendCondition(Condition{
+			cond := Condition{
 				Attribute: o.LHS.(Attribute),
 				Op:        o.Op,
 				Operands:  []Static{o.RHS.(Static)},
-			})
+			}
+			request.appendCondition(conditionPushdown(cond), cond)
 		case Attribute:
 			// Both sides are attributes, just fetch both
-			request.appendCondition(Condition{
+			request.appendCondition(PushdownNone, Condition{
 				Attribute: o.LHS.(Attribute),
 				Op:        OpNone,
 				Operands:  nil,
 			})
-			request.appendCondition(Condition{
+			request.appendCondition(PushdownNone, Condition{
 				Attribute: o.RHS.(Attribute),
 				Op:        OpNone,
 				Operands:  nil,
 			})
 		default:
 			// Just fetch LHS and try to do something smarter with RHS
-			request.appendCondition(Condition{
+			request.appendCondition(PushdownNone, Condition{
 				Attribute: o.LHS.(Attribute),
 				Op:        OpNone,
 				Operands:  nil,
 			})
 			o.RHS.extractConditions(request)
+			request.residual = true
 		}
 	case Static:
 		switch o.RHS.(type) {
 		case Static:
 			// 2 statics, don't need to send any conditions
+			request.residual = true
 			return
 		case Attribute:
-			request.appendCondition(Condition{
+			if o.Op == OpRegex || o.Op == OpNotRegex {
+				// the attribute is the pattern, which storage can't filter on
+				request.appendCondition(PushdownNone, Condition{
+					Attribute: o.RHS.(Attribute),
+					Op:        OpNone,
+					Operands:  nil,
+				})
+				return
+			}
+
+			// the condition reads "attribute op static", so comparisons are flipped
+			cond := Condition{
 				Attribute: o.RHS.(Attribute),
-				Op:        o.Op,
+				Op:        flipComparison(o.Op),
 				Operands:  []Static{o.LHS.(Static)},
-			})
+			}
+			request.appendCondition(conditionPushdown(cond), cond)
 		default:
 			o.RHS.extractConditions(request)
+			request.residual = true
 		}
 	default:
 		o.LHS.extractConditions(request)
 		o.RHS.extractConditions(request)
 		request.AllConditions = request.AllConditions && (o.Op != OpOr)
+		// only a disjunction of conditions matches what storage returns by default
+		if o.Op != OpOr {
+			request.residual = true
+		}
 	}
 }
 
+// conditionPushdown returns how much of the comparison of an attribute to a static the condition
+// decides.
+func conditionPushdown(c Condition) Pushdown {
+	if c.Op == OpNone {
+		return PushdownNone
+	}
+
+	// computed by the engine from the whole trace
+	if c.Attribute.Intrinsic == IntrinsicSelfTime || c.Attribute.Intrinsic == IntrinsicChildCount || c.Attribute.Intrinsic == IntrinsicParent {
+		return PushdownNone
+	}
+
+	// compared by the engine with each link of the span
+	if c.Attribute.Intrinsic.isLink() {
+		return PushdownNone
+	}
+
+	// storage only tells apart spans with and without the attribute, the engine compares the value
+	if len(c.Operands) == 1 && c.Operands[0].Type == TypeNil {
+		return PushdownNone
+	}
+
+	// storage matches an unscoped attribute in any scope, while the engine compares the value of
+	// the span scope if the span has one
+	if c.Attribute.Scope == AttributeScopeNone && c.Attribute.Intrinsic == IntrinsicNone {
+		return PushdownPartial
+	}
+
+	switch c.Op {
+	case OpEqual, OpNotEqual, OpGreater, OpGreaterEqual, OpLess, OpLessEqual, OpBetween, OpIn:
+		return PushdownExact
+	}
+
+	// regular expressions are matched by the regexp engine of the storage layer, which isn't
+	// guaranteed to behave like the one of the engine
+	return PushdownPartial
+}
+
 func (o UnaryOperation) extractConditions(request *FetchSpansRequest) {
 	// TODO when Op is Not we should just either negate all inner Operands or just fetch the columns with OpNone
 	o.Expression.extractConditions(request)
+	request.residual = true
+}
+
+func (o SetOperation) extractConditions(request *FetchSpansRequest) {
+	// storage has no set predicates yet, fetch the operand and check membership in the engine
+	o.Expression.extractConditions(request)
+	request.residual = true
+}
+
+func (o HasOperation) extractConditions(request *FetchSpansRequest) {
+	// OpNone fetches the attribute regardless of its value, which includes keys with a nil value
+	o.Expression.extractConditions(request)
+	request.residual = true
+}
+
+func (e ArrayElements) extractConditions(request *FetchSpansRequest) {
+	// storage only has a predicate for the array as a whole, so the elements are compared in the engine
+	request.appendCondition(PushdownNone, Condition{
+		Attribute: e.Attribute,
+		Op:        OpNone,
+		Operands:  nil,
+		Elements:  true,
+	})
+	request.residual = true
+}
+
+func (o FunctionOperation) extractConditions(request *FetchSpansRequest) {
+	// the function changes the value, so the operand can only be fetched and not filtered on
+	o.Expression.extractConditions(request)
+	request.residual = true
+}
+
+func (r Reference) extractConditions(request *FetchSpansRequest) {
+	request.residual = true
 }
 
 func (s Static) extractConditions(request *FetchSpansRequest) {
+	request.residual = true
 }
 
 func (a Attribute) extractConditions(request *FetchSpansRequest) {
-	request.appendCondition(Condition{
+	request.appendCondition(PushdownNone, Condition{
 		Attribute: a,
 		Op:        OpNone,
 		Operands:  nil,
 	})
 }
+
+// combineRangeConditions attempts to merge a lower and an upper bound on the same attribute into
+// a single OpBetween condition with inclusive operands. It returns false if the expressions are not
+// two numeric bounds of the same type on the same attribute, or if a bound can't be made inclusive.
+// The caller then falls back to extracting each side on its own.
+func combineRangeConditions(lhs, rhs FieldExpression) (Condition, bool) {
+	lAttr, lOp, lStatic, ok := rangeBound(lhs)
+	if !ok {
+		return Condition{}, false
+	}
+	rAttr, rOp, rStatic, ok := rangeBound(rhs)
+	if !ok {
+		return Condition{}, false
+	}
+
+	if lAttr != rAttr || lStatic.Type != rStatic.Type {
+		return Condition{}, false
+	}
+
+	var (
+		minOp, maxOp         Operator
+		minStatic, maxStatic Static
+	)
+	switch {
+	case isLowerBound(lOp) && isUpperBound(rOp):
+		minOp, minStatic, maxOp, maxStatic = lOp, lStatic, rOp, rStatic
+	case isUpperBound(lOp) && isLowerBound(rOp):
+		minOp, minStatic, maxOp, maxStatic = rOp, rStatic, lOp, lStatic
+	default:
+		return Condition{}, false
+	}
+
+	min, ok := inclusiveBound(minStatic, minOp == OpGreater, 1)
+	if !ok {
+		return Condition{}, false
+	}
+	max, ok := inclusiveBound(maxStatic, maxOp == OpLess, -1)
+	if !ok {
+		return Condition{}, false
+	}
+
+	return Condition{
+		Attribute: lAttr,
+		Op:        OpBetween,
+		Operands:  []Static{min, max},
+	}, true
+}
+
+// rangeBound returns the attribute, operator and static of a comparison between an attribute and
+// a numeric static. The operator is flipped when the static is on the left so the result always
+// reads as "attribute op static".
+func rangeBound(e FieldExpression) (Attribute, Operator, Static, bool) {
+	o, ok := e.(BinaryOperation)
+	if !ok || !(isLowerBound(o.Op) || isUpperBound(o.Op)) {
+		return Attribute{}, OpNone, Static{}, false
+	}
+
+	op := o.Op
+	attr, attrOk := o.LHS.(Attribute)
+	static, staticOk := o.RHS.(Static)
+	if !attrOk || !staticOk {
+		op = flipComparison(op)
+		attr, attrOk = o.RHS.(Attribute)
+		static, staticOk = o.LHS.(Static)
+	}
+
+	if !attrOk || !staticOk || !static.Type.isNumeric() {
+		return Attribute{}, OpNone, Static{}, false
+	}
+
+	return attr, op, static, true
+}
+
+func isLowerBound(op Operator) bool {
+	return op == OpGreater || op == OpGreaterEqual
+}
+
+func isUpperBound(op Operator) bool {
+	return op == OpLess || op == OpLessEqual
+}
+
+func flipComparison(op Operator) Operator {
+	switch op {
+	case OpGreater:
+		return OpLess
+	case OpGreaterEqual:
+		return OpLessEqual
+	case OpLess:
+		return OpGreater
+	case OpLessEqual:
+		return OpGreaterEqual
+	}
+	return op
+}
+
+// inclusiveBound turns an exclusive bound into an inclusive one by stepping it by delta. Only
+// integer valued statics can be stepped so exclusive float bounds are rejected.
+func inclusiveBound(s Static, exclusive bool, delta int) (Static, bool) {
+	if !exclusive {
+		return s, true
+	}
+
+	switch s.Type {
+	case TypeInt:
+		if (delta > 0 && s.N == math.MaxInt) || (delta < 0 && s.N == math.MinInt) {
+			return Static{}, false
+		}
+		return NewStaticInt(s.N + delta), true
+	case TypeDuration:
+		if (delta > 0 && s.D == math.MaxInt64) || (delta < 0 && s.D == math.MinInt64) {
+			return Static{}, false
+		}
+		return NewStaticDuration(s.D + time.Duration(delta)), true
+	case TypeTimestamp:
+		if (delta > 0 && s.N == math.MaxInt) || (delta < 0 && s.N == math.MinInt) {
+			return Static{}, false
+		}
+		return Static{Type: TypeTimestamp, N: s.N + delta}, true
+	}
+
+	return Static{}, false
+}
+
+// combineEqualityConditions turns a disjunction of equalities of the same attribute, e.g.
+// { x = a || x = b || x = c }, into a single OpIn condition with all values as operands. The
+// values must have the same type, mixed disjunctions are left to the regular extraction.
+func combineEqualityConditions(o BinaryOperation) (Condition, bool) {
+	var (
+		attr   Attribute
+		values []Static
+	)
+
+	var collect func(e FieldExpression) bool
+	collect = func(e FieldExpression) bool {
+		b, ok := e.(BinaryOperation)
+		if !ok {
+			return false
+		}
+		if b.Op == OpOr {
+			return collect(b.LHS) && collect(b.RHS)
+		}
+
+		a, static, ok := equalityOperands(b)
+		if !ok {
+			return false
+		}
+		if len(values) > 0 && (a != attr || static.Type != values[0].Type) {
+			return false
+		}
+		attr = a
+		values = append(values, static)
+		return true
+	}
+
+	if !collect(o) {
+		return Condition{}, false
+	}
+
+	return Condition{
+		Attribute: attr,
+		Op:        OpIn,
+		Operands:  values,
+	}, true
+}
+
+// equalityOperands returns the attribute and static of an equality between them, regardless of
+// the side the static is on. Only types storage has set predicates for are returned.
+func equalityOperands(o BinaryOperation) (Attribute, Static, bool) {
+	if o.Op != OpEqual {
+		return Attribute{}, Static{}, false
+	}
+
+	attr, attrOk := o.LHS.(Attribute)
+	static, staticOk := o.RHS.(Static)
+	if !attrOk || !staticOk {
+		attr, attrOk = o.RHS.(Attribute)
+		static, staticOk = o.LHS.(Static)
+	}
+	if !attrOk || !staticOk {
+		return Attribute{}, Static{}, false
+	}
+
+	switch static.Type {
+	case TypeString, TypeInt, TypeFloat, TypeDuration, TypeStatus:
+		return attr, static, true
+	}
+	return Attribute{}, Static{}, false
+}
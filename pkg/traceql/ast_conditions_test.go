@@ -2,6 +2,7 @@ package traceql
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -34,6 +35,13 @@ func TestSpansetFilter_extractConditions(t *testing.T) {
 			conditions:    []Condition{},
 			allConditions: true,
 		},
+		{
+			query: `{ bitAnd(.flags, 4) != 0 }`,
+			conditions: []Condition{
+				newCondition(NewAttribute("flags"), OpNone),
+			},
+			allConditions: true,
+		},
 		{
 			query: `{ .foo = .bar }`,
 			conditions: []Condition{
@@ -42,6 +50,16 @@ func TestSpansetFilter_extractConditions(t *testing.T) {
 			},
 			allConditions: true,
 		},
+		{
+			// only the array compared by its elements needs its array values fetched
+			query: `{ span.tags[] = "prod" && .foo = .bar }`,
+			conditions: []Condition{
+				{Attribute: NewScopedAttribute(AttributeScopeSpan, false, "tags"), Op: OpNone, Elements: true},
+				newCondition(NewAttribute("foo"), OpNone),
+				newCondition(NewAttribute("bar"), OpNone),
+			},
+			allConditions: true,
+		},
 		{
 			query: `{ (.foo = "bar") = true }`,
 			conditions: []Condition{
@@ -89,6 +107,143 @@ func TestSpansetFilter_extractConditions(t *testing.T) {
 			},
 			allConditions: true,
 		},
+		{
+			query: `{ duration > 100ms && duration < 500ms }`,
+			conditions: []Condition{
+				newCondition(NewIntrinsic(IntrinsicDuration), OpBetween, NewStaticDuration(100*time.Millisecond+1), NewStaticDuration(500*time.Millisecond-1)),
+			},
+			allConditions: true,
+		},
+		{
+			query: `{ duration <= 500ms && duration >= 100ms }`,
+			conditions: []Condition{
+				newCondition(NewIntrinsic(IntrinsicDuration), OpBetween, NewStaticDuration(100*time.Millisecond), NewStaticDuration(500*time.Millisecond)),
+			},
+			allConditions: true,
+		},
+		{
+			query: `{ 1 < .foo && .foo <= 5 }`,
+			conditions: []Condition{
+				newCondition(NewAttribute("foo"), OpBetween, NewStaticInt(2), NewStaticInt(5)),
+			},
+			allConditions: true,
+		},
+		{
+			query: `{ .foo >= 1.5 && .foo <= 2.5 }`,
+			conditions: []Condition{
+				newCondition(NewAttribute("foo"), OpBetween, NewStaticFloat(1.5), NewStaticFloat(2.5)),
+			},
+			allConditions: true,
+		},
+		{
+			// exclusive float bounds can't be combined
+			query: `{ .foo > 1.5 && .foo < 2.5 }`,
+			conditions: []Condition{
+				newCondition(NewAttribute("foo"), OpGreater, NewStaticFloat(1.5)),
+				newCondition(NewAttribute("foo"), OpLess, NewStaticFloat(2.5)),
+			},
+			allConditions: true,
+		},
+		{
+			// different attributes
+			query: `{ .foo > 1 && .bar < 5 }`,
+			conditions: []Condition{
+				newCondition(NewAttribute("foo"), OpGreater, NewStaticInt(1)),
+				newCondition(NewAttribute("bar"), OpLess, NewStaticInt(5)),
+			},
+			allConditions: true,
+		},
+		{
+			// two lower bounds
+			query: `{ .foo > 1 && .foo > 5 }`,
+			conditions: []Condition{
+				newCondition(NewAttribute("foo"), OpGreater, NewStaticInt(1)),
+				newCondition(NewAttribute("foo"), OpGreater, NewStaticInt(5)),
+			},
+			allConditions: true,
+		},
+		{
+			query: `{ has(.foo) && !has(resource.bar) }`,
+			conditions: []Condition{
+				newCondition(NewAttribute("foo"), OpNone),
+				newCondition(NewScopedAttribute(AttributeScopeResource, false, "bar"), OpNone),
+			},
+			allConditions: true,
+		},
+		{
+			query: `{ duration > 100ms || duration < 500ms }`,
+			conditions: []Condition{
+				newCondition(NewIntrinsic(IntrinsicDuration), OpGreater, NewStaticDuration(100*time.Millisecond)),
+				newCondition(NewIntrinsic(IntrinsicDuration), OpLess, NewStaticDuration(500*time.Millisecond)),
+			},
+			allConditions: false,
+		},
+		{
+			query: `{ .foo = 1 || .foo = 2 }`,
+			conditions: []Condition{
+				newCondition(NewAttribute("foo"), OpIn, NewStaticInt(1), NewStaticInt(2)),
+			},
+			allConditions: true,
+		},
+		{
+			query: `{ name = "a" || "b" = name || name = "c" }`,
+			conditions: []Condition{
+				newCondition(NewIntrinsic(IntrinsicName), OpIn, NewStaticString("a"), NewStaticString("b"), NewStaticString("c")),
+			},
+			allConditions: true,
+		},
+		{
+			query: `{ status = error || status = unset }`,
+			conditions: []Condition{
+				newCondition(NewIntrinsic(IntrinsicStatus), OpIn, NewStaticStatus(StatusError), NewStaticStatus(StatusUnset)),
+			},
+			allConditions: true,
+		},
+		{
+			// only the nested disjunction of the same attribute is combined
+			query: `{ .foo = 1 || .foo = 2 || .bar = 3 }`,
+			conditions: []Condition{
+				newCondition(NewAttribute("foo"), OpIn, NewStaticInt(1), NewStaticInt(2)),
+				newCondition(NewAttribute("bar"), OpEqual, NewStaticInt(3)),
+			},
+			allConditions: false,
+		},
+		{
+			// different attributes
+			query: `{ .foo = 1 || .bar = 2 }`,
+			conditions: []Condition{
+				newCondition(NewAttribute("foo"), OpEqual, NewStaticInt(1)),
+				newCondition(NewAttribute("bar"), OpEqual, NewStaticInt(2)),
+			},
+			allConditions: false,
+		},
+		{
+			// different scopes of the same name
+			query: `{ span.foo = 1 || resource.foo = 2 }`,
+			conditions: []Condition{
+				newCondition(NewScopedAttribute(AttributeScopeSpan, false, "foo"), OpEqual, NewStaticInt(1)),
+				newCondition(NewScopedAttribute(AttributeScopeResource, false, "foo"), OpEqual, NewStaticInt(2)),
+			},
+			allConditions: false,
+		},
+		{
+			// different types
+			query: `{ .foo = 1 || .foo = "1" }`,
+			conditions: []Condition{
+				newCondition(NewAttribute("foo"), OpEqual, NewStaticInt(1)),
+				newCondition(NewAttribute("foo"), OpEqual, NewStaticString("1")),
+			},
+			allConditions: false,
+		},
+		{
+			// not only equalities
+			query: `{ .foo = 1 || .foo > 5 }`,
+			conditions: []Condition{
+				newCondition(NewAttribute("foo"), OpEqual, NewStaticInt(1)),
+				newCondition(NewAttribute("foo"), OpGreater, NewStaticInt(5)),
+			},
+			allConditions: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.query, func(t *testing.T) {
@@ -109,3 +264,48 @@ func TestSpansetFilter_extractConditions(t *testing.T) {
 	}
 
 }
+
+func TestSpansetFilter_extractConditionsPushdown(t *testing.T) {
+	tests := []struct {
+		query    string
+		pushdown []Pushdown
+		exact    bool
+	}{
+		{query: `{ span.foo = "bar" }`, pushdown: []Pushdown{PushdownExact}, exact: true},
+		{query: `{ span.foo =~ "ba.*" }`, pushdown: []Pushdown{PushdownPartial}},
+		{query: `{ 1 < resource.foo }`, pushdown: []Pushdown{PushdownExact}, exact: true},
+		{query: `{ duration > 1s && duration < 2s }`, pushdown: []Pushdown{PushdownExact}, exact: true},
+		{query: `{ span.foo = "bar" || name = "baz" }`, pushdown: []Pushdown{PushdownExact, PushdownExact}, exact: true},
+		{query: `{ span.foo = "bar" || span.baz =~ "x" }`, pushdown: []Pushdown{PushdownExact, PushdownPartial}},
+		// storage may return spans that match only one side
+		{query: `{ span.foo = "bar" && name = "baz" }`, pushdown: []Pushdown{PushdownExact, PushdownExact}},
+		{query: `{ span.foo = "bar" || (span.a = 1 && span.b = 2) }`, pushdown: []Pushdown{PushdownExact, PushdownExact, PushdownExact}},
+		{query: `{ !(span.foo = "bar") }`, pushdown: []Pushdown{PushdownExact}},
+		// storage matches unscoped attributes in any scope
+		{query: `{ .foo = "bar" }`, pushdown: []Pushdown{PushdownPartial}},
+		{query: `{ .foo = "bar" || span.foo = "baz" }`, pushdown: []Pushdown{PushdownPartial, PushdownExact}},
+		{query: `{ .foo = .bar }`, pushdown: []Pushdown{PushdownNone, PushdownNone}},
+		{query: `{ "x" =~ .foo }`, pushdown: []Pushdown{PushdownNone}},
+		{query: `{ span.foo = "bar" && 1 = 2 }`, pushdown: []Pushdown{PushdownExact}},
+		{query: `{ selfTime > 1s }`, pushdown: []Pushdown{PushdownNone}},
+		{query: `{ link:traceID = "abc" }`, pushdown: []Pushdown{PushdownNone}},
+		{query: `{ parent = nil }`, pushdown: []Pushdown{PushdownNone}},
+		{query: `{ .foo != nil }`, pushdown: []Pushdown{PushdownNone}},
+		{query: `{ nil = name }`, pushdown: []Pushdown{PushdownNone}},
+		{query: `{ abs(.foo) = 1 }`, pushdown: []Pushdown{PushdownNone}},
+		{query: `{ span.foo = 1 || span.foo = 2 }`, pushdown: []Pushdown{PushdownExact}, exact: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			expr, err := Parse(tt.query)
+			require.NoError(t, err)
+
+			req := &FetchSpansRequest{AllConditions: true}
+			expr.Pipeline.Elements[0].(SpansetFilter).extractConditions(req)
+
+			require.Equal(t, tt.pushdown, req.Pushdown)
+			require.Len(t, req.Conditions, len(req.Pushdown))
+			require.Equal(t, tt.exact, req.exact())
+		})
+	}
+}
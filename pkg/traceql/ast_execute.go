@@ -2,31 +2,43 @@ package traceql
 
 import (
 	"fmt"
-	"regexp"
+	"math"
+	"strings"
+	"time"
 
 	"github.com/go-kit/log/level"
 
 	"github.com/grafana/tempo/pkg/util/log"
 )
 
-func appendSpans(buffer []Span, input []Spanset) []Span {
+// appendSpans appends the spans of all input spansets to buffer, skipping any span
+// whose identity is already in seen. seen is updated with every appended span.
+func appendSpans(buffer []Span, seen map[string]struct{}, input []Spanset) []Span {
 	for _, i := range input {
-		buffer = append(buffer, i.Spans...)
+		for _, s := range i.Spans {
+			if id, ok := s.identity(); ok {
+				if _, dupe := seen[id]; dupe {
+					continue
+				}
+				seen[id] = struct{}{}
+			}
+			buffer = append(buffer, s)
+		}
 	}
 	return buffer
 }
 
-func (o SpansetOperation) evaluate(input []Spanset) (output []Spanset, err error) {
+func (o SpansetOperation) evaluate(ec *evalContext, input []Spanset) (output []Spanset, err error) {
 
 	for i := range input {
 		curr := input[i : i+1]
 
-		lhs, err := o.LHS.evaluate(curr)
+		lhs, err := o.LHS.evaluate(ec, curr)
 		if err != nil {
 			return nil, err
 		}
 
-		rhs, err := o.RHS.evaluate(curr)
+		rhs, err := o.RHS.evaluate(ec, curr)
 		if err != nil {
 			return nil, err
 		}
@@ -34,17 +46,26 @@ func (o SpansetOperation) evaluate(input []Spanset) (output []Spanset, err error
 		switch o.Op {
 		case OpSpansetAnd:
 			if len(lhs) > 0 && len(rhs) > 0 {
+				seen := map[string]struct{}{}
 				matchingSpanset := input[i]
-				matchingSpanset.Spans = appendSpans(nil, lhs)
-				matchingSpanset.Spans = appendSpans(matchingSpanset.Spans, rhs)
+				matchingSpanset.Spans = appendSpans(nil, seen, lhs)
+				matchingSpanset.Spans = appendSpans(matchingSpanset.Spans, seen, rhs)
 				output = append(output, matchingSpanset)
 			}
 
 		case OpSpansetUnion:
 			if len(lhs) > 0 || len(rhs) > 0 {
+				seen := map[string]struct{}{}
 				matchingSpanset := input[i]
-				matchingSpanset.Spans = appendSpans(nil, lhs)
-				matchingSpanset.Spans = appendSpans(matchingSpanset.Spans, rhs)
+				matchingSpanset.Spans = appendSpans(nil, seen, lhs)
+				matchingSpanset.Spans = appendSpans(matchingSpanset.Spans, seen, rhs)
+				output = append(output, matchingSpanset)
+			}
+
+		case OpSpansetNotDescendant:
+			matchingSpanset := input[i]
+			matchingSpanset.Spans = notDescendants(input[i].Spans, appendSpans(nil, map[string]struct{}{}, lhs), rhs)
+			if len(matchingSpanset.Spans) > 0 {
 				output = append(output, matchingSpanset)
 			}
 
@@ -56,8 +77,52 @@ func (o SpansetOperation) evaluate(input []Spanset) (output []Spanset, err error
 	return output, nil
 }
 
-func (f SpansetFilter) matches(span Span) (bool, error) {
-	static, err := f.Expression.execute(span)
+// notDescendants returns the spans of lhs that have no descendant among the spans of rhs. The
+// descendants are found through trace, which has to hold all spans of the trace with their ParentID
+// so spans in between are linked as well. A span of lhs that isn't in trace has no descendants.
+func notDescendants(trace []Span, lhs []Span, rhs []Spanset) []Span {
+	index := buildSpanIndex(trace)
+
+	// like the index, spans sharing an ID are found at the position of the first one
+	positions := make(map[string]int, len(trace))
+	for i, s := range trace {
+		if id, ok := s.identity(); ok {
+			if _, exists := positions[id]; !exists {
+				positions[id] = i
+			}
+		}
+	}
+
+	position := func(s Span) (int, bool) {
+		id, ok := s.identity()
+		if !ok {
+			return 0, false
+		}
+		p, ok := positions[id]
+		return p, ok
+	}
+
+	matched := make([]bool, len(trace))
+	for _, ss := range rhs {
+		for _, s := range ss.Spans {
+			if p, ok := position(s); ok {
+				matched[p] = true
+			}
+		}
+	}
+
+	var spans []Span
+	for _, s := range lhs {
+		if p, ok := position(s); ok && index.hasDescendant(p, func(d int) bool { return matched[d] }) {
+			continue
+		}
+		spans = append(spans, s)
+	}
+	return spans
+}
+
+func (f SpansetFilter) matches(ec *evalContext, span Span) (bool, error) {
+	static, err := f.Expression.execute(ec, span)
 	if err != nil {
 		level.Debug(log.Logger).Log("msg", "SpanSetFilter.matches failed", "err", err)
 		return false, err
@@ -69,116 +134,626 @@ func (f SpansetFilter) matches(span Span) (bool, error) {
 	return static.B, nil
 }
 
-func (o BinaryOperation) execute(span Span) (Static, error) {
-	lhs, err := o.LHS.execute(span)
+func (o BinaryOperation) execute(ec *evalContext, span Span) (Static, error) {
+	if elements, ok := asElements(o.LHS); ok {
+		return o.executeElements(ec, span, elements, o.RHS, true)
+	}
+	if elements, ok := asElements(o.RHS); ok {
+		return o.executeElements(ec, span, elements, o.LHS, false)
+	}
+
+	lhs, err := o.LHS.execute(ec, span)
 	if err != nil {
 		return NewStaticNil(), err
 	}
 
-	rhs, err := o.RHS.execute(span)
+	rhs, err := o.RHS.execute(ec, span)
 	if err != nil {
 		return NewStaticNil(), err
 	}
 
-	// Ensure the resolved types are still valid
-	lhsT := lhs.impliedType()
-	rhsT := rhs.impliedType()
-	if !lhsT.isMatchingOperand(rhsT) {
+	if result, ok := compareWithNil(o.Op, o.LHS, o.RHS, lhs, rhs); ok {
+		return result, nil
+	}
+	return binaryOperation(ec, o.Op, lhs, rhs)
+}
+
+// compareWithNil decides = and != with a literal nil operand, which test whether the other side has
+// a value, e.g. { parent != nil }. It's decided by the expressions and not the resolved values, a
+// missing attribute also resolves to nil but doesn't match any comparison.
+func compareWithNil(op Operator, lhsExpr, rhsExpr interface{}, lhs, rhs Static) (Static, bool) {
+	if op != OpEqual && op != OpNotEqual {
+		return NewStaticNil(), false
+	}
+
+	var other Static
+	switch {
+	case isNilLiteral(rhsExpr):
+		other = lhs
+	case isNilLiteral(lhsExpr):
+		other = rhs
+	default:
+		return NewStaticNil(), false
+	}
+	return NewStaticBool((other.Type == TypeNil) == (op == OpEqual)), true
+}
+
+func isNilLiteral(e interface{}) bool {
+	s, ok := e.(Static)
+	return ok && s.Type == TypeNil
+}
+
+// asElements returns the expression as elements of an array if it is compared element by element.
+// The IDs of links are, so { link:traceID = "abc" } matches a span if any of its links matches.
+func asElements(e FieldExpression) (ArrayElements, bool) {
+	switch e := e.(type) {
+	case ArrayElements:
+		return e, true
+	case Attribute:
+		if e.Intrinsic.isLink() {
+			return newArrayElements(e, false), true
+		}
+	}
+	return ArrayElements{}, false
+}
+
+// executeElements compares every element of the array to the other operand. With any it's true
+// if an element matches and with all if every element does. So an empty array matches nothing
+// with any and everything with all. An attribute that isn't an array doesn't match either way.
+func (o BinaryOperation) executeElements(ec *evalContext, span Span, elements ArrayElements, other FieldExpression, elementsLeft bool) (Static, error) {
+	operand, err := other.execute(ec, span)
+	if err != nil {
+		return NewStaticNil(), err
+	}
+
+	array := ec.resolveAttribute(elements.Attribute, span)
+	if array.Type != TypeArray {
 		return NewStaticBool(false), nil
 	}
 
-	if !o.Op.binaryTypesValid(lhsT, rhsT) {
+	values, err := ec.arrayElements(array)
+	if err != nil {
+		return NewStaticNil(), err
+	}
+
+	for _, e := range values {
+		lhs, rhs := e, operand
+		if !elementsLeft {
+			lhs, rhs = operand, e
+		}
+		matched, err := binaryOperation(ec, o.Op, lhs, rhs)
+		if err != nil {
+			return NewStaticNil(), err
+		}
+		if matched.B != elements.All {
+			// the first match decides any and the first mismatch decides all
+			return matched, nil
+		}
+	}
+
+	return NewStaticBool(elements.All), nil
+}
+
+// binaryOperation applies the operator to two resolved operands.
+func binaryOperation(ec *evalContext, op Operator, lhs, rhs Static) (Static, error) {
+	// Ensure the resolved types are still valid. Arithmetic on invalid types, e.g. a missing
+	// attribute, has no result.
+	lhsT := lhs.impliedType()
+	rhsT := rhs.impliedType()
+
+	// nil operands are missing values here, comparisons with a literal nil are decided by
+	// compareWithNil before
+	if lhsT == TypeNil || rhsT == TypeNil || !lhsT.isMatchingOperand(rhsT) || !op.binaryTypesValid(lhsT, rhsT) {
+		if !op.isBoolean() {
+			return NewStaticNil(), nil
+		}
 		return NewStaticBool(false), nil
 	}
 
-	switch o.Op {
-	// TODO implement arithmetics
-	case OpAdd:
-	case OpSub:
-	case OpDiv:
-	case OpMod:
-	case OpMult:
-	case OpGreater:
-		return NewStaticBool(lhs.asFloat() > rhs.asFloat()), nil
-	case OpGreaterEqual:
-		return NewStaticBool(lhs.asFloat() >= rhs.asFloat()), nil
-	case OpLess:
-		return NewStaticBool(lhs.asFloat() < rhs.asFloat()), nil
-	case OpLessEqual:
-		return NewStaticBool(lhs.asFloat() <= rhs.asFloat()), nil
-	case OpPower:
-	case OpEqual:
-		return NewStaticBool(lhs.Equals(rhs)), nil
-	case OpNotEqual:
-		return NewStaticBool(!lhs.Equals(rhs)), nil
+	switch op {
+	case OpAdd, OpSub, OpDiv, OpMod, OpMult, OpPower:
+		return arithmetic(ec, op, lhs, rhs)
+	case OpGreater, OpGreaterEqual, OpLess, OpLessEqual:
+		c, err := lhs.Compare(rhs)
+		if err != nil {
+			return NewStaticNil(), err
+		}
+		switch op {
+		case OpGreater:
+			return NewStaticBool(c > 0), nil
+		case OpGreaterEqual:
+			return NewStaticBool(c >= 0), nil
+		case OpLess:
+			return NewStaticBool(c < 0), nil
+		default:
+			return NewStaticBool(c <= 0), nil
+		}
+	case OpEqual, OpNotEqual:
+		var equal bool
+		switch {
+		case lhsT == TypeString && rhsT == TypeString:
+			equal = ec.stringsEqual(lhs.S, rhs.S)
+		case lhsT == TypeFloat && rhsT == TypeFloat:
+			equal = ec.floatsEqual(lhs.F, rhs.F)
+		default:
+			equal = lhs.Equals(rhs)
+		}
+		return NewStaticBool(equal == (op == OpEqual)), nil
 	case OpRegex:
-		matched, err := regexp.MatchString(rhs.S, lhs.S)
+		matched, err := ec.matchRegex(rhs.S, lhs.S)
 		return NewStaticBool(matched), err
 	case OpNotRegex:
-		matched, err := regexp.MatchString(rhs.S, lhs.S)
+		// an absent attribute fails the type check above, so like != it doesn't match
+		matched, err := ec.matchRegex(rhs.S, lhs.S)
 		return NewStaticBool(!matched), err
 	case OpAnd:
 		return NewStaticBool(lhs.B && rhs.B), nil
 	case OpOr:
 		return NewStaticBool(lhs.B || rhs.B), nil
+	case OpBitAnd:
+		return NewStaticInt(lhs.N & rhs.N), nil
+	case OpBitOr:
+		return NewStaticInt(lhs.N | rhs.N), nil
 	default:
-		panic("unexpected operator " + o.Op.String())
+		return NewStaticNil(), unsupportedOperation(op, lhs.Type, rhs.Type)
+	}
+}
+
+// unsupportedOperation is the error of applying op to operands of the given types at runtime.
+func unsupportedOperation(op Operator, types ...StaticType) error {
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		names = append(names, t.String())
+	}
+	return fmt.Errorf("%w: %s on (%s)", ErrUnsupportedOperation, op, strings.Join(names, ", "))
+}
+
+// arithmetic applies an arithmetic operator to two numeric statics. Two ints produce an int. If
+// either side is a duration the result is a duration, except for the ratio of two durations which
+// is a float. Everything else produces a float. Division or modulo by zero returns nil for every
+// type.
+func arithmetic(ec *evalContext, op Operator, lhs, rhs Static) (Static, error) {
+	if lhs.Type == TypeInt && rhs.Type == TypeInt {
+		l, r := lhs.N, rhs.N
+		if (op == OpDiv || op == OpMod) && r == 0 {
+			return NewStaticNil(), nil
+		}
+
+		n, ok := intArithmetic(op, l, r)
+		if !ok && ec.options().IntOverflowPolicy != IntOverflowSaturate {
+			return NewStaticNil(), fmt.Errorf("%w: %d %s %d", ErrIntegerOverflow, l, op, r)
+		}
+		return NewStaticInt(n), nil
+	}
+
+	l, lok := lhs.asFloat()
+	r, rok := rhs.asFloat()
+	if !lok || !rok {
+		return NewStaticNil(), unsupportedOperation(op, lhs.Type, rhs.Type)
+	}
+	if (op == OpDiv || op == OpMod) && r == 0 {
+		return NewStaticNil(), nil
+	}
+
+	var f float64
+	switch op {
+	case OpAdd:
+		f = l + r
+	case OpSub:
+		f = l - r
+	case OpMult:
+		f = l * r
+	case OpDiv:
+		f = l / r
+	case OpMod:
+		f = math.Mod(l, r)
+	case OpPower:
+		f = math.Pow(l, r)
+	}
+
+	isDuration := lhs.Type == TypeDuration || rhs.Type == TypeDuration
+	if op == OpDiv && lhs.Type == TypeDuration && rhs.Type == TypeDuration {
+		isDuration = false
+	}
+	if isDuration {
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return NewStaticNil(), nil
+		}
+		return NewStaticDuration(time.Duration(f)), nil
+	}
+	return NewStaticFloat(f), nil
+}
+
+// intArithmetic applies the operator to two ints. It returns false if the result overflows, the
+// returned value is then clamped to math.MaxInt or math.MinInt. The divisor must not be 0.
+func intArithmetic(op Operator, l, r int) (int, bool) {
+	switch op {
+	case OpAdd:
+		n := l + r
+		if (r > 0 && n < l) || (r < 0 && n > l) {
+			return saturatedInt(r > 0), false
+		}
+		return n, true
+	case OpSub:
+		n := l - r
+		if (r < 0 && n < l) || (r > 0 && n > l) {
+			return saturatedInt(r < 0), false
+		}
+		return n, true
+	case OpMult:
+		if l == 0 || r == 0 {
+			return 0, true
+		}
+		n := l * r
+		if (l == -1 && r == math.MinInt) || (r == -1 && l == math.MinInt) || n/r != l {
+			return saturatedInt((l < 0) == (r < 0)), false
+		}
+		return n, true
+	case OpDiv:
+		if l == math.MinInt && r == -1 {
+			return math.MaxInt, false
+		}
+		return l / r, true
+	case OpMod:
+		if r == -1 {
+			// math.MinInt % -1 is 0 but panics on some architectures
+			return 0, true
+		}
+		return l % r, true
+	case OpPower:
+		f := math.Pow(float64(l), float64(r))
+		// float64(math.MaxInt) rounds up to 2^63, which doesn't fit
+		if f >= float64(math.MaxInt) || f < float64(math.MinInt) {
+			return saturatedInt(f > 0), false
+		}
+		return int(f), true
 	}
 
-	panic("operator " + o.Op.String() + " is not yet implemented")
+	return 0, true
 }
 
-func (o UnaryOperation) execute(span Span) (Static, error) {
-	static, err := o.Expression.execute(span)
+func saturatedInt(positive bool) int {
+	if positive {
+		return math.MaxInt
+	}
+	return math.MinInt
+}
+
+func (o UnaryOperation) execute(ec *evalContext, span Span) (Static, error) {
+	static, err := o.Expression.execute(ec, span)
 	if err != nil {
 		return NewStaticNil(), err
 	}
 
-	if o.Op == OpNot {
-		if static.Type != TypeBoolean {
-			return NewStaticNil(), fmt.Errorf("expression (%v) expected a boolean, but got %v", o, static.Type)
-		}
+	switch {
+	case o.Op == OpNot && static.Type == TypeBoolean:
 		return NewStaticBool(!static.B), nil
+	case o.Op == OpSub && static.Type == TypeInt:
+		return NewStaticInt(-1 * static.N), nil
+	case o.Op == OpSub && static.Type == TypeFloat:
+		return NewStaticFloat(-1 * static.F), nil
+	case o.Op == OpSub && static.Type == TypeDuration:
+		return NewStaticDuration(-1 * static.D), nil
+	case o.Op == OpSub && static.Type == TypeNil:
+		// negating a missing attribute is nil like arithmetic on it, so sum(-.a) skips spans without it
+		return NewStaticNil(), nil
 	}
-	if o.Op == OpSub {
-		if !static.Type.isNumeric() {
-			return NewStaticNil(), fmt.Errorf("expression (%v) expected a numeric, but got %v", o, static.Type)
+
+	return NewStaticNil(), unsupportedOperation(o.Op, static.Type)
+}
+
+// execute checks if the value is equal to any of the values in the set. A nil value, which is also
+// the value of a missing attribute, is only in a set that contains nil. That means nil is "not in"
+// any set of non-nil values.
+func (o SetOperation) execute(ec *evalContext, span Span) (Static, error) {
+	static, err := o.Expression.execute(ec, span)
+	if err != nil {
+		return NewStaticNil(), err
+	}
+
+	found := false
+	for _, v := range o.Values {
+		if static.Type == TypeString && v.Type == TypeString {
+			found = ec.stringsEqual(static.S, v.S)
+		} else {
+			found = static.Equals(v)
+		}
+		if found {
+			break
 		}
+	}
+
+	switch o.Op {
+	case OpIn:
+		return NewStaticBool(found), nil
+	case OpNotIn:
+		return NewStaticBool(!found), nil
+	}
+
+	return NewStaticNil(), fmt.Errorf("set operation (%v) not supported", o.Op)
+}
+
+func (e ArrayElements) execute(*evalContext, Span) (Static, error) {
+	return NewStaticNil(), fmt.Errorf("the elements of an array can only be compared: %s", e.String())
+}
+
+func (o HasOperation) execute(_ *evalContext, span Span) (Static, error) {
+	a, ok := o.Expression.(Attribute)
+	if !ok {
+		return NewStaticNil(), fmt.Errorf("expression (%v) expected an attribute", o)
+	}
+
+	if _, ok := span.Attributes[a]; ok {
+		return NewStaticBool(true), nil
+	}
+
+	if a.Scope == AttributeScopeNone {
+		for attribute := range span.Attributes {
+			if a.Name == attribute.Name && a.Parent == attribute.Parent && attribute.Intrinsic == IntrinsicNone {
+				return NewStaticBool(true), nil
+			}
+		}
+	}
+
+	return NewStaticBool(false), nil
+}
+
+// execute applies the function to a numeric value. Like arithmetic, any other value including nil
+// results in nil.
+func (o FunctionOperation) execute(ec *evalContext, span Span) (Static, error) {
+	static, err := o.Expression.execute(ec, span)
+	if err != nil {
+		return NewStaticNil(), err
+	}
+
+	if !static.Type.isNumeric() {
+		return NewStaticNil(), nil
+	}
+
+	switch o.Op {
+	case functionAbs:
+		switch static.Type {
+		case TypeInt:
+			if static.N < 0 {
+				return NewStaticInt(-static.N), nil
+			}
+			return static, nil
+		case TypeFloat:
+			return NewStaticFloat(math.Abs(static.F)), nil
+		case TypeDuration:
+			if static.D < 0 {
+				return NewStaticDuration(-static.D), nil
+			}
+			return static, nil
+		}
+	case functionSign:
+		var sign int
 		switch static.Type {
 		case TypeInt:
-			return NewStaticInt(-1 * static.N), nil
+			sign = compareOrdered(static.N, 0)
 		case TypeFloat:
-			return NewStaticFloat(-1 * static.F), nil
+			if math.IsNaN(static.F) {
+				return NewStaticNil(), nil
+			}
+			sign = compareOrdered(static.F, 0)
 		case TypeDuration:
-			return NewStaticDuration(-1 * static.D), nil
+			sign = compareOrdered(static.D, 0)
+		}
+		return NewStaticInt(sign), nil
+	}
+
+	return NewStaticNil(), fmt.Errorf("function (%v) not supported", o.Op)
+}
+
+func (r Reference) execute(ec *evalContext, _ Span) (Static, error) {
+	v, ok := ec.binding(r.Name)
+	if !ok {
+		return NewStaticNil(), fmt.Errorf("%s is not bound", r.Name)
+	}
+	return v, nil
+}
+
+// compute evaluates the aggregate over the spans of the spanset. The expression is executed for
+// every span, so it can compute a value like sum(.a - .b). Values that aren't numbers, including
+// missing attributes, are ignored. The result is nil if there are no values.
+func (a Aggregate) compute(ec *evalContext, ss Spanset) (Static, error) {
+	if a.agg == aggregateCount {
+		return NewStaticInt(len(ss.Spans)), nil
+	}
+	if a.agg == aggregateCountDistinct {
+		return a.countDistinct(ec, ss)
+	}
+
+	result := NewStaticNil()
+	count := 0
+	for _, span := range ss.Spans {
+		if err := ec.nextSpan(); err != nil {
+			return NewStaticNil(), err
+		}
+
+		v, err := a.e.execute(ec, span)
+		if err != nil {
+			return NewStaticNil(), err
+		}
+		if !v.Type.isNumeric() {
+			continue
+		}
+
+		count++
+		if count == 1 {
+			result = v
+			continue
+		}
+
+		switch a.agg {
+		case aggregateMax, aggregateMin:
+			cmp, err := v.Compare(result)
+			if err != nil {
+				return NewStaticNil(), err
+			}
+			if (a.agg == aggregateMax && cmp > 0) || (a.agg == aggregateMin && cmp < 0) {
+				result = v
+			}
+		case aggregateSum, aggregateAvg:
+			if result, err = arithmetic(ec, OpAdd, result, v); err != nil {
+				return NewStaticNil(), err
+			}
 		}
 	}
 
-	panic("UnaryOperation has Op different from Not and Sub")
+	if a.agg == aggregateAvg && count > 0 {
+		// divide by a float so the average of ints isn't truncated
+		return arithmetic(ec, OpDiv, result, NewStaticFloat(float64(count)))
+	}
+
+	return result, nil
 }
 
-func (s Static) execute(span Span) (Static, error) {
+// countDistinct counts the distinct values of the expression over the spans. Values are compared
+// like by() groups them, so 1 and 1.0 are different values.
+func (a Aggregate) countDistinct(ec *evalContext, ss Spanset) (Static, error) {
+	max := ec.options().MaxDistinctValues
+	seen := map[Static]struct{}{}
+	for _, span := range ss.Spans {
+		if err := ec.nextSpan(); err != nil {
+			return NewStaticNil(), err
+		}
+
+		v, err := a.e.execute(ec, span)
+		if err != nil {
+			return NewStaticNil(), err
+		}
+		if v.Type == TypeNil {
+			continue
+		}
+
+		if _, ok := seen[v]; !ok && max > 0 && len(seen) >= max {
+			return NewStaticNil(), fmt.Errorf("%w: %s has more than %d values", ErrTooManyDistinctValues, a.String(), max)
+		}
+		seen[v] = struct{}{}
+	}
+
+	return NewStaticInt(len(seen)), nil
+}
+
+// evaluateScalar computes the value of the scalar expression over the input. A pipeline evaluates
+// its elements before the final one and computes that over the result. Arithmetic on values that
+// aren't numbers, e.g. the max of a spanset without values, is nil.
+func evaluateScalar(ec *evalContext, e ScalarExpression, input []Spanset) (Static, error) {
+	switch e := e.(type) {
+	case Static:
+		return e, nil
+	case Aggregate:
+		return e.compute(ec, Spanset{Spans: spansOfTraces(input)})
+	case Reference:
+		// the caller selected the spanset with forSpanset, the value doesn't depend on any span
+		return e.execute(ec, Span{})
+	case ScalarOperation:
+		lhs, err := evaluateScalar(ec, e.LHS, input)
+		if err != nil {
+			return NewStaticNil(), err
+		}
+		rhs, err := evaluateScalar(ec, e.RHS, input)
+		if err != nil {
+			return NewStaticNil(), err
+		}
+		if !lhs.Type.isNumeric() || !rhs.Type.isNumeric() {
+			return NewStaticNil(), nil
+		}
+		return arithmetic(ec, e.Op, lhs, rhs)
+	case Pipeline:
+		if len(e.Elements) == 0 {
+			return NewStaticNil(), fmt.Errorf("empty pipeline is not a scalar")
+		}
+
+		last := len(e.Elements) - 1
+		final, ok := e.Elements[last].(ScalarExpression)
+		if !ok {
+			return NewStaticNil(), fmt.Errorf("pipeline doesn't end with a scalar: %s", e.String())
+		}
+
+		result, err := newPipeline(e.Elements[:last]...).evaluate(ec, input)
+		if err != nil {
+			return NewStaticNil(), err
+		}
+		return evaluateScalar(ec, final, result)
+	default:
+		return NewStaticNil(), fmt.Errorf("scalar expression (%v) not supported", e)
+	}
+}
+
+// spansOfTraces returns the spans of all spansets. Spans that are in several spansets of the same
+// trace are only returned once, span IDs aren't unique across traces.
+func spansOfTraces(input []Spanset) []Span {
+	seen := map[string]map[string]struct{}{}
+
+	var spans []Span
+	for i := range input {
+		trace := string(input[i].TraceID)
+		if seen[trace] == nil {
+			seen[trace] = map[string]struct{}{}
+		}
+		spans = appendSpans(spans, seen[trace], input[i:i+1])
+	}
+	return spans
+}
+
+// groupSpansets partitions spansets by the value of the by() that grouped them, in the order the
+// values first appear. Spansets that weren't grouped are all in a single group under nil.
+func groupSpansets(input []Spanset) ([]Static, map[Static][]Spanset) {
+	var keys []Static
+	groups := map[Static][]Spanset{}
+
+	for _, ss := range input {
+		key := NewStaticNil()
+		if ss.grouped {
+			key = ss.group
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], ss)
+	}
+
+	return keys, groups
+}
+
+func (s Static) execute(_ *evalContext, span Span) (Static, error) {
 	return s, nil
 }
 
-func (a Attribute) execute(span Span) (Static, error) {
+func (a Attribute) execute(ec *evalContext, span Span) (Static, error) {
+	return ec.resolveAttribute(a, span), nil
+}
+
+// resolve looks up the attribute on the span. Unscoped attributes prefer the span scope over
+// others.
+func (a Attribute) resolve(span Span) Static {
 	static, ok := span.Attributes[a]
 	if ok {
-		return static, nil
+		return static
 	}
 
 	if a.Scope == AttributeScopeNone {
 		for attribute, static := range span.Attributes {
 			if a.Name == attribute.Name && attribute.Scope == AttributeScopeSpan {
-				return static, nil
+				return static
 			}
 		}
 		for attribute, static := range span.Attributes {
 			if a.Name == attribute.Name {
-				return static, nil
+				return static
 			}
 		}
 	}
 
-	return NewStaticNil(), nil
+	// timestamps are always known, storage doesn't need to fetch them as attributes
+	switch a.Intrinsic {
+	case IntrinsicStartTime:
+		return Static{Type: TypeTimestamp, N: int(span.StartTimeUnixNanos)}
+	case IntrinsicEndTime:
+		return Static{Type: TypeTimestamp, N: int(span.EndtimeUnixNanos)}
+	}
+
+	return NewStaticNil()
 }
@@ -1,7 +1,9 @@
 package traceql
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"testing"
 	"time"
 
@@ -44,6 +46,16 @@ func TestSpansetFilter_matches(t *testing.T) {
 			},
 			matches: false,
 		},
+		{
+			// Missing attribute, only a literal nil tests for presence
+			query: `{ .missing != "x" }`,
+			span: Span{
+				Attributes: map[Attribute]Static{
+					NewAttribute("fzz"): NewStaticString("bar"),
+				},
+			},
+			matches: false,
+		},
 		{
 			query: `{ .foo = .bar }`,
 			span: Span{
@@ -75,6 +87,34 @@ func TestSpansetFilter_matches(t *testing.T) {
 			},
 			matches: true,
 		},
+		{
+			query: `{ .url !~ "/health" }`,
+			span: Span{
+				Attributes: map[Attribute]Static{
+					NewAttribute("url"): NewStaticString("/api/health"),
+				},
+			},
+			matches: false,
+		},
+		{
+			query: `{ .url !~ "^/health" }`,
+			span: Span{
+				Attributes: map[Attribute]Static{
+					NewAttribute("url"): NewStaticString("/api/health"),
+				},
+			},
+			matches: true,
+		},
+		{
+			// Missing attribute doesn't match, same as !=
+			query: `{ .url !~ "/health" }`,
+			span: Span{
+				Attributes: map[Attribute]Static{
+					NewAttribute("path"): NewStaticString("/api"),
+				},
+			},
+			matches: false,
+		},
 		{
 			query: `{ .foo > 2 && .foo >= 3.5 && .foo < 5 && .foo <= 3.5 && .duration > 1800ms }`,
 			span: Span{
@@ -124,6 +164,33 @@ func TestSpansetFilter_matches(t *testing.T) {
 			},
 			matches: true,
 		},
+		{
+			query:   `{ startTime > 2023-01-01T00:00:00Z }`,
+			span:    Span{StartTimeUnixNanos: uint64(time.Date(2023, 1, 1, 0, 0, 1, 0, time.UTC).UnixNano())},
+			matches: true,
+		},
+		{
+			query:   `{ startTime > 2023-01-01T00:00:00Z }`,
+			span:    Span{StartTimeUnixNanos: uint64(time.Date(2022, 12, 31, 23, 59, 59, 0, time.UTC).UnixNano())},
+			matches: false,
+		},
+		{
+			// offsets are the same instant in UTC
+			query:   `{ endTime = 2023-01-01T01:00:00+01:00 }`,
+			span:    Span{EndtimeUnixNanos: uint64(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano())},
+			matches: true,
+		},
+		{
+			// fetched timestamps take precedence over the span times
+			query: `{ startTime < 2023-01-01T00:00:00Z }`,
+			span: Span{
+				StartTimeUnixNanos: uint64(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()),
+				Attributes: map[Attribute]Static{
+					NewIntrinsic(IntrinsicStartTime): NewStaticTimestamp(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)),
+				},
+			},
+			matches: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.query, func(t *testing.T) {
@@ -132,7 +199,7 @@ func TestSpansetFilter_matches(t *testing.T) {
 
 			spansetFilter := expr.Pipeline.Elements[0].(SpansetFilter)
 
-			matches, err := spansetFilter.matches(tt.span)
+			matches, err := spansetFilter.matches(nil, tt.span)
 
 			if tt.err {
 				fmt.Println(err)
@@ -146,7 +213,494 @@ func TestSpansetFilter_matches(t *testing.T) {
 
 }
 
+func TestSpansetFilterRegexCache(t *testing.T) {
+	expr, err := Parse(`{ .url !~ "/health" || .url =~ "/health" }`)
+	require.NoError(t, err)
+	filt := expr.Pipeline.Elements[0].(SpansetFilter)
+
+	input := []Spanset{{Spans: []Span{
+		{ID: []byte{1}, Attributes: map[Attribute]Static{NewAttribute("url"): NewStaticString("/health")}},
+		{ID: []byte{2}, Attributes: map[Attribute]Static{NewAttribute("url"): NewStaticString("/api")}},
+	}}}
+
+	ec := newEvalContext(EvalOptions{})
+	output, err := filt.evaluate(ec, input)
+	require.NoError(t, err)
+	require.Len(t, output, 1)
+	require.Equal(t, input[0].Spans, output[0].Spans)
+	require.Len(t, ec.regexes, 1)
+}
+
+func TestSpansetFilter_matchesStringComparison(t *testing.T) {
+	composed := "caf\u00e9"    // é as a single code point
+	decomposed := "cafe\u0301" // e followed by a combining acute accent
+
+	span := Span{
+		Attributes: map[Attribute]Static{
+			NewAttribute("foo"): NewStaticString(decomposed),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		opts    EvalOptions
+		matches bool
+	}{
+		{
+			name:    "exact equal",
+			query:   `{ .foo = "` + composed + `" }`,
+			opts:    EvalOptions{StringComparison: StringComparisonExact},
+			matches: false,
+		},
+		{
+			name:    "exact not equal",
+			query:   `{ .foo != "` + composed + `" }`,
+			opts:    EvalOptions{StringComparison: StringComparisonExact},
+			matches: true,
+		},
+		{
+			name:    "nfc equal",
+			query:   `{ .foo = "` + composed + `" }`,
+			opts:    EvalOptions{StringComparison: StringComparisonNFC},
+			matches: true,
+		},
+		{
+			name:    "nfc not equal",
+			query:   `{ .foo != "` + composed + `" }`,
+			opts:    EvalOptions{StringComparison: StringComparisonNFC},
+			matches: false,
+		},
+		{
+			name:    "nfc different strings",
+			query:   `{ .foo = "cafe" }`,
+			opts:    EvalOptions{StringComparison: StringComparisonNFC},
+			matches: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.query)
+			require.NoError(t, err)
+
+			spansetFilter := expr.Pipeline.Elements[0].(SpansetFilter)
+
+			matches, err := spansetFilter.matches(newEvalContext(tt.opts), span)
+			require.NoError(t, err)
+			assert.Equal(t, tt.matches, matches)
+		})
+	}
+
+	// a nil evalContext uses the default, byte-exact comparison
+	expr, err := Parse(`{ .foo = "` + composed + `" }`)
+	require.NoError(t, err)
+	matches, err := expr.Pipeline.Elements[0].(SpansetFilter).matches(nil, span)
+	require.NoError(t, err)
+	assert.False(t, matches)
+}
+
+func TestSpansetFilter_matchesLinks(t *testing.T) {
+	linkSpan := func(traceIDs ...string) Span {
+		span := Span{Attributes: map[Attribute]Static{}}
+		if len(traceIDs) == 0 {
+			return span
+		}
+
+		var ids []Static
+		for _, id := range traceIDs {
+			ids = append(ids, NewStaticString(id))
+		}
+		span.Attributes[NewIntrinsic(IntrinsicLinkTraceID)] = NewStaticArray(ids)
+		return span
+	}
+
+	// a span matches if any of its links does
+	tests := []struct {
+		query   string
+		span    Span
+		matches bool
+	}{
+		{`{ link:traceID = "abc" }`, linkSpan("abc"), true},
+		{`{ link:traceID = "abc" }`, linkSpan("def", "abc"), true},
+		{`{ "abc" = link:traceID }`, linkSpan("def", "abc"), true},
+		{`{ link:traceID = "abc" }`, linkSpan("def"), false},
+		{`{ link:traceID = "abc" }`, linkSpan(), false},
+		{`{ link:traceID != "abc" }`, linkSpan("abc", "def"), true},
+		{`{ link:traceID != "abc" }`, linkSpan(), false},
+		{`{ link:traceID =~ "a.*" }`, linkSpan("def", "abc"), true},
+		{`{ link:spanID = "abc" }`, linkSpan("abc"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			expr, err := Parse(tt.query)
+			require.NoError(t, err)
+			require.NoError(t, expr.validate())
+
+			matches, err := expr.Pipeline.Elements[0].(SpansetFilter).matches(nil, tt.span)
+			require.NoError(t, err)
+			assert.Equal(t, tt.matches, matches)
+		})
+	}
+}
+
+func TestSpansetFilter_matchesStatus(t *testing.T) {
+	statusSpan := func(status Status) Span {
+		return Span{
+			Attributes: map[Attribute]Static{
+				NewIntrinsic(IntrinsicStatus): NewStaticStatus(status),
+			},
+		}
+	}
+
+	// the name, ordinal and string of a status are interchangeable
+	tests := []struct {
+		query   string
+		span    Span
+		matches bool
+	}{
+		{`{ status = error }`, statusSpan(StatusError), true},
+		{`{ status = "error" }`, statusSpan(StatusError), true},
+		{`{ status = 0 }`, statusSpan(StatusError), true},
+		{`{ "error" = status }`, statusSpan(StatusError), true},
+		{`{ status = error }`, statusSpan(StatusUnset), false},
+		{`{ status = "error" }`, statusSpan(StatusUnset), false},
+		{`{ status = 0 }`, statusSpan(StatusUnset), false},
+		{`{ status = 2 }`, statusSpan(StatusUnset), true},
+		{`{ status != "ok" }`, statusSpan(StatusUnset), true},
+		{`{ status = "failed" }`, statusSpan(StatusError), false},
+		{`{ status in ("ok", 2) }`, statusSpan(StatusUnset), true},
+		{`{ status in ("ok", 2) }`, statusSpan(StatusError), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			expr, err := Parse(tt.query)
+			require.NoError(t, err)
+			require.NoError(t, expr.validate())
+
+			matches, err := expr.Pipeline.Elements[0].(SpansetFilter).matches(nil, tt.span)
+			require.NoError(t, err)
+			assert.Equal(t, tt.matches, matches)
+		})
+	}
+
+	// statuses still can't be ordered or matched
+	for _, query := range []string{`{ status > 1 }`, `{ status =~ "err.*" }`} {
+		expr, err := Parse(query)
+		require.NoError(t, err)
+		require.Error(t, expr.validate(), query)
+	}
+}
+
+func TestSpansetFilter_matchesFloatEpsilon(t *testing.T) {
+	span := Span{
+		Attributes: map[Attribute]Static{
+			NewAttribute("f"): NewStaticFloat(0.1),
+			NewAttribute("n"): NewStaticInt(3),
+		},
+	}
+
+	tests := []struct {
+		query   string
+		epsilon float64
+		matches bool
+	}{
+		{query: `{ .f + 0.2 = 0.3 }`, matches: false},
+		{query: `{ .f + 0.2 != 0.3 }`, matches: true},
+		{query: `{ .f + 0.2 = 0.3 }`, epsilon: 1e-9, matches: true},
+		{query: `{ .f + 0.2 != 0.3 }`, epsilon: 1e-9, matches: false},
+		{query: `{ .f = 0.2 }`, epsilon: 1e-9, matches: false},
+		// ints are always exact
+		{query: `{ .n = 3 }`, epsilon: 1, matches: true},
+		{query: `{ .n = 4 }`, epsilon: 1, matches: false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s epsilon %v", tt.query, tt.epsilon), func(t *testing.T) {
+			expr, err := Parse(tt.query)
+			require.NoError(t, err)
+
+			spansetFilter := expr.Pipeline.Elements[0].(SpansetFilter)
+
+			matches, err := spansetFilter.matches(newEvalContext(EvalOptions{FloatEpsilon: tt.epsilon}), span)
+			require.NoError(t, err)
+			assert.Equal(t, tt.matches, matches)
+		})
+	}
+}
+
+func TestNilComparison(t *testing.T) {
+	span := Span{Attributes: map[Attribute]Static{
+		NewIntrinsic(IntrinsicName):     NewStaticString("foo"),
+		NewIntrinsic(IntrinsicDuration): NewStaticDuration(time.Second),
+		NewAttribute("s"):               NewStaticString("bar"),
+		NewAttribute("i"):               NewStaticInt(3),
+		NewAttribute("d"):               NewStaticDuration(time.Second),
+	}}
+
+	tests := []struct {
+		query    string
+		expected bool
+	}{
+		{query: `{ name != nil }`, expected: true},
+		{query: `{ name = nil }`, expected: false},
+		{query: `{ .s != nil }`, expected: true},
+		{query: `{ .s + 1 = nil }`, expected: true}, // arithmetic on strings has no value
+		{query: `{ .i * 2 != nil }`, expected: true},
+		{query: `{ nil = .i - 1 }`, expected: false},
+		{query: `{ duration != nil }`, expected: true},
+		{query: `{ .d / 2 = nil }`, expected: false},
+		{query: `{ .missing * 2 = nil }`, expected: true},
+		{query: `{ nil != span.missing }`, expected: false},
+		// a missing attribute resolves to nil, but only compares with a literal nil
+		{query: `{ span.missing != "x" }`, expected: false},
+		{query: `{ span.missing = "x" }`, expected: false},
+		{query: `{ 3 != .missing }`, expected: false},
+		{query: `{ .missing != 1s }`, expected: false},
+		{query: `{ .missing = .other }`, expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			expr, err := Parse(tt.query)
+			require.NoError(t, err)
+			require.NoError(t, expr.validate())
+
+			matches, err := expr.Pipeline.Elements[0].(SpansetFilter).matches(nil, span)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, matches)
+		})
+	}
+
+	// nil can only be tested for equality
+	for _, query := range []string{
+		`{ name > nil }`,
+		`{ nil < .i * 2 }`,
+		`{ duration >= nil }`,
+		`{ nil <= 1s }`,
+		`{ name !~ nil }`,
+	} {
+		expr, err := Parse(query)
+		require.NoError(t, err)
+		require.Error(t, expr.validate(), query)
+	}
+}
+
+func TestHasOperation_execute(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		span     Span
+		expected bool
+	}{
+		{
+			name:  "present with value",
+			query: `{ has(.foo) }`,
+			span: Span{
+				Attributes: map[Attribute]Static{
+					NewScopedAttribute(AttributeScopeSpan, false, "foo"): NewStaticString("bar"),
+				},
+			},
+			expected: true,
+		},
+		{
+			name:  "present with nil value",
+			query: `{ has(.foo) }`,
+			span: Span{
+				Attributes: map[Attribute]Static{
+					NewScopedAttribute(AttributeScopeResource, false, "foo"): NewStaticNil(),
+				},
+			},
+			expected: true,
+		},
+		{
+			name:  "absent",
+			query: `{ has(.foo) }`,
+			span: Span{
+				Attributes: map[Attribute]Static{
+					NewAttribute("bar"): NewStaticString("foo"),
+				},
+			},
+			expected: false,
+		},
+		{
+			name:  "present in other scope",
+			query: `{ has(span.foo) }`,
+			span: Span{
+				Attributes: map[Attribute]Static{
+					NewScopedAttribute(AttributeScopeResource, false, "foo"): NewStaticString("bar"),
+				},
+			},
+			expected: false,
+		},
+		{
+			name:  "nil value is not distinguishable with !=",
+			query: `{ .foo != nil }`,
+			span: Span{
+				Attributes: map[Attribute]Static{
+					NewAttribute("foo"): NewStaticNil(),
+				},
+			},
+			expected: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.query)
+			require.NoError(t, err)
+			require.NoError(t, expr.validate())
+
+			matches, err := expr.Pipeline.Elements[0].(SpansetFilter).matches(nil, tt.span)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, matches)
+		})
+	}
+}
+
+func TestArrayElements_execute(t *testing.T) {
+	tags := func(elements ...Static) Span {
+		return Span{Attributes: map[Attribute]Static{
+			NewScopedAttribute(AttributeScopeSpan, false, "tags"): NewStaticArray(elements),
+		}}
+	}
+	prodAndDev := tags(NewStaticString("prod"), NewStaticString("dev"))
+	onlyProd := tags(NewStaticString("prod"), NewStaticString("prod"))
+	empty := tags()
+
+	tests := []struct {
+		name     string
+		query    string
+		span     Span
+		expected bool
+	}{
+		{name: "any match", query: `{ span.tags[] = "prod" }`, span: prodAndDev, expected: true},
+		{name: "any no match", query: `{ span.tags[] = "qa" }`, span: prodAndDev, expected: false},
+		{name: "any regex", query: `{ .tags[] =~ "d.*" }`, span: prodAndDev, expected: true},
+		{name: "any operand on the left", query: `{ "dev" = .tags[] }`, span: prodAndDev, expected: true},
+		{name: "all match", query: `{ all(span.tags[]) = "prod" }`, span: onlyProd, expected: true},
+		{name: "all no match", query: `{ all(span.tags[]) = "prod" }`, span: prodAndDev, expected: false},
+		{name: "all not equal", query: `{ all(.tags[]) != "qa" }`, span: prodAndDev, expected: true},
+		{name: "any empty", query: `{ span.tags[] = "prod" }`, span: empty, expected: false},
+		{name: "all empty", query: `{ all(span.tags[]) = "prod" }`, span: empty, expected: true},
+		{name: "any missing", query: `{ span.tags[] != "prod" }`, span: Span{}, expected: false},
+		{name: "all missing", query: `{ all(span.tags[]) = "prod" }`, span: Span{}, expected: false},
+		{
+			name:  "not an array",
+			query: `{ all(.tags[]) = "prod" }`,
+			span: Span{Attributes: map[Attribute]Static{
+				NewAttribute("tags"): NewStaticString("prod"),
+			}},
+			expected: false,
+		},
+		{
+			name:     "mixed element types",
+			query:    `{ all(.tags[]) > 1 }`,
+			span:     tags(NewStaticInt(2), NewStaticFloat(1.5), NewStaticString("3")),
+			expected: false,
+		},
+		{
+			name:     "numeric elements",
+			query:    `{ .tags[] > 2 && all(.tags[]) < 10 }`,
+			span:     tags(NewStaticInt(2), NewStaticFloat(3.5)),
+			expected: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.query)
+			require.NoError(t, err)
+			require.NoError(t, expr.validate())
+
+			matches, err := expr.Pipeline.Elements[0].(SpansetFilter).matches(nil, tt.span)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, matches)
+		})
+	}
+}
+
+func TestArrayElements_executeDecodesOncePerSpan(t *testing.T) {
+	tags := NewScopedAttribute(AttributeScopeSpan, false, "tags")
+	span := Span{Attributes: map[Attribute]Static{
+		tags: NewStaticArray([]Static{NewStaticString("prod"), NewStaticString("dev")}),
+	}}
+
+	expr, err := Parse(`{ span.tags[] = "qa" || all(span.tags[]) != "qa" }`)
+	require.NoError(t, err)
+
+	ec := newEvalContext(EvalOptions{})
+	require.NoError(t, ec.nextSpan())
+	matches, err := expr.Pipeline.Elements[0].(SpansetFilter).matches(ec, span)
+	require.NoError(t, err)
+	require.True(t, matches)
+	require.Len(t, ec.arrays, 1)
+
+	require.NoError(t, ec.nextSpan())
+	require.Empty(t, ec.arrays)
+}
+
+func TestArrayElements_executeInvalid(t *testing.T) {
+	// an int element without its value
+	span := Span{Attributes: map[Attribute]Static{
+		NewScopedAttribute(AttributeScopeSpan, false, "tags"): {Type: TypeArray, S: string([]byte{byte(TypeInt)})},
+	}}
+
+	expr, err := Parse(`{ span.tags[] = 1 }`)
+	require.NoError(t, err)
+
+	_, err = expr.Pipeline.Elements[0].(SpansetFilter).matches(newEvalContext(EvalOptions{}), span)
+	require.ErrorIs(t, err, errInvalidArray)
+}
+
+func TestSetOperation_execute(t *testing.T) {
+	span := Span{
+		Attributes: map[Attribute]Static{
+			NewAttribute("foo"): NewStaticString("b"),
+			NewAttribute("bar"): NewStaticInt(2),
+			NewAttribute("nil"): NewStaticNil(),
+		},
+	}
+
+	tests := []struct {
+		query    string
+		expected bool
+	}{
+		// membership
+		{query: "{ .foo in (`a`, `b`) }", expected: true},
+		{query: "{ .bar in (1, 2) }", expected: true},
+		{query: "{ .foo not in (`a`, `b`) }", expected: false},
+		// non-membership
+		{query: "{ .foo in (`a`, `c`) }", expected: false},
+		{query: "{ .bar in (1.5, 3) }", expected: false},
+		{query: "{ .foo not in (`a`, `c`) }", expected: true},
+		// nil is only in sets containing nil
+		{query: "{ .nil in (`a`) }", expected: false},
+		{query: "{ .nil not in (`a`) }", expected: true},
+		{query: "{ .missing not in (`a`) }", expected: true},
+		{query: "{ .nil in (nil) }", expected: true},
+		{query: "{ .nil not in (nil, `a`) }", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			expr, err := Parse(tt.query)
+			require.NoError(t, err)
+			require.NoError(t, expr.validate())
+
+			filter := expr.Pipeline.Elements[0].(SpansetFilter)
+			require.Equal(t, TypeBoolean, filter.Expression.impliedType())
+
+			result, err := filter.Expression.execute(nil, span)
+			require.NoError(t, err)
+			require.Equal(t, NewStaticBool(tt.expected), result)
+		})
+	}
+}
+
 func TestSpansetOperationEvaluate(t *testing.T) {
+	child := func(id, parent byte, foo string) Span {
+		s := Span{ID: []byte{id}, Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString(foo)}}
+		if parent != 0 {
+			s.ParentID = []byte{parent}
+		}
+		return s
+	}
+
 	testCases := []struct {
 		query  string
 		input  []Spanset
@@ -195,18 +749,969 @@ func TestSpansetOperationEvaluate(t *testing.T) {
 				}},
 			},
 		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.query, func(t *testing.T) {
-			ast, err := Parse(tc.query)
-			require.NoError(t, err)
+		{
+			"{ .foo = `a` } && { .bar = `b` }",
+			[]Spanset{
+				{Spans: []Span{
+					// Matched by both sides but only returned once
+					{ID: []byte{1}, Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("a"), NewAttribute("bar"): NewStaticString("b")}},
+					{ID: []byte{2}, Attributes: map[Attribute]Static{NewAttribute("bar"): NewStaticString("b")}},
+				}},
+			},
+			[]Spanset{
+				{Spans: []Span{
+					{ID: []byte{1}, Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("a"), NewAttribute("bar"): NewStaticString("b")}},
+					{ID: []byte{2}, Attributes: map[Attribute]Static{NewAttribute("bar"): NewStaticString("b")}},
+				}},
+			},
+		},
+		{
+			"{ .foo = `a` } || { .bar = `b` }",
+			[]Spanset{
+				{Spans: []Span{
+					// Matched by both sides but only returned once
+					{ID: []byte{1}, Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("a"), NewAttribute("bar"): NewStaticString("b")}},
+				}},
+			},
+			[]Spanset{
+				{Spans: []Span{
+					{ID: []byte{1}, Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("a"), NewAttribute("bar"): NewStaticString("b")}},
+				}},
+			},
+		},
+		{
+			"{ .foo = `a` } !>> { .foo = `b` }",
+			[]Spanset{
+				{Spans: []Span{
+					// 1 has a matching grandchild through a span that matches neither side
+					child(1, 0, "a"), child(2, 1, "x"), child(3, 2, "b"),
+					// 4 only has a child that doesn't match
+					child(4, 0, "a"), child(5, 4, "c"),
+					// 6 is below a matching span, which isn't a descendant
+					child(6, 3, "a"),
+				}},
+				{Spans: []Span{
+					// This spanset will be dropped because every span matching the left has a
+					// matching descendant at some depth
+					child(1, 0, "a"), child(2, 1, "x"), child(3, 2, "x"), child(4, 3, "x"), child(5, 4, "x"), child(6, 5, "b"),
+				}},
+				{Spans: []Span{
+					// Without a match on the right every span of the left is kept
+					child(1, 0, "a"), child(2, 1, "x"),
+				}},
+			},
+			[]Spanset{
+				{Spans: []Span{child(4, 0, "a"), child(6, 3, "a")}},
+				{Spans: []Span{child(1, 0, "a")}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			ast, err := Parse(tc.query)
+			require.NoError(t, err)
 
 			filt := ast.Pipeline.Elements[0].(SpansetOperation)
 
-			actual, err := filt.evaluate(tc.input)
+			actual, err := filt.evaluate(nil, tc.input)
 			require.NoError(t, err)
 			require.Equal(t, tc.output, actual)
 		})
 	}
 }
+
+func TestFlattenOperationEvaluate(t *testing.T) {
+	shared := Span{ID: []byte{1}}
+
+	tests := []struct {
+		name   string
+		input  []Spanset
+		output []Spanset
+	}{
+		{
+			name:   "no spansets",
+			input:  []Spanset{},
+			output: []Spanset{},
+		},
+		{
+			name: "multiple spansets",
+			input: []Spanset{
+				{TraceID: []byte{1}, RootSpanName: "root", Spans: []Span{{ID: []byte{1}}, {ID: []byte{2}}}},
+				{TraceID: []byte{1}, RootSpanName: "root", Spans: []Span{{ID: []byte{3}}}},
+				{TraceID: []byte{1}, RootSpanName: "root", Spans: []Span{{ID: []byte{4}}}},
+			},
+			output: []Spanset{
+				{TraceID: []byte{1}, RootSpanName: "root", Spans: []Span{{ID: []byte{1}}, {ID: []byte{2}}, {ID: []byte{3}}, {ID: []byte{4}}}},
+			},
+		},
+		{
+			name: "overlapping spans",
+			input: []Spanset{
+				{Spans: []Span{shared, {ID: []byte{2}}}},
+				{Spans: []Span{{ID: []byte{3}}, shared}},
+				{Spans: []Span{shared}},
+			},
+			output: []Spanset{
+				{Spans: []Span{shared, {ID: []byte{2}}, {ID: []byte{3}}}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := newFlattenOperation().evaluate(nil, tc.input)
+			require.NoError(t, err)
+			require.Equal(t, tc.output, actual)
+		})
+	}
+
+	// trace wide count after flattening
+	expr, err := Parse("{ .a = 1 } | by(.b) | flatten() | count() = 3")
+	require.NoError(t, err)
+
+	input := []Spanset{{Spans: []Span{
+		{ID: []byte{1}, Attributes: map[Attribute]Static{NewAttribute("a"): NewStaticInt(1), NewAttribute("b"): NewStaticString("x")}},
+		{ID: []byte{2}, Attributes: map[Attribute]Static{NewAttribute("a"): NewStaticInt(1), NewAttribute("b"): NewStaticString("y")}},
+		{ID: []byte{3}, Attributes: map[Attribute]Static{NewAttribute("a"): NewStaticInt(1), NewAttribute("b"): NewStaticString("y")}},
+	}}}
+	actual, err := expr.Pipeline.evaluate(nil, input)
+	require.NoError(t, err)
+	require.Len(t, actual, 1)
+	require.Len(t, actual[0].Spans, 3)
+}
+
+func TestCoalesceOperationEvaluate(t *testing.T) {
+	shared := Span{ID: []byte{1}}
+
+	tests := []struct {
+		name   string
+		input  []Spanset
+		output []Spanset
+	}{
+		{
+			name:  "no spansets",
+			input: []Spanset{},
+		},
+		{
+			name: "spansets of one trace",
+			input: []Spanset{
+				{TraceID: []byte{1}, RootSpanName: "root", Spans: []Span{{ID: []byte{1}}, {ID: []byte{2}}}},
+				{TraceID: []byte{1}, RootSpanName: "root", Spans: []Span{{ID: []byte{3}}}},
+			},
+			output: []Spanset{
+				{TraceID: []byte{1}, RootSpanName: "root", Spans: []Span{{ID: []byte{1}}, {ID: []byte{2}}, {ID: []byte{3}}}},
+			},
+		},
+		{
+			name: "spansets of several traces",
+			input: []Spanset{
+				{TraceID: []byte{1}, Spans: []Span{shared}},
+				{TraceID: []byte{2}, Spans: []Span{{ID: []byte{2}}}},
+				{TraceID: []byte{1}, Spans: []Span{{ID: []byte{3}}, shared}, Truncated: true},
+				{TraceID: []byte{3}, Spans: []Span{{ID: []byte{4}}}},
+				{TraceID: []byte{2}, Spans: []Span{{ID: []byte{5}}}},
+			},
+			output: []Spanset{
+				{TraceID: []byte{1}, Spans: []Span{shared, {ID: []byte{3}}}, Truncated: true},
+				{TraceID: []byte{2}, Spans: []Span{{ID: []byte{2}}, {ID: []byte{5}}}},
+				{TraceID: []byte{3}, Spans: []Span{{ID: []byte{4}}}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := newCoalesceOperation().evaluate(nil, tc.input)
+			require.NoError(t, err)
+			require.Equal(t, tc.output, actual)
+		})
+	}
+
+	// the groups of a trace are merged again
+	expr, err := Parse("{ .a = 1 } | by(.b) | coalesce() | count() = 3")
+	require.NoError(t, err)
+
+	input := []Spanset{{TraceID: []byte{1}, Spans: []Span{
+		{ID: []byte{1}, Attributes: map[Attribute]Static{NewAttribute("a"): NewStaticInt(1), NewAttribute("b"): NewStaticString("x")}},
+		{ID: []byte{2}, Attributes: map[Attribute]Static{NewAttribute("a"): NewStaticInt(1), NewAttribute("b"): NewStaticString("y")}},
+		{ID: []byte{3}, Attributes: map[Attribute]Static{NewAttribute("a"): NewStaticInt(1), NewAttribute("b"): NewStaticString("y")}},
+	}}}
+	actual, err := expr.Pipeline.evaluate(nil, input)
+	require.NoError(t, err)
+	require.Len(t, actual, 1)
+	require.Len(t, actual[0].Spans, 3)
+}
+
+func TestArithmetic(t *testing.T) {
+	tests := []struct {
+		op       Operator
+		lhs, rhs Static
+		expected Static
+	}{
+		{OpAdd, NewStaticInt(3), NewStaticInt(2), NewStaticInt(5)},
+		{OpSub, NewStaticInt(3), NewStaticInt(5), NewStaticInt(-2)},
+		{OpMult, NewStaticInt(3), NewStaticInt(2), NewStaticInt(6)},
+		{OpDiv, NewStaticInt(7), NewStaticInt(2), NewStaticInt(3)},
+		{OpMod, NewStaticInt(7), NewStaticInt(2), NewStaticInt(1)},
+		{OpPower, NewStaticInt(2), NewStaticInt(10), NewStaticInt(1024)},
+		{OpDiv, NewStaticInt(7), NewStaticInt(0), NewStaticNil()},
+		{OpMod, NewStaticInt(7), NewStaticInt(0), NewStaticNil()},
+		{OpAdd, NewStaticFloat(1.5), NewStaticInt(2), NewStaticFloat(3.5)},
+		{OpDiv, NewStaticInt(7), NewStaticFloat(2), NewStaticFloat(3.5)},
+		{OpSub, NewStaticDuration(3 * time.Second), NewStaticDuration(time.Second), NewStaticDuration(2 * time.Second)},
+		{OpMult, NewStaticDuration(time.Second), NewStaticInt(3), NewStaticDuration(3 * time.Second)},
+		{OpDiv, NewStaticDuration(time.Second), NewStaticFloat(4), NewStaticDuration(250 * time.Millisecond)},
+		{OpDiv, NewStaticDuration(time.Second), NewStaticDuration(4 * time.Second), NewStaticFloat(0.25)},
+		{OpDiv, NewStaticDuration(time.Second), NewStaticInt(0), NewStaticNil()},
+		{OpDiv, NewStaticFloat(1.5), NewStaticFloat(0), NewStaticNil()},
+		{OpMod, NewStaticFloat(1.5), NewStaticInt(0), NewStaticNil()},
+		{OpMod, NewStaticDuration(time.Second), NewStaticDuration(0), NewStaticNil()},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%v %v %v", tc.lhs, tc.op, tc.rhs), func(t *testing.T) {
+			actual, err := newBinaryOperation(tc.op, tc.lhs, tc.rhs).execute(nil, Span{})
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestArithmetic_intOverflow(t *testing.T) {
+	tests := []struct {
+		op        Operator
+		lhs, rhs  int
+		saturated int
+	}{
+		{OpAdd, math.MaxInt, 1, math.MaxInt},
+		{OpAdd, math.MinInt, -1, math.MinInt},
+		{OpAdd, math.MaxInt, math.MaxInt, math.MaxInt},
+		{OpSub, math.MinInt, 1, math.MinInt},
+		{OpSub, math.MaxInt, -1, math.MaxInt},
+		{OpSub, 0, math.MinInt, math.MaxInt},
+		{OpMult, math.MaxInt, 2, math.MaxInt},
+		{OpMult, math.MaxInt, -2, math.MinInt},
+		{OpMult, math.MinInt, -1, math.MaxInt},
+		{OpMult, -1, math.MinInt, math.MaxInt},
+		{OpMult, math.MinInt / 2, 3, math.MinInt},
+		{OpDiv, math.MinInt, -1, math.MaxInt},
+		{OpPower, 2, 63, math.MaxInt},
+		{OpPower, -2, 65, math.MinInt},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%d %v %d", tc.lhs, tc.op, tc.rhs), func(t *testing.T) {
+			e := newBinaryOperation(tc.op, NewStaticInt(tc.lhs), NewStaticInt(tc.rhs))
+
+			// errors by default
+			actual, err := e.execute(nil, Span{})
+			require.ErrorIs(t, err, ErrIntegerOverflow)
+			require.Equal(t, NewStaticNil(), actual)
+
+			actual, err = e.execute(newEvalContext(EvalOptions{IntOverflowPolicy: IntOverflowSaturate}), Span{})
+			require.NoError(t, err)
+			require.Equal(t, NewStaticInt(tc.saturated), actual)
+		})
+	}
+
+	// results at the bounds don't overflow
+	for _, e := range []BinaryOperation{
+		newBinaryOperation(OpAdd, NewStaticInt(math.MaxInt-1), NewStaticInt(1)),
+		newBinaryOperation(OpSub, NewStaticInt(math.MinInt+1), NewStaticInt(1)),
+		newBinaryOperation(OpMult, NewStaticInt(math.MinInt/2), NewStaticInt(2)),
+		newBinaryOperation(OpMult, NewStaticInt(math.MaxInt), NewStaticInt(-1)),
+		newBinaryOperation(OpDiv, NewStaticInt(math.MinInt), NewStaticInt(1)),
+		newBinaryOperation(OpMod, NewStaticInt(math.MinInt), NewStaticInt(-1)),
+		newBinaryOperation(OpPower, NewStaticInt(-2), NewStaticInt(63)),
+	} {
+		_, err := e.execute(nil, Span{})
+		require.NoError(t, err, e.String())
+	}
+
+	// sums of large values
+	span := Span{Attributes: map[Attribute]Static{NewAttribute("n"): NewStaticInt(math.MaxInt / 2)}}
+	spans := Spanset{Spans: []Span{span, span, span}}
+	sum := newAggregate(aggregateSum, NewAttribute("n"))
+
+	_, err := sum.compute(nil, spans)
+	require.ErrorIs(t, err, ErrIntegerOverflow)
+
+	actual, err := sum.compute(newEvalContext(EvalOptions{IntOverflowPolicy: IntOverflowSaturate}), spans)
+	require.NoError(t, err)
+	require.Equal(t, NewStaticInt(math.MaxInt), actual)
+}
+
+func TestArithmetic_runtimeZeroDivisor(t *testing.T) {
+	// the divisor is only known per span, so it passes validation and a zero makes the result nil
+	for _, q := range []string{`{ 10 / span.n = 5 }`, `{ 10.5 % span.n = 0.5 }`} {
+		expr, err := Parse(q)
+		require.NoError(t, err)
+
+		filter := expr.Pipeline.Elements[0].(SpansetFilter)
+		for n, expected := range map[int]bool{2: true, 0: false} {
+			span := Span{Attributes: map[Attribute]Static{
+				NewScopedAttribute(AttributeScopeSpan, false, "n"): NewStaticInt(n),
+			}}
+			matches, err := filter.matches(nil, span)
+			require.NoError(t, err)
+			require.Equal(t, expected, matches, "%s with n = %d", q, n)
+		}
+	}
+}
+
+func TestUnsupportedOperation(t *testing.T) {
+	// attributes that haven't been resolved pass the type checks, so these used to panic
+	attribute := Static{Type: TypeAttribute}
+
+	tests := []struct {
+		e        FieldExpression
+		expected string
+	}{
+		{newBinaryOperation(OpAdd, attribute, NewStaticInt(1)), "unsupported operation: + on (attribute, int)"},
+		{newBinaryOperation(OpPower, NewStaticFloat(2), attribute), "unsupported operation: ^ on (float, attribute)"},
+		{newBinaryOperation(OpSpansetChild, attribute, attribute), "unsupported operation: > on (attribute, attribute)"},
+		{newUnaryOperation(OpAdd, NewStaticInt(1)), "unsupported operation: + on (int)"},
+		{newUnaryOperation(OpNot, NewStaticInt(1)), "unsupported operation: ! on (int)"},
+		{newUnaryOperation(OpSub, NewStaticString("a")), "unsupported operation: - on (string)"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expected, func(t *testing.T) {
+			actual, err := tc.e.execute(nil, Span{})
+			require.ErrorIs(t, err, ErrUnsupportedOperation)
+			require.EqualError(t, err, tc.expected)
+			require.Equal(t, NewStaticNil(), actual)
+		})
+	}
+}
+
+func TestBitwiseOperation_execute(t *testing.T) {
+	tests := []struct {
+		op       Operator
+		lhs, rhs Static
+		expected Static
+	}{
+		{OpBitAnd, NewStaticInt(6), NewStaticInt(4), NewStaticInt(4)},
+		{OpBitAnd, NewStaticInt(6), NewStaticInt(1), NewStaticInt(0)},
+		{OpBitAnd, NewStaticInt(-1), NewStaticInt(12), NewStaticInt(12)},
+		{OpBitOr, NewStaticInt(6), NewStaticInt(1), NewStaticInt(7)},
+		{OpBitOr, NewStaticInt(0), NewStaticInt(0), NewStaticInt(0)},
+		// only ints
+		{OpBitAnd, NewStaticFloat(6), NewStaticInt(4), NewStaticNil()},
+		{OpBitAnd, NewStaticDuration(6), NewStaticDuration(4), NewStaticNil()},
+		{OpBitOr, NewStaticNil(), NewStaticInt(4), NewStaticNil()},
+		{OpBitOr, NewStaticString("6"), NewStaticInt(4), NewStaticNil()},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%v(%v, %v)", tc.op, tc.lhs, tc.rhs), func(t *testing.T) {
+			actual, err := newBinaryOperation(tc.op, tc.lhs, tc.rhs).execute(nil, Span{})
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+
+	// flags can be tested by comparing the masked value
+	expr, err := Parse(`{ bitAnd(span.flags, 4) != 0 }`)
+	require.NoError(t, err)
+	for flags, expected := range map[int]bool{4: true, 5: true, 3: false, 0: false} {
+		span := Span{Attributes: map[Attribute]Static{
+			NewScopedAttribute(AttributeScopeSpan, false, "flags"): NewStaticInt(flags),
+		}}
+		matches, err := expr.Pipeline.Elements[0].(SpansetFilter).matches(nil, span)
+		require.NoError(t, err)
+		require.Equal(t, expected, matches, flags)
+	}
+}
+
+func TestFunctionOperation_execute(t *testing.T) {
+	tests := []struct {
+		op       FunctionOp
+		operand  Static
+		expected Static
+	}{
+		{functionAbs, NewStaticInt(3), NewStaticInt(3)},
+		{functionAbs, NewStaticInt(-3), NewStaticInt(3)},
+		{functionAbs, NewStaticInt(0), NewStaticInt(0)},
+		{functionAbs, NewStaticFloat(2.5), NewStaticFloat(2.5)},
+		{functionAbs, NewStaticFloat(-2.5), NewStaticFloat(2.5)},
+		{functionAbs, NewStaticFloat(0), NewStaticFloat(0)},
+		{functionAbs, NewStaticDuration(time.Second), NewStaticDuration(time.Second)},
+		{functionAbs, NewStaticDuration(-time.Second), NewStaticDuration(time.Second)},
+		{functionAbs, NewStaticDuration(0), NewStaticDuration(0)},
+		{functionSign, NewStaticInt(3), NewStaticInt(1)},
+		{functionSign, NewStaticInt(-3), NewStaticInt(-1)},
+		{functionSign, NewStaticInt(0), NewStaticInt(0)},
+		{functionSign, NewStaticFloat(2.5), NewStaticInt(1)},
+		{functionSign, NewStaticFloat(-2.5), NewStaticInt(-1)},
+		{functionSign, NewStaticFloat(0), NewStaticInt(0)},
+		{functionSign, NewStaticDuration(time.Second), NewStaticInt(1)},
+		{functionSign, NewStaticDuration(-time.Second), NewStaticInt(-1)},
+		{functionSign, NewStaticDuration(0), NewStaticInt(0)},
+		// not numbers
+		{functionAbs, NewStaticNil(), NewStaticNil()},
+		{functionAbs, NewStaticString("-1"), NewStaticNil()},
+		{functionSign, NewStaticFloat(math.NaN()), NewStaticNil()},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%v(%v)", tc.op, tc.operand), func(t *testing.T) {
+			actual, err := newFunctionOperation(tc.op, tc.operand).execute(nil, Span{})
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+
+	// composes with comparisons
+	expr, err := Parse(`{ abs(span.skew) > 5ms }`)
+	require.NoError(t, err)
+	for skew, expected := range map[time.Duration]bool{-10 * time.Millisecond: true, 10 * time.Millisecond: true, time.Millisecond: false} {
+		span := Span{Attributes: map[Attribute]Static{
+			NewScopedAttribute(AttributeScopeSpan, false, "skew"): NewStaticDuration(skew),
+		}}
+		matches, err := expr.Pipeline.Elements[0].(SpansetFilter).matches(nil, span)
+		require.NoError(t, err)
+		require.Equal(t, expected, matches, skew)
+	}
+}
+
+func TestSelectOperationEvaluate(t *testing.T) {
+	expr, err := Parse("{ true } | select(.foo, duration - .wait)")
+	require.NoError(t, err)
+	require.NoError(t, expr.validate())
+
+	shared := Span{ID: []byte{1}, Attributes: map[Attribute]Static{
+		NewIntrinsic(IntrinsicDuration): NewStaticDuration(10 * time.Millisecond),
+		NewAttribute("foo"):             NewStaticString("bar"),
+		NewAttribute("wait"):            NewStaticDuration(4 * time.Millisecond),
+	}}
+	input := []Spanset{
+		{Spans: []Span{shared}},
+		{Spans: []Span{{ID: []byte{2}, Attributes: map[Attribute]Static{
+			NewIntrinsic(IntrinsicDuration): NewStaticDuration(time.Second),
+		}}}},
+	}
+
+	output, err := expr.Pipeline.evaluate(nil, input)
+	require.NoError(t, err)
+	require.Len(t, output, 2)
+
+	computed := NewAttribute("duration - .wait")
+	require.Equal(t, NewStaticDuration(6*time.Millisecond), output[0].Spans[0].Attributes[computed])
+	require.Equal(t, NewStaticString("bar"), output[0].Spans[0].Attributes[NewAttribute("foo")])
+
+	// missing attributes are attached as nil
+	require.Equal(t, NewStaticNil(), output[1].Spans[0].Attributes[NewAttribute("foo")])
+	require.Equal(t, NewStaticNil(), output[1].Spans[0].Attributes[computed])
+
+	// the input spans aren't modified
+	require.Len(t, shared.Attributes, 3)
+}
+
+func TestGroupOperationEvaluate(t *testing.T) {
+	a := NewAttribute("a")
+	input := []Spanset{
+		{TraceID: []byte{1}, Spans: []Span{
+			{ID: []byte{1}, Attributes: map[Attribute]Static{a: NewStaticInt(1)}},
+			{ID: []byte{2}, Attributes: map[Attribute]Static{a: NewStaticInt(2)}},
+			{ID: []byte{3}, Attributes: map[Attribute]Static{}},
+			{ID: []byte{4}, Attributes: map[Attribute]Static{a: NewStaticInt(1)}},
+		}},
+		{TraceID: []byte{2}},
+	}
+
+	output, err := newGroupOperation(a).evaluate(nil, input)
+	require.NoError(t, err)
+	require.Equal(t, []Spanset{
+		{TraceID: []byte{1}, Spans: []Span{input[0].Spans[0], input[0].Spans[3]}, group: NewStaticInt(1), grouped: true},
+		{TraceID: []byte{1}, Spans: []Span{input[0].Spans[1]}, group: NewStaticInt(2), grouped: true},
+		{TraceID: []byte{1}, Spans: []Span{input[0].Spans[2]}, group: NewStaticNil(), grouped: true},
+	}, output)
+}
+
+func TestGroupOperationEvaluateMaxGroups(t *testing.T) {
+	a := NewAttribute("a")
+	input := []Spanset{
+		{TraceID: []byte{1}, Spans: []Span{
+			{ID: []byte{1}, Attributes: map[Attribute]Static{a: NewStaticInt(1)}},
+			{ID: []byte{2}, Attributes: map[Attribute]Static{a: NewStaticInt(2)}},
+			{ID: []byte{3}, Attributes: map[Attribute]Static{a: NewStaticInt(3)}},
+			{ID: []byte{4}, Attributes: map[Attribute]Static{a: NewStaticInt(1)}},
+		}},
+		// the limit applies to the groups of all spansets together
+		{TraceID: []byte{2}, Spans: []Span{
+			{ID: []byte{5}, Attributes: map[Attribute]Static{a: NewStaticInt(1)}},
+		}},
+	}
+	all := []Spanset{
+		{TraceID: []byte{1}, Spans: []Span{input[0].Spans[0], input[0].Spans[3]}, group: NewStaticInt(1), grouped: true},
+		{TraceID: []byte{1}, Spans: []Span{input[0].Spans[1]}, group: NewStaticInt(2), grouped: true},
+		{TraceID: []byte{1}, Spans: []Span{input[0].Spans[2]}, group: NewStaticInt(3), grouped: true},
+		{TraceID: []byte{2}, Spans: []Span{input[1].Spans[0]}, group: NewStaticInt(1), grouped: true},
+	}
+
+	for _, policy := range []GroupLimitPolicy{GroupLimitError, GroupLimitDrop} {
+		ec := newEvalContext(EvalOptions{MaxGroups: 4, GroupLimitPolicy: policy})
+		output, err := newGroupOperation(a).evaluate(ec, input)
+		require.NoError(t, err)
+		require.Equal(t, all, output)
+	}
+
+	// groups that exist keep collecting spans, spans of new groups are dropped
+	ec := newEvalContext(EvalOptions{MaxGroups: 2, GroupLimitPolicy: GroupLimitDrop})
+	output, err := newGroupOperation(a).evaluate(ec, input)
+	require.NoError(t, err)
+	require.Equal(t, all[:2], output)
+
+	ec = newEvalContext(EvalOptions{MaxGroups: 2, GroupLimitPolicy: GroupLimitError})
+	_, err = newGroupOperation(a).evaluate(ec, input)
+	require.ErrorIs(t, err, ErrTooManyGroups)
+}
+
+func TestDistinctOperationEvaluate(t *testing.T) {
+	expr, err := Parse("{ true } | distinct(span.http.route)")
+	require.NoError(t, err)
+	require.NoError(t, expr.validate())
+
+	route := NewScopedAttribute(AttributeScopeSpan, false, "http.route")
+	span := func(id byte, start uint64, r string) Span {
+		s := Span{ID: []byte{id}, StartTimeUnixNanos: start, Attributes: map[Attribute]Static{}}
+		if r != "" {
+			s.Attributes[route] = NewStaticString(r)
+		}
+		return s
+	}
+
+	input := []Spanset{
+		{Spans: []Span{
+			span(1, 30, "/a"),
+			span(2, 10, "/b"),
+			span(3, 20, "/a"), // started before 1 and replaces it
+			span(4, 40, "/a"),
+			span(5, 50, ""),
+			span(6, 5, ""), // spans without a route are deduplicated too
+		}},
+		{Spans: []Span{
+			span(7, 10, "/a"), // values are only distinct within a spanset
+			span(8, 10, "/c"),
+		}},
+	}
+
+	output, err := expr.Pipeline.evaluate(nil, input)
+	require.NoError(t, err)
+	require.Len(t, output, 2)
+
+	ids := func(ss Spanset) []byte {
+		var ids []byte
+		for _, s := range ss.Spans {
+			ids = append(ids, s.ID...)
+		}
+		return ids
+	}
+	require.Equal(t, []byte{3, 2, 6}, ids(output[0]))
+	require.Equal(t, []byte{7, 8}, ids(output[1]))
+
+	// the input isn't modified
+	require.Equal(t, []byte{1, 2, 3, 4, 5, 6}, ids(input[0]))
+}
+
+func TestTopKOperationEvaluate(t *testing.T) {
+	span := func(d time.Duration) Span {
+		s := Span{Attributes: map[Attribute]Static{}}
+		if d != 0 {
+			s.Attributes[NewIntrinsic(IntrinsicDuration)] = NewStaticDuration(d)
+		}
+		return s
+	}
+	// spansets are ranked by their slowest span
+	input := []Spanset{
+		{TraceID: []byte{1}, Spans: []Span{span(time.Second), span(5 * time.Second)}},
+		{TraceID: []byte{2}, Spans: []Span{span(2 * time.Second)}},
+		{TraceID: []byte{3}, Spans: []Span{span(0)}},
+		{TraceID: []byte{4}, Spans: []Span{span(7 * time.Second), span(time.Second)}},
+		{TraceID: []byte{5}, Spans: []Span{span(5 * time.Second)}},
+		{TraceID: []byte{6}, Spans: []Span{span(3 * time.Second)}},
+	}
+
+	tests := []struct {
+		query    string
+		expected []byte
+	}{
+		{query: "{ true } | topk(duration, 1)", expected: []byte{4}},
+		// ties keep the order of the input
+		{query: "{ true } | topk(duration, 3)", expected: []byte{4, 1, 5}},
+		{query: "{ true } | topk(duration, 2) | topk(duration, 3)", expected: []byte{4, 1}},
+		// spansets without a value are last
+		{query: "{ true } | topk(duration, 10)", expected: []byte{4, 1, 5, 6, 2, 3}},
+		// expressions rank by their own value, here the fastest span of the spanset
+		{query: "{ true } | topk(0 - duration, 3)", expected: []byte{1, 4, 2}},
+		// the spans of the kept spansets aren't changed
+		{query: "{ duration < 2s } | topk(duration, 2)", expected: []byte{1, 4}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			expr, err := Parse(tc.query)
+			require.NoError(t, err)
+			require.NoError(t, expr.validate())
+
+			output, err := expr.Pipeline.evaluate(newEvalContext(EvalOptions{}), input)
+			require.NoError(t, err)
+
+			var ids []byte
+			for _, ss := range output {
+				ids = append(ids, ss.TraceID...)
+			}
+			require.Equal(t, tc.expected, ids)
+		})
+	}
+
+	output, err := newTopKOperation(NewIntrinsic(IntrinsicDuration), 2).evaluate(nil, input)
+	require.NoError(t, err)
+	require.Equal(t, input[0].Spans, output[1].Spans)
+
+	output, err = newTopKOperation(NewIntrinsic(IntrinsicDuration), 2).evaluate(nil, nil)
+	require.NoError(t, err)
+	require.Empty(t, output)
+}
+
+func TestWithOperationEvaluate(t *testing.T) {
+	expr, err := Parse("{ true } | with(m = max(duration)) | { duration > m * 0.9 }")
+	require.NoError(t, err)
+	require.NoError(t, expr.validate())
+
+	span := func(id byte, d time.Duration) Span {
+		return Span{ID: []byte{id}, Attributes: map[Attribute]Static{
+			NewIntrinsic(IntrinsicDuration): NewStaticDuration(d),
+		}}
+	}
+	input := []Spanset{
+		{TraceID: []byte{1}, Spans: []Span{span(1, 10*time.Millisecond), span(2, 95*time.Millisecond), span(3, 100*time.Millisecond)}},
+		// the value is bound per spanset
+		{TraceID: []byte{2}, Spans: []Span{span(4, 8*time.Millisecond), span(5, 10*time.Millisecond)}},
+	}
+
+	for _, ec := range []*evalContext{nil, newEvalContext(EvalOptions{})} {
+		output, err := expr.Pipeline.evaluate(ec, input)
+		require.NoError(t, err)
+		require.Len(t, output, 2)
+
+		require.Equal(t, []Span{span(2, 95*time.Millisecond), span(3, 100*time.Millisecond)}, output[0].Spans)
+		require.Equal(t, []Span{span(5, 10*time.Millisecond)}, output[1].Spans)
+	}
+
+	// the input spansets aren't modified
+	require.Nil(t, input[0].bindings)
+}
+
+func TestScalarFilterReference(t *testing.T) {
+	// the max is bound per spanset and the next scalar filter keeps the spansets whose average is
+	// close to it
+	expr, err := Parse("{ true } | with(m = max(duration)) | avg(duration) > m / 2")
+	require.NoError(t, err)
+	require.NoError(t, expr.validate())
+
+	span := func(id byte, d time.Duration) Span {
+		return Span{ID: []byte{id}, Attributes: map[Attribute]Static{
+			NewIntrinsic(IntrinsicDuration): NewStaticDuration(d),
+		}}
+	}
+	input := []Spanset{
+		{TraceID: []byte{1}, Spans: []Span{span(1, 10*time.Millisecond), span(2, 20*time.Millisecond), span(3, 100*time.Millisecond)}},
+		{TraceID: []byte{2}, Spans: []Span{span(4, 80*time.Millisecond), span(5, 100*time.Millisecond)}},
+	}
+
+	for _, ec := range []*evalContext{nil, newEvalContext(EvalOptions{})} {
+		output, err := expr.Pipeline.evaluate(ec, input)
+		require.NoError(t, err)
+		require.Len(t, output, 1)
+		require.Equal(t, []byte{2}, output[0].TraceID)
+	}
+
+	// a name that isn't bound fails evaluation as well as validation
+	_, err = evaluateScalar(nil, newReference("m"), input[:1])
+	require.EqualError(t, err, "m is not bound")
+}
+
+func TestAggregateCompute(t *testing.T) {
+	ss := Spanset{Spans: []Span{
+		{Attributes: map[Attribute]Static{NewAttribute("a"): NewStaticInt(2)}},
+		{Attributes: map[Attribute]Static{NewAttribute("a"): NewStaticInt(5)}},
+		{Attributes: map[Attribute]Static{NewAttribute("a"): NewStaticString("ignored")}},
+		{Attributes: map[Attribute]Static{}},
+	}}
+
+	tests := []struct {
+		agg      Aggregate
+		expected Static
+	}{
+		{newAggregate(aggregateCount, nil), NewStaticInt(4)},
+		{newAggregate(aggregateMax, NewAttribute("a")), NewStaticInt(5)},
+		{newAggregate(aggregateMin, NewAttribute("a")), NewStaticInt(2)},
+		{newAggregate(aggregateSum, NewAttribute("a")), NewStaticInt(7)},
+		{newAggregate(aggregateAvg, NewAttribute("a")), NewStaticFloat(3.5)},
+		{newAggregate(aggregateMax, NewAttribute("missing")), NewStaticNil()},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.agg.String(), func(t *testing.T) {
+			actual, err := tc.agg.compute(nil, ss)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestAggregateComputedExpression(t *testing.T) {
+	span := func(a, b int, x Static) Span {
+		return Span{Attributes: map[Attribute]Static{
+			NewScopedAttribute(AttributeScopeSpan, false, "a"): NewStaticInt(a),
+			NewScopedAttribute(AttributeScopeSpan, false, "b"): NewStaticInt(b),
+			NewScopedAttribute(AttributeScopeSpan, false, "x"): x,
+		}}
+	}
+	input := []Spanset{
+		{TraceID: []byte{1}, Spans: []Span{span(5, 2, NewStaticFloat(1.5)), span(1, 4, NewStaticInt(3))}},
+		{TraceID: []byte{2}, Spans: []Span{span(10, 1, NewStaticString("ignored")), {}}},
+	}
+
+	tests := []struct {
+		query    string
+		expected Static
+	}{
+		// the expression is computed for every span and then aggregated
+		{query: "{ true } | sum(span.a - span.b)", expected: NewStaticInt(3 - 3 + 9)},
+		{query: "{ true } | avg(span.x * 2)", expected: NewStaticFloat((3 + 6) / 2.0)},
+		{query: "{ true } | max(abs(span.a - span.b))", expected: NewStaticInt(9)},
+		{query: "{ true } | min(-span.a)", expected: NewStaticInt(-10)},
+		{query: "{ true } | sum(span.a - span.b) * 2", expected: NewStaticInt(18)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			expr, err := Parse(tc.query)
+			require.NoError(t, err)
+
+			actual, err := EvaluateScalar(expr, input)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+
+	// filters compute the aggregate per spanset
+	expr, err := Parse("{ true } | sum(span.a - span.b) > 0")
+	require.NoError(t, err)
+	output, err := NewEvaluator(EvalOptions{}, nil).Evaluate(context.Background(), expr.Pipeline, input)
+	require.NoError(t, err)
+	require.Len(t, output, 1)
+	require.Equal(t, []byte{2}, output[0].TraceID)
+}
+
+func TestAggregateCountDistinct(t *testing.T) {
+	route := func(v Static) Span {
+		return Span{Attributes: map[Attribute]Static{NewScopedAttribute(AttributeScopeSpan, false, "http.route"): v}}
+	}
+	countDistinct := newAggregate(aggregateCountDistinct, NewScopedAttribute(AttributeScopeSpan, false, "http.route"))
+
+	tests := []struct {
+		name     string
+		spans    []Span
+		expected int
+	}{
+		{
+			name:     "all distinct",
+			spans:    []Span{route(NewStaticString("/a")), route(NewStaticString("/b")), route(NewStaticString("/c"))},
+			expected: 3,
+		},
+		{
+			name:     "all same",
+			spans:    []Span{route(NewStaticString("/a")), route(NewStaticString("/a")), route(NewStaticString("/a"))},
+			expected: 1,
+		},
+		{
+			name:     "mixed",
+			spans:    []Span{route(NewStaticString("/a")), route(NewStaticString("/b")), route(NewStaticString("/a")), route(NewStaticInt(1)), {}},
+			expected: 3,
+		},
+		{
+			name:     "no values",
+			spans:    []Span{{}, {}},
+			expected: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := countDistinct.compute(nil, Spanset{Spans: tc.spans})
+			require.NoError(t, err)
+			require.Equal(t, NewStaticInt(tc.expected), actual)
+		})
+	}
+
+	// the number of values is bounded, repeated values don't count towards the limit
+	spans := []Span{route(NewStaticString("/a")), route(NewStaticString("/b")), route(NewStaticString("/a"))}
+	actual, err := countDistinct.compute(newEvalContext(EvalOptions{MaxDistinctValues: 2}), Spanset{Spans: spans})
+	require.NoError(t, err)
+	require.Equal(t, NewStaticInt(2), actual)
+
+	spans = append(spans, route(NewStaticString("/c")))
+	_, err = countDistinct.compute(newEvalContext(EvalOptions{MaxDistinctValues: 2}), Spanset{Spans: spans})
+	require.ErrorIs(t, err, ErrTooManyDistinctValues)
+
+	// values are counted per spanset when filtering
+	expr, err := Parse(`{ true } | count_distinct(span.http.route) > 1`)
+	require.NoError(t, err)
+	output, err := NewEvaluator(EvalOptions{}, nil).Evaluate(context.Background(), expr.Pipeline, []Spanset{
+		{TraceID: []byte{1}, Spans: []Span{route(NewStaticString("/a")), route(NewStaticString("/a"))}},
+		{TraceID: []byte{2}, Spans: []Span{route(NewStaticString("/a")), route(NewStaticString("/b"))}},
+	})
+	require.NoError(t, err)
+	require.Len(t, output, 1)
+	require.Equal(t, []byte{2}, output[0].TraceID)
+}
+
+func TestReferenceNotBound(t *testing.T) {
+	_, err := newReference("m").execute(nil, Span{})
+	require.EqualError(t, err, "m is not bound")
+}
+
+func TestAppendSpansDedup(t *testing.T) {
+	shared := Span{ID: []byte{1}, Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("a")}}
+
+	lhs := []Spanset{{Spans: []Span{shared, {ID: []byte{2}}}}}
+	rhs := []Spanset{{Spans: []Span{{ID: []byte{3}}, shared}}}
+
+	seen := map[string]struct{}{}
+	spans := appendSpans(nil, seen, lhs)
+	spans = appendSpans(spans, seen, rhs)
+
+	require.Equal(t, []Span{shared, {ID: []byte{2}}, {ID: []byte{3}}}, spans)
+
+	// spans without an ID have no identity and are always kept
+	spans = appendSpans(nil, map[string]struct{}{}, []Spanset{{Spans: []Span{{}, {}}}})
+	require.Len(t, spans, 2)
+}
+
+func TestSpansetFilterEvaluateAttributeCache(t *testing.T) {
+	expr, err := Parse("{ .a > 1 && .a < 10 && .b = `x` }")
+	require.NoError(t, err)
+	filt := expr.Pipeline.Elements[0].(SpansetFilter)
+
+	// consecutive spans with different values make sure the cache doesn't leak across spans
+	input := []Spanset{{Spans: []Span{
+		{ID: []byte{1}, Attributes: map[Attribute]Static{NewAttribute("a"): NewStaticInt(5), NewAttribute("b"): NewStaticString("x")}},
+		{ID: []byte{2}, Attributes: map[Attribute]Static{NewAttribute("a"): NewStaticInt(50), NewAttribute("b"): NewStaticString("x")}},
+		{ID: []byte{3}, Attributes: map[Attribute]Static{NewAttribute("b"): NewStaticString("x")}},
+		{ID: []byte{4}, Attributes: map[Attribute]Static{NewAttribute("a"): NewStaticInt(2), NewAttribute("b"): NewStaticString("x")}},
+		{ID: []byte{5}, Attributes: map[Attribute]Static{NewAttribute("a"): NewStaticInt(2)}},
+	}}}
+
+	uncached := newEvalContext(EvalOptions{})
+	expected, err := filt.evaluate(uncached, input)
+	require.NoError(t, err)
+	require.Len(t, expected, 1)
+	require.Equal(t, []Span{input[0].Spans[0], input[0].Spans[3]}, expected[0].Spans)
+
+	cached := newEvalContext(EvalOptions{CacheAttributes: true})
+	actual, err := filt.evaluate(cached, input)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	// .a is resolved once per span instead of twice
+	require.Less(t, cached.resolutions, uncached.resolutions)
+}
+
+func BenchmarkSpansetFilterEvaluateAttributeCache(b *testing.B) {
+	expr, err := Parse("{ .a > 1 && .a < 10 && .a != 5 }")
+	require.NoError(b, err)
+	filt := expr.Pipeline.Elements[0].(SpansetFilter)
+
+	spans := make([]Span, 1000)
+	for i := range spans {
+		spans[i] = Span{Attributes: map[Attribute]Static{
+			NewScopedAttribute(AttributeScopeResource, false, "a"): NewStaticInt(i % 20),
+			NewScopedAttribute(AttributeScopeSpan, false, "b"):     NewStaticInt(i),
+		}}
+	}
+	input := []Spanset{{Spans: spans}}
+
+	for _, cache := range []bool{false, true} {
+		b.Run(fmt.Sprintf("cache=%v", cache), func(b *testing.B) {
+			ec := newEvalContext(EvalOptions{CacheAttributes: cache})
+			for i := 0; i < b.N; i++ {
+				_, _ = filt.evaluate(ec, input)
+			}
+			b.ReportMetric(float64(ec.resolutions)/float64(b.N), "resolutions/op")
+		})
+	}
+}
+
+func TestPipelineEvaluateSpanPool(t *testing.T) {
+	expr, err := Parse("{ .a < 10 } | { .a > 2 } | { .a != 5 }")
+	require.NoError(t, err)
+
+	input := func() []Spanset {
+		spans := make([]Span, 20)
+		for i := range spans {
+			spans[i] = Span{ID: []byte{byte(i)}, Attributes: map[Attribute]Static{
+				NewScopedAttribute(AttributeScopeSpan, false, "a"): NewStaticInt(i),
+			}}
+		}
+		return []Spanset{{Spans: spans}}
+	}
+	ids := func(ss []Spanset) []byte {
+		var ids []byte
+		for _, s := range ss {
+			for _, span := range s.Spans {
+				ids = append(ids, span.ID[0])
+			}
+		}
+		return ids
+	}
+
+	ec := newEvalContext(EvalOptions{})
+	first, err := expr.Pipeline.evaluate(ec, input())
+	require.NoError(t, err)
+	require.Equal(t, []byte{3, 4, 6, 7, 8, 9}, ids(first))
+
+	// the slices of the intermediate spansets are recycled, the ones of the result are not
+	for i := 0; i < 10; i++ {
+		result, err := expr.Pipeline.evaluate(newEvalContext(EvalOptions{}), input())
+		require.NoError(t, err)
+		require.Equal(t, ids(first), ids(result))
+	}
+	require.Equal(t, []byte{3, 4, 6, 7, 8, 9}, ids(first))
+
+	// filters that don't match return their slices right away
+	empty, err := expr.Pipeline.evaluate(ec, []Spanset{{Spans: input()[0].Spans[10:]}})
+	require.NoError(t, err)
+	require.Empty(t, empty)
+}
+
+func BenchmarkPipelineEvaluateSpanPool(b *testing.B) {
+	expr, err := Parse("{ .a > 1 } | { .a < 18 } | { .a != 5 }")
+	require.NoError(b, err)
+
+	input := make([]Spanset, 100)
+	for i := range input {
+		spans := make([]Span, 100)
+		for j := range spans {
+			spans[j] = Span{Attributes: map[Attribute]Static{
+				NewScopedAttribute(AttributeScopeSpan, false, "a"): NewStaticInt(j % 20),
+			}}
+		}
+		input[i] = Spanset{Spans: spans}
+	}
+
+	for _, pool := range []bool{false, true} {
+		b.Run(fmt.Sprintf("pool=%v", pool), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ec := newEvalContext(EvalOptions{})
+				if !pool {
+					ec.pool = nil
+				}
+				_, _ = expr.Pipeline.evaluate(ec, input)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package traceql
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+// Equal returns true if both expressions have the same structure, operators and operands. Statics
+// are compared with the same semantics as Static.Equals.
+func (r *RootExpr) Equal(other *RootExpr) bool {
+	if r == nil || other == nil {
+		return r == other
+	}
+	return bytes.Equal(r.canonical(), other.canonical())
+}
+
+// Hash returns a hash of the expression that is stable across processes. Expressions that are Equal
+// have the same hash.
+func (r *RootExpr) Hash() uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write(r.canonical())
+	return h.Sum64()
+}
+
+func (r *RootExpr) canonical() []byte {
+	w := &canonicalWriter{}
+	if r != nil {
+		w.element(r.Pipeline)
+	}
+	return w.buf.Bytes()
+}
+
+// canonicalWriter serializes an AST into a byte sequence that identifies it structurally. Every node
+// is written as a tag followed by its fields so that distinct trees can't produce the same bytes.
+type canonicalWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *canonicalWriter) tag(t byte) {
+	w.buf.WriteByte(t)
+}
+
+func (w *canonicalWriter) int(i int64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(b[:], i)
+	w.buf.Write(b[:n])
+}
+
+func (w *canonicalWriter) bool(b bool) {
+	if b {
+		w.buf.WriteByte(1)
+	} else {
+		w.buf.WriteByte(0)
+	}
+}
+
+func (w *canonicalWriter) string(s string) {
+	w.int(int64(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *canonicalWriter) element(e Element) {
+	switch e := e.(type) {
+	case nil:
+		w.tag('0')
+	case Pipeline:
+		w.tag('P')
+		w.int(int64(len(e.Elements)))
+		for _, el := range e.Elements {
+			w.element(el)
+		}
+	case GroupOperation:
+		w.tag('G')
+		w.element(e.Expression)
+	case CoalesceOperation:
+		w.tag('C')
+	case FlattenOperation:
+		w.tag('L')
+	case SelectOperation:
+		w.tag('X')
+		w.int(int64(len(e.Exprs)))
+		for _, ex := range e.Exprs {
+			w.element(ex)
+		}
+	case DistinctOperation:
+		w.tag('D')
+		w.element(e.Expression)
+	case TopKOperation:
+		w.tag('K')
+		w.element(e.Expression)
+		w.int(int64(e.K))
+	case WithOperation:
+		w.tag('W')
+		w.string(e.Name)
+		w.element(e.Value)
+	case ScalarOperation:
+		w.tag('O')
+		w.int(int64(e.Op))
+		w.element(e.LHS)
+		w.element(e.RHS)
+	case Aggregate:
+		w.tag('A')
+		w.int(int64(e.agg))
+		w.element(e.e)
+	case SpansetOperation:
+		w.tag('S')
+		w.int(int64(e.Op))
+		w.element(e.LHS)
+		w.element(e.RHS)
+	case SpansetFilter:
+		w.tag('F')
+		w.element(e.Expression)
+	case ScalarFilter:
+		w.tag('f')
+		w.int(int64(e.op))
+		w.element(e.lhs)
+		w.element(e.rhs)
+	case BinaryOperation:
+		w.tag('B')
+		w.int(int64(e.Op))
+		w.element(e.LHS)
+		w.element(e.RHS)
+	case UnaryOperation:
+		w.tag('U')
+		w.int(int64(e.Op))
+		w.element(e.Expression)
+	case SetOperation:
+		w.tag('I')
+		w.int(int64(e.Op))
+		w.element(e.Expression)
+		w.int(int64(len(e.Values)))
+		for _, v := range e.Values {
+			w.static(v)
+		}
+	case HasOperation:
+		w.tag('H')
+		w.element(e.Expression)
+	case ArrayElements:
+		w.tag('Y')
+		w.element(e.Attribute)
+		w.bool(e.All)
+	case FunctionOperation:
+		w.tag('N')
+		w.int(int64(e.Op))
+		w.element(e.Expression)
+	case Reference:
+		w.tag('R')
+		w.string(e.Name)
+	case Static:
+		w.tag('V')
+		w.static(e)
+	case Attribute:
+		w.tag('a')
+		w.int(int64(e.Scope))
+		w.bool(e.Parent)
+		w.string(e.Name)
+		w.int(int64(e.Intrinsic))
+	default:
+		w.tag('?')
+		w.string(fmt.Sprintf("%T", e))
+		w.string(e.String())
+	}
+}
+
+func (w *canonicalWriter) static(s Static) {
+	switch s.Type {
+	case TypeInt:
+		w.int(int64(TypeInt))
+		w.int(int64(s.N))
+	case TypeStatus:
+		// Static.Equals treats a status and the equivalent int as equal
+		w.int(int64(TypeInt))
+		w.int(int64(s.Status))
+	case TypeFloat:
+		w.int(int64(TypeFloat))
+		w.int(int64(math.Float64bits(s.F)))
+	case TypeString:
+		w.int(int64(TypeString))
+		w.string(s.S)
+	case TypeBoolean:
+		w.int(int64(TypeBoolean))
+		w.bool(s.B)
+	case TypeDuration:
+		w.int(int64(TypeDuration))
+		w.int(int64(s.D))
+	case TypeTimestamp:
+		w.int(int64(TypeTimestamp))
+		w.int(int64(s.N))
+	case TypeArray:
+		w.int(int64(TypeArray))
+		w.string(s.S)
+	default:
+		w.int(int64(s.Type))
+	}
+}
@@ -0,0 +1,79 @@
+package traceql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRootExprEqualAndHash(t *testing.T) {
+	equal := []struct {
+		lhs string
+		rhs string
+	}{
+		{`{ .foo = "bar" }`, `{   .foo =   "bar"   }`},
+		{`{ .foo = "bar" } | count() > 1`, `{ .foo = "bar" }|count() > 1`},
+		{`{ (.a = 1) && (.b = 2) }`, `{ .a = 1 && .b = 2 }`},
+		{`{ status = error }`, `{ status = error }`},
+		{`{ duration > 1s } >> { .foo }`, `{ duration > 1000ms } >> { .foo }`},
+		{`{ .foo = 1.5 } | by(.bar) | avg(duration) > 1s`, `{ .foo = 1.5 } | by(.bar) | avg(duration) > 1s`},
+	}
+	for _, tc := range equal {
+		t.Run(tc.lhs+" == "+tc.rhs, func(t *testing.T) {
+			lhs, err := Parse(tc.lhs)
+			require.NoError(t, err)
+			rhs, err := Parse(tc.rhs)
+			require.NoError(t, err)
+
+			assert.True(t, lhs.Equal(rhs))
+			assert.True(t, rhs.Equal(lhs))
+			assert.Equal(t, lhs.Hash(), rhs.Hash())
+		})
+	}
+
+	notEqual := []struct {
+		lhs string
+		rhs string
+	}{
+		{`{ .foo = "bar" }`, `{ .foo = "baz" }`},
+		{`{ .foo = "bar" }`, `{ span.foo = "bar" }`},
+		{`{ .foo = 1 }`, `{ .foo = 1.0 }`},
+		{`{ .foo > 1 }`, `{ 1 > .foo }`},
+		{`{ .a - .b = 1 }`, `{ .b - .a = 1 }`},
+		{`{ .a } >> { .b }`, `{ .b } >> { .a }`},
+		{`{ .a } | count() > 1`, `{ .a } | count() > 2`},
+		{`{ .a } | max(duration) > 1s`, `{ .a } | min(duration) > 1s`},
+		{`{ .a }`, `{ .a } | coalesce()`},
+		{`{ .a } | topk(duration, 1)`, `{ .a } | topk(duration, 2)`},
+	}
+	for _, tc := range notEqual {
+		t.Run(tc.lhs+" != "+tc.rhs, func(t *testing.T) {
+			lhs, err := Parse(tc.lhs)
+			require.NoError(t, err)
+			rhs, err := Parse(tc.rhs)
+			require.NoError(t, err)
+
+			assert.False(t, lhs.Equal(rhs))
+			assert.False(t, rhs.Equal(lhs))
+			assert.NotEqual(t, lhs.Hash(), rhs.Hash())
+		})
+	}
+}
+
+func TestRootExprHashIsStable(t *testing.T) {
+	expr, err := Parse(`{ .foo = "bar" && duration > 1s } | count() > 1`)
+	require.NoError(t, err)
+
+	// the hash must not change between processes so it is safe to persist
+	assert.Equal(t, uint64(0xcb0d30566f8f6091), expr.Hash())
+}
+
+func TestStaticCanonicalMatchesEquals(t *testing.T) {
+	status := &RootExpr{Pipeline: newPipeline(newSpansetFilter(newBinaryOperation(OpEqual, NewIntrinsic(IntrinsicStatus), NewStaticStatus(StatusOk))))}
+	integer := &RootExpr{Pipeline: newPipeline(newSpansetFilter(newBinaryOperation(OpEqual, NewIntrinsic(IntrinsicStatus), NewStaticInt(int(StatusOk)))))}
+
+	require.True(t, NewStaticStatus(StatusOk).Equals(NewStaticInt(int(StatusOk))))
+	assert.True(t, status.Equal(integer))
+	assert.Equal(t, status.Hash(), integer.Hash())
+}
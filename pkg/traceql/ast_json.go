@@ -0,0 +1,157 @@
+package traceql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// staticJSONTypes are the names of the types of statics in JSON. Spansets and attributes aren't
+// values and can't be serialized.
+var staticJSONTypes = map[StaticType]string{
+	TypeNil:       "nil",
+	TypeInt:       "int",
+	TypeFloat:     "float",
+	TypeString:    "string",
+	TypeBoolean:   "bool",
+	TypeDuration:  "duration",
+	TypeStatus:    "status",
+	TypeTimestamp: "timestamp",
+	TypeArray:     "array",
+}
+
+type staticJSON struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// MarshalJSON encodes the static with its type, e.g. {"type":"duration","value":"1.5s"}. Durations,
+// statuses and timestamps are encoded as strings the same way they are written in queries. Floats that JSON
+// numbers can't hold are encoded as the strings "NaN", "+Inf" and "-Inf". Nil has no value and the
+// value of an array is the list of its encoded elements.
+func (s Static) MarshalJSON() ([]byte, error) {
+	name, ok := staticJSONTypes[s.Type]
+	if !ok {
+		return nil, fmt.Errorf("static of type %d can't be encoded as JSON", s.Type)
+	}
+
+	var value interface{}
+	switch s.Type {
+	case TypeNil:
+		return json.Marshal(staticJSON{Type: name})
+	case TypeInt:
+		value = s.N
+	case TypeFloat:
+		value = s.F
+		if math.IsNaN(s.F) || math.IsInf(s.F, 0) {
+			value = strconv.FormatFloat(s.F, 'g', -1, 64)
+		}
+	case TypeString:
+		value = s.S
+	case TypeBoolean:
+		value = s.B
+	case TypeDuration:
+		value = s.D.String()
+	case TypeStatus:
+		value = s.Status.String()
+	case TypeTimestamp:
+		value = time.Unix(0, int64(s.N)).UTC().Format(time.RFC3339Nano)
+	case TypeArray:
+		elements, err := s.Elements()
+		if err != nil {
+			return nil, err
+		}
+		if elements == nil {
+			elements = []Static{}
+		}
+		value = elements
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(staticJSON{Type: name, Value: raw})
+}
+
+// UnmarshalJSON decodes a static encoded by MarshalJSON.
+func (s *Static) UnmarshalJSON(data []byte) error {
+	var sj staticJSON
+	if err := json.Unmarshal(data, &sj); err != nil {
+		return err
+	}
+
+	if sj.Type == staticJSONTypes[TypeNil] {
+		*s = NewStaticNil()
+		return nil
+	}
+	if len(sj.Value) == 0 {
+		return fmt.Errorf("static of type %q without a value", sj.Type)
+	}
+
+	var err error
+	switch sj.Type {
+	case staticJSONTypes[TypeInt]:
+		var n int
+		err = json.Unmarshal(sj.Value, &n)
+		*s = NewStaticInt(n)
+	case staticJSONTypes[TypeFloat]:
+		var f float64
+		if err = json.Unmarshal(sj.Value, &f); err != nil {
+			// non-finite floats are strings
+			var str string
+			if json.Unmarshal(sj.Value, &str) == nil {
+				f, err = strconv.ParseFloat(str, 64)
+			}
+		}
+		*s = NewStaticFloat(f)
+	case staticJSONTypes[TypeString]:
+		var str string
+		err = json.Unmarshal(sj.Value, &str)
+		*s = NewStaticString(str)
+	case staticJSONTypes[TypeBoolean]:
+		var b bool
+		err = json.Unmarshal(sj.Value, &b)
+		*s = NewStaticBool(b)
+	case staticJSONTypes[TypeDuration]:
+		var str string
+		if err = json.Unmarshal(sj.Value, &str); err == nil {
+			var d time.Duration
+			d, err = time.ParseDuration(str)
+			*s = NewStaticDuration(d)
+		}
+	case staticJSONTypes[TypeStatus]:
+		var str string
+		if err = json.Unmarshal(sj.Value, &str); err == nil {
+			var status Status
+			status, err = statusFromString(str)
+			*s = NewStaticStatus(status)
+		}
+	case staticJSONTypes[TypeTimestamp]:
+		var t time.Time
+		err = json.Unmarshal(sj.Value, &t)
+		*s = NewStaticTimestamp(t)
+	case staticJSONTypes[TypeArray]:
+		var elements []Static
+		err = json.Unmarshal(sj.Value, &elements)
+		*s = NewStaticArray(elements)
+	default:
+		return fmt.Errorf("unknown static type %q", sj.Type)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid value for static of type %q: %w", sj.Type, err)
+	}
+	return nil
+}
+
+func statusFromString(s string) (Status, error) {
+	for _, status := range []Status{StatusError, StatusOk, StatusUnset} {
+		if status.String() == s {
+			return status, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown status %q", s)
+}
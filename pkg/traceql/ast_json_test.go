@@ -0,0 +1,82 @@
+package traceql
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticJSON(t *testing.T) {
+	tests := []struct {
+		static   Static
+		expected string
+	}{
+		{NewStaticNil(), `{"type":"nil"}`},
+		{NewStaticInt(-5), `{"type":"int","value":-5}`},
+		{NewStaticFloat(1.25), `{"type":"float","value":1.25}`},
+		{NewStaticFloat(math.Inf(1)), `{"type":"float","value":"+Inf"}`},
+		{NewStaticFloat(math.Inf(-1)), `{"type":"float","value":"-Inf"}`},
+		{NewStaticString(`a "quoted" string`), `{"type":"string","value":"a \"quoted\" string"}`},
+		{NewStaticString(""), `{"type":"string","value":""}`},
+		{NewStaticBool(false), `{"type":"bool","value":false}`},
+		{NewStaticDuration(1500 * time.Millisecond), `{"type":"duration","value":"1.5s"}`},
+		{NewStaticDuration(1), `{"type":"duration","value":"1ns"}`},
+		{NewStaticStatus(StatusError), `{"type":"status","value":"error"}`},
+		{NewStaticStatus(StatusOk), `{"type":"status","value":"ok"}`},
+		{NewStaticStatus(StatusUnset), `{"type":"status","value":"unset"}`},
+		{NewStaticTimestamp(time.Date(2023, 1, 1, 0, 0, 0, 500, time.UTC)), `{"type":"timestamp","value":"2023-01-01T00:00:00.0000005Z"}`},
+		{NewStaticArray([]Static{NewStaticString("a"), NewStaticInt(1)}), `{"type":"array","value":[{"type":"string","value":"a"},{"type":"int","value":1}]}`},
+		{NewStaticArray(nil), `{"type":"array","value":[]}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expected, func(t *testing.T) {
+			b, err := json.Marshal(tc.static)
+			require.NoError(t, err)
+			require.JSONEq(t, tc.expected, string(b))
+
+			var actual Static
+			require.NoError(t, json.Unmarshal(b, &actual))
+			require.Equal(t, tc.static, actual)
+		})
+	}
+
+	// NaN doesn't equal itself
+	b, err := json.Marshal(NewStaticFloat(math.NaN()))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"float","value":"NaN"}`, string(b))
+	var nan Static
+	require.NoError(t, json.Unmarshal(b, &nan))
+	require.Equal(t, TypeFloat, nan.Type)
+	require.True(t, math.IsNaN(nan.F))
+
+	// statics are serialized as part of results
+	b, err = json.Marshal(map[string]Static{"count": NewStaticInt(3)})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"count":{"type":"int","value":3}}`, string(b))
+}
+
+func TestStaticJSONErrors(t *testing.T) {
+	_, err := json.Marshal(Static{Type: TypeSpanset})
+	require.Error(t, err)
+	_, err = json.Marshal(Static{Type: TypeAttribute})
+	require.Error(t, err)
+
+	for _, data := range []string{
+		`{"type":"spanset"}`,
+		`{"type":"int"}`,
+		`{"type":"int","value":"1"}`,
+		`{"type":"float","value":"one"}`,
+		`{"type":"duration","value":"forever"}`,
+		`{"type":"duration","value":5}`,
+		`{"type":"status","value":"failed"}`,
+		`{"type":"timestamp","value":"yesterday"}`,
+		`[]`,
+	} {
+		var s Static
+		require.Error(t, json.Unmarshal([]byte(data), &s), data)
+	}
+}
@@ -0,0 +1,143 @@
+package traceql
+
+import (
+	"strconv"
+	"strings"
+)
+
+const prettyIndent = "  "
+
+// Pretty renders the expression for display. Every pipeline stage goes on its own line and
+// parentheses are only added where operator precedence requires them. Use String() when the
+// output needs to be parsed again.
+func (r RootExpr) Pretty() string {
+	return prettyPipeline(r.Pipeline, 0)
+}
+
+func prettyPipeline(p Pipeline, depth int) string {
+	stages := make([]string, 0, len(p.Elements))
+	for _, e := range p.Elements {
+		stages = append(stages, prettyElement(e, depth))
+	}
+	return strings.Join(stages, "\n"+strings.Repeat(prettyIndent, depth)+"| ")
+}
+
+func prettyElement(e Element, depth int) string {
+	switch e := e.(type) {
+	case Pipeline:
+		return prettyPipeline(e, depth)
+	case GroupOperation:
+		return "by(" + prettyElement(e.Expression, depth) + ")"
+	case DistinctOperation:
+		return "distinct(" + prettyElement(e.Expression, depth) + ")"
+	case TopKOperation:
+		return "topk(" + prettyElement(e.Expression, depth) + ", " + strconv.Itoa(e.K) + ")"
+	case Aggregate:
+		if e.e == nil {
+			return e.agg.String() + "()"
+		}
+		return e.agg.String() + "(" + prettyElement(e.e, depth) + ")"
+	case SpansetFilter:
+		return "{ " + prettyElement(e.Expression, depth) + " }"
+	case SpansetOperation:
+		return prettyBinary(e.Op, e.LHS, e.RHS, depth)
+	case ScalarOperation:
+		return prettyBinary(e.Op, e.LHS, e.RHS, depth)
+	case ScalarFilter:
+		return prettyBinary(e.op, e.lhs, e.rhs, depth)
+	case BinaryOperation:
+		if e.Op.isBitwise() {
+			return e.Op.String() + "(" + prettyElement(e.LHS, depth) + ", " + prettyElement(e.RHS, depth) + ")"
+		}
+		return prettyBinary(e.Op, e.LHS, e.RHS, depth)
+	case UnaryOperation:
+		operand := prettyElement(e.Expression, depth)
+		if _, ok := prettyPrecedence(e.Expression); ok {
+			operand = "(" + operand + ")"
+		}
+		return e.Op.String() + operand
+	case SetOperation:
+		values := make([]string, 0, len(e.Values))
+		for _, v := range e.Values {
+			values = append(values, prettyElement(v, depth))
+		}
+		return prettyOperand(e.Expression, operatorPrecedence(e.Op), false, depth) + " " + e.Op.String() + " (" + strings.Join(values, ", ") + ")"
+	case HasOperation:
+		return "has(" + prettyElement(e.Expression, depth) + ")"
+	case ArrayElements:
+		return e.String()
+	case FunctionOperation:
+		return e.Op.String() + "(" + prettyElement(e.Expression, depth) + ")"
+	case Static:
+		if e.Type == TypeString {
+			return strconv.Quote(e.S)
+		}
+	}
+	return e.String()
+}
+
+func prettyBinary(op Operator, lhs, rhs Element, depth int) string {
+	prec := operatorPrecedence(op)
+	// Power is the only right associative operator
+	right := op == OpPower
+	return prettyOperand(lhs, prec, right, depth) + " " + op.String() + " " + prettyOperand(rhs, prec, !right, depth)
+}
+
+// prettyOperand renders an operand of an operator with the given precedence. tight is set when
+// an operand of the same precedence has to be wrapped to keep its grouping.
+func prettyOperand(e Element, parentPrec int, tight bool, depth int) string {
+	if p, ok := e.(Pipeline); ok {
+		if len(p.Elements) == 1 {
+			return prettyOperand(p.Elements[0], parentPrec, tight, depth)
+		}
+		indent := strings.Repeat(prettyIndent, depth)
+		return "(\n" + indent + prettyIndent + prettyPipeline(p, depth+1) + "\n" + indent + ")"
+	}
+
+	s := prettyElement(e, depth)
+	if prec, ok := prettyPrecedence(e); ok && (prec < parentPrec || (tight && prec == parentPrec)) {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+// prettyPrecedence returns the precedence of the operator at the root of e. Returns false if e
+// isn't an operation that could need parenthesizing.
+func prettyPrecedence(e Element) (int, bool) {
+	switch e := e.(type) {
+	case SpansetOperation:
+		return operatorPrecedence(e.Op), true
+	case ScalarOperation:
+		return operatorPrecedence(e.Op), true
+	case ScalarFilter:
+		return operatorPrecedence(e.op), true
+	case BinaryOperation:
+		if e.Op.isBitwise() {
+			return 0, false
+		}
+		return operatorPrecedence(e.Op), true
+	case SetOperation:
+		return operatorPrecedence(e.Op), true
+	}
+	return 0, false
+}
+
+// operatorPrecedence mirrors the precedence declarations in expr.y. Higher binds tighter.
+func operatorPrecedence(op Operator) int {
+	switch op {
+	case OpAnd, OpOr, OpSpansetAnd, OpSpansetUnion:
+		return 1
+	case OpEqual, OpNotEqual, OpLess, OpLessEqual, OpGreater, OpGreaterEqual, OpRegex, OpNotRegex,
+		OpSpansetChild, OpSpansetDescendant, OpSpansetNotDescendant, OpSpansetSibling, OpIn, OpNotIn:
+		return 2
+	case OpAdd, OpSub:
+		return 3
+	case OpNot:
+		return 4
+	case OpMult, OpDiv, OpMod:
+		return 5
+	case OpPower:
+		return 6
+	}
+	return 0
+}
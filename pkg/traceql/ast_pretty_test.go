@@ -0,0 +1,43 @@
+package traceql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPretty(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{
+			query:    `{ .a = 1 && (.b = "foo" || .c = 3) } | by(resource.ns) | count() > 1 + 2 * 3`,
+			expected: "{ .a = 1 && (.b = \"foo\" || .c = 3) }\n| by(resource.ns)\n| count() > 1 + 2 * 3",
+		},
+		{
+			query:    `{ (.a + .b) * .c > 10 && .d - (.e - .f) = 1 }`,
+			expected: `{ (.a + .b) * .c > 10 && .d - (.e - .f) = 1 }`,
+		},
+		{
+			query:    `{ ((.a - .b) - .c) = 1 && .a ^ (.b ^ .c) = 2 && (.a ^ .b) ^ .c = 3 }`,
+			expected: `{ .a - .b - .c = 1 && .a ^ .b ^ .c = 2 && (.a ^ .b) ^ .c = 3 }`,
+		},
+		{
+			query:    `{ !(.a = 1 || .b = 2) && .c in (1, 2) }`,
+			expected: `{ !(.a = 1 || .b = 2) && .c in (1, 2) }`,
+		},
+		{
+			query:    `({ .a = 1 } | by(.b) | avg(duration) > 1s) && (({ .c = 2 } || { .d = 3 }) > { .e = 4 })`,
+			expected: "(\n  { .a = 1 }\n  | by(.b)\n  | avg(duration) > 1s\n) && ({ .c = 2 } || { .d = 3 }) > { .e = 4 }",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			expr, err := Parse(tc.query)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, expr.Pretty())
+		})
+	}
+}
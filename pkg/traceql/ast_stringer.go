@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 func (r RootExpr) String() string {
@@ -26,6 +29,30 @@ func (o CoalesceOperation) String() string {
 	return "coalesce()"
 }
 
+func (o FlattenOperation) String() string {
+	return "flatten()"
+}
+
+func (o SelectOperation) String() string {
+	exprs := make([]string, 0, len(o.Exprs))
+	for _, e := range o.Exprs {
+		exprs = append(exprs, e.String())
+	}
+	return "select(" + strings.Join(exprs, ", ") + ")"
+}
+
+func (o DistinctOperation) String() string {
+	return "distinct(" + o.Expression.String() + ")"
+}
+
+func (o TopKOperation) String() string {
+	return "topk(" + o.Expression.String() + ", " + strconv.Itoa(o.K) + ")"
+}
+
+func (o WithOperation) String() string {
+	return "with(" + o.Name + " = " + o.Value.String() + ")"
+}
+
 func (o ScalarOperation) String() string {
 	return binaryOp(o.Op, o.LHS, o.RHS)
 }
@@ -51,6 +78,9 @@ func (f ScalarFilter) String() string {
 }
 
 func (o BinaryOperation) String() string {
+	if o.Op.isBitwise() {
+		return o.Op.String() + "(" + o.LHS.String() + ", " + o.RHS.String() + ")"
+	}
 	return binaryOp(o.Op, o.LHS, o.RHS)
 }
 
@@ -58,6 +88,33 @@ func (o UnaryOperation) String() string {
 	return unaryOp(o.Op, o.Expression)
 }
 
+func (o SetOperation) String() string {
+	values := make([]string, 0, len(o.Values))
+	for _, v := range o.Values {
+		values = append(values, v.String())
+	}
+	return wrapElement(o.Expression) + " " + o.Op.String() + " (" + strings.Join(values, ", ") + ")"
+}
+
+func (e ArrayElements) String() string {
+	if e.All {
+		return "all(" + e.Attribute.String() + "[])"
+	}
+	return e.Attribute.String() + "[]"
+}
+
+func (o HasOperation) String() string {
+	return "has(" + o.Expression.String() + ")"
+}
+
+func (o FunctionOperation) String() string {
+	return o.Op.String() + "(" + o.Expression.String() + ")"
+}
+
+func (r Reference) String() string {
+	return r.Name
+}
+
 func (n Static) String() string {
 	switch n.Type {
 	case TypeInt:
@@ -74,6 +131,18 @@ func (n Static) String() string {
 		return n.D.String()
 	case TypeStatus:
 		return n.Status.String()
+	case TypeTimestamp:
+		return time.Unix(0, int64(n.N)).UTC().Format(time.RFC3339Nano)
+	case TypeArray:
+		elements, err := n.Elements()
+		if err != nil {
+			return fmt.Sprintf("array(%v)", err)
+		}
+		values := make([]string, 0, len(elements))
+		for _, e := range elements {
+			values = append(values, e.String())
+		}
+		return "[" + strings.Join(values, ", ") + "]"
 	}
 
 	return fmt.Sprintf("static(%d)", n.Type)
@@ -93,6 +162,8 @@ func (a Attribute) String() string {
 	att := a.Name
 	if a.Intrinsic != IntrinsicNone {
 		att = a.Intrinsic.String()
+	} else if attributeNeedsQuoting(att) {
+		att = strconv.Quote(att)
 	}
 
 	scope := ""
@@ -108,6 +179,34 @@ func (a Attribute) String() string {
 	return scope + att
 }
 
+// attributeNeedsQuoting returns true if the name wouldn't be lexed back to the same attribute
+// without quotes.
+func attributeNeedsQuoting(name string) bool {
+	if name == "" {
+		return true
+	}
+
+	first, _ := utf8.DecodeRuneInString(name)
+	if first != '_' && !unicode.IsLetter(first) {
+		return true
+	}
+
+	// a leading scope would be parsed as the scope of the attribute
+	for _, tok := range []string{"resource.", "span."} {
+		if strings.HasPrefix(name, tok) {
+			return true
+		}
+	}
+
+	for _, r := range name {
+		if !isAttributeRune(r) || r == '"' || r == '`' {
+			return true
+		}
+	}
+
+	return false
+}
+
 func binaryOp(op Operator, lhs Element, rhs Element) string {
 	return wrapElement(lhs) + " " + op.String() + " " + wrapElement(rhs)
 }
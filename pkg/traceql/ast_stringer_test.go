@@ -46,6 +46,10 @@ func TestStringerRoundtrip(t *testing.T) {
 		"{ parent.duration = 1s }",
 		"{ span.duration = 1s }",
 		"{ resource.duration = 1s }",
+		`{ span."foo bar" = 1 }`, // Quoted names that can't be written without quotes
+		`{ ."span.foo" = 1 }`,
+		`{ parent.resource."a{b}" = 1 }`,
+		`{ ."" = 1 }`,
 	}
 
 	for _, q := range roundtrippable {
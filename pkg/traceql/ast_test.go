@@ -1,7 +1,10 @@
 package traceql
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"sort"
 	"testing"
 	"time"
 
@@ -23,6 +26,9 @@ func TestStatic_Equals(t *testing.T) {
 		{NewStaticStatus(StatusError), NewStaticInt(0)},
 		{NewStaticStatus(StatusOk), NewStaticInt(1)},
 		{NewStaticStatus(StatusUnset), NewStaticInt(2)},
+		// Status and string comparison
+		{NewStaticStatus(StatusError), NewStaticString("error")},
+		{NewStaticStatus(StatusUnset), NewStaticString("unset")},
 	}
 	areNotEqual := []struct {
 		lhs, rhs Static
@@ -35,6 +41,9 @@ func TestStatic_Equals(t *testing.T) {
 		{NewStaticStatus(StatusError), NewStaticStatus(StatusOk)},
 		{NewStaticStatus(StatusOk), NewStaticInt(0)},
 		{NewStaticStatus(StatusError), NewStaticFloat(0)},
+		{NewStaticStatus(StatusError), NewStaticString("ok")},
+		{NewStaticStatus(StatusError), NewStaticString("ERROR")},
+		{NewStaticInt(0), NewStaticString("error")},
 	}
 	for _, tt := range areEqual {
 		t.Run(fmt.Sprintf("%v == %v", tt.lhs, tt.rhs), func(t *testing.T) {
@@ -50,6 +59,146 @@ func TestStatic_Equals(t *testing.T) {
 	}
 }
 
+func TestStatic_Elements(t *testing.T) {
+	elements := []Static{
+		NewStaticNil(),
+		NewStaticInt(-42),
+		NewStaticFloat(math.Inf(-1)),
+		NewStaticString("prod"),
+		NewStaticString(""),
+		NewStaticBool(true),
+		NewStaticDuration(1500 * time.Millisecond),
+		NewStaticStatus(StatusError),
+		NewStaticTimestamp(time.Unix(1, 2)),
+		NewStaticArray([]Static{NewStaticString("nested"), NewStaticInt(1)}),
+	}
+
+	array := NewStaticArray(elements)
+	actual, err := array.Elements()
+	require.NoError(t, err)
+	require.Equal(t, elements, actual)
+
+	// equal elements have the same encoding
+	require.Equal(t, array, NewStaticArray(actual))
+
+	empty, err := NewStaticArray(nil).Elements()
+	require.NoError(t, err)
+	require.Empty(t, empty)
+
+	// truncated elements are an error
+	floats := NewStaticArray([]Static{NewStaticFloat(1)})
+	_, err = Static{Type: TypeArray, S: floats.S[:3]}.Elements()
+	require.ErrorIs(t, err, errInvalidArray)
+	_, err = Static{Type: TypeArray, S: string([]byte{byte(TypeString), 10, 'a'})}.Elements()
+	require.ErrorIs(t, err, errInvalidArray)
+}
+
+func TestStatic_Compare(t *testing.T) {
+	tests := []struct {
+		lhs, rhs Static
+		expected int
+	}{
+		{NewStaticInt(1), NewStaticInt(1), 0},
+		{NewStaticInt(1), NewStaticInt(2), -1},
+		{NewStaticInt(-1), NewStaticInt(-2), 1},
+		{NewStaticFloat(1.5), NewStaticFloat(1.5), 0},
+		{NewStaticFloat(1.5), NewStaticFloat(2.5), -1},
+		{NewStaticDuration(time.Second), NewStaticDuration(1000 * time.Millisecond), 0},
+		{NewStaticDuration(time.Second), NewStaticDuration(time.Minute), -1},
+		// Numeric bridging
+		{NewStaticInt(1), NewStaticFloat(1), 0},
+		{NewStaticInt(1), NewStaticFloat(1.5), -1},
+		{NewStaticFloat(2.5), NewStaticInt(2), 1},
+		{NewStaticDuration(10), NewStaticInt(10), 0},
+		{NewStaticDuration(10), NewStaticInt(9), 1},
+		{NewStaticDuration(10), NewStaticFloat(10.5), -1},
+		{NewStaticString("foo"), NewStaticString("foo"), 0},
+		{NewStaticString("bar"), NewStaticString("foo"), -1},
+		{NewStaticString("foo"), NewStaticString("fo"), 1},
+		{NewStaticString(""), NewStaticString("a"), -1},
+		{NewStaticStatus(StatusOk), NewStaticStatus(StatusOk), 0},
+		{NewStaticStatus(StatusError), NewStaticStatus(StatusOk), -1},
+		{NewStaticStatus(StatusUnset), NewStaticStatus(StatusOk), 1},
+		// Status and int comparison
+		{NewStaticStatus(StatusOk), NewStaticInt(1), 0},
+		{NewStaticStatus(StatusError), NewStaticInt(1), -1},
+		{NewStaticStatus(StatusUnset), NewStaticInt(1), 1},
+		{NewStaticBool(true), NewStaticBool(true), 0},
+		{NewStaticBool(false), NewStaticBool(true), -1},
+		{NewStaticNil(), NewStaticNil(), 0},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v <=> %v", tt.lhs, tt.rhs), func(t *testing.T) {
+			c, err := tt.lhs.Compare(tt.rhs)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, c)
+
+			c, err = tt.rhs.Compare(tt.lhs)
+			require.NoError(t, err)
+			assert.Equal(t, -tt.expected, c)
+		})
+	}
+
+	incomparable := []struct {
+		lhs, rhs Static
+	}{
+		{NewStaticBool(true), NewStaticString("true")},
+		{NewStaticBool(true), NewStaticInt(1)},
+		{NewStaticBool(false), NewStaticNil()},
+		{NewStaticString("1"), NewStaticInt(1)},
+		{NewStaticString("1"), NewStaticFloat(1)},
+		{NewStaticString("1s"), NewStaticDuration(time.Second)},
+		{NewStaticString("ok"), NewStaticStatus(StatusOk)},
+		{NewStaticString(""), NewStaticNil()},
+		{NewStaticStatus(StatusOk), NewStaticFloat(1)},
+		{NewStaticStatus(StatusOk), NewStaticDuration(1)},
+		{NewStaticStatus(StatusOk), NewStaticBool(true)},
+		{NewStaticStatus(StatusOk), NewStaticNil()},
+		{NewStaticInt(0), NewStaticNil()},
+		{NewStaticFloat(0), NewStaticNil()},
+		{NewStaticDuration(0), NewStaticNil()},
+	}
+	for _, tt := range incomparable {
+		t.Run(fmt.Sprintf("%v <=> %v", tt.lhs, tt.rhs), func(t *testing.T) {
+			_, err := tt.lhs.Compare(tt.rhs)
+			assert.Error(t, err)
+
+			_, err = tt.rhs.Compare(tt.lhs)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestStatic_CompareCollated(t *testing.T) {
+	values := []string{"zebra", "Äpfel", "apple", "Zürich", "éclair", "banana", "Eclair"}
+
+	sortWith := func(c Collation) []string {
+		sorted := append([]string(nil), values...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			cmp, err := NewStaticString(sorted[i]).CompareCollated(NewStaticString(sorted[j]), c)
+			require.NoError(t, err)
+			return cmp < 0
+		})
+		return sorted
+	}
+
+	// byte order puts upper case before lower case and accented letters last
+	require.Equal(t, []string{"Eclair", "Zürich", "apple", "banana", "zebra", "Äpfel", "éclair"}, sortWith(nil))
+
+	c, err := NewLocaleCollation("en")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Äpfel", "apple", "banana", "Eclair", "éclair", "zebra", "Zürich"}, sortWith(c))
+
+	// other types are compared the same way as without a collation
+	cmp, err := NewStaticInt(1).CompareCollated(NewStaticFloat(2), c)
+	require.NoError(t, err)
+	require.Equal(t, -1, cmp)
+	_, err = NewStaticString("a").CompareCollated(NewStaticInt(1), c)
+	require.Error(t, err)
+
+	_, err = NewLocaleCollation("not a locale!")
+	require.Error(t, err)
+}
 func TestPipelineEvaluate(t *testing.T) {
 	testCases := []struct {
 		query  string
@@ -92,7 +241,7 @@ func TestPipelineEvaluate(t *testing.T) {
 			ast, err := Parse(tc.query)
 			require.NoError(t, err)
 
-			actual, err := ast.Pipeline.evaluate(tc.input)
+			actual, err := ast.Pipeline.evaluate(nil, tc.input)
 			require.NoError(t, err)
 			require.Equal(t, tc.output, actual)
 		})
@@ -172,9 +321,57 @@ func TestSpansetFilterEvaluate(t *testing.T) {
 
 			filt := ast.Pipeline.Elements[0].(SpansetFilter)
 
-			actual, err := filt.evaluate(tc.input)
+			actual, err := filt.evaluate(nil, tc.input)
 			require.NoError(t, err)
 			require.Equal(t, tc.output, actual)
 		})
 	}
 }
+
+func TestSpansetFilterEvaluateCancelled(t *testing.T) {
+	ast, err := Parse("{ .foo = `a` }")
+	require.NoError(t, err)
+	filt := ast.Pipeline.Elements[0].(SpansetFilter)
+
+	spans := make([]Span, 10*cancellationCheckInterval)
+	for i := range spans {
+		spans[i] = Span{Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("a")}}
+	}
+	input := []Spanset{{Spans: spans}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = filt.evaluate(newEvalContextWithContext(ctx, EvalOptions{}), input)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// without a deadline the same spanset is fully evaluated
+	actual, err := filt.evaluate(newEvalContext(EvalOptions{}), input)
+	require.NoError(t, err)
+	require.Len(t, actual, 1)
+	require.Len(t, actual[0].Spans, len(spans))
+}
+
+func TestRootExprRequiresTraceContext(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected bool
+	}{
+		{`{ span.foo = "bar" && duration > 1s }`, false},
+		{`{ resource.service.name = "svc" } | count() > 2`, false},
+		{`{ .foo = "a" } && { name = "b" } | by(.foo)`, false},
+		{`{ parent.foo = "bar" }`, true},
+		{`{ childCount > 2 }`, true},
+		{`{ name = "GET" } | { selfTime > 1s }`, true},
+		{`{ .foo = "a" } >> { .foo = "b" }`, true},
+		{`({ .foo = "a" } | count() > 1) ~ ({ .foo = "b" })`, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			expr, err := Parse(tc.query)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, expr.RequiresTraceContext())
+		})
+	}
+}
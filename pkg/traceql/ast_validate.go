@@ -1,21 +1,81 @@
 package traceql
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+)
 
 func (r RootExpr) validate() error {
 	return r.Pipeline.validate()
 }
 
+// validateRegexes checks the static patterns of =~ and !~ below the element against the options.
+// Unlike validate it is applied to every query, so an abusive pattern is rejected before any span
+// is fetched.
+func validateRegexes(e Element, opts EvalOptions) error {
+	var err error
+	Walk(e, func(e Element) bool {
+		o, ok := e.(BinaryOperation)
+		if !ok || (o.Op != OpRegex && o.Op != OpNotRegex) {
+			return true
+		}
+		if pattern, ok := o.RHS.(Static); ok && pattern.Type == TypeString {
+			if err = opts.checkRegexLength(pattern.S); err == nil {
+				_, err = regexp.Compile(pattern.S)
+			}
+		}
+		return err == nil
+	})
+	return err
+}
+
 func (p Pipeline) validate() error {
+	// names bound by with() are visible to the elements after it
+	bound := map[string]struct{}{}
+
 	for _, p := range p.Elements {
 		err := p.validate()
 		if err != nil {
 			return err
 		}
+
+		if r, ok := unboundReference(p, bound); ok {
+			return fmt.Errorf("%s is not bound by an earlier with(): %s", r.Name, p.String())
+		}
+
+		if w, ok := p.(WithOperation); ok {
+			bound[w.Name] = struct{}{}
+		}
 	}
 	return nil
 }
 
+// unboundReference returns the first reference in the element to a name that isn't bound. Nested
+// pipelines are validated on their own and not searched.
+func unboundReference(e Element, bound map[string]struct{}) (Reference, bool) {
+	var (
+		unbound Reference
+		found   bool
+	)
+
+	Walk(e, func(e Element) bool {
+		if found {
+			return false
+		}
+		switch e := e.(type) {
+		case Pipeline:
+			return false
+		case Reference:
+			if _, ok := bound[e.Name]; !ok {
+				unbound, found = e, true
+			}
+		}
+		return true
+	})
+
+	return unbound, found
+}
+
 func (o GroupOperation) validate() error {
 	if !o.Expression.referencesSpan() {
 		return fmt.Errorf("grouping field expressions must reference the span: %s", o.String())
@@ -28,6 +88,50 @@ func (o CoalesceOperation) validate() error {
 	return nil
 }
 
+func (o FlattenOperation) validate() error {
+	return nil
+}
+
+func (o SelectOperation) validate() error {
+	for _, e := range o.Exprs {
+		if !e.referencesSpan() {
+			return fmt.Errorf("selected field expressions must reference the span: %s", o.String())
+		}
+		if err := e.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o DistinctOperation) validate() error {
+	if !o.Expression.referencesSpan() {
+		return fmt.Errorf("distinct field expressions must reference the span: %s", o.String())
+	}
+
+	return o.Expression.validate()
+}
+
+func (o TopKOperation) validate() error {
+	if o.K <= 0 {
+		return fmt.Errorf("topk must keep at least one spanset: %s", o.String())
+	}
+	if err := o.Expression.validate(); err != nil {
+		return err
+	}
+
+	t := o.Expression.impliedType()
+	if t != TypeAttribute && !t.isNumeric() {
+		return fmt.Errorf("topk field expressions must resolve to a number type: %s", o.String())
+	}
+	if !o.Expression.referencesSpan() {
+		return fmt.Errorf("topk field expressions must reference the span: %s", o.String())
+	}
+
+	return nil
+}
+
 func (o ScalarOperation) validate() error {
 	if err := o.LHS.validate(); err != nil {
 		return err
@@ -46,9 +150,42 @@ func (o ScalarOperation) validate() error {
 		return fmt.Errorf("illegal operation for the given types: %s", o.String())
 	}
 
+	if (o.Op == OpDiv || o.Op == OpMod) && isConstantScalar(o.RHS) {
+		if rhs, err := evaluateScalar(nil, o.RHS, nil); err == nil && isZero(rhs) {
+			return fmt.Errorf("division or modulo by zero: %s", o.String())
+		}
+	}
+
 	return nil
 }
 
+// isConstantScalar reports whether the scalar expression is built from statics only, so its value
+// is known before evaluation.
+func isConstantScalar(e ScalarExpression) bool {
+	switch e := e.(type) {
+	case Static:
+		return true
+	case ScalarOperation:
+		return isConstantScalar(e.LHS) && isConstantScalar(e.RHS)
+	default:
+		return false
+	}
+}
+
+// isZero reports whether the static is a number equal to zero.
+func isZero(s Static) bool {
+	switch s.Type {
+	case TypeInt:
+		return s.N == 0
+	case TypeFloat:
+		return s.F == 0
+	case TypeDuration:
+		return s.D == 0
+	default:
+		return false
+	}
+}
+
 func (a Aggregate) validate() error {
 	if a.e == nil {
 		return nil
@@ -58,9 +195,10 @@ func (a Aggregate) validate() error {
 		return err
 	}
 
-	// aggregate field expressions require a type of a number or attribute
+	// aggregate field expressions require a type of a number or attribute, distinct values of
+	// any type can be counted
 	t := a.e.impliedType()
-	if t != TypeAttribute && !t.isNumeric() {
+	if a.agg != aggregateCountDistinct && t != TypeAttribute && !t.isNumeric() {
 		return fmt.Errorf("aggregate field expressions must resolve to a number type: %s", a.String())
 	}
 
@@ -72,13 +210,35 @@ func (a Aggregate) validate() error {
 }
 
 func (o SpansetOperation) validate() error {
-	// TODO validate operator is a SpanSetOperator
+	switch o.Op {
+	case OpSpansetAnd, OpSpansetUnion, OpSpansetChild, OpSpansetDescendant, OpSpansetNotDescendant, OpSpansetSibling:
+	default:
+		return fmt.Errorf("illegal operation for spansets: %s", o.String())
+	}
+
+	if o.LHS == nil || o.RHS == nil {
+		return fmt.Errorf("spanset operations require a spanset expression on both sides: %s", o.Op)
+	}
+	for _, operand := range []SpansetExpression{o.LHS, o.RHS} {
+		if isScalarPipeline(operand) {
+			return fmt.Errorf("spanset operations can't be applied to scalar expressions: %s", o.String())
+		}
+	}
+
 	if err := o.LHS.validate(); err != nil {
 		return err
 	}
 	return o.RHS.validate()
 }
 
+// isScalarPipeline reports whether the spanset expression is a pipeline that ends in a scalar, like
+// ({ .a } | count()). Pipelines are both spanset and scalar expressions, which one they are depends
+// on their last element.
+func isScalarPipeline(e SpansetExpression) bool {
+	p, ok := e.(Pipeline)
+	return ok && p.impliedType() != TypeSpanset
+}
+
 func (f SpansetFilter) validate() error {
 	if err := f.Expression.validate(); err != nil {
 		return err
@@ -114,13 +274,33 @@ func (f ScalarFilter) validate() error {
 }
 
 func (o BinaryOperation) validate() error {
-	if err := o.LHS.validate(); err != nil {
-		return err
+	_, lhsElements := o.LHS.(ArrayElements)
+	_, rhsElements := o.RHS.(ArrayElements)
+	if lhsElements || rhsElements {
+		if lhsElements && rhsElements {
+			return fmt.Errorf("only one side of a comparison can be the elements of an array: %s", o.String())
+		}
+		if !o.Op.isComparison() {
+			return fmt.Errorf("the elements of an array can only be compared: %s", o.String())
+		}
 	}
-	if err := o.RHS.validate(); err != nil {
+
+	if err := o.validateChain(); err != nil {
 		return err
 	}
 
+	// the elements of an array are only valid as an operand, their type is checked at query time
+	if !lhsElements {
+		if err := o.LHS.validate(); err != nil {
+			return err
+		}
+	}
+	if !rhsElements {
+		if err := o.RHS.validate(); err != nil {
+			return err
+		}
+	}
+
 	lhsT := o.LHS.impliedType()
 	rhsT := o.RHS.impliedType()
 	if !lhsT.isMatchingOperand(rhsT) {
@@ -131,9 +311,60 @@ func (o BinaryOperation) validate() error {
 		return fmt.Errorf("illegal operation for the given types: %s", o.String())
 	}
 
+	if o.Op == OpRegex || o.Op == OpNotRegex {
+		if pattern, ok := o.RHS.(Static); ok && pattern.Type == TypeString {
+			if _, err := regexp.Compile(pattern.S); err != nil {
+				return fmt.Errorf("invalid regular expression %q: %s", pattern.S, o.String())
+			}
+		}
+	}
+
+	// a divisor that doesn't reference the span is the same for every span, so a zero is caught
+	// here instead of making every span nil
+	if (o.Op == OpDiv || o.Op == OpMod) && !o.RHS.referencesSpan() {
+		if rhs, err := o.RHS.execute(nil, Span{}); err == nil && isZero(rhs) {
+			return fmt.Errorf("division or modulo by zero: %s", o.String())
+		}
+	}
+
 	return nil
 }
 
+// validateChain rejects chained comparisons like 1 < .x < 10. Operators are left associative, so
+// they compare the boolean result of the first comparison to the last operand instead of
+// checking a range.
+func (o BinaryOperation) validateChain() error {
+	if !o.Op.isComparison() || o.Op == OpRegex || o.Op == OpNotRegex {
+		return nil
+	}
+
+	lhs, lhsChained := o.LHS.(BinaryOperation)
+	lhsChained = lhsChained && lhs.Op.isOrdering()
+	rhs, rhsChained := o.RHS.(BinaryOperation)
+	rhsChained = rhsChained && rhs.Op.isOrdering()
+
+	var other FieldExpression
+	switch {
+	case lhsChained:
+		other = o.RHS
+	case rhsChained:
+		other = o.LHS
+	default:
+		return nil
+	}
+
+	// comparing the result to a boolean, like (.x < 10) = true, is fine
+	if !o.Op.isOrdering() && (other.impliedType() == TypeBoolean || other.impliedType() == TypeAttribute) {
+		return nil
+	}
+
+	suggestion := newBinaryOperation(OpAnd, lhs, newBinaryOperation(o.Op, lhs.RHS, o.RHS))
+	if !lhsChained {
+		suggestion = newBinaryOperation(OpAnd, newBinaryOperation(o.Op, o.LHS, rhs.LHS), rhs)
+	}
+	return fmt.Errorf("comparisons can't be chained, combine them with && instead, e.g. %s: %s", suggestion.String(), o.String())
+}
+
 func (o UnaryOperation) validate() error {
 	if err := o.Expression.validate(); err != nil {
 		return err
@@ -151,6 +382,73 @@ func (o UnaryOperation) validate() error {
 	return nil
 }
 
+func (o SetOperation) validate() error {
+	if err := o.Expression.validate(); err != nil {
+		return err
+	}
+
+	if o.Op != OpIn && o.Op != OpNotIn {
+		return fmt.Errorf("illegal set operation: %s", o.String())
+	}
+
+	if len(o.Values) == 0 {
+		return fmt.Errorf("set operations require at least one value: %s", o.String())
+	}
+
+	// all values must be of the expression's type except nil, which can be checked
+	// for membership in a set of any type
+	t := o.Expression.impliedType()
+	for _, v := range o.Values {
+		if v.Type == TypeNil {
+			continue
+		}
+		if t == TypeAttribute || t == TypeNil {
+			// the first typed value determines the type of the set
+			t = v.Type
+			continue
+		}
+		if !t.isMatchingOperand(v.Type) {
+			return fmt.Errorf("set operations must operate on the same type: %s", o.String())
+		}
+	}
+
+	return nil
+}
+
+func (e ArrayElements) validate() error {
+	return fmt.Errorf("the elements of an array can only be compared: %s", e.String())
+}
+
+func (o HasOperation) validate() error {
+	a, ok := o.Expression.(Attribute)
+	if !ok || a.Intrinsic != IntrinsicNone {
+		return fmt.Errorf("has() expects an attribute: %s", o.String())
+	}
+
+	return nil
+}
+
+func (o FunctionOperation) validate() error {
+	if err := o.Expression.validate(); err != nil {
+		return err
+	}
+
+	t := o.Expression.impliedType()
+	if t != TypeAttribute && !t.isNumeric() {
+		return fmt.Errorf("%s() expects a numeric operand: %s", o.Op, o.String())
+	}
+
+	return nil
+}
+
+func (o WithOperation) validate() error {
+	return o.Value.validate()
+}
+
+func (r Reference) validate() error {
+	return nil
+}
+
 func (n Static) validate() error {
 	return nil
 }
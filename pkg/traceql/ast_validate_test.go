@@ -60,3 +60,31 @@ func TestExamples(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateChainedComparisons(t *testing.T) {
+	p, err := Parse(`{ 1 < span.x < 10 }`)
+	require.NoError(t, err)
+	require.EqualError(t, p.validate(), "comparisons can't be chained, combine them with && instead, e.g. (1 < span.x) && (span.x < 10): (1 < span.x) < 10")
+
+	p, err = Parse(`{ 1 = (span.x < 10) }`)
+	require.NoError(t, err)
+	require.EqualError(t, p.validate(), "comparisons can't be chained, combine them with && instead, e.g. (1 = span.x) && (span.x < 10): 1 = (span.x < 10)")
+}
+
+func TestValidateSpansetOperationOperands(t *testing.T) {
+	spanset := newPipeline(newSpansetFilter(NewStaticBool(true)), newSpansetFilter(NewAttribute("b")))
+	scalar := newPipeline(newSpansetFilter(NewStaticBool(true)), newAggregate(aggregateCount, nil))
+
+	op := newSpansetOperation(OpSpansetAnd, newSpansetFilter(NewAttribute("a")), spanset)
+	require.NoError(t, op.validate())
+
+	op = newSpansetOperation(OpSpansetAnd, newSpansetFilter(NewAttribute("a")), scalar)
+	require.EqualError(t, op.validate(), "spanset operations can't be applied to scalar expressions: "+op.String())
+
+	op = newSpansetOperation(OpSpansetChild, scalar, newSpansetFilter(NewAttribute("a")))
+	require.EqualError(t, op.validate(), "spanset operations can't be applied to scalar expressions: "+op.String())
+
+	// the parser doesn't build these operations either
+	_, err := Parse("{ .a } && ({ true } | count())")
+	require.Error(t, err)
+}
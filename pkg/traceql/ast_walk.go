@@ -0,0 +1,69 @@
+package traceql
+
+// Walk calls visit for the element and then, if visit returns true, walks the children of the
+// element in order. This includes the stages of pipelines nested in the element.
+func Walk(e Element, visit func(Element) bool) {
+	if e == nil || !visit(e) {
+		return
+	}
+
+	for _, c := range children(e) {
+		Walk(c, visit)
+	}
+}
+
+// children returns the elements directly below e in the AST.
+func children(e Element) []Element {
+	switch e := e.(type) {
+	case *RootExpr:
+		return []Element{e.Pipeline}
+	case RootExpr:
+		return []Element{e.Pipeline}
+	case Pipeline:
+		elements := make([]Element, 0, len(e.Elements))
+		for _, p := range e.Elements {
+			elements = append(elements, p)
+		}
+		return elements
+	case WithOperation:
+		return []Element{e.Value}
+	case SelectOperation:
+		elements := make([]Element, 0, len(e.Exprs))
+		for _, ex := range e.Exprs {
+			elements = append(elements, ex)
+		}
+		return elements
+	case GroupOperation:
+		return []Element{e.Expression}
+	case DistinctOperation:
+		return []Element{e.Expression}
+	case TopKOperation:
+		return []Element{e.Expression}
+	case SpansetFilter:
+		return []Element{e.Expression}
+	case SpansetOperation:
+		return []Element{e.LHS, e.RHS}
+	case ScalarFilter:
+		return []Element{e.lhs, e.rhs}
+	case ScalarOperation:
+		return []Element{e.LHS, e.RHS}
+	case Aggregate:
+		if e.e != nil {
+			return []Element{e.e}
+		}
+	case BinaryOperation:
+		return []Element{e.LHS, e.RHS}
+	case UnaryOperation:
+		return []Element{e.Expression}
+	case SetOperation:
+		return []Element{e.Expression}
+	case HasOperation:
+		return []Element{e.Expression}
+	case ArrayElements:
+		return []Element{e.Attribute}
+	case FunctionOperation:
+		return []Element{e.Expression}
+	}
+
+	return nil
+}
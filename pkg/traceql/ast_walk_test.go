@@ -0,0 +1,30 @@
+package traceql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalk(t *testing.T) {
+	expr, err := Parse(`({ .a = 1 } | by(.b)) && ({ has(.c) })`)
+	require.NoError(t, err)
+
+	var attributes []string
+	Walk(expr, func(e Element) bool {
+		if a, ok := e.(Attribute); ok {
+			attributes = append(attributes, a.Name)
+		}
+		return true
+	})
+	require.Equal(t, []string{"a", "b", "c"}, attributes)
+
+	// children aren't walked if visit returns false
+	var visited int
+	Walk(expr, func(e Element) bool {
+		visited++
+		_, ok := e.(SpansetOperation)
+		return !ok
+	})
+	require.Equal(t, 3, visited) // root, pipeline and spanset operation
+}
@@ -0,0 +1,25 @@
+package traceql
+
+import (
+	"fmt"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// Collation orders strings. A nil Collation orders strings by their bytes.
+type Collation interface {
+	CompareString(a, b string) int
+}
+
+// NewLocaleCollation returns a collation that orders strings the way users of the given BCP 47
+// locale, e.g. "en" or "de-AT", expect. It is considerably slower than byte order and should only
+// be used when explicitly requested.
+func NewLocaleCollation(locale string) (Collation, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return nil, fmt.Errorf("invalid collation locale %q: %w", locale, err)
+	}
+
+	return collate.New(tag), nil
+}
@@ -13,11 +13,18 @@ import (
 
 type Engine struct {
 	spansPerSpanSet int
+	evalOptions     EvalOptions
 }
 
 func NewEngine() *Engine {
+	return NewEngineWithOptions(EvalOptions{})
+}
+
+// NewEngineWithOptions creates an Engine that evaluates queries using the given options.
+func NewEngineWithOptions(opts EvalOptions) *Engine {
 	return &Engine{
 		spansPerSpanSet: 3, // TODO make configurable
+		evalOptions:     opts,
 	}
 }
 
@@ -33,6 +40,9 @@ func (e *Engine) Execute(ctx context.Context, searchReq *tempopb.SearchRequest,
 	if err != nil {
 		return nil, err
 	}
+	if err := validateRegexes(*spanSetFilter, e.evalOptions); err != nil {
+		return nil, err
+	}
 
 	fetchSpansRequest := e.createFetchSpansRequest(searchReq, spanSetFilter)
 
@@ -44,6 +54,11 @@ func (e *Engine) Execute(ctx context.Context, searchReq *tempopb.SearchRequest,
 	}
 	iterator := fetchSpansResponse.Results
 
+	// spans fetched by an exact pushdown are known to match the filter and aren't evaluated again,
+	// unless strings are compared in a way storage layers don't
+	postFilter := !fetchSpansRequest.exact() || e.evalOptions.StringComparison != StringComparisonExact
+	traceIntrinsics := referencedTraceIntrinsics(*spanSetFilter)
+
 	res := &tempopb.SearchResponse{
 		Traces: nil,
 		// TODO capture and update metrics
@@ -63,12 +78,29 @@ func (e *Engine) Execute(ctx context.Context, searchReq *tempopb.SearchRequest,
 
 		span.LogKV("msg", "iterator.Next", "rootSpanName", spanSet.RootSpanName, "rootServiceName", spanSet.RootServiceName, "spans", len(spanSet.Spans))
 
-		spanSet = e.validateSpanSet(spanSetFilter, spanSet)
-		if spanSet == nil {
-			continue
+		if traceIntrinsics.any() {
+			traceIntrinsics.set(*spanSet)
 		}
+		// truncated after the intrinsics are set, which need the children of every span
+		if max := e.evalOptions.MaxSpansPerSpanset; max > 0 && len(spanSet.Spans) > max {
+			spanSet.Spans = spanSet.Spans[:max]
+			spanSet.Truncated = true
+		}
+
+		if postFilter {
+			spanSet, err = e.validateSpanSet(ctx, spanSetFilter, spanSet)
+			if err != nil {
+				span.LogKV("msg", "validateSpanSet", "err", err)
+				return nil, err
+			}
+			if spanSet == nil {
+				continue
+			}
 
-		span.LogKV("msg", "validateSpanSet", "spans", len(spanSet.Spans))
+			span.LogKV("msg", "validateSpanSet", "spans", len(spanSet.Spans))
+		} else if len(spanSet.Spans) == 0 {
+			continue
+		}
 
 		traceSearchMetadata, err := e.asTraceSearchMetadata(spanSet)
 		if err != nil {
@@ -81,6 +113,7 @@ func (e *Engine) Execute(ctx context.Context, searchReq *tempopb.SearchRequest,
 		}
 	}
 
+	res.Metrics.InspectedBytes = fetchSpansResponse.BytesRead()
 	span.SetTag("traces_found", len(res.Traces))
 
 	return res, nil
@@ -97,6 +130,11 @@ func (e *Engine) parseQueryAndExtractSpanSetFilter(searchReq *tempopb.SearchRequ
 		return nil, err
 	}
 
+	// the grammar parses any identifier as a reference, which is only bound by an earlier with()
+	if err := ast.validate(); err != nil {
+		return nil, err
+	}
+
 	if len(ast.Pipeline.Elements) != 1 {
 		return nil, fmt.Errorf("queries with multiple pipeline elements aren't supported yet")
 	}
@@ -123,20 +161,30 @@ func (e *Engine) createFetchSpansRequest(searchReq *tempopb.SearchRequest, spanS
 		AllConditions:      true,
 	}
 	spanSetFilter.extractConditions(&req)
+	if e.evalOptions.FloatEpsilon > 0 {
+		req.relaxFloatEquality()
+	}
 	return req
 }
 
-// validateSpanSet will validate the Spanset fulfills the SpansetFilter.
-func (e *Engine) validateSpanSet(spanSetFilter *SpansetFilter, spanSet *Spanset) *Spanset {
+// validateSpanSet will validate the Spanset fulfills the SpansetFilter. Returns the context error
+// if ctx is done before all spans have been validated.
+func (e *Engine) validateSpanSet(ctx context.Context, spanSetFilter *SpansetFilter, spanSet *Spanset) (*Spanset, error) {
 	newSpanSet := &Spanset{
 		TraceID:         spanSet.TraceID,
 		RootSpanName:    spanSet.RootSpanName,
 		RootServiceName: spanSet.RootServiceName,
 		Spans:           nil,
+		Truncated:       spanSet.Truncated,
 	}
 
+	ec := newEvalContextWithContext(ctx, e.evalOptions)
 	for _, span := range spanSet.Spans {
-		matches, _ := spanSetFilter.matches(span)
+		if err := ec.nextSpan(); err != nil {
+			return nil, err
+		}
+
+		matches, _ := spanSetFilter.matches(ec, span)
 		if !matches {
 			continue
 		}
@@ -145,10 +193,10 @@ func (e *Engine) validateSpanSet(spanSetFilter *SpansetFilter, spanSet *Spanset)
 	}
 
 	if len(newSpanSet.Spans) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	return newSpanSet
+	return newSpanSet, nil
 }
 
 func (e *Engine) asTraceSearchMetadata(spanset *Spanset) (*tempopb.TraceSearchMetadata, error) {
@@ -237,6 +285,30 @@ func asAnyValue(static Static) (*common_v1.AnyValue, error) {
 				StringValue: static.Status.String(),
 			},
 		}, nil
+	case TypeTimestamp:
+		return &common_v1.AnyValue{
+			Value: &common_v1.AnyValue_StringValue{
+				StringValue: static.String(),
+			},
+		}, nil
+	case TypeArray:
+		elements, err := static.Elements()
+		if err != nil {
+			return nil, err
+		}
+		values := make([]*common_v1.AnyValue, 0, len(elements))
+		for _, e := range elements {
+			v, err := asAnyValue(e)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return &common_v1.AnyValue{
+			Value: &common_v1.AnyValue_ArrayValue{
+				ArrayValue: &common_v1.ArrayValue{Values: values},
+			},
+		}, nil
 	default:
 		return nil, fmt.Errorf("static has unexpected type %v", static.Type)
 	}
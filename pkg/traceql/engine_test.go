@@ -72,6 +72,7 @@ func TestEngine_Execute(t *testing.T) {
 			newCondition(NewAttribute("foo"), OpNone),
 			newCondition(NewAttribute("bar"), OpNone),
 		},
+		Pushdown:      []Pushdown{PushdownNone, PushdownNone},
 		AllConditions: true,
 	}
 	assert.Equal(t, expectedFetchSpansRequest, spanSetFetcher.capturedRequest)
@@ -131,6 +132,127 @@ func TestEngine_Execute(t *testing.T) {
 	assert.Equal(t, expectedTraceSearchMetadata, response.Traces)
 }
 
+func TestEngine_ExecuteExactPushdown(t *testing.T) {
+	// the span doesn't have the attribute, which only a post filter notices
+	fetcher := func() *MockSpanSetFetcher {
+		return &MockSpanSetFetcher{
+			iterator: &MockSpanSetIterator{
+				results: []*Spanset{
+					{TraceID: []byte{1}, Spans: []Span{{ID: []byte{1}, Attributes: map[Attribute]Static{}}}},
+				},
+			},
+		}
+	}
+
+	// storage is trusted to have applied an exact pushdown
+	response, err := NewEngine().Execute(context.Background(), &tempopb.SearchRequest{Query: `{ span.foo = "bar" }`}, fetcher())
+	require.NoError(t, err)
+	require.Len(t, response.Traces, 1)
+
+	response, err = NewEngine().Execute(context.Background(), &tempopb.SearchRequest{Query: `{ span.foo =~ "bar" }`}, fetcher())
+	require.NoError(t, err)
+	require.Empty(t, response.Traces)
+
+	// NFC comparisons are always evaluated by the engine
+	response, err = NewEngineWithOptions(EvalOptions{StringComparison: StringComparisonNFC}).Execute(context.Background(), &tempopb.SearchRequest{Query: `{ span.foo = "bar" }`}, fetcher())
+	require.NoError(t, err)
+	require.Empty(t, response.Traces)
+}
+
+func TestEngine_ExecuteUnscopedPushdown(t *testing.T) {
+	// storage matched the resource attribute, but the engine compares the span attribute
+	fetcher := &MockSpanSetFetcher{
+		iterator: &MockSpanSetIterator{
+			results: []*Spanset{
+				{TraceID: []byte{1}, Spans: []Span{{ID: []byte{1}, Attributes: map[Attribute]Static{
+					NewScopedAttribute(AttributeScopeSpan, false, "foo"):     NewStaticString("baz"),
+					NewScopedAttribute(AttributeScopeResource, false, "foo"): NewStaticString("bar"),
+				}}}},
+			},
+		},
+	}
+
+	response, err := NewEngine().Execute(context.Background(), &tempopb.SearchRequest{Query: `{ .foo = "bar" }`}, fetcher)
+	require.NoError(t, err)
+	require.Empty(t, response.Traces)
+	require.Equal(t, []Pushdown{PushdownPartial}, fetcher.capturedRequest.Pushdown)
+}
+
+func TestEngine_ExecuteFloatEpsilon(t *testing.T) {
+	fetcher := &MockSpanSetFetcher{
+		iterator: &MockSpanSetIterator{
+			results: []*Spanset{
+				{TraceID: []byte{1}, Spans: []Span{{ID: []byte{1}, Attributes: map[Attribute]Static{
+					NewAttribute("f"): NewStaticFloat(0.1 + 0.2),
+				}}}},
+			},
+		},
+	}
+
+	// storage compares floats exactly, so the condition only fetches the attribute
+	response, err := NewEngineWithOptions(EvalOptions{FloatEpsilon: 1e-9}).Execute(context.Background(), &tempopb.SearchRequest{Query: `{ .f = 0.3 }`}, fetcher)
+	require.NoError(t, err)
+	require.Len(t, response.Traces, 1)
+	require.Equal(t, []Condition{newCondition(NewAttribute("f"), OpNone)}, fetcher.capturedRequest.Conditions)
+	require.Equal(t, []Pushdown{PushdownNone}, fetcher.capturedRequest.Pushdown)
+}
+
+func TestEngine_ExecuteMaxSpansPerSpanset(t *testing.T) {
+	var spans []Span
+	for i := byte(0); i < 5; i++ {
+		spans = append(spans, Span{ID: []byte{i}, Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("bar")}})
+	}
+	fetcher := &MockSpanSetFetcher{
+		iterator: &MockSpanSetIterator{results: []*Spanset{{TraceID: []byte{1}, Spans: spans}}},
+	}
+
+	response, err := NewEngineWithOptions(EvalOptions{MaxSpansPerSpanset: 2}).Execute(context.Background(), &tempopb.SearchRequest{Query: `{ .foo = "bar" }`}, fetcher)
+	require.NoError(t, err)
+	require.Len(t, response.Traces, 1)
+	require.Equal(t, uint32(2), response.Traces[0].SpanSet.Matched)
+}
+
+func TestEngine_ExecuteMaxRegexLength(t *testing.T) {
+	fetcher := func() *MockSpanSetFetcher {
+		return &MockSpanSetFetcher{
+			iterator: &MockSpanSetIterator{
+				results: []*Spanset{
+					{TraceID: []byte{1}, Spans: []Span{{ID: []byte{1}, Attributes: map[Attribute]Static{
+						NewAttribute("foo"): NewStaticString("bar"),
+					}}}},
+				},
+			},
+		}
+	}
+	engine := NewEngineWithOptions(EvalOptions{MaxRegexLength: 8})
+
+	response, err := engine.Execute(context.Background(), &tempopb.SearchRequest{Query: `{ .foo =~ "b.r" }`}, fetcher())
+	require.NoError(t, err)
+	require.Len(t, response.Traces, 1)
+
+	// rejected before anything is fetched
+	f := fetcher()
+	_, err = engine.Execute(context.Background(), &tempopb.SearchRequest{Query: `{ .foo !~ "` + strings.Repeat("(a|b)", 10) + `" }`}, f)
+	require.ErrorIs(t, err, ErrRegexTooLong)
+	require.Nil(t, f.capturedRequest.Conditions)
+
+	_, err = engine.Execute(context.Background(), &tempopb.SearchRequest{Query: `{ .foo =~ "b(" }`}, fetcher())
+	require.Error(t, err)
+}
+
+func TestEngine_ExecuteUnboundReference(t *testing.T) {
+	// with() can't be used in a single filter, so a bare identifier is never bound
+	for _, query := range []string{
+		`{ foo = "bar" }`,
+		`{ kind = client }`,
+	} {
+		fetcher := &MockSpanSetFetcher{iterator: &MockSpanSetIterator{}}
+		_, err := NewEngine().Execute(context.Background(), &tempopb.SearchRequest{Query: query}, fetcher)
+		require.Error(t, err, query)
+		require.Nil(t, fetcher.capturedRequest.Conditions, query)
+	}
+}
+
 func TestEngine_asTraceSearchMetadata(t *testing.T) {
 	now := time.Now()
 
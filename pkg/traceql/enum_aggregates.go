@@ -10,6 +10,8 @@ const (
 	aggregateMin
 	aggregateSum
 	aggregateAvg
+	// aggregateCountDistinct is the number of distinct values of the expression, nil isn't counted
+	aggregateCountDistinct
 )
 
 func (a AggregateOp) String() string {
@@ -25,6 +27,8 @@ func (a AggregateOp) String() string {
 		return "sum"
 	case aggregateAvg:
 		return "avg"
+	case aggregateCountDistinct:
+		return "count_distinct"
 	}
 
 	return fmt.Sprintf("aggregate(%d)", a)
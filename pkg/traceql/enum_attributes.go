@@ -32,6 +32,13 @@ const (
 	IntrinsicName
 	IntrinsicStatus
 	IntrinsicParent
+	IntrinsicSelfTime
+	IntrinsicStartTime
+	IntrinsicEndTime
+	// IntrinsicLinkTraceID and IntrinsicLinkSpanID are the IDs of the spans the span links to. Storage
+	// layers set them as arrays of the IDs in hex, like they are written in search results.
+	IntrinsicLinkTraceID
+	IntrinsicLinkSpanID
 )
 
 func (i Intrinsic) String() string {
@@ -48,6 +55,16 @@ func (i Intrinsic) String() string {
 		return "childCount"
 	case IntrinsicParent:
 		return "parent"
+	case IntrinsicSelfTime:
+		return "selfTime"
+	case IntrinsicStartTime:
+		return "startTime"
+	case IntrinsicEndTime:
+		return "endTime"
+	case IntrinsicLinkTraceID:
+		return "link:traceID"
+	case IntrinsicLinkSpanID:
+		return "link:spanID"
 	}
 
 	return fmt.Sprintf("intrinsic(%d)", i)
@@ -66,7 +83,22 @@ func intrinsicFromString(s string) Intrinsic {
 		return IntrinsicChildCount
 	case "parent":
 		return IntrinsicParent
+	case "selfTime":
+		return IntrinsicSelfTime
+	case "startTime":
+		return IntrinsicStartTime
+	case "endTime":
+		return IntrinsicEndTime
+	case "link:traceID":
+		return IntrinsicLinkTraceID
+	case "link:spanID":
+		return IntrinsicLinkSpanID
 	}
 
 	return IntrinsicNone
 }
+
+// isLink reports whether the intrinsic has a value for every link of a span.
+func (i Intrinsic) isLink() bool {
+	return i == IntrinsicLinkTraceID || i == IntrinsicLinkSpanID
+}
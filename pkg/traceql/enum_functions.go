@@ -0,0 +1,22 @@
+package traceql
+
+import "fmt"
+
+// FunctionOp is a function that maps a single field expression to a new value.
+type FunctionOp int
+
+const (
+	functionAbs FunctionOp = iota
+	functionSign
+)
+
+func (f FunctionOp) String() string {
+	switch f {
+	case functionAbs:
+		return "abs"
+	case functionSign:
+		return "sign"
+	}
+
+	return fmt.Sprintf("function(%d)", f)
+}
@@ -28,8 +28,39 @@ const (
 	OpSpansetAnd
 	OpSpansetUnion
 	OpSpansetSibling
+	OpIn
+	OpNotIn
+	OpBitAnd
+	OpBitOr
+	OpSpansetNotDescendant
+
+	// OpBetween is not part of the language. It is only emitted in a Condition by extractConditions
+	// when an upper and lower bound on the same attribute can be combined. Its two Operands are the
+	// inclusive min and max.
+	OpBetween
 )
 
+// isComparison returns true for the operators that compare two values, which are the only ones
+// that can be applied to the elements of an array.
+func (op Operator) isComparison() bool {
+	return op == OpEqual ||
+		op == OpNotEqual ||
+		op == OpRegex ||
+		op == OpNotRegex ||
+		op == OpGreater ||
+		op == OpGreaterEqual ||
+		op == OpLess ||
+		op == OpLessEqual
+}
+
+// isOrdering returns true for the operators that compare the order of two values.
+func (op Operator) isOrdering() bool {
+	return op == OpGreater ||
+		op == OpGreaterEqual ||
+		op == OpLess ||
+		op == OpLessEqual
+}
+
 func (op Operator) isBoolean() bool {
 	return op == OpOr ||
 		op == OpAnd ||
@@ -41,7 +72,15 @@ func (op Operator) isBoolean() bool {
 		op == OpGreaterEqual ||
 		op == OpLess ||
 		op == OpLessEqual ||
-		op == OpNot
+		op == OpNot ||
+		op == OpIn ||
+		op == OpNotIn
+}
+
+// isBitwise reports whether the operator is one of the bitwise functions bitAnd and bitOr, which
+// are written as function calls but evaluated like binary operators.
+func (op Operator) isBitwise() bool {
+	return op == OpBitAnd || op == OpBitOr
 }
 
 func (op Operator) binaryTypesValid(lhsT StaticType, rhsT StaticType) bool {
@@ -53,6 +92,10 @@ func binaryTypeValid(op Operator, t StaticType) bool {
 		return true
 	}
 
+	if op.isBitwise() {
+		return t == TypeInt
+	}
+
 	switch t {
 	case TypeBoolean:
 		return op == OpAnd ||
@@ -76,6 +119,13 @@ func binaryTypeValid(op Operator, t StaticType) bool {
 			op == OpGreaterEqual ||
 			op == OpLess ||
 			op == OpLessEqual
+	case TypeTimestamp:
+		return op == OpEqual ||
+			op == OpNotEqual ||
+			op == OpGreater ||
+			op == OpGreaterEqual ||
+			op == OpLess ||
+			op == OpLessEqual
 	case TypeString:
 		return op == OpEqual ||
 			op == OpNotEqual ||
@@ -83,6 +133,8 @@ func binaryTypeValid(op Operator, t StaticType) bool {
 			op == OpNotRegex
 	case TypeNil:
 		fallthrough
+	case TypeArray:
+		fallthrough
 	case TypeStatus:
 		return op == OpEqual || op == OpNotEqual
 	}
@@ -152,6 +204,18 @@ func (op Operator) String() string {
 		return "~"
 	case OpSpansetUnion:
 		return "||"
+	case OpSpansetNotDescendant:
+		return "!>>"
+	case OpIn:
+		return "in"
+	case OpNotIn:
+		return "not in"
+	case OpBetween:
+		return "between"
+	case OpBitAnd:
+		return "bitAnd"
+	case OpBitOr:
+		return "bitOr"
 	}
 
 	return fmt.Sprintf("operator(%d)", op)
@@ -33,6 +33,7 @@ func TestOperatorIsBoolean(t *testing.T) {
 		{OpSpansetAnd, false},
 		{OpSpansetUnion, false},
 		{OpSpansetSibling, false},
+		{OpSpansetNotDescendant, false},
 	}
 
 	for _, tc := range tt {
@@ -139,6 +140,7 @@ func TestOperatorUnaryTypesValid(t *testing.T) {
 		{OpSpansetAnd, TypeInt, false},
 		{OpSpansetUnion, TypeInt, false},
 		{OpSpansetSibling, TypeInt, false},
+		{OpSpansetNotDescendant, TypeInt, false},
 		// not
 		{OpNot, TypeBoolean, true},
 		{OpNot, TypeInt, false},
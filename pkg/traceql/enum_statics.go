@@ -14,6 +14,8 @@ const (
 	TypeBoolean
 	TypeDuration
 	TypeStatus
+	TypeTimestamp // a point in time, stored in N as nanoseconds since the unix epoch
+	TypeArray     // the values of an array attribute, encoded in S. see NewStaticArray
 )
 
 // isMatchingOperand returns whether two types can be combined with a binary operator. the kind of operator is
@@ -24,6 +26,12 @@ func (t StaticType) isMatchingOperand(otherT StaticType) bool {
 		return true
 	}
 
+	// any value can be compared with nil to test whether it exists. nil is only valid for = and !=,
+	// so ordering against nil is still rejected by binaryTypesValid
+	if t == TypeNil || otherT == TypeNil {
+		return true
+	}
+
 	if t == otherT {
 		return true
 	}
@@ -32,6 +40,11 @@ func (t StaticType) isMatchingOperand(otherT StaticType) bool {
 		return true
 	}
 
+	// statuses can be written as their name or ordinal, see Static.Equals
+	if t == TypeStatus || otherT == TypeStatus {
+		return otherT == TypeInt || otherT == TypeString || t == TypeInt || t == TypeString
+	}
+
 	return false
 }
 
@@ -39,6 +52,35 @@ func (t StaticType) isNumeric() bool {
 	return t == TypeInt || t == TypeFloat || t == TypeDuration
 }
 
+func (t StaticType) String() string {
+	switch t {
+	case TypeNil:
+		return "nil"
+	case TypeSpanset:
+		return "spanset"
+	case TypeAttribute:
+		return "attribute"
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeString:
+		return "string"
+	case TypeBoolean:
+		return "bool"
+	case TypeDuration:
+		return "duration"
+	case TypeStatus:
+		return "status"
+	case TypeTimestamp:
+		return "timestamp"
+	case TypeArray:
+		return "array"
+	}
+
+	return fmt.Sprintf("type(%d)", int(t))
+}
+
 // Status represents valid static values of typeStatus
 type Status int
 
@@ -0,0 +1,339 @@
+package traceql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+
+	"github.com/opentracing/opentracing-go"
+	"golang.org/x/text/unicode/norm"
+)
+
+// cancellationCheckInterval is the number of spans evaluated between checks of the context.
+const cancellationCheckInterval = 1000
+
+// StringComparison controls how string statics are compared for equality.
+type StringComparison int
+
+const (
+	// StringComparisonExact compares strings byte for byte. This is the default.
+	StringComparisonExact StringComparison = iota
+	// StringComparisonNFC normalizes both operands to Unicode NFC before comparing them. This
+	// makes composed and decomposed forms of the same character equal at the cost of an
+	// allocation per comparison.
+	StringComparisonNFC
+)
+
+// GroupLimitPolicy controls what by() does once it created EvalOptions.MaxGroups groups.
+type GroupLimitPolicy int
+
+const (
+	// GroupLimitError fails the evaluation with ErrTooManyGroups. This is the default.
+	GroupLimitError GroupLimitPolicy = iota
+	// GroupLimitDrop keeps the groups created so far and drops the spans that would create others.
+	GroupLimitDrop
+)
+
+// IntOverflowPolicy controls the result of integer arithmetic that doesn't fit into an int.
+type IntOverflowPolicy int
+
+const (
+	// IntOverflowError fails the evaluation with ErrIntegerOverflow. This is the default.
+	IntOverflowError IntOverflowPolicy = iota
+	// IntOverflowSaturate clamps the result to math.MaxInt or math.MinInt.
+	IntOverflowSaturate
+)
+
+// ErrTooManyGroups is returned by the evaluation of by() if it would create more groups than
+// EvalOptions.MaxGroups allows.
+var ErrTooManyGroups = errors.New("too many groups")
+
+// ErrUnsupportedOperation is returned if an operator is applied to operands of types it can't
+// handle. Validation rejects most of these, but attributes are only resolved to a type while
+// evaluating and an unexpected combination fails the evaluation with this error.
+var ErrUnsupportedOperation = errors.New("unsupported operation")
+
+// ErrIntegerOverflow is returned if the result of integer arithmetic doesn't fit into an int and
+// EvalOptions.IntOverflowPolicy is IntOverflowError.
+var ErrIntegerOverflow = errors.New("integer overflow")
+
+// ErrTooManyDistinctValues is returned by count_distinct() if it would track more values than
+// EvalOptions.MaxDistinctValues allows.
+var ErrTooManyDistinctValues = errors.New("too many distinct values")
+
+// ErrRegexTooLong is returned if a pattern of =~ or !~ is longer than EvalOptions.MaxRegexLength.
+var ErrRegexTooLong = errors.New("regular expression too long")
+
+// EvalOptions configures how expressions are evaluated against spans.
+type EvalOptions struct {
+	StringComparison StringComparison
+	// FloatEpsilon makes = and != treat two floats as equal if they differ by at most this much, so
+	// computed values like 0.1 + 0.2 equal 0.3. It only applies if both operands are floats, ints and
+	// durations are always compared exactly. 0 compares floats exactly.
+	FloatEpsilon float64
+	// CacheAttributes memoizes attribute lookups while evaluating a span, so expressions that
+	// reference the same attribute several times only resolve it once.
+	CacheAttributes bool
+	// MaxGroups limits the number of groups a by() creates over all spansets, which protects
+	// against grouping by attributes of high cardinality. 0 is unlimited.
+	MaxGroups        int
+	GroupLimitPolicy GroupLimitPolicy
+	// MaxSpansPerSpanset keeps only the first spans of each spanset that is evaluated, so a single
+	// trace with a huge number of matching spans doesn't slow down every element. Unlike the limit
+	// of spans read per trace it applies to the spansets passed to the evaluation. Spansets that lost
+	// spans are flagged as Truncated. 0 is unlimited.
+	MaxSpansPerSpanset int
+	// IntOverflowPolicy decides what happens if +, -, *, / or ^ on two ints overflows, for example
+	// while summing large values. Arithmetic involving floats or durations isn't affected.
+	IntOverflowPolicy IntOverflowPolicy
+	// MaxRegexLength rejects patterns of =~ and !~ that are longer than this many bytes. RE2 matches
+	// in linear time, but the size of the compiled program grows with the pattern. Static patterns
+	// are rejected before any span is evaluated, patterns read from attributes fail the evaluation.
+	// 0 is unlimited.
+	MaxRegexLength int
+	// MaxDistinctValues limits the number of values count_distinct() keeps in memory to count a
+	// spanset. Counting more values fails the evaluation with ErrTooManyDistinctValues, so a count
+	// is never approximated. 0 is unlimited.
+	MaxDistinctValues int
+}
+
+// checkRegexLength returns ErrRegexTooLong if the pattern is longer than MaxRegexLength.
+func (o EvalOptions) checkRegexLength(pattern string) error {
+	if o.MaxRegexLength > 0 && len(pattern) > o.MaxRegexLength {
+		return fmt.Errorf("%w: %d bytes, at most %d are allowed", ErrRegexTooLong, len(pattern), o.MaxRegexLength)
+	}
+	return nil
+}
+
+// truncateSpansets applies MaxSpansPerSpanset to the spansets. The input isn't modified.
+func truncateSpansets(input []Spanset, max int) []Spanset {
+	if max <= 0 {
+		return input
+	}
+
+	var output []Spanset
+	for i, ss := range input {
+		if len(ss.Spans) <= max {
+			if output != nil {
+				output = append(output, ss)
+			}
+			continue
+		}
+		if output == nil {
+			output = append(make([]Spanset, 0, len(input)), input[:i]...)
+		}
+		ss.Spans = ss.Spans[:max:max]
+		ss.Truncated = true
+		output = append(output, ss)
+	}
+
+	if output == nil {
+		return input
+	}
+	return output
+}
+
+// evalContext carries per-evaluation state through the AST. A nil *evalContext is
+// valid and uses the default options.
+type evalContext struct {
+	opts EvalOptions
+
+	ctx   context.Context
+	spans int
+
+	// attributes caches resolved attributes of the current span if CacheAttributes is set
+	attributes map[Attribute]Static
+	// resolutions counts attribute lookups that weren't served from the cache
+	resolutions int
+
+	// bindings of the spanset that is currently evaluated
+	bindings map[string]Static
+
+	// regexes caches compiled patterns of =~ and !~ by their source
+	regexes map[string]*regexp.Regexp
+
+	// arrays caches the decoded elements of the arrays of the current span by their encoding
+	arrays map[string][]Static
+
+	// pool recycles the span slices of intermediate spansets, pooled are the slices handed on by
+	// the elements evaluated so far
+	pool   *spanPool
+	pooled [][]Span
+}
+
+func newEvalContext(opts EvalOptions) *evalContext {
+	return newEvalContextWithContext(context.Background(), opts)
+}
+
+// newEvalContextWithContext creates an evalContext that aborts evaluation once ctx is done.
+func newEvalContextWithContext(ctx context.Context, opts EvalOptions) *evalContext {
+	ec := &evalContext{
+		opts: opts,
+		ctx:  ctx,
+		pool: evalSpanPool,
+	}
+	if opts.CacheAttributes {
+		ec.attributes = map[Attribute]Static{}
+	}
+	return ec
+}
+
+func (ec *evalContext) options() EvalOptions {
+	if ec == nil {
+		return EvalOptions{}
+	}
+	return ec.opts
+}
+
+// stringsEqual compares two strings using the configured StringComparison.
+func (ec *evalContext) stringsEqual(a, b string) bool {
+	if ec.options().StringComparison == StringComparisonNFC {
+		return norm.NFC.String(a) == norm.NFC.String(b)
+	}
+	return a == b
+}
+
+// floatsEqual compares two floats within the configured FloatEpsilon.
+func (ec *evalContext) floatsEqual(a, b float64) bool {
+	if epsilon := ec.options().FloatEpsilon; epsilon > 0 {
+		return math.Abs(a-b) <= epsilon
+	}
+	return a == b
+}
+
+// matchRegex reports whether s matches the pattern. Patterns are compiled once per evaluation.
+// Matching stops with the context error once the context of the evaluation is done.
+func (ec *evalContext) matchRegex(pattern, s string) (bool, error) {
+	if ec == nil {
+		return regexp.MatchString(pattern, s)
+	}
+	if ec.ctx != nil {
+		if err := ec.ctx.Err(); err != nil {
+			return false, err
+		}
+	}
+
+	re, ok := ec.regexes[pattern]
+	if !ok {
+		if err := ec.opts.checkRegexLength(pattern); err != nil {
+			return false, err
+		}
+		var err error
+		re, err = regexp.Compile(pattern)
+		if err != nil {
+			return false, err
+		}
+		if ec.regexes == nil {
+			ec.regexes = map[string]*regexp.Regexp{}
+		}
+		ec.regexes[pattern] = re
+	}
+	return re.MatchString(s), nil
+}
+
+// nextSpan must be called before evaluating each span. It drops the attributes cached for the
+// previous span and periodically returns the context error, so long running evaluations stop
+// shortly after their deadline.
+func (ec *evalContext) nextSpan() error {
+	if ec == nil {
+		return nil
+	}
+	for a := range ec.attributes {
+		delete(ec.attributes, a)
+	}
+	for s := range ec.arrays {
+		delete(ec.arrays, s)
+	}
+	if ec.ctx == nil {
+		return nil
+	}
+	n := ec.spans
+	ec.spans++
+	if n%cancellationCheckInterval != 0 {
+		return nil
+	}
+	return ec.ctx.Err()
+}
+
+// startSpan starts a tracing span for the evaluation of a pipeline element. Elements evaluated until
+// the returned function is called are children of it. Spans are only started if the context of
+// the evaluation is part of a trace, so untraced evaluations don't create root spans.
+func (ec *evalContext) startSpan(e Element) (finish func()) {
+	if ec == nil || ec.ctx == nil || opentracing.SpanFromContext(ec.ctx) == nil {
+		return func() {}
+	}
+
+	parent := ec.ctx
+	span, ctx := opentracing.StartSpanFromContext(parent, fmt.Sprintf("%T.evaluate", e))
+	span.SetTag("element", e.String())
+	ec.ctx = ctx
+	return func() {
+		ec.ctx = parent
+		span.Finish()
+	}
+}
+
+// forSpanset must be called before evaluating the spans of a spanset. It makes the values bound
+// to the spanset available to references. The returned context is only nil if ec is nil and the
+// spanset has no bindings.
+func (ec *evalContext) forSpanset(ss Spanset) *evalContext {
+	if ec == nil {
+		if ss.bindings == nil {
+			return nil
+		}
+		ec = &evalContext{}
+	}
+	ec.bindings = ss.bindings
+	return ec
+}
+
+// binding returns the value bound to the name for the current spanset.
+func (ec *evalContext) binding(name string) (Static, bool) {
+	if ec == nil {
+		return NewStaticNil(), false
+	}
+	v, ok := ec.bindings[name]
+	return v, ok
+}
+
+// arrayElements returns the decoded elements of the array. Each array is decoded once per span, so
+// comparing several elements or conditions with the same array doesn't decode it again.
+func (ec *evalContext) arrayElements(array Static) ([]Static, error) {
+	if ec == nil {
+		return array.Elements()
+	}
+
+	if elements, ok := ec.arrays[array.S]; ok {
+		return elements, nil
+	}
+
+	elements, err := array.Elements()
+	if err != nil {
+		return nil, err
+	}
+	if ec.arrays == nil {
+		ec.arrays = map[string][]Static{}
+	}
+	ec.arrays[array.S] = elements
+	return elements, nil
+}
+
+// resolveAttribute returns the value of the attribute on the span, using the cache if enabled.
+func (ec *evalContext) resolveAttribute(a Attribute, span Span) Static {
+	if ec == nil {
+		return a.resolve(span)
+	}
+
+	if static, ok := ec.attributes[a]; ok {
+		return static
+	}
+
+	ec.resolutions++
+	static := a.resolve(span)
+	if ec.attributes != nil {
+		ec.attributes[a] = static
+	}
+	return static
+}
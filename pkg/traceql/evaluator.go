@@ -0,0 +1,258 @@
+package traceql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ElementStats describes the evaluation of a single pipeline element.
+type ElementStats struct {
+	// Index is the position of the element in the pipeline.
+	Index   int
+	Element Element
+	// SpansScanned is the number of spans in the spansets passed to the element.
+	SpansScanned int
+	// SpansetsIn is the number of spansets passed to the element.
+	SpansetsIn int
+	// SpansetsDropped is the number of spansets the element didn't return.
+	SpansetsDropped int
+	Duration        time.Duration
+}
+
+// MetricsSink receives statistics from an Evaluator. Implementations must be safe for concurrent
+// use if the Evaluator is shared.
+type MetricsSink interface {
+	ObserveElement(stats ElementStats)
+}
+
+type noopMetricsSink struct{}
+
+func (noopMetricsSink) ObserveElement(ElementStats) {}
+
+// Evaluator evaluates pipelines against spansets and reports per element statistics to a
+// MetricsSink.
+type Evaluator struct {
+	opts EvalOptions
+	sink MetricsSink
+}
+
+// NewEvaluator creates an Evaluator. A nil sink discards all statistics.
+func NewEvaluator(opts EvalOptions, sink MetricsSink) *Evaluator {
+	if sink == nil {
+		sink = noopMetricsSink{}
+	}
+
+	return &Evaluator{
+		opts: opts,
+		sink: sink,
+	}
+}
+
+// QueryStats is the work done to evaluate a query. It is meant for enforcing per tenant budgets.
+type QueryStats struct {
+	// SpansExamined is the number of spans passed to each element, summed over all elements.
+	SpansExamined int
+	// SpansetsProduced is the number of spansets returned by the pipeline.
+	SpansetsProduced int
+	// InspectedBytes is the number of bytes read by the storage layer. It is only set by callers
+	// that know which fetch produced the input, see FetchSpansResponse.BytesRead.
+	InspectedBytes uint64
+}
+
+// Evaluate runs the pipeline against the input. Every top level element that is evaluated is
+// observed, evaluation stops early once an element returns no spansets.
+func (e *Evaluator) Evaluate(ctx context.Context, p Pipeline, input []Spanset) ([]Spanset, error) {
+	result, _, err := e.EvaluateWithStats(ctx, p, input)
+	return result, err
+}
+
+// EvaluateWithStats is Evaluate but also returns the accumulated statistics of all elements.
+func (e *Evaluator) EvaluateWithStats(ctx context.Context, p Pipeline, input []Spanset) ([]Spanset, QueryStats, error) {
+	if err := validateRegexes(p, e.opts); err != nil {
+		return nil, QueryStats{}, err
+	}
+	setTraceIntrinsics(p, input)
+
+	ec := newEvalContextWithContext(ctx, e.opts)
+	result := truncateSpansets(input, e.opts.MaxSpansPerSpanset)
+	var qs QueryStats
+	stages := ec.startStages()
+
+	for i, element := range p.Elements {
+		stats := ElementStats{
+			Index:      i,
+			Element:    element,
+			SpansetsIn: len(result),
+		}
+		for _, ss := range result {
+			stats.SpansScanned += len(ss.Spans)
+		}
+		qs.SpansExamined += stats.SpansScanned
+
+		start := time.Now()
+		finish := ec.startSpan(element)
+		output, err := element.evaluate(ec, result)
+		finish()
+		stats.Duration = time.Since(start)
+		stages.evaluated(element)
+		if err != nil {
+			return nil, qs, err
+		}
+
+		if dropped := len(result) - len(output); dropped > 0 {
+			stats.SpansetsDropped = dropped
+		}
+		e.sink.ObserveElement(stats)
+
+		result = output
+		if len(result) == 0 {
+			stages.discard()
+			return []Spanset{}, qs, nil
+		}
+	}
+
+	qs.SpansetsProduced = len(result)
+	return result, qs, nil
+}
+
+// GroupScalar is the value of a scalar query for one group of a by().
+type GroupScalar struct {
+	Group Static
+	Value Static
+}
+
+// GroupOrder is the order in which EvaluateScalarGroupsOrdered returns the groups.
+type GroupOrder int
+
+const (
+	// GroupOrderFirstSeen returns the groups in the order they first appear in the input. This is
+	// the default.
+	GroupOrderFirstSeen GroupOrder = iota
+	// GroupOrderAscending orders the groups by their value, smallest first.
+	GroupOrderAscending
+	// GroupOrderDescending orders the groups by their value, largest first, so the first n groups
+	// are the top n.
+	GroupOrderDescending
+)
+
+// EvaluateScalar runs a query that returns a scalar, like { true } | count(), against the input and
+// returns its value. Aggregates are global, they are computed over all spans of all spansets that
+// reach them regardless of the trace or group they belong to.
+func EvaluateScalar(root *RootExpr, input []Spanset) (Static, error) {
+	if err := validateScalarRoot(root); err != nil {
+		return NewStaticNil(), err
+	}
+	setTraceIntrinsics(root.Pipeline, input)
+
+	return evaluateScalar(newEvalContext(EvalOptions{}), root.Pipeline, input)
+}
+
+// EvaluateScalarGroups is like EvaluateScalar but computes the final scalar once per group of the
+// last by() of the query, across all traces. Queries without a by() return a single group under nil,
+// grouped queries without results return no groups. Groups are returned in the order they first
+// appear in the input.
+func EvaluateScalarGroups(root *RootExpr, input []Spanset) ([]GroupScalar, error) {
+	return EvaluateScalarGroupsOrdered(root, input, GroupOrderFirstSeen)
+}
+
+// EvaluateScalarGroupsOrdered is EvaluateScalarGroups with the groups in the given order. Groups with
+// equal values keep the order they first appear in, groups without a value, e.g. the avg() of no
+// spans, are last.
+func EvaluateScalarGroupsOrdered(root *RootExpr, input []Spanset, order GroupOrder) ([]GroupScalar, error) {
+	if err := validateScalarRoot(root); err != nil {
+		return nil, err
+	}
+	setTraceIntrinsics(root.Pipeline, input)
+
+	ec := newEvalContext(EvalOptions{})
+	last := len(root.Pipeline.Elements) - 1
+	final := root.Pipeline.Elements[last].(ScalarExpression)
+
+	result, err := newPipeline(root.Pipeline.Elements[:last]...).evaluate(ec, input)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, groups := groupSpansets(result)
+	if len(keys) == 0 && !hasGroupOperation(root.Pipeline) {
+		keys = []Static{NewStaticNil()}
+	}
+
+	output := make([]GroupScalar, 0, len(keys))
+	for _, key := range keys {
+		v, err := evaluateScalar(ec, final, groups[key])
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, GroupScalar{Group: key, Value: v})
+	}
+
+	if order != GroupOrderFirstSeen {
+		sortGroupScalars(output, order == GroupOrderDescending)
+	}
+	return output, nil
+}
+
+func sortGroupScalars(groups []GroupScalar, descending bool) {
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i].Value, groups[j].Value
+		if a.Type == TypeNil || b.Type == TypeNil {
+			return b.Type == TypeNil && a.Type != TypeNil
+		}
+		c, err := a.Compare(b)
+		if err != nil {
+			return false
+		}
+		if descending {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+func hasGroupOperation(p Pipeline) bool {
+	for _, e := range p.Elements {
+		if _, ok := e.(GroupOperation); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func validateScalarRoot(root *RootExpr) error {
+	if root.Pipeline.impliedType() == TypeSpanset {
+		return fmt.Errorf("query doesn't return a scalar: %s", root.String())
+	}
+	return root.validate()
+}
+
+// Matches reports whether the span satisfies the query, which must be a single span filter like
+// { .foo = "bar" }. It evaluates the span the way the filter evaluates the spans of a spanset and
+// is meant for checking queries against sample spans. The span isn't modified.
+func Matches(expr *RootExpr, span Span) (bool, error) {
+	if err := expr.validate(); err != nil {
+		return false, err
+	}
+
+	var filter SpansetFilter
+	if len(expr.Pipeline.Elements) == 1 {
+		filter, _ = expr.Pipeline.Elements[0].(SpansetFilter)
+	}
+	if filter.Expression == nil {
+		return false, fmt.Errorf("only a single span filter can be matched against a span: %s", expr.String())
+	}
+
+	if t := referencedTraceIntrinsics(filter); t.any() {
+		// the intrinsics are stored with the attributes, the caller's map is left alone
+		attributes := make(map[Attribute]Static, len(span.Attributes)+3)
+		for a, v := range span.Attributes {
+			attributes[a] = v
+		}
+		span.Attributes = attributes
+		t.set(Spanset{Spans: []Span{span}})
+	}
+
+	return filter.matches(newEvalContext(EvalOptions{}), span)
+}
@@ -0,0 +1,524 @@
+package traceql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMetricsSink struct {
+	observed []ElementStats
+}
+
+func (f *fakeMetricsSink) ObserveElement(stats ElementStats) {
+	f.observed = append(f.observed, stats)
+}
+
+func TestEvaluatorObservesElements(t *testing.T) {
+	expr, err := Parse("{ .foo = `a` } | { .bar = `b` } | { .baz = `c` }")
+	require.NoError(t, err)
+
+	input := []Spanset{
+		{Spans: []Span{
+			{ID: []byte{1}, Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("a"), NewAttribute("bar"): NewStaticString("b")}},
+			{ID: []byte{2}, Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("a")}},
+		}},
+		{Spans: []Span{
+			{ID: []byte{3}, Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("a")}},
+		}},
+		{Spans: []Span{
+			{ID: []byte{4}, Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("x")}},
+		}},
+	}
+
+	sink := &fakeMetricsSink{}
+	output, err := NewEvaluator(EvalOptions{}, sink).Evaluate(context.Background(), expr.Pipeline, input)
+	require.NoError(t, err)
+	require.Empty(t, output)
+
+	require.Len(t, sink.observed, 3)
+	for i, stats := range sink.observed {
+		require.Equal(t, i, stats.Index)
+		require.Equal(t, expr.Pipeline.Elements[i], stats.Element)
+	}
+
+	// { .foo = `a` } drops the last spanset and the spans that don't match
+	require.Equal(t, 4, sink.observed[0].SpansScanned)
+	require.Equal(t, 3, sink.observed[0].SpansetsIn)
+	require.Equal(t, 1, sink.observed[0].SpansetsDropped)
+
+	// { .bar = `b` } keeps only the first span of the first spanset
+	require.Equal(t, 3, sink.observed[1].SpansScanned)
+	require.Equal(t, 2, sink.observed[1].SpansetsIn)
+	require.Equal(t, 1, sink.observed[1].SpansetsDropped)
+
+	// { .baz = `c` } drops everything
+	require.Equal(t, 1, sink.observed[2].SpansScanned)
+	require.Equal(t, 1, sink.observed[2].SpansetsIn)
+	require.Equal(t, 1, sink.observed[2].SpansetsDropped)
+}
+
+// recordingTracer records the operation names of all spans started from it.
+type recordingTracer struct {
+	opentracing.NoopTracer
+	operations []string
+}
+
+func (r *recordingTracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	r.operations = append(r.operations, operationName)
+	return r.NoopTracer.StartSpan(operationName, opts...)
+}
+
+func TestEvaluatorTracesElements(t *testing.T) {
+	expr, err := Parse("{ .foo = `a` } | by(.foo) | { .bar = `b` }")
+	require.NoError(t, err)
+
+	input := []Spanset{
+		{Spans: []Span{{ID: []byte{1}, Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("a"), NewAttribute("bar"): NewStaticString("b")}}}},
+	}
+
+	tracer := &recordingTracer{}
+	previous := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(tracer)
+	t.Cleanup(func() { opentracing.SetGlobalTracer(previous) })
+
+	root := tracer.StartSpan("query")
+	ctx := opentracing.ContextWithSpan(context.Background(), root)
+
+	_, err = NewEvaluator(EvalOptions{}, nil).Evaluate(ctx, expr.Pipeline, input)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"query",
+		"traceql.SpansetFilter.evaluate",
+		"traceql.GroupOperation.evaluate",
+		"traceql.SpansetFilter.evaluate",
+	}, tracer.operations)
+
+	// evaluations outside of a trace don't start spans
+	tracer.operations = nil
+	_, err = NewEvaluator(EvalOptions{}, nil).Evaluate(context.Background(), expr.Pipeline, input)
+	require.NoError(t, err)
+	require.Empty(t, tracer.operations)
+}
+
+func TestEvaluatorStopsWhenEmpty(t *testing.T) {
+	expr, err := Parse("{ .foo = `a` } | { .bar = `b` }")
+	require.NoError(t, err)
+
+	input := []Spanset{
+		{Spans: []Span{{Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("x")}}}},
+	}
+
+	sink := &fakeMetricsSink{}
+	output, err := NewEvaluator(EvalOptions{}, sink).Evaluate(context.Background(), expr.Pipeline, input)
+	require.NoError(t, err)
+	require.Empty(t, output)
+	require.Len(t, sink.observed, 1)
+
+	// a nil sink is allowed
+	_, err = NewEvaluator(EvalOptions{}, nil).Evaluate(context.Background(), expr.Pipeline, input)
+	require.NoError(t, err)
+}
+
+func TestEvaluatorQueryStats(t *testing.T) {
+	expr, err := Parse("{ .foo = `a` } | { .bar = `b` }")
+	require.NoError(t, err)
+
+	input := []Spanset{
+		{Spans: []Span{
+			{ID: []byte{1}, Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("a"), NewAttribute("bar"): NewStaticString("b")}},
+			{ID: []byte{2}, Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("a")}},
+		}},
+		{Spans: []Span{
+			{ID: []byte{3}, Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("a"), NewAttribute("bar"): NewStaticString("b")}},
+		}},
+		{Spans: []Span{
+			{ID: []byte{4}, Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("x")}},
+		}},
+	}
+
+	output, stats, err := NewEvaluator(EvalOptions{}, nil).EvaluateWithStats(context.Background(), expr.Pipeline, input)
+	require.NoError(t, err)
+	require.Len(t, output, 2)
+
+	// 4 spans are passed to the first filter and the 3 matching ones to the second
+	require.Equal(t, QueryStats{
+		SpansExamined:    7,
+		SpansetsProduced: 2,
+	}, stats)
+
+	// stats are still returned when evaluation stops early
+	_, stats, err = NewEvaluator(EvalOptions{}, nil).EvaluateWithStats(context.Background(), expr.Pipeline, input[2:])
+	require.NoError(t, err)
+	require.Equal(t, QueryStats{SpansExamined: 1}, stats)
+}
+
+func TestEvaluatorMaxSpansPerSpanset(t *testing.T) {
+	expr, err := Parse("{ .foo = `a` }")
+	require.NoError(t, err)
+
+	spans := func(ids ...byte) []Span {
+		var spans []Span
+		for _, id := range ids {
+			spans = append(spans, Span{ID: []byte{id}, Attributes: map[Attribute]Static{NewAttribute("foo"): NewStaticString("a")}})
+		}
+		return spans
+	}
+	input := []Spanset{
+		{TraceID: []byte{1}, Spans: spans(1, 2, 3, 4, 5)},
+		{TraceID: []byte{2}, Spans: spans(6, 7)},
+	}
+
+	output, err := NewEvaluator(EvalOptions{MaxSpansPerSpanset: 3}, nil).Evaluate(context.Background(), expr.Pipeline, input)
+	require.NoError(t, err)
+	require.Len(t, output, 2)
+
+	// the first spans are kept
+	require.Equal(t, spans(1, 2, 3), output[0].Spans)
+	require.True(t, output[0].Truncated)
+	require.Equal(t, spans(6, 7), output[1].Spans)
+	require.False(t, output[1].Truncated)
+
+	// the input is left alone
+	require.Len(t, input[0].Spans, 5)
+	require.False(t, input[0].Truncated)
+
+	// 0 is unlimited
+	output, err = NewEvaluator(EvalOptions{}, nil).Evaluate(context.Background(), expr.Pipeline, input)
+	require.NoError(t, err)
+	require.Len(t, output[0].Spans, 5)
+	require.False(t, output[0].Truncated)
+}
+
+func TestEvaluatorMaxRegexLength(t *testing.T) {
+	input := []Spanset{
+		{TraceID: []byte{1}, Spans: []Span{{ID: []byte{1}, Attributes: map[Attribute]Static{
+			NewAttribute("foo"):     NewStaticString("bar"),
+			NewAttribute("pattern"): NewStaticString("ba+r|" + strings.Repeat("x", 10)),
+		}}}},
+	}
+	evaluator := NewEvaluator(EvalOptions{MaxRegexLength: 10}, nil)
+
+	expr, err := Parse(`{ .foo =~ "ba+r" }`)
+	require.NoError(t, err)
+	output, err := evaluator.Evaluate(context.Background(), expr.Pipeline, input)
+	require.NoError(t, err)
+	require.Len(t, output, 1)
+
+	expr, err = Parse(`{ .foo =~ "` + strings.Repeat("ba+r", 3) + `" }`)
+	require.NoError(t, err)
+	_, err = evaluator.Evaluate(context.Background(), expr.Pipeline, input)
+	require.ErrorIs(t, err, ErrRegexTooLong)
+
+	// patterns read from the span are checked when they are compiled
+	expr, err = Parse(`{ .foo =~ .pattern }`)
+	require.NoError(t, err)
+	_, err = evaluator.Evaluate(context.Background(), expr.Pipeline, input)
+	require.ErrorIs(t, err, ErrRegexTooLong)
+
+	// matching stops at the deadline of the evaluation
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	expr, err = Parse(`{ .foo =~ "ba+r" }`)
+	require.NoError(t, err)
+	_, err = evaluator.Evaluate(ctx, expr.Pipeline, input)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestEvaluatorTraceAggregateThreshold(t *testing.T) {
+	// spans are numbered per trace, error spans have odd IDs
+	spans := func(ids ...byte) []Span {
+		var spans []Span
+		for _, id := range ids {
+			status := StatusOk
+			if id%2 == 1 {
+				status = StatusError
+			}
+			spans = append(spans, Span{ID: []byte{id}, Attributes: map[Attribute]Static{
+				NewIntrinsic(IntrinsicStatus): NewStaticStatus(status),
+			}})
+		}
+		return spans
+	}
+
+	// storage returns several spansets per trace, e.g. one per batch
+	input := []Spanset{
+		{TraceID: []byte{1}, Spans: spans(1, 2, 3, 5, 7)},
+		{TraceID: []byte{2}, Spans: spans(1, 3, 5, 7, 9, 11)},
+		{TraceID: []byte{1}, Spans: spans(4, 9, 11, 13)},
+		{TraceID: []byte{3}, Spans: spans(1, 3, 5)},
+		{TraceID: []byte{3}, Spans: spans(5, 7, 9)}, // span 5 is counted once
+	}
+
+	evaluate := func(query string) []Spanset {
+		expr, err := Parse(query)
+		require.NoError(t, err)
+		require.NoError(t, expr.validate())
+
+		output, err := NewEvaluator(EvalOptions{}, nil).Evaluate(context.Background(), expr.Pipeline, input)
+		require.NoError(t, err)
+		return output
+	}
+
+	// trace 1 has 7 error spans, trace 2 has 6 and trace 3 has 5
+	output := evaluate("{ status = error } | coalesce() | count() > 5")
+	require.Equal(t, []Spanset{
+		{TraceID: []byte{1}, Spans: spans(1, 3, 5, 7, 9, 11, 13)},
+		{TraceID: []byte{2}, Spans: spans(1, 3, 5, 7, 9, 11)},
+	}, output)
+
+	output = evaluate("{ status = error } | coalesce() | count() > 6")
+	require.Equal(t, []Spanset{
+		{TraceID: []byte{1}, Spans: spans(1, 3, 5, 7, 9, 11, 13)},
+	}, output)
+
+	// the threshold can be on either side
+	output = evaluate("{ status = error } | coalesce() | 6 >= count()")
+	require.Equal(t, []Spanset{
+		{TraceID: []byte{2}, Spans: spans(1, 3, 5, 7, 9, 11)},
+		{TraceID: []byte{3}, Spans: spans(1, 3, 5, 7, 9)},
+	}, output)
+
+	// without coalesce() the spans are counted per spanset
+	output = evaluate("{ status = error } | count() > 5")
+	require.Equal(t, []Spanset{
+		{TraceID: []byte{2}, Spans: spans(1, 3, 5, 7, 9, 11)},
+	}, output)
+
+	require.Empty(t, evaluate("{ status = error } | coalesce() | count() > 7"))
+}
+
+func TestFetchSpansResponseBytesRead(t *testing.T) {
+	require.Zero(t, FetchSpansResponse{}.BytesRead())
+	require.Equal(t, uint64(10), FetchSpansResponse{Bytes: func() uint64 { return 10 }}.BytesRead())
+}
+
+func TestEvaluateScalar(t *testing.T) {
+	span := func(id byte, d time.Duration) Span {
+		return Span{ID: []byte{id}, Attributes: map[Attribute]Static{
+			NewIntrinsic(IntrinsicDuration): NewStaticDuration(d),
+			NewAttribute("foo"):             NewStaticString("a"),
+		}}
+	}
+	input := []Spanset{
+		{Spans: []Span{span(1, time.Second), span(2, 3*time.Second)}},
+		{Spans: []Span{span(3, 2*time.Second), span(1, time.Second)}}, // span 1 is only counted once
+	}
+
+	tcs := []struct {
+		query    string
+		expected Static
+	}{
+		{query: "{ true } | count()", expected: NewStaticInt(3)},
+		{query: "{ true } | avg(duration) * 2", expected: NewStaticDuration(4 * time.Second)},
+		{query: "{ duration > 1s } | count() + max(duration) / 1s", expected: NewStaticFloat(5)},
+		{query: "{ false } | count()", expected: NewStaticInt(0)},
+		{query: "{ false } | max(duration) * 2", expected: NewStaticNil()},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.query, func(t *testing.T) {
+			expr, err := Parse(tc.query)
+			require.NoError(t, err)
+
+			actual, err := EvaluateScalar(expr, input)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+
+	expr, err := Parse("{ true } | by(.foo)")
+	require.NoError(t, err)
+	_, err = EvaluateScalar(expr, input)
+	require.EqualError(t, err, "query doesn't return a scalar: { true }|by(.foo)")
+}
+
+func TestEvaluateScalarGroups(t *testing.T) {
+	span := func(id byte, service string, isErr bool) Span {
+		status := StatusOk
+		if isErr {
+			status = StatusError
+		}
+		return Span{ID: []byte{id}, Attributes: map[Attribute]Static{
+			NewIntrinsic(IntrinsicStatus):                            NewStaticStatus(status),
+			NewScopedAttribute(AttributeScopeResource, false, "svc"): NewStaticString(service),
+		}}
+	}
+	// span IDs repeat across traces but the spans are different
+	input := []Spanset{
+		{TraceID: []byte{1}, Spans: []Span{span(1, "a", true), span(2, "b", true), span(3, "a", false)}},
+		{TraceID: []byte{2}, Spans: []Span{span(1, "a", true)}},
+		{TraceID: []byte{3}, Spans: []Span{span(1, "b", true), span(2, "b", true), span(3, "c", false)}},
+	}
+
+	perSpanset := 0
+	filter, err := Parse("{ status = error }")
+	require.NoError(t, err)
+	matches, err := filter.Pipeline.evaluate(nil, input)
+	require.NoError(t, err)
+	for _, ss := range matches {
+		count, err := newAggregate(aggregateCount, nil).compute(nil, ss)
+		require.NoError(t, err)
+		perSpanset += count.N
+	}
+
+	// the global count is the sum of the per spanset counts
+	expr, err := Parse("{ status = error } | count()")
+	require.NoError(t, err)
+	global, err := EvaluateScalar(expr, input)
+	require.NoError(t, err)
+	require.Equal(t, NewStaticInt(perSpanset), global)
+	require.Equal(t, NewStaticInt(5), global)
+
+	groups, err := EvaluateScalarGroups(expr, input)
+	require.NoError(t, err)
+	require.Equal(t, []GroupScalar{{Group: NewStaticNil(), Value: NewStaticInt(5)}}, groups)
+
+	// with by() the count is global within each group
+	expr, err = Parse("{ status = error } | by(resource.svc) | count()")
+	require.NoError(t, err)
+	groups, err = EvaluateScalarGroups(expr, input)
+	require.NoError(t, err)
+	require.Equal(t, []GroupScalar{
+		{Group: NewStaticString("a"), Value: NewStaticInt(2)},
+		{Group: NewStaticString("b"), Value: NewStaticInt(3)},
+	}, groups)
+
+	// EvaluateScalar ignores the groups
+	global, err = EvaluateScalar(expr, input)
+	require.NoError(t, err)
+	require.Equal(t, NewStaticInt(5), global)
+
+	groups, err = EvaluateScalarGroups(expr, nil)
+	require.NoError(t, err)
+	require.Empty(t, groups)
+}
+
+func TestEvaluateScalarGroupsOrdered(t *testing.T) {
+	span := func(service string, x int) Span {
+		attrs := map[Attribute]Static{NewScopedAttribute(AttributeScopeResource, false, "svc"): NewStaticString(service)}
+		if x != 0 {
+			attrs[NewScopedAttribute(AttributeScopeSpan, false, "x")] = NewStaticInt(x)
+		}
+		return Span{Attributes: attrs}
+	}
+	input := []Spanset{
+		{TraceID: []byte{1}, Spans: []Span{span("a", 1), span("b", 2), span("c", 0)}},
+		{TraceID: []byte{2}, Spans: []Span{span("b", 2), span("d", 5), span("c", 0)}},
+		{TraceID: []byte{3}, Spans: []Span{span("b", 2), span("d", 5), span("e", 0)}},
+	}
+	groups := func(query string, order GroupOrder) []GroupScalar {
+		expr, err := Parse(query)
+		require.NoError(t, err)
+		groups, err := EvaluateScalarGroupsOrdered(expr, input, order)
+		require.NoError(t, err)
+		return groups
+	}
+	group := func(service string, value Static) GroupScalar {
+		return GroupScalar{Group: NewStaticString(service), Value: value}
+	}
+
+	query := "{ true } | by(resource.svc) | count()"
+	require.Equal(t, []GroupScalar{
+		group("a", NewStaticInt(1)),
+		group("b", NewStaticInt(3)),
+		group("c", NewStaticInt(2)),
+		group("d", NewStaticInt(2)),
+		group("e", NewStaticInt(1)),
+	}, groups(query, GroupOrderFirstSeen))
+
+	// ties keep the order of the input
+	require.Equal(t, []GroupScalar{
+		group("b", NewStaticInt(3)),
+		group("c", NewStaticInt(2)),
+		group("d", NewStaticInt(2)),
+		group("a", NewStaticInt(1)),
+		group("e", NewStaticInt(1)),
+	}, groups(query, GroupOrderDescending))
+	require.Equal(t, []GroupScalar{
+		group("a", NewStaticInt(1)),
+		group("e", NewStaticInt(1)),
+		group("c", NewStaticInt(2)),
+		group("d", NewStaticInt(2)),
+		group("b", NewStaticInt(3)),
+	}, groups(query, GroupOrderAscending))
+
+	// groups without a value are last in either order
+	query = "{ true } | by(resource.svc) | max(.x)"
+	ordered := groups(query, GroupOrderDescending)
+	require.Equal(t, []Static{NewStaticString("d"), NewStaticString("b"), NewStaticString("a")},
+		[]Static{ordered[0].Group, ordered[1].Group, ordered[2].Group})
+	for _, g := range ordered[3:] {
+		require.Equal(t, TypeNil, g.Value.Type)
+	}
+	ordered = groups(query, GroupOrderAscending)
+	require.Equal(t, NewStaticString("a"), ordered[0].Group)
+	require.Equal(t, TypeNil, ordered[4].Value.Type)
+
+	// the order doesn't change queries without by()
+	require.Equal(t, []GroupScalar{{Group: NewStaticNil(), Value: NewStaticInt(9)}}, groups("{ true } | count()", GroupOrderDescending))
+}
+
+func TestMatches(t *testing.T) {
+	span := Span{
+		ID:                 []byte{1},
+		StartTimeUnixNanos: uint64(time.Second),
+		EndtimeUnixNanos:   uint64(3 * time.Second),
+		Attributes: map[Attribute]Static{
+			NewIntrinsic(IntrinsicName):                                       NewStaticString("GET /api"),
+			NewIntrinsic(IntrinsicDuration):                                   NewStaticDuration(2 * time.Second),
+			NewIntrinsic(IntrinsicStatus):                                     NewStaticStatus(StatusError),
+			NewScopedAttribute(AttributeScopeSpan, false, "http.status_code"): NewStaticInt(500),
+			NewScopedAttribute(AttributeScopeResource, false, "service.name"): NewStaticString("api"),
+		},
+	}
+
+	tcs := []struct {
+		query   string
+		matches bool
+	}{
+		{query: `{ name = "GET /api" }`, matches: true},
+		{query: `{ name != "GET /api" }`, matches: false},
+		{query: `{ name =~ "GET .*" }`, matches: true},
+		{query: `{ name !~ "GET .*" }`, matches: false},
+		{query: `{ duration > 1s }`, matches: true},
+		{query: `{ duration <= 1s }`, matches: false},
+		{query: `{ .http.status_code >= 500 && resource.service.name = "api" }`, matches: true},
+		{query: `{ .http.status_code = 200 || status = ok }`, matches: false},
+		{query: `{ status = error }`, matches: true},
+		{query: `{ .missing = "foo" }`, matches: false},
+		{query: `{ .http.status_code in (404, 500) }`, matches: true},
+		{query: `{ selfTime = 2s }`, matches: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.query, func(t *testing.T) {
+			expr, err := Parse(tc.query)
+			require.NoError(t, err)
+
+			matches, err := Matches(expr, span)
+			require.NoError(t, err)
+			require.Equal(t, tc.matches, matches)
+		})
+	}
+
+	// selfTime isn't added to the span of the caller
+	require.Len(t, span.Attributes, 5)
+
+	for _, query := range []string{
+		`{ true } | count() > 1`,
+		`{ true } && { false }`,
+		`{ true } | { false }`,
+		`{ true } | by(name)`,
+		`{ 1 + "foo" = 2 }`,
+	} {
+		expr, err := Parse(query)
+		require.NoError(t, err)
+		_, err = Matches(expr, span)
+		require.Error(t, err, query)
+	}
+}
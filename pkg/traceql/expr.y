@@ -12,6 +12,11 @@ import (
     root RootExpr
     groupOperation GroupOperation
     coalesceOperation CoalesceOperation
+    flattenOperation FlattenOperation
+    selectOperation SelectOperation
+    withOperation WithOperation
+    distinctOperation DistinctOperation
+    topKOperation TopKOperation
 
     spansetExpression SpansetExpression
     spansetPipelineExpression SpansetExpression
@@ -29,6 +34,7 @@ import (
     aggregate Aggregate
 
     fieldExpression FieldExpression
+    fieldExpressionList []FieldExpression
     static Static
     intrinsicField Attribute
     attributeField Attribute
@@ -38,11 +44,18 @@ import (
     staticStr   string
     staticFloat float64
     staticDuration time.Duration
+    staticTimestamp time.Time
+    staticList  []Static
 }
 
 %type <RootExpr> root
 %type <groupOperation> groupOperation
 %type <coalesceOperation> coalesceOperation
+%type <flattenOperation> flattenOperation
+%type <selectOperation> selectOperation
+%type <withOperation> withOperation
+%type <distinctOperation> distinctOperation
+%type <topKOperation> topKOperation
 
 %type <spansetExpression> spansetExpression
 %type <spansetPipelineExpression> spansetPipelineExpression
@@ -60,7 +73,9 @@ import (
 %type <aggregate> aggregate 
 
 %type <fieldExpression> fieldExpression
+%type <fieldExpressionList> selectExpressionList
 %type <static> static
+%type <staticList> staticList
 %type <intrinsicField> intrinsicField
 %type <attributeField> attributeField
 
@@ -68,18 +83,20 @@ import (
 %token <staticInt>      INTEGER
 %token <staticFloat>    FLOAT
 %token <staticDuration> DURATION
+%token <staticTimestamp> TIMESTAMP
 %token <val>            DOT OPEN_BRACE CLOSE_BRACE OPEN_PARENS CLOSE_PARENS
                         NIL TRUE FALSE STATUS_ERROR STATUS_OK STATUS_UNSET
-                        IDURATION CHILDCOUNT NAME STATUS PARENT
+                        IDURATION CHILDCOUNT NAME STATUS PARENT SELFTIME STARTTIME ENDTIME LINK_TRACEID LINK_SPANID
                         PARENT_DOT RESOURCE_DOT SPAN_DOT
-                        COUNT AVG MAX MIN SUM
-                        BY COALESCE
+                        COUNT AVG MAX MIN SUM COUNT_DISTINCT
+                        BY COALESCE FLATTEN SELECT WITH DISTINCT TOPK HAS ABS SIGN BITAND BITOR COMMA
+                        ARRAY ALL
                         END_ATTRIBUTE
 
 // Operators are listed with increasing precedence.
 %left <binOp> PIPE
 %left <binOp> AND OR
-%left <binOp> EQ NEQ LT LTE GT GTE NRE RE DESC TILDE
+%left <binOp> EQ NEQ LT LTE GT GTE NRE RE DESC NOT_DESC TILDE IN NOT_IN
 %left <binOp> ADD SUB
 %left <binOp> NOT
 %left <binOp> MUL DIV MOD
@@ -93,6 +110,14 @@ root:
     spansetPipeline                             { yylex.(*lexer).expr = newRootExpr($1) }
   | spansetPipelineExpression                   { yylex.(*lexer).expr = newRootExpr($1) }
   | scalarPipelineExpressionFilter              { yylex.(*lexer).expr = newRootExpr($1) }
+  | spansetPipeline PIPE scalarExpression       {
+      e, ok := $3.(pipelineElement)
+      if !ok {
+        yylex.Error("scalar pipelines must end with an aggregate")
+      } else {
+        yylex.(*lexer).expr = newRootExpr($1.addItem(e))
+      }
+    }
   ;
 
 // **********************
@@ -103,6 +128,7 @@ spansetPipelineExpression: // shares the same operators as spansetExpression. sp
   | spansetPipelineExpression AND   spansetPipelineExpression    { $$ = newSpansetOperation(OpSpansetAnd, $1, $3) }
   | spansetPipelineExpression GT    spansetPipelineExpression    { $$ = newSpansetOperation(OpSpansetChild, $1, $3) }
   | spansetPipelineExpression DESC  spansetPipelineExpression    { $$ = newSpansetOperation(OpSpansetDescendant, $1, $3) }
+  | spansetPipelineExpression NOT_DESC spansetPipelineExpression { $$ = newSpansetOperation(OpSpansetNotDescendant, $1, $3) }
   | spansetPipelineExpression OR    spansetPipelineExpression    { $$ = newSpansetOperation(OpSpansetUnion, $1, $3) }
   | spansetPipelineExpression TILDE spansetPipelineExpression    { $$ = newSpansetOperation(OpSpansetSibling, $1, $3) }
   | wrappedSpansetPipeline                                       { $$ = $1 }
@@ -119,6 +145,11 @@ spansetPipeline:
   | spansetPipeline PIPE scalarFilter          { $$ = $1.addItem($3)  }
   | spansetPipeline PIPE groupOperation        { $$ = $1.addItem($3)  }
   | spansetPipeline PIPE coalesceOperation     { $$ = $1.addItem($3)  }
+  | spansetPipeline PIPE flattenOperation      { $$ = $1.addItem($3)  }
+  | spansetPipeline PIPE selectOperation       { $$ = $1.addItem($3)  }
+  | spansetPipeline PIPE withOperation         { $$ = $1.addItem($3)  }
+  | spansetPipeline PIPE distinctOperation     { $$ = $1.addItem($3)  }
+  | spansetPipeline PIPE topKOperation         { $$ = $1.addItem($3)  }
   ;
 
 groupOperation:
@@ -129,11 +160,41 @@ coalesceOperation:
     COALESCE OPEN_PARENS CLOSE_PARENS           { $$ = newCoalesceOperation() }
   ;
 
+flattenOperation:
+    FLATTEN OPEN_PARENS CLOSE_PARENS            { $$ = newFlattenOperation() }
+  ;
+
+selectOperation:
+    SELECT OPEN_PARENS selectExpressionList CLOSE_PARENS { $$ = newSelectOperation($3) }
+  ;
+
+withOperation:
+    WITH OPEN_PARENS IDENTIFIER EQ aggregate CLOSE_PARENS { $$ = newWithOperation($3, $5) }
+  ;
+
+distinctOperation:
+    DISTINCT OPEN_PARENS fieldExpression CLOSE_PARENS { $$ = newDistinctOperation($3) }
+  ;
+
+topKOperation:
+    TOPK OPEN_PARENS fieldExpression COMMA INTEGER CLOSE_PARENS { $$ = newTopKOperation($3, $5) }
+  ;
+
+// aggregates are the same for every span and can't be selected, they are only accepted to reject
+// them with a better message than a syntax error
+selectExpressionList:
+    fieldExpression                            { $$ = []FieldExpression{$1} }
+  | aggregate                                  { yylex.Error("select() can't select aggregates"); $$ = nil }
+  | selectExpressionList COMMA fieldExpression { $$ = append($1, $3)      }
+  | selectExpressionList COMMA aggregate       { yylex.Error("select() can't select aggregates"); $$ = $1 }
+  ;
+
 spansetExpression: // shares the same operators as scalarPipelineExpression. split out for readability
     OPEN_PARENS spansetExpression CLOSE_PARENS   { $$ = $2 }
   | spansetExpression AND   spansetExpression    { $$ = newSpansetOperation(OpSpansetAnd, $1, $3) }
   | spansetExpression GT    spansetExpression    { $$ = newSpansetOperation(OpSpansetChild, $1, $3) }
   | spansetExpression DESC  spansetExpression    { $$ = newSpansetOperation(OpSpansetDescendant, $1, $3) }
+  | spansetExpression NOT_DESC spansetExpression { $$ = newSpansetOperation(OpSpansetNotDescendant, $1, $3) }
   | spansetExpression OR    spansetExpression    { $$ = newSpansetOperation(OpSpansetUnion, $1, $3) }
   | spansetExpression TILDE spansetExpression    { $$ = newSpansetOperation(OpSpansetSibling, $1, $3) }
   | spansetFilter                                { $$ = $1 } 
@@ -193,6 +254,7 @@ scalarExpression: // shares the same operators as scalarPipelineExpression. spli
   | scalarExpression POW scalarExpression      { $$ = newScalarOperation(OpPower, $1, $3) }
   | aggregate                                  { $$ = $1 }
   | static                                     { $$ = $1 }
+  | IDENTIFIER                                 { $$ = newReference($1) }
   ;
 
 aggregate:
@@ -201,6 +263,7 @@ aggregate:
   | MIN OPEN_PARENS fieldExpression CLOSE_PARENS  { $$ = newAggregate(aggregateMin, $3) }
   | AVG OPEN_PARENS fieldExpression CLOSE_PARENS  { $$ = newAggregate(aggregateAvg, $3) }
   | SUM OPEN_PARENS fieldExpression CLOSE_PARENS  { $$ = newAggregate(aggregateSum, $3) }
+  | COUNT_DISTINCT OPEN_PARENS fieldExpression CLOSE_PARENS { $$ = newAggregate(aggregateCountDistinct, $3) }
   ;
 
 // **********************
@@ -224,11 +287,21 @@ fieldExpression:
   | fieldExpression POW fieldExpression      { $$ = newBinaryOperation(OpPower, $1, $3) }
   | fieldExpression AND fieldExpression      { $$ = newBinaryOperation(OpAnd, $1, $3) }
   | fieldExpression OR fieldExpression       { $$ = newBinaryOperation(OpOr, $1, $3) }
+  | fieldExpression IN OPEN_PARENS staticList CLOSE_PARENS     { $$ = newSetOperation(OpIn, $1, $4) }
+  | fieldExpression NOT_IN OPEN_PARENS staticList CLOSE_PARENS { $$ = newSetOperation(OpNotIn, $1, $4) }
   | SUB fieldExpression                      { $$ = newUnaryOperation(OpSub, $2) }
   | NOT fieldExpression                      { $$ = newUnaryOperation(OpNot, $2) }
+  | HAS OPEN_PARENS fieldExpression CLOSE_PARENS { $$ = newHasOperation($3) }
+  | attributeField ARRAY                     { $$ = newArrayElements($1, false) }
+  | ALL OPEN_PARENS attributeField ARRAY CLOSE_PARENS { $$ = newArrayElements($3, true) }
+  | ABS OPEN_PARENS fieldExpression CLOSE_PARENS { $$ = newFunctionOperation(functionAbs, $3) }
+  | SIGN OPEN_PARENS fieldExpression CLOSE_PARENS { $$ = newFunctionOperation(functionSign, $3) }
+  | BITAND OPEN_PARENS fieldExpression COMMA fieldExpression CLOSE_PARENS { $$ = newBinaryOperation(OpBitAnd, $3, $5) }
+  | BITOR OPEN_PARENS fieldExpression COMMA fieldExpression CLOSE_PARENS  { $$ = newBinaryOperation(OpBitOr, $3, $5) }
   | static                                   { $$ = $1 }
   | intrinsicField                           { $$ = $1 }
   | attributeField                           { $$ = $1 }
+  | IDENTIFIER                               { $$ = newReference($1) }
   ;
 
 // **********************
@@ -242,17 +315,28 @@ static:
   | FALSE         { $$ = NewStaticBool(false)         }
   | NIL           { $$ = NewStaticNil()               }
   | DURATION      { $$ = NewStaticDuration($1)        }
+  | TIMESTAMP     { $$ = NewStaticTimestamp($1)       }
   | STATUS_OK     { $$ = NewStaticStatus(StatusOk)    }
   | STATUS_ERROR  { $$ = NewStaticStatus(StatusError) }
   | STATUS_UNSET  { $$ = NewStaticStatus(StatusUnset) }
   ;
 
+staticList:
+    static                  { $$ = []Static{$1}     }
+  | staticList COMMA static { $$ = append($1, $3)   }
+  ;
+
 intrinsicField:
     IDURATION      { $$ = NewIntrinsic(IntrinsicDuration)   }
   | CHILDCOUNT     { $$ = NewIntrinsic(IntrinsicChildCount) }
   | NAME           { $$ = NewIntrinsic(IntrinsicName)       }
   | STATUS         { $$ = NewIntrinsic(IntrinsicStatus)     }
   | PARENT         { $$ = NewIntrinsic(IntrinsicParent)     }
+  | SELFTIME       { $$ = NewIntrinsic(IntrinsicSelfTime)   }
+  | STARTTIME      { $$ = NewIntrinsic(IntrinsicStartTime)  }
+  | ENDTIME        { $$ = NewIntrinsic(IntrinsicEndTime)    }
+  | LINK_TRACEID   { $$ = NewIntrinsic(IntrinsicLinkTraceID) }
+  | LINK_SPANID    { $$ = NewIntrinsic(IntrinsicLinkSpanID)  }
   ;
 
 attributeField:
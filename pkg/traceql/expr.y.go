@@ -17,6 +17,11 @@ type yySymType struct {
 	root              RootExpr
 	groupOperation    GroupOperation
 	coalesceOperation CoalesceOperation
+	flattenOperation  FlattenOperation
+	selectOperation   SelectOperation
+	withOperation     WithOperation
+	distinctOperation DistinctOperation
+	topKOperation     TopKOperation
 
 	spansetExpression         SpansetExpression
 	spansetPipelineExpression SpansetExpression
@@ -33,16 +38,19 @@ type yySymType struct {
 	scalarPipeline                 Pipeline
 	aggregate                      Aggregate
 
-	fieldExpression FieldExpression
-	static          Static
-	intrinsicField  Attribute
-	attributeField  Attribute
-
-	binOp          Operator
-	staticInt      int
-	staticStr      string
-	staticFloat    float64
-	staticDuration time.Duration
+	fieldExpression     FieldExpression
+	fieldExpressionList []FieldExpression
+	static              Static
+	intrinsicField      Attribute
+	attributeField      Attribute
+
+	binOp           Operator
+	staticInt       int
+	staticStr       string
+	staticFloat     float64
+	staticDuration  time.Duration
+	staticTimestamp time.Time
+	staticList      []Static
 }
 
 const IDENTIFIER = 57346
@@ -50,53 +58,76 @@ const STRING = 57347
 const INTEGER = 57348
 const FLOAT = 57349
 const DURATION = 57350
-const DOT = 57351
-const OPEN_BRACE = 57352
-const CLOSE_BRACE = 57353
-const OPEN_PARENS = 57354
-const CLOSE_PARENS = 57355
-const NIL = 57356
-const TRUE = 57357
-const FALSE = 57358
-const STATUS_ERROR = 57359
-const STATUS_OK = 57360
-const STATUS_UNSET = 57361
-const IDURATION = 57362
-const CHILDCOUNT = 57363
-const NAME = 57364
-const STATUS = 57365
-const PARENT = 57366
-const PARENT_DOT = 57367
-const RESOURCE_DOT = 57368
-const SPAN_DOT = 57369
-const COUNT = 57370
-const AVG = 57371
-const MAX = 57372
-const MIN = 57373
-const SUM = 57374
-const BY = 57375
-const COALESCE = 57376
-const END_ATTRIBUTE = 57377
-const PIPE = 57378
-const AND = 57379
-const OR = 57380
-const EQ = 57381
-const NEQ = 57382
-const LT = 57383
-const LTE = 57384
-const GT = 57385
-const GTE = 57386
-const NRE = 57387
-const RE = 57388
-const DESC = 57389
-const TILDE = 57390
-const ADD = 57391
-const SUB = 57392
-const NOT = 57393
-const MUL = 57394
-const DIV = 57395
-const MOD = 57396
-const POW = 57397
+const TIMESTAMP = 57351
+const DOT = 57352
+const OPEN_BRACE = 57353
+const CLOSE_BRACE = 57354
+const OPEN_PARENS = 57355
+const CLOSE_PARENS = 57356
+const NIL = 57357
+const TRUE = 57358
+const FALSE = 57359
+const STATUS_ERROR = 57360
+const STATUS_OK = 57361
+const STATUS_UNSET = 57362
+const IDURATION = 57363
+const CHILDCOUNT = 57364
+const NAME = 57365
+const STATUS = 57366
+const PARENT = 57367
+const SELFTIME = 57368
+const STARTTIME = 57369
+const ENDTIME = 57370
+const LINK_TRACEID = 57371
+const LINK_SPANID = 57372
+const PARENT_DOT = 57373
+const RESOURCE_DOT = 57374
+const SPAN_DOT = 57375
+const COUNT = 57376
+const AVG = 57377
+const MAX = 57378
+const MIN = 57379
+const SUM = 57380
+const COUNT_DISTINCT = 57381
+const BY = 57382
+const COALESCE = 57383
+const FLATTEN = 57384
+const SELECT = 57385
+const WITH = 57386
+const DISTINCT = 57387
+const TOPK = 57388
+const HAS = 57389
+const ABS = 57390
+const SIGN = 57391
+const BITAND = 57392
+const BITOR = 57393
+const COMMA = 57394
+const ARRAY = 57395
+const ALL = 57396
+const END_ATTRIBUTE = 57397
+const PIPE = 57398
+const AND = 57399
+const OR = 57400
+const EQ = 57401
+const NEQ = 57402
+const LT = 57403
+const LTE = 57404
+const GT = 57405
+const GTE = 57406
+const NRE = 57407
+const RE = 57408
+const DESC = 57409
+const NOT_DESC = 57410
+const TILDE = 57411
+const IN = 57412
+const NOT_IN = 57413
+const ADD = 57414
+const SUB = 57415
+const NOT = 57416
+const MUL = 57417
+const DIV = 57418
+const MOD = 57419
+const POW = 57420
 
 var yyToknames = [...]string{
 	"$end",
@@ -107,6 +138,7 @@ var yyToknames = [...]string{
 	"INTEGER",
 	"FLOAT",
 	"DURATION",
+	"TIMESTAMP",
 	"DOT",
 	"OPEN_BRACE",
 	"CLOSE_BRACE",
@@ -123,6 +155,11 @@ var yyToknames = [...]string{
 	"NAME",
 	"STATUS",
 	"PARENT",
+	"SELFTIME",
+	"STARTTIME",
+	"ENDTIME",
+	"LINK_TRACEID",
+	"LINK_SPANID",
 	"PARENT_DOT",
 	"RESOURCE_DOT",
 	"SPAN_DOT",
@@ -131,8 +168,22 @@ var yyToknames = [...]string{
 	"MAX",
 	"MIN",
 	"SUM",
+	"COUNT_DISTINCT",
 	"BY",
 	"COALESCE",
+	"FLATTEN",
+	"SELECT",
+	"WITH",
+	"DISTINCT",
+	"TOPK",
+	"HAS",
+	"ABS",
+	"SIGN",
+	"BITAND",
+	"BITOR",
+	"COMMA",
+	"ARRAY",
+	"ALL",
 	"END_ATTRIBUTE",
 	"PIPE",
 	"AND",
@@ -146,7 +197,10 @@ var yyToknames = [...]string{
 	"NRE",
 	"RE",
 	"DESC",
+	"NOT_DESC",
 	"TILDE",
+	"IN",
+	"NOT_IN",
 	"ADD",
 	"SUB",
 	"NOT",
@@ -166,187 +220,275 @@ var yyExca = [...]int{
 	-1, 1,
 	1, -1,
 	-2, 0,
-	-1, 171,
-	13, 47,
-	-2, 55,
+	-1, 217,
+	14, 64,
+	-2, 72,
 }
 
 const yyPrivate = 57344
 
-const yyLast = 636
+const yyLast = 1167
 
 var yyAct = [...]int{
 
-	75, 17, 6, 7, 5, 16, 149, 12, 69, 17,
-	169, 2, 56, 46, 112, 33, 49, 71, 116, 45,
-	136, 137, 202, 138, 139, 140, 149, 47, 10, 138,
-	139, 140, 149, 111, 17, 204, 93, 94, 92, 66,
-	67, 68, 69, 203, 104, 106, 107, 108, 109, 195,
-	194, 118, 193, 192, 64, 65, 33, 66, 67, 68,
-	69, 161, 115, 111, 17, 17, 17, 17, 17, 17,
-	17, 126, 128, 129, 130, 131, 132, 133, 117, 120,
-	121, 122, 123, 124, 125, 114, 112, 168, 134, 158,
-	152, 153, 154, 53, 54, 55, 56, 17, 40, 119,
-	17, 166, 41, 43, 167, 99, 162, 163, 164, 165,
-	166, 159, 160, 17, 91, 93, 94, 92, 171, 90,
-	17, 51, 52, 89, 53, 54, 55, 56, 17, 35,
-	173, 88, 87, 36, 38, 167, 141, 142, 143, 144,
-	145, 146, 148, 147, 70, 197, 136, 137, 196, 138,
-	139, 140, 149, 157, 175, 176, 177, 178, 179, 180,
-	181, 182, 183, 184, 185, 186, 187, 188, 189, 190,
-	15, 17, 105, 17, 156, 46, 155, 46, 49, 63,
-	49, 77, 116, 173, 23, 24, 25, 29, 83, 76,
-	50, 72, 48, 28, 26, 27, 31, 30, 32, 78,
-	79, 80, 81, 82, 86, 84, 85, 201, 57, 58,
-	59, 60, 61, 62, 14, 4, 11, 9, 64, 65,
-	95, 66, 67, 68, 69, 1, 200, 0, 0, 73,
-	74, 150, 151, 141, 142, 143, 144, 145, 146, 148,
-	147, 0, 0, 136, 137, 199, 138, 139, 140, 149,
-	150, 151, 141, 142, 143, 144, 145, 146, 148, 147,
-	0, 0, 136, 137, 198, 138, 139, 140, 149, 150,
-	151, 141, 142, 143, 144, 145, 146, 148, 147, 0,
-	0, 136, 137, 191, 138, 139, 140, 149, 150, 151,
-	141, 142, 143, 144, 145, 146, 148, 147, 0, 0,
-	136, 137, 174, 138, 139, 140, 149, 150, 151, 141,
-	142, 143, 144, 145, 146, 148, 147, 0, 0, 136,
-	137, 135, 138, 139, 140, 149, 150, 151, 141, 142,
-	143, 144, 145, 146, 148, 147, 0, 0, 136, 137,
-	0, 138, 139, 140, 149, 0, 0, 150, 151, 141,
-	142, 143, 144, 145, 146, 148, 147, 0, 0, 136,
-	137, 0, 138, 139, 140, 149, 57, 58, 59, 60,
-	61, 62, 0, 0, 0, 0, 64, 65, 0, 66,
-	67, 68, 69, 57, 58, 59, 60, 61, 62, 0,
-	113, 0, 110, 51, 52, 0, 53, 54, 55, 56,
-	64, 65, 0, 66, 67, 68, 69, 51, 52, 0,
-	53, 54, 55, 56, 39, 42, 34, 37, 0, 0,
-	40, 0, 35, 0, 41, 43, 36, 38, 23, 24,
-	25, 29, 0, 15, 0, 96, 0, 28, 26, 27,
-	31, 30, 32, 0, 0, 0, 0, 0, 0, 0,
-	0, 18, 21, 19, 20, 22, 13, 97, 39, 42,
-	0, 34, 37, 0, 40, 0, 0, 35, 41, 43,
-	0, 36, 38, 23, 24, 25, 29, 0, 15, 0,
-	172, 0, 28, 26, 27, 31, 30, 32, 0, 0,
-	0, 0, 0, 0, 0, 0, 18, 21, 19, 20,
-	22, 13, 23, 24, 25, 29, 0, 15, 0, 170,
-	0, 28, 26, 27, 31, 30, 32, 0, 0, 0,
-	0, 0, 0, 0, 0, 18, 21, 19, 20, 22,
-	13, 23, 24, 25, 29, 0, 15, 0, 8, 0,
-	28, 26, 27, 31, 30, 32, 0, 0, 0, 0,
-	0, 0, 0, 0, 18, 21, 19, 20, 22, 13,
-	23, 24, 25, 29, 0, 15, 0, 96, 0, 28,
-	26, 27, 31, 30, 32, 0, 0, 0, 0, 0,
-	0, 44, 3, 18, 21, 19, 20, 22, 23, 24,
-	25, 29, 0, 0, 0, 127, 0, 28, 26, 27,
-	31, 30, 32, 0, 0, 0, 0, 0, 0, 0,
-	0, 18, 21, 19, 20, 22, 98, 100, 101, 102,
-	103, 23, 24, 25, 29, 0, 0, 0, 119, 0,
-	28, 26, 27, 31, 30, 32,
+	76, 16, 5, 6, 7, 266, 81, 215, 2, 180,
+	294, 51, 169, 170, 171, 180, 50, 62, 63, 64,
+	65, 66, 67, 74, 61, 147, 143, 279, 12, 36,
+	69, 70, 276, 71, 72, 73, 74, 54, 275, 111,
+	112, 113, 71, 72, 73, 74, 134, 136, 137, 138,
+	139, 140, 249, 181, 182, 172, 173, 174, 175, 176,
+	177, 179, 178, 142, 248, 110, 183, 184, 167, 168,
+	247, 169, 170, 171, 180, 142, 165, 246, 185, 186,
+	187, 87, 17, 69, 70, 270, 71, 72, 73, 74,
+	17, 58, 59, 60, 61, 284, 282, 157, 159, 160,
+	161, 162, 163, 164, 189, 265, 202, 203, 204, 205,
+	206, 62, 63, 64, 65, 66, 67, 143, 17, 277,
+	296, 295, 285, 207, 56, 57, 258, 58, 59, 60,
+	61, 257, 201, 283, 283, 44, 146, 149, 207, 45,
+	46, 48, 145, 238, 237, 217, 111, 112, 113, 208,
+	17, 17, 17, 17, 17, 17, 17, 278, 219, 167,
+	168, 214, 169, 170, 171, 180, 213, 212, 221, 222,
+	223, 224, 225, 226, 227, 228, 229, 230, 231, 232,
+	233, 234, 235, 236, 15, 211, 135, 208, 210, 240,
+	209, 194, 242, 243, 244, 245, 193, 241, 192, 191,
+	56, 57, 17, 58, 59, 60, 61, 190, 188, 150,
+	17, 128, 260, 261, 263, 264, 90, 262, 144, 51,
+	109, 51, 108, 38, 107, 17, 219, 39, 40, 42,
+	69, 70, 17, 71, 72, 73, 74, 93, 91, 92,
+	17, 106, 291, 147, 268, 54, 105, 54, 56, 57,
+	104, 58, 59, 60, 61, 19, 22, 20, 21, 23,
+	24, 43, 47, 75, 251, 293, 198, 44, 111, 112,
+	113, 45, 46, 48, 286, 287, 68, 88, 250, 288,
+	289, 290, 197, 196, 195, 259, 53, 55, 62, 63,
+	64, 65, 66, 67, 199, 200, 14, 4, 17, 11,
+	17, 69, 70, 9, 71, 72, 73, 74, 181, 182,
+	172, 173, 174, 175, 176, 177, 179, 178, 119, 267,
+	267, 183, 184, 167, 168, 281, 169, 170, 171, 180,
+	181, 182, 172, 173, 174, 175, 176, 177, 179, 178,
+	118, 117, 116, 183, 184, 167, 168, 17, 169, 170,
+	171, 180, 115, 114, 1, 89, 25, 26, 27, 31,
+	32, 90, 0, 0, 77, 292, 30, 28, 29, 34,
+	33, 35, 94, 95, 96, 97, 98, 99, 100, 101,
+	102, 103, 93, 91, 92, 19, 22, 20, 21, 23,
+	24, 0, 52, 10, 0, 0, 0, 0, 80, 83,
+	84, 85, 86, 43, 47, 82, 0, 0, 0, 44,
+	0, 0, 0, 45, 46, 48, 0, 89, 25, 26,
+	27, 31, 32, 90, 78, 79, 77, 0, 30, 28,
+	29, 34, 33, 35, 94, 95, 96, 97, 98, 99,
+	100, 101, 102, 103, 93, 91, 92, 280, 148, 151,
+	152, 153, 154, 155, 156, 0, 0, 0, 0, 0,
+	80, 83, 84, 85, 86, 37, 41, 82, 0, 0,
+	0, 38, 0, 0, 0, 39, 40, 42, 0, 0,
+	0, 0, 0, 0, 0, 0, 78, 79, 0, 0,
+	181, 182, 172, 173, 174, 175, 176, 177, 179, 178,
+	0, 0, 0, 183, 184, 167, 168, 274, 169, 170,
+	171, 180, 181, 182, 172, 173, 174, 175, 176, 177,
+	179, 178, 272, 0, 0, 183, 184, 167, 168, 273,
+	169, 170, 171, 180, 181, 182, 172, 173, 174, 175,
+	176, 177, 179, 178, 271, 0, 0, 183, 184, 167,
+	168, 0, 169, 170, 171, 180, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 181, 182, 172, 173, 174,
+	175, 176, 177, 179, 178, 269, 0, 0, 183, 184,
+	167, 168, 0, 169, 170, 171, 180, 181, 182, 172,
+	173, 174, 175, 176, 177, 179, 178, 256, 0, 0,
+	183, 184, 167, 168, 0, 169, 170, 171, 180, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 181, 182,
+	172, 173, 174, 175, 176, 177, 179, 178, 255, 0,
+	0, 183, 184, 167, 168, 0, 169, 170, 171, 180,
+	181, 182, 172, 173, 174, 175, 176, 177, 179, 178,
+	254, 0, 0, 183, 184, 167, 168, 0, 169, 170,
+	171, 180, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 181, 182, 172, 173, 174, 175, 176, 177, 179,
+	178, 253, 0, 0, 183, 184, 167, 168, 0, 169,
+	170, 171, 180, 181, 182, 172, 173, 174, 175, 176,
+	177, 179, 178, 252, 0, 0, 183, 184, 167, 168,
+	0, 169, 170, 171, 180, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 181, 182, 172, 173, 174, 175,
+	176, 177, 179, 178, 239, 0, 0, 183, 184, 167,
+	168, 0, 169, 170, 171, 180, 181, 182, 172, 173,
+	174, 175, 176, 177, 179, 178, 220, 0, 0, 183,
+	184, 167, 168, 0, 169, 170, 171, 180, 0, 0,
+	0, 0, 0, 0, 0, 0, 166, 181, 182, 172,
+	173, 174, 175, 176, 177, 179, 178, 0, 0, 0,
+	183, 184, 167, 168, 0, 169, 170, 171, 180, 181,
+	182, 172, 173, 174, 175, 176, 177, 179, 178, 0,
+	0, 0, 183, 184, 167, 168, 0, 169, 170, 171,
+	180, 181, 182, 172, 173, 174, 175, 176, 177, 179,
+	178, 0, 0, 0, 183, 184, 167, 168, 0, 169,
+	170, 171, 180, 181, 182, 172, 173, 174, 175, 176,
+	177, 179, 178, 141, 0, 0, 183, 184, 167, 168,
+	0, 169, 170, 171, 180, 172, 173, 174, 175, 176,
+	177, 179, 178, 0, 0, 0, 183, 184, 167, 168,
+	0, 169, 170, 171, 180, 0, 0, 0, 49, 3,
+	0, 0, 0, 0, 0, 0, 37, 41, 0, 0,
+	0, 0, 38, 0, 0, 0, 39, 40, 42, 18,
+	25, 26, 27, 31, 32, 0, 15, 0, 120, 0,
+	30, 28, 29, 34, 33, 35, 127, 129, 130, 131,
+	132, 133, 0, 0, 0, 0, 0, 0, 0, 19,
+	22, 20, 21, 23, 24, 13, 121, 122, 123, 124,
+	125, 126, 18, 25, 26, 27, 31, 32, 0, 15,
+	0, 218, 0, 30, 28, 29, 34, 33, 35, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 19, 22, 20, 21, 23, 24, 13, 18,
+	25, 26, 27, 31, 32, 0, 15, 0, 216, 0,
+	30, 28, 29, 34, 33, 35, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 19,
+	22, 20, 21, 23, 24, 13, 18, 25, 26, 27,
+	31, 32, 0, 15, 0, 8, 0, 30, 28, 29,
+	34, 33, 35, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 19, 22, 20, 21,
+	23, 24, 13, 18, 25, 26, 27, 31, 32, 0,
+	15, 0, 120, 0, 30, 28, 29, 34, 33, 35,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 19, 22, 20, 21, 23, 24, 18,
+	25, 26, 27, 31, 32, 0, 0, 0, 158, 0,
+	30, 28, 29, 34, 33, 35, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 19,
+	22, 20, 21, 23, 24, 25, 26, 27, 31, 32,
+	0, 0, 0, 150, 0, 30, 28, 29, 34, 33,
+	35, 25, 26, 27, 31, 32, 0, 0, 0, 0,
+	0, 30, 28, 29, 34, 33, 35,
 }
 var yyPact = [...]int{
 
-	526, -1000, -21, 424, -1000, 421, -1000, -1000, 526, -1000,
-	344, -1000, 327, 132, -1000, 179, -1000, -1000, 120, 119,
-	111, 107, 102, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, 423, 93, 93, 93, 93, 93, 160,
-	160, 160, 160, 160, 379, 50, 377, 72, 49, 169,
-	616, 87, 87, 87, 87, 87, 87, -1000, -1000, -1000,
-	-1000, -1000, -1000, 583, 583, 583, 583, 583, 583, 583,
-	179, 310, 179, 179, 179, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, 172, 170, 149, 85, 48, 179, 179,
-	179, 179, 421, -1000, -1000, -1000, 555, 75, 86, 497,
-	-1000, -1000, 86, -1000, 55, 160, -1000, -1000, 55, -1000,
-	-1000, -1000, 423, -1000, -1000, -1000, -1000, 358, -1000, 468,
-	41, 41, -43, -43, -43, -43, 351, 583, -13, -13,
-	-47, -47, -47, -47, 289, -1000, 179, 179, 179, 179,
-	179, 179, 179, 179, 179, 179, 179, 179, 179, 179,
-	179, 179, 270, -23, -23, 18, 17, 15, 14, 144,
-	141, -1000, 251, 232, 213, 194, 377, 5, 9, 20,
-	497, -1000, 468, -22, -1000, -23, -23, -49, -49, -49,
-	-29, -29, -29, -29, -29, -29, -29, -29, -49, 97,
-	97, -1000, -1000, -1000, -1000, -1000, 8, 0, -1000, -1000,
-	-1000, -1000, -1000, -1000, -1000,
+	1022, -1000, -27, 408, -1000, 346, -1000, -1000, 1022, -1000,
+	52, -1000, -42, 250, -1000, 413, -1000, -1000, -1000, 237,
+	233, 228, 211, 209, 207, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, 905, 198, 198, 198,
+	198, 198, 198, 173, 173, 173, 173, 173, 173, 839,
+	61, 204, 128, 122, 229, 1130, 196, 196, 196, 196,
+	196, 196, -1000, -1000, -1000, -1000, -1000, -1000, 1095, 1095,
+	1095, 1095, 1095, 1095, 1095, 413, 764, 413, 413, 413,
+	195, 51, 194, 186, 185, 183, 178, -1000, -1000, -1000,
+	280, 279, 278, 262, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, 118, 413, 413, 413, 413, 413,
+	-42, 346, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	1059, 177, 175, 172, 154, 153, 148, 160, 985, -1000,
+	-1000, -1000, 160, -1000, 72, 173, -1000, -1000, -1000, 72,
+	-1000, -1000, -1000, 905, -1000, -1000, -1000, -1000, 176, -1000,
+	948, 16, 16, -54, -54, -54, -54, 158, 1095, -33,
+	-33, -55, -55, -55, -55, 742, -1000, 413, 413, 413,
+	413, 413, 413, 413, 413, 413, 413, 413, 413, 413,
+	413, 413, 413, 131, 130, 720, -63, -63, 413, -1000,
+	206, 413, 413, 413, 413, 22, 15, 9, -3, 274,
+	260, -1000, 689, 667, 636, 614, 583, 204, 11, 117,
+	112, 351, 213, 413, 413, 49, 985, -1000, 948, -30,
+	-1000, -63, -63, -69, -69, -69, 87, 87, 87, 87,
+	87, 87, 87, 87, -69, 806, 806, 1146, 1146, -1000,
+	561, 32, 530, 508, 477, 455, -1000, -1000, -1000, -1000,
+	-17, -23, -1000, -1000, -1000, -1000, -1000, -1000, -1000, 105,
+	786, -1000, -32, 433, 273, 905, 82, -1000, 81, -1000,
+	108, -1000, -1000, 413, 413, -1000, -1000, -1000, 351, 221,
+	-1000, 236, -1000, 1146, -1000, -1000, 251, -4, 786, -1000,
+	107, 106, -1000, -1000, -1000, -1000, -1000,
 }
 var yyPgo = [...]int{
 
-	0, 225, 3, 220, 4, 581, 217, 10, 216, 2,
-	179, 215, 27, 7, 214, 192, 5, 17, 0, 189,
-	181,
+	0, 354, 4, 353, 352, 342, 341, 340, 318, 2,
+	888, 303, 7, 299, 3, 276, 297, 392, 28, 296,
+	286, 1, 0, 285, 81, 5, 277, 6,
 }
 var yyR1 = [...]int{
 
-	0, 1, 1, 1, 5, 5, 5, 5, 5, 5,
-	5, 6, 7, 7, 7, 7, 7, 7, 7, 2,
-	3, 4, 4, 4, 4, 4, 4, 4, 8, 9,
-	10, 10, 10, 10, 10, 10, 11, 11, 12, 12,
-	12, 12, 12, 12, 12, 12, 14, 15, 13, 13,
-	13, 13, 13, 13, 13, 13, 13, 16, 16, 16,
-	16, 16, 17, 17, 17, 17, 17, 17, 17, 17,
-	17, 17, 17, 17, 17, 17, 17, 17, 17, 17,
-	17, 17, 17, 17, 18, 18, 18, 18, 18, 18,
-	18, 18, 18, 18, 19, 19, 19, 19, 19, 20,
-	20, 20, 20, 20, 20,
+	0, 1, 1, 1, 1, 10, 10, 10, 10, 10,
+	10, 10, 10, 11, 12, 12, 12, 12, 12, 12,
+	12, 12, 12, 12, 12, 12, 2, 3, 4, 5,
+	6, 7, 8, 23, 23, 23, 23, 9, 9, 9,
+	9, 9, 9, 9, 9, 13, 14, 15, 15, 15,
+	15, 15, 15, 16, 16, 17, 17, 17, 17, 17,
+	17, 17, 17, 19, 20, 18, 18, 18, 18, 18,
+	18, 18, 18, 18, 18, 21, 21, 21, 21, 21,
+	21, 22, 22, 22, 22, 22, 22, 22, 22, 22,
+	22, 22, 22, 22, 22, 22, 22, 22, 22, 22,
+	22, 22, 22, 22, 22, 22, 22, 22, 22, 22,
+	22, 22, 22, 24, 24, 24, 24, 24, 24, 24,
+	24, 24, 24, 24, 25, 25, 26, 26, 26, 26,
+	26, 26, 26, 26, 26, 26, 27, 27, 27, 27,
+	27, 27,
 }
 var yyR2 = [...]int{
 
 	0, 1, 1, 1, 3, 3, 3, 3, 3, 3,
-	1, 3, 1, 1, 1, 3, 3, 3, 3, 4,
-	3, 3, 3, 3, 3, 3, 3, 1, 3, 3,
+	3, 3, 1, 3, 1, 1, 1, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 4, 3, 3, 4,
+	6, 4, 6, 1, 1, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 1, 3, 3, 1, 1, 1,
+	1, 1, 1, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 1, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 1, 1, 1, 3, 4, 4, 4, 4,
+	4, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 5, 5,
+	2, 2, 4, 2, 5, 4, 4, 6, 6, 1,
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 3, 1, 1, 1, 1,
 	1, 1, 1, 1, 1, 1, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 1, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 1, 1, 3, 4, 4,
-	4, 4, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 3, 2,
-	2, 1, 1, 1, 1, 1, 1, 1, 1, 1,
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 3,
-	3, 3, 3, 4, 4,
+	4, 4,
 }
 var yyChk = [...]int{
 
-	-1000, -1, -7, -5, -11, -4, -9, -2, 12, -6,
-	-12, -8, -13, 33, -14, 10, -16, -18, 28, 30,
-	31, 29, 32, 5, 6, 7, 15, 16, 14, 8,
-	18, 17, 19, 36, 37, 43, 47, 38, 48, 37,
-	43, 47, 38, 48, -5, -7, -4, -12, -15, -13,
-	-10, 49, 50, 52, 53, 54, 55, 39, 40, 41,
-	42, 43, 44, -10, 49, 50, 52, 53, 54, 55,
-	12, -17, 12, 50, 51, -18, -19, -20, 20, 21,
-	22, 23, 24, 9, 26, 27, 25, 12, 12, 12,
-	12, 12, -4, -9, -2, -3, 12, 34, -5, 12,
-	-5, -5, -5, -5, -4, 12, -4, -4, -4, -4,
-	13, 13, 36, 13, 13, 13, 13, -12, -18, 12,
-	-12, -12, -12, -12, -12, -12, -13, 12, -13, -13,
-	-13, -13, -13, -13, -17, 11, 49, 50, 52, 53,
-	54, 39, 40, 41, 42, 43, 44, 46, 45, 55,
-	37, 38, -17, -17, -17, 4, 4, 4, 4, 26,
-	27, 13, -17, -17, -17, -17, -4, -13, 12, -7,
-	12, -16, 12, -7, 13, -17, -17, -17, -17, -17,
-	-17, -17, -17, -17, -17, -17, -17, -17, -17, -17,
-	-17, 13, 35, 35, 35, 35, 4, 4, 13, 13,
-	13, 13, 13, 35, 35,
+	-1000, -1, -12, -10, -16, -9, -14, -2, 13, -11,
+	-17, -13, -18, 40, -19, 11, -21, -24, 4, 34,
+	36, 37, 35, 38, 39, 5, 6, 7, 16, 17,
+	15, 8, 9, 19, 18, 20, 56, 57, 63, 67,
+	68, 58, 69, 57, 63, 67, 68, 58, 69, -10,
+	-12, -9, -17, -20, -18, -15, 72, 73, 75, 76,
+	77, 78, 59, 60, 61, 62, 63, 64, -15, 72,
+	73, 75, 76, 77, 78, 13, -22, 13, 73, 74,
+	47, -27, 54, 48, 49, 50, 51, -24, -26, 4,
+	10, 32, 33, 31, 21, 22, 23, 24, 25, 26,
+	27, 28, 29, 30, 13, 13, 13, 13, 13, 13,
+	-18, -9, -14, -2, -3, -4, -5, -6, -7, -8,
+	13, 41, 42, 43, 44, 45, 46, -10, 13, -10,
+	-10, -10, -10, -10, -9, 13, -9, -9, -9, -9,
+	-9, 14, 14, 56, 14, 14, 14, 14, -17, -24,
+	13, -17, -17, -17, -17, -17, -17, -18, 13, -18,
+	-18, -18, -18, -18, -18, -22, 12, 72, 73, 75,
+	76, 77, 59, 60, 61, 62, 63, 64, 66, 65,
+	78, 57, 58, 70, 71, -22, -22, -22, 13, 53,
+	13, 13, 13, 13, 13, 4, 4, 4, 4, 32,
+	33, 14, -22, -22, -22, -22, -22, -9, -18, 13,
+	13, 13, 13, 13, 13, -12, 13, -21, 13, -12,
+	14, -22, -22, -22, -22, -22, -22, -22, -22, -22,
+	-22, -22, -22, -22, -22, -22, -22, 13, 13, 14,
+	-22, -27, -22, -22, -22, -22, 55, 55, 55, 55,
+	4, 4, 14, 14, 14, 14, 14, 14, 14, -23,
+	-22, -21, 4, -22, -22, 56, -25, -24, -25, 14,
+	53, 14, 14, 52, 52, 55, 55, 14, 52, 59,
+	14, 52, 14, 52, 14, 14, -22, -22, -22, -21,
+	-21, 6, -24, 14, 14, 14, 14,
 }
 var yyDef = [...]int{
 
-	0, -2, 1, 2, 3, 12, 13, 14, 0, 10,
-	0, 27, 0, 0, 45, 0, 55, 56, 0, 0,
-	0, 0, 0, 84, 85, 86, 87, 88, 89, 90,
-	91, 92, 93, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 12, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 30, 31, 32,
-	33, 34, 35, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 81, 82, 83, 94, 95,
-	96, 97, 98, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 15, 16, 17, 18, 0, 0, 5, 0,
-	6, 7, 8, 9, 22, 0, 23, 24, 25, 26,
-	4, 11, 0, 21, 38, 46, 48, 36, 37, 0,
-	39, 40, 41, 42, 43, 44, 29, 0, 49, 50,
-	51, 52, 53, 54, 0, 28, 0, 0, 0, 0,
+	0, -2, 1, 2, 3, 14, 15, 16, 0, 12,
+	0, 44, 0, 0, 62, 0, 72, 73, 74, 0,
+	0, 0, 0, 0, 0, 113, 114, 115, 116, 117,
+	118, 119, 120, 121, 122, 123, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 14, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 47, 48, 49, 50, 51, 52, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 79, 80, 0, 0, 0, 0, 0,
-	0, 57, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, -2, 0, 0, 19, 63, 64, 65, 66, 67,
-	68, 69, 70, 71, 72, 73, 74, 75, 76, 77,
-	78, 62, 99, 100, 101, 102, 0, 0, 58, 59,
-	60, 61, 20, 103, 104,
+	0, 111, 0, 0, 0, 0, 0, 109, 110, 112,
+	0, 0, 0, 0, 126, 127, 128, 129, 130, 131,
+	132, 133, 134, 135, 0, 0, 0, 0, 0, 0,
+	4, 17, 18, 19, 20, 21, 22, 23, 24, 25,
+	0, 0, 0, 0, 0, 0, 0, 6, 0, 7,
+	8, 9, 10, 11, 38, 0, 39, 40, 41, 42,
+	43, 5, 13, 0, 37, 55, 63, 65, 53, 54,
+	0, 56, 57, 58, 59, 60, 61, 46, 0, 66,
+	67, 68, 69, 70, 71, 0, 45, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 100, 101, 0, 103,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 75, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, -2, 0, 0,
+	26, 82, 83, 84, 85, 86, 87, 88, 89, 90,
+	91, 92, 93, 94, 95, 96, 97, 0, 0, 81,
+	0, 0, 0, 0, 0, 0, 136, 137, 138, 139,
+	0, 0, 76, 77, 78, 79, 80, 27, 28, 0,
+	33, 34, 0, 0, 0, 0, 0, 124, 0, 102,
+	0, 105, 106, 0, 0, 140, 141, 29, 0, 0,
+	31, 0, 98, 0, 99, 104, 0, 0, 35, 36,
+	0, 0, 125, 107, 108, 30, 32,
 }
 var yyTok1 = [...]int{
 
@@ -359,7 +501,9 @@ var yyTok2 = [...]int{
 	22, 23, 24, 25, 26, 27, 28, 29, 30, 31,
 	32, 33, 34, 35, 36, 37, 38, 39, 40, 41,
 	42, 43, 44, 45, 46, 47, 48, 49, 50, 51,
-	52, 53, 54, 55,
+	52, 53, 54, 55, 56, 57, 58, 59, 60, 61,
+	62, 63, 64, 65, 66, 67, 68, 69, 70, 71,
+	72, 73, 74, 75, 76, 77, 78,
 }
 var yyTok3 = [...]int{
 	0,
@@ -704,625 +848,854 @@ yydefault:
 
 	case 1:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:93
+//line pkg/traceql/expr.y:110
 		{
 			yylex.(*lexer).expr = newRootExpr(yyDollar[1].spansetPipeline)
 		}
 	case 2:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:94
+//line pkg/traceql/expr.y:111
 		{
 			yylex.(*lexer).expr = newRootExpr(yyDollar[1].spansetPipelineExpression)
 		}
 	case 3:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:95
+//line pkg/traceql/expr.y:112
 		{
 			yylex.(*lexer).expr = newRootExpr(yyDollar[1].scalarPipelineExpressionFilter)
 		}
 	case 4:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:102
+//line pkg/traceql/expr.y:113
 		{
-			yyVAL.spansetPipelineExpression = yyDollar[2].spansetPipelineExpression
+			e, ok := yyDollar[3].scalarExpression.(pipelineElement)
+			if !ok {
+				yylex.Error("scalar pipelines must end with an aggregate")
+			} else {
+				yylex.(*lexer).expr = newRootExpr(yyDollar[1].spansetPipeline.addItem(e))
+			}
 		}
 	case 5:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:103
+//line pkg/traceql/expr.y:127
 		{
-			yyVAL.spansetPipelineExpression = newSpansetOperation(OpSpansetAnd, yyDollar[1].spansetPipelineExpression, yyDollar[3].spansetPipelineExpression)
+			yyVAL.spansetPipelineExpression = yyDollar[2].spansetPipelineExpression
 		}
 	case 6:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:104
+//line pkg/traceql/expr.y:128
 		{
-			yyVAL.spansetPipelineExpression = newSpansetOperation(OpSpansetChild, yyDollar[1].spansetPipelineExpression, yyDollar[3].spansetPipelineExpression)
+			yyVAL.spansetPipelineExpression = newSpansetOperation(OpSpansetAnd, yyDollar[1].spansetPipelineExpression, yyDollar[3].spansetPipelineExpression)
 		}
 	case 7:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:105
+//line pkg/traceql/expr.y:129
 		{
-			yyVAL.spansetPipelineExpression = newSpansetOperation(OpSpansetDescendant, yyDollar[1].spansetPipelineExpression, yyDollar[3].spansetPipelineExpression)
+			yyVAL.spansetPipelineExpression = newSpansetOperation(OpSpansetChild, yyDollar[1].spansetPipelineExpression, yyDollar[3].spansetPipelineExpression)
 		}
 	case 8:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:106
+//line pkg/traceql/expr.y:130
 		{
-			yyVAL.spansetPipelineExpression = newSpansetOperation(OpSpansetUnion, yyDollar[1].spansetPipelineExpression, yyDollar[3].spansetPipelineExpression)
+			yyVAL.spansetPipelineExpression = newSpansetOperation(OpSpansetDescendant, yyDollar[1].spansetPipelineExpression, yyDollar[3].spansetPipelineExpression)
 		}
 	case 9:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:107
+//line pkg/traceql/expr.y:131
 		{
-			yyVAL.spansetPipelineExpression = newSpansetOperation(OpSpansetSibling, yyDollar[1].spansetPipelineExpression, yyDollar[3].spansetPipelineExpression)
+			yyVAL.spansetPipelineExpression = newSpansetOperation(OpSpansetNotDescendant, yyDollar[1].spansetPipelineExpression, yyDollar[3].spansetPipelineExpression)
 		}
 	case 10:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line pkg/traceql/expr.y:132
+		{
+			yyVAL.spansetPipelineExpression = newSpansetOperation(OpSpansetUnion, yyDollar[1].spansetPipelineExpression, yyDollar[3].spansetPipelineExpression)
+		}
+	case 11:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line pkg/traceql/expr.y:133
+		{
+			yyVAL.spansetPipelineExpression = newSpansetOperation(OpSpansetSibling, yyDollar[1].spansetPipelineExpression, yyDollar[3].spansetPipelineExpression)
+		}
+	case 12:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:108
+//line pkg/traceql/expr.y:134
 		{
 			yyVAL.spansetPipelineExpression = yyDollar[1].wrappedSpansetPipeline
 		}
-	case 11:
+	case 13:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:112
+//line pkg/traceql/expr.y:138
 		{
 			yyVAL.wrappedSpansetPipeline = yyDollar[2].spansetPipeline
 		}
-	case 12:
+	case 14:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:115
+//line pkg/traceql/expr.y:141
 		{
 			yyVAL.spansetPipeline = newPipeline(yyDollar[1].spansetExpression)
 		}
-	case 13:
+	case 15:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:116
+//line pkg/traceql/expr.y:142
 		{
 			yyVAL.spansetPipeline = newPipeline(yyDollar[1].scalarFilter)
 		}
-	case 14:
+	case 16:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:117
+//line pkg/traceql/expr.y:143
 		{
 			yyVAL.spansetPipeline = newPipeline(yyDollar[1].groupOperation)
 		}
-	case 15:
+	case 17:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:118
+//line pkg/traceql/expr.y:144
 		{
 			yyVAL.spansetPipeline = yyDollar[1].spansetPipeline.addItem(yyDollar[3].spansetExpression)
 		}
-	case 16:
+	case 18:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:119
+//line pkg/traceql/expr.y:145
 		{
 			yyVAL.spansetPipeline = yyDollar[1].spansetPipeline.addItem(yyDollar[3].scalarFilter)
 		}
-	case 17:
+	case 19:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:120
+//line pkg/traceql/expr.y:146
 		{
 			yyVAL.spansetPipeline = yyDollar[1].spansetPipeline.addItem(yyDollar[3].groupOperation)
 		}
-	case 18:
+	case 20:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:121
+//line pkg/traceql/expr.y:147
 		{
 			yyVAL.spansetPipeline = yyDollar[1].spansetPipeline.addItem(yyDollar[3].coalesceOperation)
 		}
-	case 19:
+	case 21:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line pkg/traceql/expr.y:148
+		{
+			yyVAL.spansetPipeline = yyDollar[1].spansetPipeline.addItem(yyDollar[3].flattenOperation)
+		}
+	case 22:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line pkg/traceql/expr.y:149
+		{
+			yyVAL.spansetPipeline = yyDollar[1].spansetPipeline.addItem(yyDollar[3].selectOperation)
+		}
+	case 23:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line pkg/traceql/expr.y:150
+		{
+			yyVAL.spansetPipeline = yyDollar[1].spansetPipeline.addItem(yyDollar[3].withOperation)
+		}
+	case 24:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line pkg/traceql/expr.y:151
+		{
+			yyVAL.spansetPipeline = yyDollar[1].spansetPipeline.addItem(yyDollar[3].distinctOperation)
+		}
+	case 25:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line pkg/traceql/expr.y:152
+		{
+			yyVAL.spansetPipeline = yyDollar[1].spansetPipeline.addItem(yyDollar[3].topKOperation)
+		}
+	case 26:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/traceql/expr.y:125
+//line pkg/traceql/expr.y:156
 		{
 			yyVAL.groupOperation = newGroupOperation(yyDollar[3].fieldExpression)
 		}
-	case 20:
+	case 27:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:129
+//line pkg/traceql/expr.y:160
 		{
 			yyVAL.coalesceOperation = newCoalesceOperation()
 		}
-	case 21:
+	case 28:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:133
+//line pkg/traceql/expr.y:164
+		{
+			yyVAL.flattenOperation = newFlattenOperation()
+		}
+	case 29:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line pkg/traceql/expr.y:168
+		{
+			yyVAL.selectOperation = newSelectOperation(yyDollar[3].fieldExpressionList)
+		}
+	case 30:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line pkg/traceql/expr.y:172
+		{
+			yyVAL.withOperation = newWithOperation(yyDollar[3].staticStr, yyDollar[5].aggregate)
+		}
+	case 31:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line pkg/traceql/expr.y:176
+		{
+			yyVAL.distinctOperation = newDistinctOperation(yyDollar[3].fieldExpression)
+		}
+	case 32:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line pkg/traceql/expr.y:180
+		{
+			yyVAL.topKOperation = newTopKOperation(yyDollar[3].fieldExpression, yyDollar[5].staticInt)
+		}
+	case 33:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:186
+		{
+			yyVAL.fieldExpressionList = []FieldExpression{yyDollar[1].fieldExpression}
+		}
+	case 34:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:187
+		{
+			yylex.Error("select() can't select aggregates")
+			yyVAL.fieldExpressionList = nil
+		}
+	case 35:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line pkg/traceql/expr.y:188
+		{
+			yyVAL.fieldExpressionList = append(yyDollar[1].fieldExpressionList, yyDollar[3].fieldExpression)
+		}
+	case 36:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line pkg/traceql/expr.y:189
+		{
+			yylex.Error("select() can't select aggregates")
+			yyVAL.fieldExpressionList = yyDollar[1].fieldExpressionList
+		}
+	case 37:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line pkg/traceql/expr.y:193
 		{
 			yyVAL.spansetExpression = yyDollar[2].spansetExpression
 		}
-	case 22:
+	case 38:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:134
+//line pkg/traceql/expr.y:194
 		{
 			yyVAL.spansetExpression = newSpansetOperation(OpSpansetAnd, yyDollar[1].spansetExpression, yyDollar[3].spansetExpression)
 		}
-	case 23:
+	case 39:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:135
+//line pkg/traceql/expr.y:195
 		{
 			yyVAL.spansetExpression = newSpansetOperation(OpSpansetChild, yyDollar[1].spansetExpression, yyDollar[3].spansetExpression)
 		}
-	case 24:
+	case 40:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:136
+//line pkg/traceql/expr.y:196
 		{
 			yyVAL.spansetExpression = newSpansetOperation(OpSpansetDescendant, yyDollar[1].spansetExpression, yyDollar[3].spansetExpression)
 		}
-	case 25:
+	case 41:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line pkg/traceql/expr.y:197
+		{
+			yyVAL.spansetExpression = newSpansetOperation(OpSpansetNotDescendant, yyDollar[1].spansetExpression, yyDollar[3].spansetExpression)
+		}
+	case 42:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:137
+//line pkg/traceql/expr.y:198
 		{
 			yyVAL.spansetExpression = newSpansetOperation(OpSpansetUnion, yyDollar[1].spansetExpression, yyDollar[3].spansetExpression)
 		}
-	case 26:
+	case 43:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:138
+//line pkg/traceql/expr.y:199
 		{
 			yyVAL.spansetExpression = newSpansetOperation(OpSpansetSibling, yyDollar[1].spansetExpression, yyDollar[3].spansetExpression)
 		}
-	case 27:
+	case 44:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:139
+//line pkg/traceql/expr.y:200
 		{
 			yyVAL.spansetExpression = yyDollar[1].spansetFilter
 		}
-	case 28:
+	case 45:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:143
+//line pkg/traceql/expr.y:204
 		{
 			yyVAL.spansetFilter = newSpansetFilter(yyDollar[2].fieldExpression)
 		}
-	case 29:
+	case 46:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:147
+//line pkg/traceql/expr.y:208
 		{
 			yyVAL.scalarFilter = newScalarFilter(yyDollar[2].scalarFilterOperation, yyDollar[1].scalarExpression, yyDollar[3].scalarExpression)
 		}
-	case 30:
+	case 47:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:151
+//line pkg/traceql/expr.y:212
 		{
 			yyVAL.scalarFilterOperation = OpEqual
 		}
-	case 31:
+	case 48:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:152
+//line pkg/traceql/expr.y:213
 		{
 			yyVAL.scalarFilterOperation = OpNotEqual
 		}
-	case 32:
+	case 49:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:153
+//line pkg/traceql/expr.y:214
 		{
 			yyVAL.scalarFilterOperation = OpLess
 		}
-	case 33:
+	case 50:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:154
+//line pkg/traceql/expr.y:215
 		{
 			yyVAL.scalarFilterOperation = OpLessEqual
 		}
-	case 34:
+	case 51:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:155
+//line pkg/traceql/expr.y:216
 		{
 			yyVAL.scalarFilterOperation = OpGreater
 		}
-	case 35:
+	case 52:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:156
+//line pkg/traceql/expr.y:217
 		{
 			yyVAL.scalarFilterOperation = OpGreaterEqual
 		}
-	case 36:
+	case 53:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:163
+//line pkg/traceql/expr.y:224
 		{
 			yyVAL.scalarPipelineExpressionFilter = newScalarFilter(yyDollar[2].scalarFilterOperation, yyDollar[1].scalarPipelineExpression, yyDollar[3].scalarPipelineExpression)
 		}
-	case 37:
+	case 54:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:164
+//line pkg/traceql/expr.y:225
 		{
 			yyVAL.scalarPipelineExpressionFilter = newScalarFilter(yyDollar[2].scalarFilterOperation, yyDollar[1].scalarPipelineExpression, yyDollar[3].static)
 		}
-	case 38:
+	case 55:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:168
+//line pkg/traceql/expr.y:229
 		{
 			yyVAL.scalarPipelineExpression = yyDollar[2].scalarPipelineExpression
 		}
-	case 39:
+	case 56:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:169
+//line pkg/traceql/expr.y:230
 		{
 			yyVAL.scalarPipelineExpression = newScalarOperation(OpAdd, yyDollar[1].scalarPipelineExpression, yyDollar[3].scalarPipelineExpression)
 		}
-	case 40:
+	case 57:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:170
+//line pkg/traceql/expr.y:231
 		{
 			yyVAL.scalarPipelineExpression = newScalarOperation(OpSub, yyDollar[1].scalarPipelineExpression, yyDollar[3].scalarPipelineExpression)
 		}
-	case 41:
+	case 58:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:171
+//line pkg/traceql/expr.y:232
 		{
 			yyVAL.scalarPipelineExpression = newScalarOperation(OpMult, yyDollar[1].scalarPipelineExpression, yyDollar[3].scalarPipelineExpression)
 		}
-	case 42:
+	case 59:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:172
+//line pkg/traceql/expr.y:233
 		{
 			yyVAL.scalarPipelineExpression = newScalarOperation(OpDiv, yyDollar[1].scalarPipelineExpression, yyDollar[3].scalarPipelineExpression)
 		}
-	case 43:
+	case 60:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:173
+//line pkg/traceql/expr.y:234
 		{
 			yyVAL.scalarPipelineExpression = newScalarOperation(OpMod, yyDollar[1].scalarPipelineExpression, yyDollar[3].scalarPipelineExpression)
 		}
-	case 44:
+	case 61:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:174
+//line pkg/traceql/expr.y:235
 		{
 			yyVAL.scalarPipelineExpression = newScalarOperation(OpPower, yyDollar[1].scalarPipelineExpression, yyDollar[3].scalarPipelineExpression)
 		}
-	case 45:
+	case 62:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:175
+//line pkg/traceql/expr.y:236
 		{
 			yyVAL.scalarPipelineExpression = yyDollar[1].wrappedScalarPipeline
 		}
-	case 46:
+	case 63:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:179
+//line pkg/traceql/expr.y:240
 		{
 			yyVAL.wrappedScalarPipeline = yyDollar[2].scalarPipeline
 		}
-	case 47:
+	case 64:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:183
+//line pkg/traceql/expr.y:244
 		{
 			yyVAL.scalarPipeline = yyDollar[1].spansetPipeline.addItem(yyDollar[3].aggregate)
 		}
-	case 48:
+	case 65:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:187
+//line pkg/traceql/expr.y:248
 		{
 			yyVAL.scalarExpression = yyDollar[2].scalarExpression
 		}
-	case 49:
+	case 66:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:188
+//line pkg/traceql/expr.y:249
 		{
 			yyVAL.scalarExpression = newScalarOperation(OpAdd, yyDollar[1].scalarExpression, yyDollar[3].scalarExpression)
 		}
-	case 50:
+	case 67:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:189
+//line pkg/traceql/expr.y:250
 		{
 			yyVAL.scalarExpression = newScalarOperation(OpSub, yyDollar[1].scalarExpression, yyDollar[3].scalarExpression)
 		}
-	case 51:
+	case 68:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:190
+//line pkg/traceql/expr.y:251
 		{
 			yyVAL.scalarExpression = newScalarOperation(OpMult, yyDollar[1].scalarExpression, yyDollar[3].scalarExpression)
 		}
-	case 52:
+	case 69:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:191
+//line pkg/traceql/expr.y:252
 		{
 			yyVAL.scalarExpression = newScalarOperation(OpDiv, yyDollar[1].scalarExpression, yyDollar[3].scalarExpression)
 		}
-	case 53:
+	case 70:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:192
+//line pkg/traceql/expr.y:253
 		{
 			yyVAL.scalarExpression = newScalarOperation(OpMod, yyDollar[1].scalarExpression, yyDollar[3].scalarExpression)
 		}
-	case 54:
+	case 71:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:193
+//line pkg/traceql/expr.y:254
 		{
 			yyVAL.scalarExpression = newScalarOperation(OpPower, yyDollar[1].scalarExpression, yyDollar[3].scalarExpression)
 		}
-	case 55:
+	case 72:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:194
+//line pkg/traceql/expr.y:255
 		{
 			yyVAL.scalarExpression = yyDollar[1].aggregate
 		}
-	case 56:
+	case 73:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:195
+//line pkg/traceql/expr.y:256
 		{
 			yyVAL.scalarExpression = yyDollar[1].static
 		}
-	case 57:
+	case 74:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:257
+		{
+			yyVAL.scalarExpression = newReference(yyDollar[1].staticStr)
+		}
+	case 75:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:199
+//line pkg/traceql/expr.y:261
 		{
 			yyVAL.aggregate = newAggregate(aggregateCount, nil)
 		}
-	case 58:
+	case 76:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/traceql/expr.y:200
+//line pkg/traceql/expr.y:262
 		{
 			yyVAL.aggregate = newAggregate(aggregateMax, yyDollar[3].fieldExpression)
 		}
-	case 59:
+	case 77:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/traceql/expr.y:201
+//line pkg/traceql/expr.y:263
 		{
 			yyVAL.aggregate = newAggregate(aggregateMin, yyDollar[3].fieldExpression)
 		}
-	case 60:
+	case 78:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/traceql/expr.y:202
+//line pkg/traceql/expr.y:264
 		{
 			yyVAL.aggregate = newAggregate(aggregateAvg, yyDollar[3].fieldExpression)
 		}
-	case 61:
+	case 79:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/traceql/expr.y:203
+//line pkg/traceql/expr.y:265
 		{
 			yyVAL.aggregate = newAggregate(aggregateSum, yyDollar[3].fieldExpression)
 		}
-	case 62:
+	case 80:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line pkg/traceql/expr.y:266
+		{
+			yyVAL.aggregate = newAggregate(aggregateCountDistinct, yyDollar[3].fieldExpression)
+		}
+	case 81:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:210
+//line pkg/traceql/expr.y:273
 		{
 			yyVAL.fieldExpression = yyDollar[2].fieldExpression
 		}
-	case 63:
+	case 82:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:211
+//line pkg/traceql/expr.y:274
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpAdd, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 64:
+	case 83:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:212
+//line pkg/traceql/expr.y:275
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpSub, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 65:
+	case 84:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:213
+//line pkg/traceql/expr.y:276
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpMult, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 66:
+	case 85:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:214
+//line pkg/traceql/expr.y:277
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpDiv, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 67:
+	case 86:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:215
+//line pkg/traceql/expr.y:278
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpMod, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 68:
+	case 87:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:216
+//line pkg/traceql/expr.y:279
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpEqual, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 69:
+	case 88:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:217
+//line pkg/traceql/expr.y:280
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpNotEqual, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 70:
+	case 89:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:218
+//line pkg/traceql/expr.y:281
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpLess, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 71:
+	case 90:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:219
+//line pkg/traceql/expr.y:282
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpLessEqual, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 72:
+	case 91:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:220
+//line pkg/traceql/expr.y:283
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpGreater, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 73:
+	case 92:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:221
+//line pkg/traceql/expr.y:284
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpGreaterEqual, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 74:
+	case 93:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:222
+//line pkg/traceql/expr.y:285
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpRegex, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 75:
+	case 94:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:223
+//line pkg/traceql/expr.y:286
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpNotRegex, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 76:
+	case 95:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:224
+//line pkg/traceql/expr.y:287
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpPower, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 77:
+	case 96:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:225
+//line pkg/traceql/expr.y:288
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpAnd, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 78:
+	case 97:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:226
+//line pkg/traceql/expr.y:289
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpOr, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 79:
+	case 98:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line pkg/traceql/expr.y:290
+		{
+			yyVAL.fieldExpression = newSetOperation(OpIn, yyDollar[1].fieldExpression, yyDollar[4].staticList)
+		}
+	case 99:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line pkg/traceql/expr.y:291
+		{
+			yyVAL.fieldExpression = newSetOperation(OpNotIn, yyDollar[1].fieldExpression, yyDollar[4].staticList)
+		}
+	case 100:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/traceql/expr.y:227
+//line pkg/traceql/expr.y:292
 		{
 			yyVAL.fieldExpression = newUnaryOperation(OpSub, yyDollar[2].fieldExpression)
 		}
-	case 80:
+	case 101:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/traceql/expr.y:228
+//line pkg/traceql/expr.y:293
 		{
 			yyVAL.fieldExpression = newUnaryOperation(OpNot, yyDollar[2].fieldExpression)
 		}
-	case 81:
+	case 102:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line pkg/traceql/expr.y:294
+		{
+			yyVAL.fieldExpression = newHasOperation(yyDollar[3].fieldExpression)
+		}
+	case 103:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line pkg/traceql/expr.y:295
+		{
+			yyVAL.fieldExpression = newArrayElements(yyDollar[1].attributeField, false)
+		}
+	case 104:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line pkg/traceql/expr.y:296
+		{
+			yyVAL.fieldExpression = newArrayElements(yyDollar[3].attributeField, true)
+		}
+	case 105:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line pkg/traceql/expr.y:297
+		{
+			yyVAL.fieldExpression = newFunctionOperation(functionAbs, yyDollar[3].fieldExpression)
+		}
+	case 106:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line pkg/traceql/expr.y:298
+		{
+			yyVAL.fieldExpression = newFunctionOperation(functionSign, yyDollar[3].fieldExpression)
+		}
+	case 107:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line pkg/traceql/expr.y:299
+		{
+			yyVAL.fieldExpression = newBinaryOperation(OpBitAnd, yyDollar[3].fieldExpression, yyDollar[5].fieldExpression)
+		}
+	case 108:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line pkg/traceql/expr.y:300
+		{
+			yyVAL.fieldExpression = newBinaryOperation(OpBitOr, yyDollar[3].fieldExpression, yyDollar[5].fieldExpression)
+		}
+	case 109:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:229
+//line pkg/traceql/expr.y:301
 		{
 			yyVAL.fieldExpression = yyDollar[1].static
 		}
-	case 82:
+	case 110:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:230
+//line pkg/traceql/expr.y:302
 		{
 			yyVAL.fieldExpression = yyDollar[1].intrinsicField
 		}
-	case 83:
+	case 111:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:231
+//line pkg/traceql/expr.y:303
 		{
 			yyVAL.fieldExpression = yyDollar[1].attributeField
 		}
-	case 84:
+	case 112:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:304
+		{
+			yyVAL.fieldExpression = newReference(yyDollar[1].staticStr)
+		}
+	case 113:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:238
+//line pkg/traceql/expr.y:311
 		{
 			yyVAL.static = NewStaticString(yyDollar[1].staticStr)
 		}
-	case 85:
+	case 114:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:239
+//line pkg/traceql/expr.y:312
 		{
 			yyVAL.static = NewStaticInt(yyDollar[1].staticInt)
 		}
-	case 86:
+	case 115:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:240
+//line pkg/traceql/expr.y:313
 		{
 			yyVAL.static = NewStaticFloat(yyDollar[1].staticFloat)
 		}
-	case 87:
+	case 116:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:241
+//line pkg/traceql/expr.y:314
 		{
 			yyVAL.static = NewStaticBool(true)
 		}
-	case 88:
+	case 117:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:242
+//line pkg/traceql/expr.y:315
 		{
 			yyVAL.static = NewStaticBool(false)
 		}
-	case 89:
+	case 118:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:243
+//line pkg/traceql/expr.y:316
 		{
 			yyVAL.static = NewStaticNil()
 		}
-	case 90:
+	case 119:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:244
+//line pkg/traceql/expr.y:317
 		{
 			yyVAL.static = NewStaticDuration(yyDollar[1].staticDuration)
 		}
-	case 91:
+	case 120:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:245
+//line pkg/traceql/expr.y:318
+		{
+			yyVAL.static = NewStaticTimestamp(yyDollar[1].staticTimestamp)
+		}
+	case 121:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:319
 		{
 			yyVAL.static = NewStaticStatus(StatusOk)
 		}
-	case 92:
+	case 122:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:246
+//line pkg/traceql/expr.y:320
 		{
 			yyVAL.static = NewStaticStatus(StatusError)
 		}
-	case 93:
+	case 123:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:247
+//line pkg/traceql/expr.y:321
 		{
 			yyVAL.static = NewStaticStatus(StatusUnset)
 		}
-	case 94:
+	case 124:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:251
+//line pkg/traceql/expr.y:325
+		{
+			yyVAL.staticList = []Static{yyDollar[1].static}
+		}
+	case 125:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line pkg/traceql/expr.y:326
+		{
+			yyVAL.staticList = append(yyDollar[1].staticList, yyDollar[3].static)
+		}
+	case 126:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:330
 		{
 			yyVAL.intrinsicField = NewIntrinsic(IntrinsicDuration)
 		}
-	case 95:
+	case 127:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:252
+//line pkg/traceql/expr.y:331
 		{
 			yyVAL.intrinsicField = NewIntrinsic(IntrinsicChildCount)
 		}
-	case 96:
+	case 128:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:253
+//line pkg/traceql/expr.y:332
 		{
 			yyVAL.intrinsicField = NewIntrinsic(IntrinsicName)
 		}
-	case 97:
+	case 129:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:254
+//line pkg/traceql/expr.y:333
 		{
 			yyVAL.intrinsicField = NewIntrinsic(IntrinsicStatus)
 		}
-	case 98:
+	case 130:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:255
+//line pkg/traceql/expr.y:334
 		{
 			yyVAL.intrinsicField = NewIntrinsic(IntrinsicParent)
 		}
-	case 99:
+	case 131:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:335
+		{
+			yyVAL.intrinsicField = NewIntrinsic(IntrinsicSelfTime)
+		}
+	case 132:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:336
+		{
+			yyVAL.intrinsicField = NewIntrinsic(IntrinsicStartTime)
+		}
+	case 133:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:337
+		{
+			yyVAL.intrinsicField = NewIntrinsic(IntrinsicEndTime)
+		}
+	case 134:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:338
+		{
+			yyVAL.intrinsicField = NewIntrinsic(IntrinsicLinkTraceID)
+		}
+	case 135:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:339
+		{
+			yyVAL.intrinsicField = NewIntrinsic(IntrinsicLinkSpanID)
+		}
+	case 136:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:259
+//line pkg/traceql/expr.y:343
 		{
 			yyVAL.attributeField = NewAttribute(yyDollar[2].staticStr)
 		}
-	case 100:
+	case 137:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:260
+//line pkg/traceql/expr.y:344
 		{
 			yyVAL.attributeField = NewScopedAttribute(AttributeScopeResource, false, yyDollar[2].staticStr)
 		}
-	case 101:
+	case 138:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:261
+//line pkg/traceql/expr.y:345
 		{
 			yyVAL.attributeField = NewScopedAttribute(AttributeScopeSpan, false, yyDollar[2].staticStr)
 		}
-	case 102:
+	case 139:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:262
+//line pkg/traceql/expr.y:346
 		{
 			yyVAL.attributeField = NewScopedAttribute(AttributeScopeNone, true, yyDollar[2].staticStr)
 		}
-	case 103:
+	case 140:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/traceql/expr.y:263
+//line pkg/traceql/expr.y:347
 		{
 			yyVAL.attributeField = NewScopedAttribute(AttributeScopeResource, true, yyDollar[3].staticStr)
 		}
-	case 104:
+	case 141:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/traceql/expr.y:264
+//line pkg/traceql/expr.y:348
 		{
 			yyVAL.attributeField = NewScopedAttribute(AttributeScopeSpan, true, yyDollar[3].staticStr)
 		}
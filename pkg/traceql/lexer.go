@@ -11,52 +11,70 @@ import (
 )
 
 var tokens = map[string]int{
-	".":          DOT,
-	"{":          OPEN_BRACE,
-	"}":          CLOSE_BRACE,
-	"(":          OPEN_PARENS,
-	")":          CLOSE_PARENS,
-	"=":          EQ,
-	"!=":         NEQ,
-	"=~":         RE,
-	"!~":         NRE,
-	">":          GT,
-	">=":         GTE,
-	"<":          LT,
-	"<=":         LTE,
-	"+":          ADD,
-	"-":          SUB,
-	"/":          DIV,
-	"%":          MOD,
-	"*":          MUL,
-	"^":          POW,
-	"true":       TRUE,
-	"false":      FALSE,
-	"nil":        NIL,
-	"ok":         STATUS_OK,
-	"error":      STATUS_ERROR,
-	"unset":      STATUS_UNSET,
-	"&&":         AND,
-	"||":         OR,
-	"!":          NOT,
-	"|":          PIPE,
-	">>":         DESC,
-	"~":          TILDE,
-	"duration":   IDURATION,
-	"childCount": CHILDCOUNT,
-	"name":       NAME,
-	"status":     STATUS,
-	"parent":     PARENT,
-	"parent.":    PARENT_DOT,
-	"resource.":  RESOURCE_DOT,
-	"span.":      SPAN_DOT,
-	"count":      COUNT,
-	"avg":        AVG,
-	"max":        MAX,
-	"min":        MIN,
-	"sum":        SUM,
-	"by":         BY,
-	"coalesce":   COALESCE,
+	".":              DOT,
+	"{":              OPEN_BRACE,
+	"}":              CLOSE_BRACE,
+	"(":              OPEN_PARENS,
+	")":              CLOSE_PARENS,
+	"=":              EQ,
+	"!=":             NEQ,
+	"=~":             RE,
+	"!~":             NRE,
+	">":              GT,
+	">=":             GTE,
+	"<":              LT,
+	"<=":             LTE,
+	"+":              ADD,
+	"-":              SUB,
+	"/":              DIV,
+	"%":              MOD,
+	"*":              MUL,
+	"^":              POW,
+	"true":           TRUE,
+	"false":          FALSE,
+	"nil":            NIL,
+	"ok":             STATUS_OK,
+	"error":          STATUS_ERROR,
+	"unset":          STATUS_UNSET,
+	"&&":             AND,
+	"||":             OR,
+	"!":              NOT,
+	"|":              PIPE,
+	">>":             DESC,
+	"~":              TILDE,
+	"duration":       IDURATION,
+	"childCount":     CHILDCOUNT,
+	"name":           NAME,
+	"status":         STATUS,
+	"parent":         PARENT,
+	"selfTime":       SELFTIME,
+	"startTime":      STARTTIME,
+	"endTime":        ENDTIME,
+	"parent.":        PARENT_DOT,
+	"resource.":      RESOURCE_DOT,
+	"span.":          SPAN_DOT,
+	"count":          COUNT,
+	"avg":            AVG,
+	"max":            MAX,
+	"min":            MIN,
+	"sum":            SUM,
+	"count_distinct": COUNT_DISTINCT,
+	"by":             BY,
+	"coalesce":       COALESCE,
+	"flatten":        FLATTEN,
+	"select":         SELECT,
+	"with":           WITH,
+	"distinct":       DISTINCT,
+	"topk":           TOPK,
+	"has":            HAS,
+	"abs":            ABS,
+	"sign":           SIGN,
+	"bitAnd":         BITAND,
+	"bitOr":          BITOR,
+	"in":             IN,
+	",":              COMMA,
+	"[]":             ARRAY,
+	"all":            ALL,
 }
 
 type lexer struct {
@@ -66,22 +84,44 @@ type lexer struct {
 	errs   []ParseError
 
 	parsingAttribute bool
+	// quotedAttribute is set once a quoted attribute name has been scanned. the closing quote
+	// always ends the attribute.
+	quotedAttribute bool
+	// arrayAttribute is set if the attribute name ended in [], which follows the end of the attribute
+	arrayAttribute bool
 }
 
 func (l *lexer) Lex(lval *yySymType) int {
 	// if we are currently parsing an attribute and the next rune suggests that
 	//  this attribute will end, then return a special token indicating that the attribute is
 	//  done parsing
-	if l.parsingAttribute && !isAttributeRune(l.Peek()) {
+	if l.parsingAttribute && (l.quotedAttribute || !isAttributeRune(l.Peek())) {
 		l.parsingAttribute = false
+		l.quotedAttribute = false
 		return END_ATTRIBUTE
 	}
+	if l.arrayAttribute {
+		l.arrayAttribute = false
+		return ARRAY
+	}
 
 	r := l.Scan()
 
 	// if we are currently parsing an attribute then just grab everything until we find a character that ends the attribute.
 	// we will handle parsing this out in ast.go
 	if l.parsingAttribute {
+		// quoted names can contain any character, e.g. span."http.request.header.x-foo"
+		if r == scanner.String {
+			var err error
+			lval.staticStr, err = strconv.Unquote(l.TokenText())
+			if err != nil {
+				l.Error(err.Error())
+				return 0
+			}
+			l.quotedAttribute = true
+			return IDENTIFIER
+		}
+
 		str := l.TokenText()
 		// parse out any scopes here
 		tok := tokens[str+string(l.Peek())]
@@ -97,6 +137,12 @@ func (l *lexer) Lex(lval *yySymType) int {
 			r = l.Peek()
 		}
 
+		// span.tags[] refers to the elements of the attribute tags
+		if len(str) > len("[]") && strings.HasSuffix(str, "[]") {
+			str = strings.TrimSuffix(str, "[]")
+			l.arrayAttribute = true
+		}
+
 		lval.staticStr = str
 		return IDENTIFIER
 	}
@@ -118,7 +164,13 @@ func (l *lexer) Lex(lval *yySymType) int {
 	case scanner.Int:
 		numberText := l.TokenText()
 
-		// first try to parse as duration
+		// timestamps start with their year, e.g. 2023-01-01T00:00:00Z
+		if ts, ok := tryScanTimestamp(numberText, &l.Scanner); ok {
+			lval.staticTimestamp = ts
+			return TIMESTAMP
+		}
+
+		// then try to parse as duration
 		duration, ok := tryScanDuration(numberText, &l.Scanner)
 		if ok {
 			lval.staticDuration = duration
@@ -144,6 +196,11 @@ func (l *lexer) Lex(lval *yySymType) int {
 		return FLOAT
 	}
 
+	// "!>>" is the only operator made up of three runes
+	if l.TokenText() == "!" && tryScanRunes(&l.Scanner, ">>") {
+		return NOT_DESC
+	}
+
 	tokStrNext := l.TokenText() + string(l.Peek())
 	if tok, ok := tokens[tokStrNext]; ok {
 		l.Next()
@@ -156,10 +213,51 @@ func (l *lexer) Lex(lval *yySymType) int {
 		return tok
 	}
 
+	// the intrinsics of links are scoped by "link:"
+	if l.TokenText() == "link" {
+		if tryScanRunes(&l.Scanner, ":traceID") {
+			return LINK_TRACEID
+		}
+		if tryScanRunes(&l.Scanner, ":spanID") {
+			return LINK_SPANID
+		}
+	}
+
+	// "not in" is a single operator made up of two words
+	if l.TokenText() == "not" && tryScanIn(&l.Scanner) {
+		return NOT_IN
+	}
+
 	lval.staticStr = l.TokenText()
 	return IDENTIFIER
 }
 
+// tryScanIn consumes the next token if it is "in" and returns true.
+func tryScanIn(l *scanner.Scanner) bool {
+	//copy the scanner to avoid advancing it in case it's not followed by "in".
+	s := *l
+	if s.Scan() != scanner.Ident || s.TokenText() != "in" {
+		return false
+	}
+	_ = l.Scan()
+	return true
+}
+
+// tryScanRunes consumes the next runes if they spell out runes and returns true.
+func tryScanRunes(l *scanner.Scanner, runes string) bool {
+	//copy the scanner to avoid advancing it in case the runes don't follow.
+	s := *l
+	for _, r := range runes {
+		if s.Next() != r {
+			return false
+		}
+	}
+	for range runes {
+		_ = l.Next()
+	}
+	return true
+}
+
 func (l *lexer) Error(msg string) {
 	l.errs = append(l.errs, newParseError(msg, l.Line, l.Column))
 }
@@ -229,7 +327,8 @@ func isAttributeRune(r rune) bool {
 		r != '(' &&
 		r != ')' &&
 		r != '}' &&
-		r != '{'
+		r != '{' &&
+		r != ','
 }
 
 func startsAttribute(tok int) bool {
@@ -238,3 +337,34 @@ func startsAttribute(tok int) bool {
 		tok == SPAN_DOT ||
 		tok == PARENT_DOT
 }
+
+// tryScanTimestamp consumes the rest of an RFC 3339 timestamp that starts with number.
+func tryScanTimestamp(number string, l *scanner.Scanner) (time.Time, bool) {
+	if l.Peek() != '-' {
+		return time.Time{}, false
+	}
+
+	var sb strings.Builder
+	sb.WriteString(number)
+	//copy the scanner to avoid advancing it in case it's not a timestamp.
+	s := *l
+	consumed := 0
+	for r := s.Peek(); isTimestampRune(r); r = s.Peek() {
+		_, _ = sb.WriteRune(r)
+		_ = s.Next()
+		consumed++
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, sb.String())
+	if err != nil {
+		return time.Time{}, false
+	}
+	for i := 0; i < consumed; i++ {
+		_ = l.Next()
+	}
+	return t, true
+}
+
+func isTimestampRune(r rune) bool {
+	return unicode.IsDigit(r) || strings.ContainsRune("-:.+TZ", r)
+}
@@ -52,13 +52,24 @@ func TestLexerAttributes(t *testing.T) {
 		{`parent.resource.foo3`, []int{PARENT_DOT, RESOURCE_DOT, IDENTIFIER, END_ATTRIBUTE}},
 		{`parent.resource.foo+bar`, []int{PARENT_DOT, RESOURCE_DOT, IDENTIFIER, END_ATTRIBUTE}},
 		{`parent.resource.foo-bar`, []int{PARENT_DOT, RESOURCE_DOT, IDENTIFIER, END_ATTRIBUTE}},
-		// attribute enders: <space>, {, }, (, ) all force end an attribute
+		// attribute enders: <space>, {, }, (, ), comma all force end an attribute
 		{`.foo .bar`, []int{DOT, IDENTIFIER, END_ATTRIBUTE, DOT, IDENTIFIER, END_ATTRIBUTE}},
 		{`.foo}.bar`, []int{DOT, IDENTIFIER, END_ATTRIBUTE, CLOSE_BRACE, DOT, IDENTIFIER, END_ATTRIBUTE}},
 		{`.foo{.bar`, []int{DOT, IDENTIFIER, END_ATTRIBUTE, OPEN_BRACE, DOT, IDENTIFIER, END_ATTRIBUTE}},
 		{`.foo).bar`, []int{DOT, IDENTIFIER, END_ATTRIBUTE, CLOSE_PARENS, DOT, IDENTIFIER, END_ATTRIBUTE}},
 		{`.foo(.bar`, []int{DOT, IDENTIFIER, END_ATTRIBUTE, OPEN_PARENS, DOT, IDENTIFIER, END_ATTRIBUTE}},
+		{`.foo,.bar`, []int{DOT, IDENTIFIER, END_ATTRIBUTE, COMMA, DOT, IDENTIFIER, END_ATTRIBUTE}},
 		{`. foo`, []int{DOT, END_ATTRIBUTE, IDENTIFIER}},
+		// quoted attributes
+		{`."foo bar"`, []int{DOT, IDENTIFIER, END_ATTRIBUTE}},
+		{`span."http.request.header.x-foo"`, []int{SPAN_DOT, IDENTIFIER, END_ATTRIBUTE}},
+		{`parent.resource."foo}"`, []int{PARENT_DOT, RESOURCE_DOT, IDENTIFIER, END_ATTRIBUTE}},
+		{`."foo"="bar"`, []int{DOT, IDENTIFIER, END_ATTRIBUTE, EQ, STRING}},
+		// array elements
+		{`span.tags[]`, []int{SPAN_DOT, IDENTIFIER, END_ATTRIBUTE, ARRAY}},
+		{`all(.tags[])`, []int{ALL, OPEN_PARENS, DOT, IDENTIFIER, END_ATTRIBUTE, ARRAY, CLOSE_PARENS}},
+		{`span."foo bar"[]`, []int{SPAN_DOT, IDENTIFIER, END_ATTRIBUTE, ARRAY}},
+		{`.foo[0]`, []int{DOT, IDENTIFIER, END_ATTRIBUTE}},
 		// not attributes
 		{`.3`, []int{FLOAT}},
 		{`.24h`, []int{FLOAT, IDENTIFIER}},
@@ -84,6 +95,20 @@ func TestLexerDuration(t *testing.T) {
 	}))
 }
 
+func TestLexerTimestamp(t *testing.T) {
+	testLexer(t, ([]lexerTestCase{
+		{"2023-01-01T00:00:00Z", []int{TIMESTAMP}},
+		{"2023-01-01T00:00:00.123456789Z", []int{TIMESTAMP}},
+		{"2023-01-01T02:00:00+02:00", []int{TIMESTAMP}},
+		{"startTime > 2023-01-01T00:00:00Z", []int{STARTTIME, GT, TIMESTAMP}},
+		{"{ endTime<2023-01-01T00:00:00Z}", []int{OPEN_BRACE, ENDTIME, LT, TIMESTAMP, CLOSE_BRACE}},
+		// not timestamps
+		{"2023-1", []int{INTEGER, SUB, INTEGER}},
+		{"2023-01-01", []int{INTEGER, SUB, INTEGER, SUB, INTEGER}},
+		{"2023 - 1h", []int{INTEGER, SUB, DURATION}},
+	}))
+}
+
 func TestLexerParseDuration(t *testing.T) {
 	const MICROSECOND = 1000 * time.Nanosecond
 	const DAY = 24 * time.Hour
@@ -286,6 +286,18 @@ func TestGroupCoalesceOperation(t *testing.T) {
 	}{
 		{in: "by(.a) | coalesce()", expected: newPipeline(newGroupOperation(NewAttribute("a")), newCoalesceOperation())},
 		{in: "by(.a + .b)", expected: newPipeline(newGroupOperation(newBinaryOperation(OpAdd, NewAttribute("a"), NewAttribute("b"))))},
+		{in: "by(.a) | flatten()", expected: newPipeline(newGroupOperation(NewAttribute("a")), newFlattenOperation())},
+		{in: "by(.a) | select(.b, duration - .c)", expected: newPipeline(newGroupOperation(NewAttribute("a")), newSelectOperation([]FieldExpression{
+			NewAttribute("b"),
+			newBinaryOperation(OpSub, NewIntrinsic(IntrinsicDuration), NewAttribute("c")),
+		}))},
+		{in: "by(.a) | distinct(span.http.route)", expected: newPipeline(newGroupOperation(NewAttribute("a")), newDistinctOperation(NewScopedAttribute(AttributeScopeSpan, false, "http.route")))},
+		{in: "by(.a) | topk(duration * 2, 10)", expected: newPipeline(newGroupOperation(NewAttribute("a")), newTopKOperation(newBinaryOperation(OpMult, NewIntrinsic(IntrinsicDuration), NewStaticInt(2)), 10))},
+		{in: "by(.a) | with(m = max(duration)) | { duration > m }", expected: newPipeline(
+			newGroupOperation(NewAttribute("a")),
+			newWithOperation("m", newAggregate(aggregateMax, NewIntrinsic(IntrinsicDuration))),
+			newSpansetFilter(newBinaryOperation(OpGreater, NewIntrinsic(IntrinsicDuration), newReference("m"))),
+		)},
 	}
 
 	for _, tc := range tests {
@@ -355,6 +367,20 @@ func TestSpansetExpressionPrecedence(t *testing.T) {
 				newSpansetFilter(NewStaticString("a")),
 			),
 		},
+		{
+			in: "{ true } && { false } !>> { `a` }",
+			expected: newSpansetOperation(OpSpansetAnd,
+				newSpansetFilter(NewStaticBool(true)),
+				newSpansetOperation(OpSpansetNotDescendant, newSpansetFilter(NewStaticBool(false)), newSpansetFilter(NewStaticString("a"))),
+			),
+		},
+		{
+			in: "{ true } !>> { false } >> { `a` }",
+			expected: newSpansetOperation(OpSpansetDescendant,
+				newSpansetOperation(OpSpansetNotDescendant, newSpansetFilter(NewStaticBool(true)), newSpansetFilter(NewStaticBool(false))),
+				newSpansetFilter(NewStaticString("a")),
+			),
+		},
 	}
 
 	for _, tc := range tests {
@@ -475,10 +501,12 @@ func TestSpansetFilterErrors(t *testing.T) {
 		in  string
 		err error
 	}{
-		{in: "wharblgarbl", err: newParseError("syntax error: unexpected IDENTIFIER", 1, 1)},
+		{in: "wharblgarbl", err: newParseError("syntax error: unexpected $end", 1, 12)},
 		{in: "{ 2 <> 3}", err: newParseError("syntax error: unexpected >", 1, 6)},
 		{in: "{ 2 = .b ", err: newParseError("syntax error: unexpected $end", 1, 10)},
 		{in: "{ + }", err: newParseError("syntax error: unexpected +", 1, 3)},
+		{in: "{ true } | select(count())", err: newParseError("select() can't select aggregates", 1, 25)},
+		{in: "{ true } | select(.a, max(.b))", err: newParseError("select() can't select aggregates", 1, 29)},
 	}
 
 	for _, tc := range tests {
@@ -625,10 +653,16 @@ func TestSpansetFilterStatics(t *testing.T) {
 		{in: "{ name }", expected: NewIntrinsic(IntrinsicName)},
 		{in: "{ parent }", expected: NewIntrinsic(IntrinsicParent)},
 		{in: "{ status }", expected: NewIntrinsic(IntrinsicStatus)},
+		{in: "{ selfTime }", expected: NewIntrinsic(IntrinsicSelfTime)},
+		{in: "{ startTime }", expected: NewIntrinsic(IntrinsicStartTime)},
+		{in: "{ endTime }", expected: NewIntrinsic(IntrinsicEndTime)},
+		{in: "{ link:traceID }", expected: NewIntrinsic(IntrinsicLinkTraceID)},
+		{in: "{ link:spanID }", expected: NewIntrinsic(IntrinsicLinkSpanID)},
 		{in: "{ 4321 }", expected: NewStaticInt(4321)},
 		{in: "{ 1.234 }", expected: NewStaticFloat(1.234)},
 		{in: "{ nil }", expected: NewStaticNil()},
 		{in: "{ 3h }", expected: NewStaticDuration(3 * time.Hour)},
+		{in: "{ 2023-01-01T00:00:00Z }", expected: NewStaticTimestamp(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))},
 		{in: "{ error }", expected: NewStaticStatus(StatusError)},
 		{in: "{ ok }", expected: NewStaticStatus(StatusOk)},
 		{in: "{ unset }", expected: NewStaticStatus(StatusUnset)},
@@ -668,6 +702,11 @@ func TestSpansetFilterOperators(t *testing.T) {
 		{in: "{ .a || .b }", expected: newBinaryOperation(OpOr, NewAttribute("a"), NewAttribute("b"))},
 		{in: "{ !.b }", expected: newUnaryOperation(OpNot, NewAttribute("b"))},
 		{in: "{ -.b }", expected: newUnaryOperation(OpSub, NewAttribute("b"))},
+		{in: "{ .a in (1, 2) }", expected: newSetOperation(OpIn, NewAttribute("a"), []Static{NewStaticInt(1), NewStaticInt(2)})},
+		{in: "{ .a not in (`x`) }", expected: newSetOperation(OpNotIn, NewAttribute("a"), []Static{NewStaticString("x")})},
+		{in: "{ span.a[] = 1 }", expected: newBinaryOperation(OpEqual, newArrayElements(NewScopedAttribute(AttributeScopeSpan, false, "a"), false), NewStaticInt(1))},
+		{in: "{ all(.a[]) = 1 }", expected: newBinaryOperation(OpEqual, newArrayElements(NewAttribute("a"), true), NewStaticInt(1))},
+		{in: "{ .a[] = 1 && .b }", expected: newBinaryOperation(OpAnd, newBinaryOperation(OpEqual, newArrayElements(NewAttribute("a"), false), NewStaticInt(1)), NewAttribute("b"))},
 	}
 
 	for _, tc := range tests {
@@ -726,6 +765,11 @@ func TestAttributes(t *testing.T) {
 		{in: "parent.span.foo", expected: NewScopedAttribute(AttributeScopeSpan, true, "foo")},
 		{in: "parent.resource.foo.bar.baz", expected: NewScopedAttribute(AttributeScopeResource, true, "foo.bar.baz")},
 		{in: "parent.span.foo.bar", expected: NewScopedAttribute(AttributeScopeSpan, true, "foo.bar")},
+		{in: `."foo"`, expected: NewAttribute("foo")},
+		{in: `."foo bar"`, expected: NewAttribute("foo bar")},
+		{in: `span."http.request.header.x-foo"`, expected: NewScopedAttribute(AttributeScopeSpan, false, "http.request.header.x-foo")},
+		{in: `resource."{a}"`, expected: NewScopedAttribute(AttributeScopeResource, false, "{a}")},
+		{in: `parent.span."a \"b\""`, expected: NewScopedAttribute(AttributeScopeSpan, true, `a "b"`)},
 	}
 
 	for _, tc := range tests {
@@ -767,6 +811,9 @@ func TestIntrinsics(t *testing.T) {
 		{in: "name", expected: IntrinsicName},
 		{in: "status", expected: IntrinsicStatus},
 		{in: "parent", expected: IntrinsicParent},
+		{in: "selfTime", expected: IntrinsicSelfTime},
+		{in: "startTime", expected: IntrinsicStartTime},
+		{in: "endTime", expected: IntrinsicEndTime},
 	}
 
 	for _, tc := range tests {
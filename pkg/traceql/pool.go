@@ -0,0 +1,126 @@
+package traceql
+
+import "sync"
+
+// spanPool recycles the span slices of spansets that only live while a pipeline is evaluated, like
+// the output of a filter that the next filter of the pipeline narrows down further.
+type spanPool struct {
+	pool sync.Pool
+}
+
+func newSpanPool(defaultSize int) *spanPool {
+	return &spanPool{
+		pool: sync.Pool{
+			New: func() any {
+				return make([]Span, 0, defaultSize)
+			},
+		},
+	}
+}
+
+var evalSpanPool = newSpanPool(16)
+
+func (p *spanPool) Get() []Span {
+	return p.pool.Get().([]Span)
+}
+
+func (p *spanPool) Put(spans []Span) {
+	// Clear before putting into the pool, so pooled slices don't keep the attributes of the spans
+	// alive.
+	spans = spans[:cap(spans)]
+	for i := range spans {
+		spans[i] = Span{}
+	}
+	p.pool.Put(spans[:0]) //nolint:all //SA6002
+}
+
+// spanCopier is implemented by elements whose output never shares the span slices of their input,
+// so the span slices of the input can be recycled once the element is evaluated.
+type spanCopier interface {
+	copiesSpans()
+}
+
+func (SpansetFilter) copiesSpans()     {}
+func (SelectOperation) copiesSpans()   {}
+func (DistinctOperation) copiesSpans() {}
+
+// getSpans returns an empty span slice from the pool of the context. The slice is owned by the
+// evaluation until the pipeline that evaluates the current element recycles it, see
+// Pipeline.evaluate. Without a pool it returns nil.
+func (ec *evalContext) getSpans() []Span {
+	if ec == nil || ec.pool == nil {
+		return nil
+	}
+	return ec.pool.Get()
+}
+
+// keepSpans hands the span slice from getSpans on as part of the output of the current element.
+func (ec *evalContext) keepSpans(spans []Span) {
+	if ec == nil || ec.pool == nil || spans == nil {
+		return
+	}
+	ec.pooled = append(ec.pooled, spans)
+}
+
+// putSpans returns a span slice from getSpans that isn't part of any output to the pool.
+func (ec *evalContext) putSpans(spans []Span) {
+	if ec == nil || ec.pool == nil || spans == nil {
+		return
+	}
+	ec.pool.Put(spans)
+}
+
+// stageSpans tracks the pooled span slices of the spansets passed between the elements of a
+// pipeline. The slices of a stage are recycled once an element that copies spans has consumed it.
+// The slices of the last stage belong to the result and are handed on to the caller.
+type stageSpans struct {
+	ec      *evalContext
+	outer   [][]Span
+	current [][]Span
+}
+
+func (ec *evalContext) startStages() *stageSpans {
+	st := &stageSpans{ec: ec}
+	if ec != nil {
+		st.outer, ec.pooled = ec.pooled, nil
+	}
+	return st
+}
+
+// evaluated takes the span slices the element kept while it was evaluated.
+func (st *stageSpans) evaluated(element pipelineElement) {
+	if st.ec == nil {
+		return
+	}
+	produced := st.ec.pooled
+	st.ec.pooled = nil
+
+	if _, ok := element.(spanCopier); ok {
+		st.recycle()
+	}
+	st.current = append(st.current, produced...)
+}
+
+// finish hands the span slices of the last stage on to the caller.
+func (st *stageSpans) finish() {
+	if st.ec == nil {
+		return
+	}
+	st.ec.pooled = append(st.outer, st.current...)
+}
+
+// discard recycles the span slices of the last stage, if the result of the pipeline is empty.
+func (st *stageSpans) discard() {
+	if st.ec == nil {
+		return
+	}
+	st.recycle()
+	st.ec.pooled = st.outer
+}
+
+func (st *stageSpans) recycle() {
+	for _, spans := range st.current {
+		st.ec.putSpans(spans)
+	}
+	st.current = nil
+}
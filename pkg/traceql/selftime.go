@@ -0,0 +1,123 @@
+package traceql
+
+import (
+	"sort"
+	"time"
+)
+
+var (
+	selfTimeAttribute   = NewIntrinsic(IntrinsicSelfTime)
+	childCountAttribute = NewIntrinsic(IntrinsicChildCount)
+	parentAttribute     = NewIntrinsic(IntrinsicParent)
+)
+
+// traceIntrinsics are the intrinsics the engine computes from the parents and children of spans.
+type traceIntrinsics struct {
+	selfTime   bool
+	childCount bool
+	parent     bool
+}
+
+// referencedTraceIntrinsics returns the trace intrinsics the element or any element below it uses.
+func referencedTraceIntrinsics(e Element) traceIntrinsics {
+	var t traceIntrinsics
+	Walk(e, func(e Element) bool {
+		if a, ok := e.(Attribute); ok {
+			switch a.Intrinsic {
+			case IntrinsicSelfTime:
+				t.selfTime = true
+			case IntrinsicChildCount:
+				t.childCount = true
+			case IntrinsicParent:
+				t.parent = true
+			}
+		}
+		return true
+	})
+	return t
+}
+
+func (t traceIntrinsics) any() bool {
+	return t.selfTime || t.childCount || t.parent
+}
+
+// setTraceIntrinsics stores the trace intrinsics of every span of the input spansets that the
+// element references. It must be called with the spansets as fetched, before any element drops
+// spans, because the parents and children of a span are taken from its spanset.
+func setTraceIntrinsics(e Element, input []Spanset) {
+	t := referencedTraceIntrinsics(e)
+	if !t.any() {
+		return
+	}
+
+	for _, ss := range input {
+		t.set(ss)
+	}
+}
+
+// set stores the intrinsics of each span of the spanset as intrinsic attributes. A span without
+// children has a childCount of 0. parent is nil for root spans, including orphans whose parent
+// isn't in the spanset, and true for all others, so { parent = nil } matches the roots.
+func (t traceIntrinsics) set(ss Spanset) {
+	index := buildSpanIndex(ss.Spans)
+
+	for i := range ss.Spans {
+		span := &ss.Spans[i]
+		if span.Attributes == nil {
+			span.Attributes = map[Attribute]Static{}
+		}
+
+		if t.childCount {
+			span.Attributes[childCountAttribute] = NewStaticInt(len(index.childrenOf(i)))
+		}
+		if t.parent {
+			if _, ok := index.parent(i); ok {
+				span.Attributes[parentAttribute] = NewStaticBool(true)
+			}
+		}
+		if !t.selfTime {
+			continue
+		}
+
+		children := make([]Span, 0, len(index.childrenOf(i)))
+		for _, c := range index.childrenOf(i) {
+			children = append(children, ss.Spans[c])
+		}
+		span.Attributes[selfTimeAttribute] = NewStaticDuration(selfTime(*span, children))
+	}
+}
+
+// selfTime is the duration of the span that isn't covered by any of its children. Concurrent
+// children that overlap each other count once and children are clipped to the span, so the self
+// time is never negative. A span without children has its duration as self time.
+func selfTime(span Span, children []Span) time.Duration {
+	start, end := span.StartTimeUnixNanos, span.EndtimeUnixNanos
+	if end <= start {
+		return 0
+	}
+
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].StartTimeUnixNanos < children[j].StartTimeUnixNanos
+	})
+
+	// until is the end of the children seen so far. It only moves forward, so time that
+	// overlapping children share is covered once.
+	var covered uint64
+	until := start
+	for _, c := range children {
+		from := c.StartTimeUnixNanos
+		if from < until {
+			from = until
+		}
+		to := c.EndtimeUnixNanos
+		if to > end {
+			to = end
+		}
+		if to > from {
+			covered += to - from
+			until = to
+		}
+	}
+
+	return time.Duration(end - start - covered)
+}
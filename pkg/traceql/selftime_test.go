@@ -0,0 +1,198 @@
+package traceql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfTime(t *testing.T) {
+	span := func(id, parent byte, start, end uint64) Span {
+		s := Span{ID: []byte{id}, StartTimeUnixNanos: start, EndtimeUnixNanos: end}
+		if parent != 0 {
+			s.ParentID = []byte{parent}
+		}
+		return s
+	}
+
+	tests := []struct {
+		name     string
+		spans    []Span
+		expected time.Duration
+	}{
+		{
+			name:     "no children",
+			spans:    []Span{span(1, 0, 100, 200)},
+			expected: 100,
+		},
+		{
+			name:     "sequential children",
+			spans:    []Span{span(1, 0, 100, 200), span(2, 1, 110, 130), span(3, 1, 150, 190)},
+			expected: 40,
+		},
+		{
+			name:     "overlapping children",
+			spans:    []Span{span(1, 0, 100, 200), span(2, 1, 110, 160), span(3, 1, 120, 180), span(4, 1, 130, 140)},
+			expected: 30,
+		},
+		{
+			name:     "children outside of the span",
+			spans:    []Span{span(1, 0, 100, 200), span(2, 1, 50, 120), span(3, 1, 180, 250)},
+			expected: 60,
+		},
+		{
+			name:     "grandchildren",
+			spans:    []Span{span(1, 0, 100, 200), span(2, 1, 100, 150), span(3, 2, 110, 140)},
+			expected: 50,
+		},
+		{
+			name:     "fully covered",
+			spans:    []Span{span(1, 0, 100, 200), span(2, 1, 90, 210)},
+			expected: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ss := Spanset{Spans: tc.spans}
+			traceIntrinsics{selfTime: true}.set(ss)
+			require.Equal(t, NewStaticDuration(tc.expected), ss.Spans[0].Attributes[selfTimeAttribute])
+		})
+	}
+}
+
+func TestChildCount(t *testing.T) {
+	span := func(id, parent byte) Span {
+		s := Span{ID: []byte{id}}
+		if parent != 0 {
+			s.ParentID = []byte{parent}
+		}
+		return s
+	}
+
+	ss := Spanset{Spans: []Span{
+		span(1, 0),
+		span(2, 1), // single child
+		span(3, 2), // leaf
+		span(4, 1),
+		span(5, 4),
+		span(6, 4),
+		span(7, 4),
+	}}
+	traceIntrinsics{childCount: true}.set(ss)
+
+	var counts []Static
+	for _, s := range ss.Spans {
+		counts = append(counts, s.Attributes[childCountAttribute])
+		require.NotContains(t, s.Attributes, selfTimeAttribute)
+	}
+	require.Equal(t, []Static{
+		NewStaticInt(2),
+		NewStaticInt(1),
+		NewStaticInt(0),
+		NewStaticInt(3),
+		NewStaticInt(0),
+		NewStaticInt(0),
+		NewStaticInt(0),
+	}, counts)
+
+	expr, err := Parse("{ childCount > 1 }")
+	require.NoError(t, err)
+	output, err := NewEvaluator(EvalOptions{}, nil).Evaluate(context.Background(), expr.Pipeline, []Spanset{{Spans: []Span{
+		span(1, 0),
+		span(2, 1),
+		span(3, 2),
+		span(4, 1),
+	}}})
+	require.NoError(t, err)
+	require.Len(t, output, 1)
+	require.Len(t, output[0].Spans, 1)
+	require.Equal(t, []byte{1}, output[0].Spans[0].ID)
+
+	// a single span has no children
+	expr, err = Parse("{ childCount = 0 }")
+	require.NoError(t, err)
+	matches, err := Matches(expr, span(1, 0))
+	require.NoError(t, err)
+	require.True(t, matches)
+}
+
+func TestParent(t *testing.T) {
+	span := func(id, parent byte) Span {
+		s := Span{ID: []byte{id}}
+		if parent != 0 {
+			s.ParentID = []byte{parent}
+		}
+		return s
+	}
+	ids := func(output []Spanset) []byte {
+		var ids []byte
+		for _, ss := range output {
+			for _, s := range ss.Spans {
+				ids = append(ids, s.ID...)
+			}
+		}
+		return ids
+	}
+
+	tests := []struct {
+		query    string
+		expected []byte
+	}{
+		// 3 is an orphan, its parent isn't in the spanset
+		{query: "{ parent = nil }", expected: []byte{1, 3}},
+		{query: "{ nil = parent }", expected: []byte{1, 3}},
+		{query: "{ parent != nil }", expected: []byte{2, 4}},
+		{query: "{ parent != nil && childCount = 0 }", expected: []byte{4}},
+		// a missing parent isn't a value that differs from others
+		{query: "{ parent != true }", expected: nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			expr, err := Parse(tc.query)
+			require.NoError(t, err)
+			require.NoError(t, expr.validate())
+
+			input := []Spanset{{Spans: []Span{span(1, 0), span(2, 1), span(3, 9), span(4, 2)}}}
+			output, err := NewEvaluator(EvalOptions{}, nil).Evaluate(context.Background(), expr.Pipeline, input)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, ids(output))
+		})
+	}
+
+	// a single span is a root
+	expr, err := Parse("{ parent = nil }")
+	require.NoError(t, err)
+	matches, err := Matches(expr, span(2, 1))
+	require.NoError(t, err)
+	require.True(t, matches)
+}
+
+func TestEvaluatorSelfTime(t *testing.T) {
+	expr, err := Parse(`{ .a = "parent" } | { selfTime > 50ns }`)
+	require.NoError(t, err)
+
+	newInput := func() []Spanset {
+		return []Spanset{{Spans: []Span{
+			{ID: []byte{1}, StartTimeUnixNanos: 100, EndtimeUnixNanos: 200, Attributes: map[Attribute]Static{NewAttribute("a"): NewStaticString("parent")}},
+			{ID: []byte{2}, ParentID: []byte{1}, StartTimeUnixNanos: 110, EndtimeUnixNanos: 130, Attributes: map[Attribute]Static{}},
+			{ID: []byte{3}, ParentID: []byte{1}, StartTimeUnixNanos: 120, EndtimeUnixNanos: 190, Attributes: map[Attribute]Static{}},
+		}}}
+	}
+
+	// the children are dropped by the first filter, but the self time of 20ns was computed before
+	output, err := NewEvaluator(EvalOptions{}, nil).Evaluate(context.Background(), expr.Pipeline, newInput())
+	require.NoError(t, err)
+	require.Empty(t, output)
+
+	expr, err = Parse(`{ .a = "parent" } | { selfTime < 50ns }`)
+	require.NoError(t, err)
+
+	output, err = NewEvaluator(EvalOptions{}, nil).Evaluate(context.Background(), expr.Pipeline, newInput())
+	require.NoError(t, err)
+	require.Len(t, output, 1)
+	require.Len(t, output[0].Spans, 1)
+	require.Equal(t, NewStaticDuration(20), output[0].Spans[0].Attributes[selfTimeAttribute])
+}
@@ -0,0 +1,113 @@
+package traceql
+
+// SpanIndex links the spans of a trace to their parents and children by position in the slice it
+// was built from. Spans are linked through ParentID, so the storage layer must populate it.
+type SpanIndex struct {
+	parents  []int
+	children [][]int
+	roots    []int
+}
+
+// buildSpanIndex indexes the spans of one trace. Malformed traces are handled defensively:
+//   - a span whose parent isn't in spans is a root, as is a span that is its own parent
+//   - if several spans share an ID, children are linked to the first one
+//   - a cycle of parents is broken at the span that closes it, which becomes a root
+func buildSpanIndex(spans []Span) *SpanIndex {
+	x := &SpanIndex{
+		parents:  make([]int, len(spans)),
+		children: make([][]int, len(spans)),
+	}
+
+	byID := make(map[string]int, len(spans))
+	for i, s := range spans {
+		if id, ok := s.identity(); ok {
+			if _, exists := byID[id]; !exists {
+				byID[id] = i
+			}
+		}
+	}
+
+	for i, s := range spans {
+		x.parents[i] = -1
+		if len(s.ParentID) == 0 {
+			continue
+		}
+		if p, ok := byID[string(s.ParentID)]; ok && p != i {
+			x.parents[i] = p
+		}
+	}
+
+	x.breakCycles()
+
+	for i, p := range x.parents {
+		if p == -1 {
+			x.roots = append(x.roots, i)
+			continue
+		}
+		x.children[p] = append(x.children[p], i)
+	}
+
+	return x
+}
+
+// breakCycles follows the parents of every span. A walk that returns to a span it already passed
+// is a cycle and the link back is dropped.
+func (x *SpanIndex) breakCycles() {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+
+	state := make([]int, len(x.parents))
+	var path []int
+	for i := range x.parents {
+		path = path[:0]
+		for cur := i; cur != -1 && state[cur] == unvisited; {
+			state[cur] = visiting
+			path = append(path, cur)
+
+			p := x.parents[cur]
+			if p != -1 && state[p] == visiting {
+				x.parents[cur] = -1
+				p = -1
+			}
+			cur = p
+		}
+		for _, s := range path {
+			state[s] = done
+		}
+	}
+}
+
+// parent returns the position of the parent of the span at i.
+func (x *SpanIndex) parent(i int) (int, bool) {
+	p := x.parents[i]
+	return p, p != -1
+}
+
+// childrenOf returns the positions of the direct children of the span at i.
+func (x *SpanIndex) childrenOf(i int) []int {
+	return x.children[i]
+}
+
+// hasDescendant reports whether any span below the span at i, at any depth, satisfies fn.
+func (x *SpanIndex) hasDescendant(i int, fn func(int) bool) bool {
+	// the index is a forest once cycles are broken, so the walk ends without tracking visited spans
+	stack := append([]int(nil), x.children[i]...)
+	for len(stack) > 0 {
+		d := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if fn(d) {
+			return true
+		}
+		stack = append(stack, x.children[d]...)
+	}
+	return false
+}
+
+// rootSpans returns the positions of all spans without a parent in the index, which includes
+// orphans of incomplete traces.
+func (x *SpanIndex) rootSpans() []int {
+	return x.roots
+}
@@ -0,0 +1,82 @@
+package traceql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSpanIndex(t *testing.T) {
+	span := func(id, parent string) Span {
+		return Span{ID: []byte(id), ParentID: []byte(parent)}
+	}
+
+	tests := []struct {
+		name     string
+		spans    []Span
+		parents  []int
+		children [][]int
+		roots    []int
+	}{
+		{
+			name:     "tree",
+			spans:    []Span{span("a", ""), span("b", "a"), span("c", "a"), span("d", "b")},
+			parents:  []int{-1, 0, 0, 1},
+			children: [][]int{{1, 2}, {3}, nil, nil},
+			roots:    []int{0},
+		},
+		{
+			name:     "children before parents",
+			spans:    []Span{span("d", "b"), span("b", "a"), span("a", "")},
+			parents:  []int{1, 2, -1},
+			children: [][]int{nil, {0}, {1}},
+			roots:    []int{2},
+		},
+		{
+			name:     "orphan",
+			spans:    []Span{span("a", ""), span("b", "missing"), span("c", "b")},
+			parents:  []int{-1, -1, 1},
+			children: [][]int{nil, {2}, nil},
+			roots:    []int{0, 1},
+		},
+		{
+			name:     "self referential",
+			spans:    []Span{span("a", ""), span("b", "b"), span("c", "b")},
+			parents:  []int{-1, -1, 1},
+			children: [][]int{nil, {2}, nil},
+			roots:    []int{0, 1},
+		},
+		{
+			name:  "cycle",
+			spans: []Span{span("a", "c"), span("b", "a"), span("c", "b"), span("d", "b")},
+			// a -> c -> b -> a, the walk from a closes the cycle at b
+			parents:  []int{2, -1, 1, 1},
+			children: [][]int{nil, {2, 3}, {0}, nil},
+			roots:    []int{1},
+		},
+		{
+			name:     "duplicate IDs",
+			spans:    []Span{span("a", ""), span("a", ""), span("b", "a")},
+			parents:  []int{-1, -1, 0},
+			children: [][]int{{2}, nil, nil},
+			roots:    []int{0, 1},
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			x := buildSpanIndex(tc.spans)
+
+			for i := range tc.spans {
+				p, ok := x.parent(i)
+				require.Equal(t, tc.parents[i] != -1, ok, "span %d", i)
+				require.Equal(t, tc.parents[i], p, "span %d", i)
+				require.Equal(t, tc.children[i], x.childrenOf(i), "span %d", i)
+			}
+			require.Equal(t, tc.roots, x.rootSpans())
+		})
+	}
+}
@@ -11,13 +11,38 @@ type Condition struct {
 	Attribute Attribute
 	Op        Operator
 	Operands  Operands
+	// Elements is set if the engine compares the elements of the attribute, see ArrayElements.
+	// Storage layers only need to fetch array values for these conditions.
+	Elements bool
 }
 
+// Pushdown describes how much of the filter a condition decides in the storage layer.
+type Pushdown int
+
+const (
+	// PushdownNone conditions only fetch the attribute, the filter is evaluated by the engine.
+	PushdownNone Pushdown = iota
+	// PushdownPartial conditions narrow down the fetched spans, but the engine has to check them
+	// again.
+	PushdownPartial
+	// PushdownExact conditions are fully decided by the predicate of the fetch.
+	PushdownExact
+)
+
 type FetchSpansRequest struct {
 	StartTimeUnixNanos uint64
 	EndTimeUnixNanos   uint64
 	Conditions         []Condition
 
+	// Pushdown is the report of extractConditions, it holds the pushdown of each condition at the
+	// same index. Storage layers can ignore it.
+	Pushdown []Pushdown
+
+	// residual is set if parts of the filter didn't turn into a condition, or conditions were
+	// combined in a way the storage layer doesn't guarantee, so the filter must be evaluated
+	// regardless of the pushdown of the conditions.
+	residual bool
+
 	// Hints
 
 	// By default the storage layer fetches spans meeting any of the criteria.
@@ -27,17 +52,61 @@ type FetchSpansRequest struct {
 	AllConditions bool
 }
 
-func (f *FetchSpansRequest) appendCondition(c ...Condition) {
+func (f *FetchSpansRequest) appendCondition(p Pushdown, c ...Condition) {
 	f.Conditions = append(f.Conditions, c...)
+	for range c {
+		f.Pushdown = append(f.Pushdown, p)
+	}
+}
+
+// relaxFloatEquality turns =, != and sets of floats into conditions that only fetch the attribute. Storage
+// layers compare floats exactly and would drop values that are only equal within an epsilon.
+func (f *FetchSpansRequest) relaxFloatEquality() {
+	for i, c := range f.Conditions {
+		if (c.Op == OpEqual || c.Op == OpNotEqual || c.Op == OpIn) && len(c.Operands) > 0 && c.Operands[0].Type == TypeFloat {
+			f.Conditions[i] = Condition{Attribute: c.Attribute, Op: OpNone}
+			f.Pushdown[i] = PushdownNone
+		}
+	}
+}
+
+// exact reports whether the fetched spans are exactly the spans matching the filter the request
+// was extracted from. That is only the case if all conditions are exact and the filter is a
+// single condition or a disjunction of conditions, since storage layers by default return the
+// spans that match any condition. AllConditions is only a hint and isn't relied upon.
+func (f *FetchSpansRequest) exact() bool {
+	if f.residual || len(f.Conditions) == 0 {
+		return false
+	}
+	for _, p := range f.Pushdown {
+		if p != PushdownExact {
+			return false
+		}
+	}
+	return true
 }
 
 type Span struct {
-	ID                 []byte
+	// ID is the identity of the span and must be populated by the storage layer. Spans with the same
+	// ID are considered the same span when combining spansets.
+	ID []byte
+	// ParentID is the ID of the parent span. It is only needed to compute selfTime and childCount
+	// and storage layers populate it if a condition requests either.
+	ParentID           []byte
 	StartTimeUnixNanos uint64
 	EndtimeUnixNanos   uint64
 	Attributes         map[Attribute]Static
 }
 
+// identity returns a comparable key for the span derived from its ID. Spans
+// without an ID have no identity and are never deduplicated.
+func (s Span) identity() (string, bool) {
+	if len(s.ID) == 0 {
+		return "", false
+	}
+	return string(s.ID), true
+}
+
 type Spanset struct {
 	TraceID            []byte
 	RootSpanName       string
@@ -45,6 +114,17 @@ type Spanset struct {
 	StartTimeUnixNanos uint64
 	DurationNanos      uint64
 	Spans              []Span
+	// Truncated is set if spans of the spanset were dropped because of EvalOptions.MaxSpansPerSpanset.
+	Truncated bool
+
+	// bindings are the values bound by with() while evaluating a pipeline. Storage never sets them,
+	// they start out empty for every query. with() replaces the map instead of writing to it, because
+	// spansets derived from one another share it, and filters keep the bindings of their input.
+	bindings map[string]Static
+
+	// group is the value of the by() expression the spans of a grouped spanset share
+	group   Static
+	grouped bool
 }
 
 type SpansetIterator interface {
@@ -53,6 +133,16 @@ type SpansetIterator interface {
 
 type FetchSpansResponse struct {
 	Results SpansetIterator
+	// Bytes optionally reports the number of bytes read so far to produce the results.
+	Bytes func() uint64
+}
+
+// BytesRead returns the number of bytes read so far, or 0 if the storage layer doesn't report it.
+func (r FetchSpansResponse) BytesRead() uint64 {
+	if r.Bytes == nil {
+		return 0
+	}
+	return r.Bytes()
 }
 
 type SpansetFetcher interface {
@@ -87,6 +177,16 @@ func ExtractCondition(query string) (cond Condition, err error) {
 
 	switch e := f.Expression.(type) {
 	case BinaryOperation:
+		if e.Op == OpAnd {
+			if c, ok := combineRangeConditions(e.LHS, e.RHS); ok {
+				return c, nil
+			}
+		}
+		if e.Op == OpOr {
+			if c, ok := combineEqualityConditions(e); ok {
+				return c, nil
+			}
+		}
 		cond.Attribute = e.LHS.(Attribute)
 		cond.Op = e.Op
 		cond.Operands = []Static{e.RHS.(Static)}
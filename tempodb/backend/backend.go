@@ -13,6 +13,8 @@ var (
 	ErrEmptyTenantID = fmt.Errorf("empty tenant id")
 	ErrEmptyBlockID  = fmt.Errorf("empty block id")
 	ErrBadSeedFile   = fmt.Errorf("bad seed file")
+	// ErrRangeUnsupported is returned by ReadRange of backends that can only read whole objects
+	ErrRangeUnsupported = fmt.Errorf("range reads unsupported")
 )
 
 // AppendTracker is an empty interface usable by the backend to track a long running append operation
@@ -43,6 +45,7 @@ type Reader interface {
 	StreamReader(ctx context.Context, name string, blockID uuid.UUID, tenantID string) (io.ReadCloser, int64, error)
 	// ReadRange is for reading parts of large objects from the backend.
 	// There will be an attempt to retrieve this from cache if shouldCache is true. Cache key will be tenantID:blockID:offset:bufferLength
+	// Returns ErrRangeUnsupported if the backend can't read parts of objects.
 	ReadRange(ctx context.Context, name string, blockID uuid.UUID, tenantID string, offset uint64, buffer []byte, shouldCache bool) error
 	// Tenants returns a list of all tenants in a backend
 	Tenants(ctx context.Context) ([]string, error)
@@ -7,6 +7,13 @@ import (
 
 var ErrUnsupported = errors.New("unsupported")
 
+// ErrInspectedBytesBudgetExceeded is returned by lookups that read more than SearchOptions.MaxInspectedBytes.
+var ErrInspectedBytesBudgetExceeded = errors.New("inspected bytes budget exceeded")
+
+// ErrDuplicateTraceID is returned by FindTraceByID if the block has more than one row for the trace ID
+// and SearchOptions.DuplicateTraceIDs is DuplicateTraceIDsError.
+var ErrDuplicateTraceID = errors.New("duplicate trace ID")
+
 const (
 	// NameObjects names the backend data object
 	NameObjects = "data"
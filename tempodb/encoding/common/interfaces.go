@@ -7,6 +7,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/grafana/tempo/pkg/model"
 	"github.com/grafana/tempo/pkg/tempopb"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
 	"github.com/grafana/tempo/pkg/traceql"
 	"github.com/grafana/tempo/tempodb/backend"
 )
@@ -31,15 +32,96 @@ type CacheControl struct {
 	OffsetIndex bool
 }
 
+// DuplicateTraceIDs is what FindTraceByID does if a block has more than one row for a trace ID. Blocks
+// never should, but corrupt ones have.
+type DuplicateTraceIDs int
+
+const (
+	// DuplicateTraceIDsIgnore returns the first row and doesn't look for others. This is the default.
+	DuplicateTraceIDsIgnore DuplicateTraceIDs = iota
+	// DuplicateTraceIDsError fails the lookup with ErrDuplicateTraceID.
+	DuplicateTraceIDsError
+	// DuplicateTraceIDsMerge returns the combined spans of all rows.
+	DuplicateTraceIDsMerge
+)
+
 type SearchOptions struct {
 	ChunkSizeBytes     uint32 // Buffer size to read from backend storage.
 	StartPage          int    // Controls searching only a subset of the block. Which page to begin searching at.
 	TotalPages         int    // Controls searching only a subset of the block. How many pages to search.
 	MaxBytes           int    // Max allowable trace size in bytes. Traces exceeding this are not searched.
+	MaxSpansPerTrace   int    // Max spans read for a single trace by FindTraceByID. Traces exceeding this are truncated. 0 is unlimited.
 	PrefetchTraceCount int    // How many traces to prefetch async.
 	ReadBufferCount    int
 	ReadBufferSize     int
+	ReadAheadPages     int // How many pages the column iterators of trace ID lookups read ahead of the page being processed. 0 disables read-ahead.
+	DecodeConcurrency  int // How many pages of a column chunk the column iterators of Search decode at once. 0 or 1 decodes serially.
 	CacheControl       CacheControl
+	ReadRetries        int           // How many times a backend read that failed with a transient error is retried. 0 disables retries.
+	ReadRetryBackoff   time.Duration // Wait before the first retry of a backend read. Doubles with every further retry.
+
+	// TimeWindowStartUnixNano and TimeWindowEndUnixNano limit FindTraceByID to traces that overlap the
+	// window, which tells apart traces that reused an ID at different times. 0 leaves the bound open.
+	TimeWindowStartUnixNano uint64
+	TimeWindowEndUnixNano   uint64
+
+	// MaxTagValues stops SearchTagValues once it reported this many distinct values. 0 is unlimited.
+	MaxTagValues int
+
+	// ServiceName limits FindTraceByID and FindTracesByIDs to the spans of batches with this resource
+	// service name. A trace without such spans is returned without spans. Empty returns all spans.
+	ServiceName string
+
+	// MaxInspectedBytes aborts FindTraceByID with ErrInspectedBytesBudgetExceeded once it read more
+	// bytes than this, which protects against runaway reads of corrupt blocks. 0 is unlimited.
+	MaxInspectedBytes uint64
+
+	// CacheRowGroupMins keeps the minimum trace IDs of the row groups that FindTraceByID reads to
+	// locate a trace on the block, so later lookups in the same block don't read them again.
+	CacheRowGroupMins bool
+
+	// TraceCacheTTL keeps the traces found by FindTraceByID on the block for this long and returns
+	// them to later lookups of the same ID without reading the backend. Only lookups of whole traces
+	// use the cache, not those with a ServiceName, time window, MaxSpansPerTrace, SpanLess,
+	// DuplicateTraceIDs or callbacks. 0 disables the cache.
+	TraceCacheTTL time.Duration
+	// TraceCacheMaxEntries and TraceCacheMaxBytes bound the traces cached per block, the oldest are
+	// evicted first. Sizes are of the marshaled traces. 0 is unlimited.
+	TraceCacheMaxEntries int
+	TraceCacheMaxBytes   int
+
+	// DuplicateTraceIDs makes FindTraceByID scan the row groups of a trace for further rows with its
+	// ID, unless it is DuplicateTraceIDsIgnore. The time window and service name are checked
+	// on the first row, the span limit applies to every row.
+	DuplicateTraceIDs DuplicateTraceIDs
+
+	// SkipBloom makes FindTraceByID search the rows of the block without testing the bloom filter
+	// first. It shows whether a trace that isn't found is missing from the data or from the bloom.
+	SkipBloom bool
+
+	// ReadPageIndex reads the column and offset indexes of the pages when a block is opened for search.
+	// A block whose page index can't be read is opened without it, like it is by default.
+	ReadPageIndex bool
+
+	// MultiBlockConcurrency limits how many blocks FindTraceByIDMulti searches at once. 0 searches
+	// all blocks at once.
+	MultiBlockConcurrency int
+	// MultiBlockFoundLimit stops FindTraceByIDMulti once the trace was found in this many blocks and
+	// cancels the remaining lookups. 0 searches all blocks and combines everything that was found.
+	MultiBlockFoundLimit int
+
+	// SpanLess orders the spans of traces returned by FindTraceByID and FindTracesByIDs, for example
+	// trace.SpansByStartTime.
+	// Spans are ordered within their scope, see trace.SortTraceBy. nil keeps the order of the block.
+	SpanLess func(a, b *v1.Span) bool
+
+	// TraceTruncated is called when FindTraceByID returns a partial trace because of MaxSpansPerTrace.
+	TraceTruncated func(id ID, spansDiscarded int)
+
+	// TraceCompleteness is called with every trace returned by FindTraceByID. complete reports whether
+	// the trace has a root span, a span without a parent. Traces without one likely have spans in
+	// other blocks. nil doesn't check the trace.
+	TraceCompleteness func(id ID, complete bool)
 }
 
 type Compactor interface {
@@ -5,7 +5,6 @@ import (
 
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/encoding/common"
-	"github.com/segmentio/parquet-go"
 )
 
 const (
@@ -16,20 +15,36 @@ type backendBlock struct {
 	meta *backend.BlockMeta
 	r    backend.Reader
 
-	openMtx  sync.Mutex
-	pf       *parquet.File
-	readerAt *BackendReaderAt
+	openMtx sync.Mutex
+	opened  map[openKey]openedFile
+	columns schemaColumns
+
+	// rowGroupMins are the minimum trace IDs of the row groups FindTraceByID has read with
+	// SearchOptions.CacheRowGroupMins, indexed like rowGroupIndex.mins.
+	rowGroupMinsMtx sync.Mutex
+	rowGroupMins    []common.ID
+
+	// traces are the traces FindTraceByID found with SearchOptions.TraceCacheTTL.
+	traces *traceCache
 }
 
 var _ common.BackendBlock = (*backendBlock)(nil)
 
 func newBackendBlock(meta *backend.BlockMeta, r backend.Reader) *backendBlock {
 	return &backendBlock{
-		meta: meta,
-		r:    r,
+		meta:   meta,
+		r:      r,
+		opened: map[openKey]openedFile{},
+		traces: newTraceCache(),
 	}
 }
 
 func (b *backendBlock) BlockMeta() *backend.BlockMeta {
 	return b.meta
 }
+
+// reader returns the backend reader to read the block with, wrapped to retry transient errors if
+// enabled in the options.
+func (b *backendBlock) reader(opts common.SearchOptions) backend.Reader {
+	return newRetryReader(b.r, opts.ReadRetries, opts.ReadRetryBackoff)
+}
@@ -5,17 +5,21 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sort"
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/log"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/segmentio/parquet-go"
 	"github.com/willf/bloom"
 
+	"github.com/grafana/tempo/pkg/model/trace"
 	"github.com/grafana/tempo/pkg/parquetquery"
 	pq "github.com/grafana/tempo/pkg/parquetquery"
 	"github.com/grafana/tempo/pkg/tempopb"
-	"github.com/grafana/tempo/pkg/util"
+	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/encoding/common"
 )
 
@@ -27,7 +31,13 @@ const (
 	TraceIDColumnName = "TraceID"
 )
 
-func (b *backendBlock) checkBloom(ctx context.Context, id common.ID) (found bool, err error) {
+var metricDuplicateTraceIDs = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "tempodb",
+	Name:      "vparquet_duplicate_trace_ids_total",
+	Help:      "Total number of FindTraceByID lookups that found more than one row for the trace ID in a block.",
+})
+
+func (b *backendBlock) checkBloom(ctx context.Context, id common.ID, opts common.SearchOptions, budget *readBudget) (found bool, err error) {
 	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "parquet.backendBlock.checkBloom",
 		opentracing.Tags{
 			"blockID":  b.meta.BlockID,
@@ -39,11 +49,16 @@ func (b *backendBlock) checkBloom(ctx context.Context, id common.ID) (found bool
 	nameBloom := common.BloomName(shardKey)
 	span.SetTag("bloom", nameBloom)
 
-	bloomBytes, err := b.r.Read(derivedCtx, nameBloom, b.meta.BlockID, b.meta.TenantID, true)
+	bloomBytes, err := b.reader(opts).Read(derivedCtx, nameBloom, b.meta.BlockID, b.meta.TenantID, true)
 	if err != nil {
 		return false, fmt.Errorf("error retrieving bloom %s (%s, %s): %w", nameBloom, b.meta.TenantID, b.meta.BlockID, err)
 	}
 
+	budget.addBloom(len(bloomBytes))
+	if err := budget.check("bloom"); err != nil {
+		return false, err
+	}
+
 	filter := &bloom.BloomFilter{}
 	_, err = filter.ReadFrom(bytes.NewReader(bloomBytes))
 	if err != nil {
@@ -53,7 +68,21 @@ func (b *backendBlock) checkBloom(ctx context.Context, id common.ID) (found bool
 	return filter.Test(id), nil
 }
 
-func (b *backendBlock) FindTraceByID(ctx context.Context, traceID common.ID, opts common.SearchOptions) (_ *tempopb.Trace, err error) {
+// TraceLocation is where a trace was found in a block. RowNumber counts rows from the start of the
+// file, so a reader of the whole file can seek to it directly.
+type TraceLocation struct {
+	RowGroup  int
+	RowNumber int64
+}
+
+func (b *backendBlock) FindTraceByID(ctx context.Context, traceID common.ID, opts common.SearchOptions) (*tempopb.Trace, error) {
+	tr, _, err := b.FindTraceByIDWithLocation(ctx, traceID, opts)
+	return tr, err
+}
+
+// FindTraceByIDWithLocation is FindTraceByID but also returns the location of the trace's row, e.g.
+// to cache it for reading the trace again. The location is zero if the trace isn't found.
+func (b *backendBlock) FindTraceByIDWithLocation(ctx context.Context, traceID common.ID, opts common.SearchOptions) (*tempopb.Trace, TraceLocation, error) {
 	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "parquet.backendBlock.FindTraceByID",
 		opentracing.Tags{
 			"blockID":   b.meta.BlockID,
@@ -62,69 +91,578 @@ func (b *backendBlock) FindTraceByID(ctx context.Context, traceID common.ID, opt
 		})
 	defer span.Finish()
 
-	found, err := b.checkBloom(derivedCtx, traceID)
-	if err != nil {
-		return nil, err
+	cache := traceCacheEnabled(opts)
+	if cache {
+		if tr, loc, ok := b.traces.get(traceID); ok {
+			metricTraceCacheRequests.WithLabelValues(traceCacheHit).Inc()
+			span.SetTag("cached", true)
+			return tr, loc, nil
+		}
+		metricTraceCacheRequests.WithLabelValues(traceCacheMiss).Inc()
 	}
-	if !found {
-		return nil, nil
+
+	budget := newReadBudget(opts)
+
+	if opts.SkipBloom {
+		span.SetTag("bloomSkipped", true)
+	} else {
+		found, err := b.checkBloom(derivedCtx, traceID, opts, budget)
+		if err != nil {
+			return nil, TraceLocation{}, err
+		}
+		if !found {
+			return nil, TraceLocation{}, nil
+		}
 	}
 
-	pf, rr, err := b.openForSearch(derivedCtx, opts)
+	// lookups with a budget read the file with readers of their own, the readers of the shared file
+	// count the bytes of all lookups of the block
+	open := b.openForSearch
+	if budget != nil {
+		open = b.openForLookup
+	}
+	pf, rr, err := open(derivedCtx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("unexpected error opening parquet file: %w", err)
+		return nil, TraceLocation{}, fmt.Errorf("unexpected error opening parquet file: %w", err)
 	}
 	defer func() {
 		span.SetTag("inspectedBytes", rr.TotalBytesRead.Load())
 		//fmt.Println("read bytes:", rr.TotalBytesRead.Load())
 	}()
+	budget.setReader(rr)
+
+	loc, found, err := b.locateTrace(derivedCtx, pf, traceID, opts, budget)
+	if err != nil {
+		return nil, TraceLocation{}, err
+	}
+	if !found {
+		return nil, TraceLocation{}, nil
+	}
+	if len(loc.duplicates) > 0 {
+		metricDuplicateTraceIDs.Inc()
+		span.SetTag("duplicates", len(loc.duplicates))
+		if opts.DuplicateTraceIDs == common.DuplicateTraceIDsError {
+			return nil, TraceLocation{}, fmt.Errorf("%w: %d rows for trace %x in block %s", common.ErrDuplicateTraceID, len(loc.duplicates)+1, traceID, b.meta.BlockID)
+		}
+	}
+
+	if opts.TimeWindowStartUnixNano != 0 || opts.TimeWindowEndUnixNano != 0 {
+		overlaps, err := traceOverlapsWindow(derivedCtx, pf, loc, opts)
+		if err != nil {
+			return nil, TraceLocation{}, err
+		}
+		if !overlaps {
+			span.LogFields(log.Message("trace outside of time window"))
+			return nil, TraceLocation{}, nil
+		}
+		if err := budget.check("time window"); err != nil {
+			return nil, TraceLocation{}, err
+		}
+	}
+
+	hasService := true
+	if opts.ServiceName != "" {
+		hasService, err = traceHasService(derivedCtx, pf, loc, opts.ServiceName)
+		if err != nil {
+			return nil, TraceLocation{}, err
+		}
+		if err := budget.check("service name"); err != nil {
+			return nil, TraceLocation{}, err
+		}
+	}
+
+	tr := &Trace{TraceID: traceID}
+	if hasService {
+		// seek to row and read
+		r := parquet.NewReader(pf, parquet.SchemaOf(new(Trace)))
+		err = r.SeekToRow(loc.offset)
+		if err != nil {
+			return nil, TraceLocation{}, errors.Wrap(err, "seek to row")
+		}
+
+		span.LogFields(log.Message("seeked to row"), log.Int64("row", loc.offset))
+
+		tr, err = readTrace(r, traceID, opts, span)
+		if err != nil {
+			return nil, TraceLocation{}, err
+		}
+		if err := budget.check("trace read"); err != nil {
+			return nil, TraceLocation{}, err
+		}
+
+		span.LogFields(log.Message("read trace"))
+
+		for _, dup := range loc.duplicates {
+			if err = r.SeekToRow(dup.offset); err != nil {
+				return nil, TraceLocation{}, errors.Wrap(err, "seek to duplicate row")
+			}
+			dupTr, err := readTrace(r, traceID, opts, span)
+			if err != nil {
+				return nil, TraceLocation{}, err
+			}
+			if err := budget.check("duplicate trace read"); err != nil {
+				return nil, TraceLocation{}, err
+			}
+			tr = CombineTraces(tr, dupTr)
+			span.LogFields(log.Message("merged duplicate row"), log.Int64("row", dup.offset))
+		}
+	} else {
+		span.LogFields(log.Message("no spans of service"))
+	}
+
+	if opts.TraceCompleteness != nil {
+		opts.TraceCompleteness(traceID, hasRootSpan(tr))
+	}
+
+	// convert to proto trace and return
+	protoTrace := tempopbTrace(tr, opts)
+	location := TraceLocation{RowGroup: loc.rowGroup, RowNumber: loc.offset}
+	if cache {
+		b.traces.put(traceID, protoTrace, location, opts)
+	}
+	return protoTrace, location, nil
+}
 
+// tempopbTrace converts the trace to proto and orders its spans if requested.
+func tempopbTrace(tr *Trace, opts common.SearchOptions) *tempopb.Trace {
+	protoTrace := parquetTraceToTempopbTrace(tr)
+	if opts.SpanLess != nil {
+		trace.SortTraceBy(protoTrace, opts.SpanLess)
+	}
+	return protoTrace
+}
+
+// hasRootSpan returns true if any span of the trace has no parent. The spans read are checked, so
+// a trace truncated by MaxSpansPerTrace is only complete if its root span was kept.
+func hasRootSpan(tr *Trace) bool {
+	for _, rs := range tr.ResourceSpans {
+		for _, ils := range rs.ScopeSpans {
+			for _, s := range ils.Spans {
+				if len(s.ParentSpanID) == 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// traceLocation is the position of a trace's row in a block.
+type traceLocation struct {
+	rowGroup int
+	// row is the row number relative to the row group
+	row pq.RowNumber
+	// offset is the row number from the start of the file
+	offset int64
+	// duplicates are the further rows of the trace ID in the row group, they are only looked for
+	// if SearchOptions.DuplicateTraceIDs asks to
+	duplicates []traceLocation
+}
+
+// locateTrace finds the row of the trace in the file. Returns false if the trace isn't in the block.
+// The budget is checked after reading the row group mins and after scanning the row group, it may be nil.
+func (b *backendBlock) locateTrace(ctx context.Context, pf *parquet.File, traceID common.ID, opts common.SearchOptions, budget *readBudget) (traceLocation, bool, error) {
 	// traceID column index
-	colIndex, _ := pq.GetColumnIndexByPath(pf, TraceIDColumnName)
+	colIndex, _ := pq.GetColumnIndexByPath(pf, b.columns.traceID)
 	if colIndex == -1 {
-		return nil, fmt.Errorf("unable to get index for column: %s", TraceIDColumnName)
+		return traceLocation{}, false, fmt.Errorf("unable to get index for column: %s", b.columns.traceID)
+	}
+
+	index := newRowGroupIndex(pf, colIndex, b.meta)
+	if opts.CacheRowGroupMins {
+		b.loadRowGroupMins(index)
+		defer b.storeRowGroupMins(index)
+	}
+
+	rowGroup, err := index.find(traceID, 0)
+	if err != nil {
+		return traceLocation{}, false, errors.Wrap(err, "error binary searching row groups")
+	}
+
+	if err := budget.check("row group mins"); err != nil {
+		return traceLocation{}, false, err
 	}
 
-	numRowGroups := len(pf.RowGroups())
-	buf := make(parquet.Row, 1)
+	if rowGroup == -1 {
+		// Not within the bounds of any row group
+		return traceLocation{}, false, nil
+	}
+
+	if opts.DuplicateTraceIDs != common.DuplicateTraceIDsIgnore {
+		return locateTraceRows(ctx, pf, index, colIndex, traceID, rowGroup, opts, budget)
+	}
+
+	// Now iterate the matching row group
+	iter := parquetquery.NewColumnIterator(ctx, pf.RowGroups()[rowGroup:rowGroup+1], colIndex, "", 1000, parquetquery.NewStringEqualPredicate(traceID), "", pq.WithReadAhead(opts.ReadAheadPages))
+	defer iter.Close()
+
+	res, err := iter.Next()
+	if err != nil {
+		return traceLocation{}, false, err
+	}
+	if err := budget.check("trace ID scan"); err != nil {
+		return traceLocation{}, false, err
+	}
+	if res == nil {
+		// TraceID not found in this block
+		return traceLocation{}, false, nil
+	}
+
+	// The row number coming out of the iterator is relative,
+	// so offset it using the num rows in all previous groups
+	rowMatch := int64(0)
+	for _, rg := range pf.RowGroups()[0:rowGroup] {
+		rowMatch += rg.NumRows()
+	}
+	rowMatch += res.RowNumber[0]
 
-	// Cache of row group bounds
-	rowGroupMins := make([]common.ID, numRowGroups+1)
-	rowGroupMins[0] = b.meta.MinID
-	rowGroupMins[numRowGroups] = b.meta.MaxID // This is actually inclusive and the logic is special for the last row group below
+	return traceLocation{
+		rowGroup: rowGroup,
+		row:      res.RowNumber,
+		offset:   rowMatch,
+	}, true, nil
+}
 
-	// Gets the minimum trace ID within the row group. Since the column is sorted
-	// ascending we just read the first value from the first page.
-	getRowGroupMin := func(rgIdx int) (common.ID, error) {
-		min := rowGroupMins[rgIdx]
-		if len(min) > 0 {
-			// Already loaded
-			return min, nil
+// locateTraceRows finds all rows of the trace in the file, starting at the row group the index found
+// for it. The rows of a trace ID are next to each other, but may continue into the row groups before
+// and after it. The first row is returned with the others as its duplicates.
+func locateTraceRows(ctx context.Context, pf *parquet.File, index *rowGroupIndex, colIndex int, traceID common.ID, rowGroup int, opts common.SearchOptions, budget *readBudget) (traceLocation, bool, error) {
+	first, err := index.firstRowGroupOf(traceID, rowGroup)
+	if err != nil {
+		return traceLocation{}, false, err
+	}
+
+	offset := int64(0)
+	for _, rg := range pf.RowGroups()[0:first] {
+		offset += rg.NumRows()
+	}
+
+	var rows []traceLocation
+	for rg := first; rg < index.numRowGroups(); rg++ {
+		if rg > rowGroup {
+			// the trace continues only in row groups that start with it
+			min, err := index.min(rg)
+			if err != nil {
+				return traceLocation{}, false, err
+			}
+			if !bytes.Equal(min, traceID) {
+				break
+			}
 		}
 
-		pages := pf.RowGroups()[rgIdx].ColumnChunks()[colIndex].Pages()
-		defer pages.Close()
+		iter := parquetquery.NewColumnIterator(ctx, pf.RowGroups()[rg:rg+1], colIndex, "", 1000, parquetquery.NewStringEqualPredicate(traceID), "", pq.WithReadAhead(opts.ReadAheadPages))
+		for {
+			res, err := iter.Next()
+			if err != nil {
+				iter.Close()
+				return traceLocation{}, false, err
+			}
+			if res == nil {
+				break
+			}
+			rows = append(rows, traceLocation{
+				rowGroup: rg,
+				row:      res.RowNumber,
+				offset:   offset + res.RowNumber[0],
+			})
+		}
+		iter.Close()
+		offset += pf.RowGroups()[rg].NumRows()
+	}
+
+	if err := budget.check("trace ID scan"); err != nil {
+		return traceLocation{}, false, err
+	}
+	if len(rows) == 0 {
+		return traceLocation{}, false, nil
+	}
+
+	loc := rows[0]
+	loc.duplicates = rows[1:]
+	return loc, true, nil
+}
+
+// readBudget tracks the bytes read by a lookup against SearchOptions.MaxInspectedBytes. A nil
+// budget is unlimited. Bytes are counted from the reader of the parquet file, which the lookup opens
+// for itself, plus the blooms that are read separately.
+type readBudget struct {
+	max   uint64
+	bloom uint64
+
+	rr    *BackendReaderAt
+	start uint64
+}
+
+func newReadBudget(opts common.SearchOptions) *readBudget {
+	if opts.MaxInspectedBytes == 0 {
+		return nil
+	}
+	return &readBudget{max: opts.MaxInspectedBytes}
+}
+
+func (r *readBudget) addBloom(n int) {
+	if r != nil {
+		r.bloom += uint64(n)
+	}
+}
+
+// setReader starts counting the bytes read by rr from now on.
+func (r *readBudget) setReader(rr *BackendReaderAt) {
+	if r != nil {
+		r.rr = rr
+		r.start = rr.TotalBytesRead.Load()
+	}
+}
+
+func (r *readBudget) inspected() uint64 {
+	n := r.bloom
+	if r.rr != nil {
+		n += r.rr.TotalBytesRead.Load() - r.start
+	}
+	return n
+}
+
+// check returns an error if the budget is exceeded. The stage names the read that just finished.
+func (r *readBudget) check(stage string) error {
+	if r == nil {
+		return nil
+	}
+	if n := r.inspected(); n > r.max {
+		return fmt.Errorf("%w: read %d bytes of %d after %s", common.ErrInspectedBytesBudgetExceeded, n, r.max, stage)
+	}
+	return nil
+}
+
+// traceOverlapsWindow reads the start and end time of the trace at loc and checks them against the
+// time window in opts.
+func traceOverlapsWindow(ctx context.Context, pf *parquet.File, loc traceLocation, opts common.SearchOptions) (bool, error) {
+	makeIter := makeIterFunc(ctx, pf.RowGroups()[loc.rowGroup:loc.rowGroup+1], pf)
+
+	iter := pq.NewJoinIterator(DefinitionLevelTrace, []pq.Iterator{
+		&rowNumberIterator{rowNumbers: []pq.RowNumber{loc.row}},
+		makeIter("StartTimeUnixNano", nil, "StartTimeUnixNano"),
+		makeIter("EndTimeUnixNano", nil, "EndTimeUnixNano"),
+	}, nil)
+	defer iter.Close()
+
+	match, err := iter.Next()
+	if err != nil {
+		return false, errors.Wrap(err, "reading trace times")
+	}
+	if match == nil {
+		return false, fmt.Errorf("trace times not found at row %d", loc.offset)
+	}
+
+	matchMap := match.ToMap()
+	start := matchMap["StartTimeUnixNano"][0].Uint64()
+	end := matchMap["EndTimeUnixNano"][0].Uint64()
+
+	if opts.TimeWindowEndUnixNano != 0 && start > opts.TimeWindowEndUnixNano {
+		return false, nil
+	}
+	if opts.TimeWindowStartUnixNano != 0 && end < opts.TimeWindowStartUnixNano {
+		return false, nil
+	}
+	return true, nil
+}
+
+// traceHasService checks with a predicate on the service name column whether any batch of the
+// trace at loc has the service name, without reading the rest of the trace.
+func traceHasService(ctx context.Context, pf *parquet.File, loc traceLocation, service string) (bool, error) {
+	makeIter := makeIterFunc(ctx, pf.RowGroups()[loc.rowGroup:loc.rowGroup+1], pf)
+
+	iter := pq.NewJoinIterator(DefinitionLevelTrace, []pq.Iterator{
+		&rowNumberIterator{rowNumbers: []pq.RowNumber{loc.row}},
+		makeIter(columnPathResourceServiceName, pq.NewStringInPredicate([]string{service}), ""),
+	}, nil)
+	defer iter.Close()
+
+	match, err := iter.Next()
+	if err != nil {
+		return false, errors.Wrap(err, "reading service names")
+	}
+	return match != nil, nil
+}
+
+// readTrace reads the next row from r applying the ServiceName filter and the MaxSpansPerTrace
+// limit in opts. The limit applies to the spans of the service.
+func readTrace(r *parquet.Reader, traceID common.ID, opts common.SearchOptions, span opentracing.Span) (*Trace, error) {
+	tr := new(Trace)
 
-		page, err := pages.ReadPage()
+	if opts.MaxSpansPerTrace <= 0 && opts.ServiceName == "" {
+		err := r.Read(tr)
 		if err != nil {
-			return nil, err
+			return nil, errors.Wrap(err, "error reading row from backend")
 		}
+		return tr, nil
+	}
 
-		c, err := page.Values().ReadValues(buf)
-		if err != nil && err != io.EOF {
-			return nil, err
+	discarded, err := readFilteredTrace(r, tr, opts.ServiceName, opts.MaxSpansPerTrace)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading row from backend")
+	}
+	if discarded > 0 {
+		span.LogFields(log.Message("truncated trace"), log.Int("spansDiscarded", discarded))
+		if opts.TraceTruncated != nil {
+			opts.TraceTruncated(traceID, discarded)
+		}
+	}
+	return tr, nil
+}
+
+// rowGroupIndex locates the row group containing a trace ID. Since the trace ID column is sorted
+// ascending the row group is found with a binary search over the minimum ID of each row group,
+// which is read lazily from the first page of the column chunk and cached.
+//
+// Empty row groups shouldn't be written but have been observed. They are skipped by the search and
+// inherit the minimum ID of the next row group, so they don't break the bounds of their neighbors.
+type rowGroupIndex struct {
+	rowGroups []parquet.RowGroup
+	colIndex  int
+	meta      *backend.BlockMeta
+	buf       parquet.Row
+
+	// mins[i] is the minimum ID of row group i. mins[len(row groups)] is the
+	// max ID of the block, which is inclusive unlike the others.
+	mins []common.ID
+	// nonEmpty are the indexes of the row groups that have rows
+	nonEmpty []int
+	// reads counts the mins read from the file
+	reads int
+}
+
+func newRowGroupIndex(pf *parquet.File, colIndex int, meta *backend.BlockMeta) *rowGroupIndex {
+	return newRowGroupIndexFor(pf.RowGroups(), colIndex, meta)
+}
+
+func newRowGroupIndexFor(rowGroups []parquet.RowGroup, colIndex int, meta *backend.BlockMeta) *rowGroupIndex {
+	numRowGroups := len(rowGroups)
+
+	mins := make([]common.ID, numRowGroups+1)
+	mins[0] = meta.MinID
+	mins[numRowGroups] = meta.MaxID
+
+	nonEmpty := make([]int, 0, numRowGroups)
+	for i, rg := range rowGroups {
+		if rg.NumRows() > 0 {
+			nonEmpty = append(nonEmpty, i)
 		}
-		if c < 1 {
-			return nil, fmt.Errorf("failed to read value from page: traceID: %s blockID:%v rowGroupIdx:%d", util.TraceIDToHexString(traceID), b.meta.BlockID, rgIdx)
+	}
+
+	return &rowGroupIndex{
+		rowGroups: rowGroups,
+		colIndex:  colIndex,
+		meta:      meta,
+		buf:       make(parquet.Row, 1),
+		mins:      mins,
+		nonEmpty:  nonEmpty,
+	}
+}
+
+// loadRowGroupMins sets the mins of the index that are cached on the block.
+func (b *backendBlock) loadRowGroupMins(x *rowGroupIndex) {
+	b.rowGroupMinsMtx.Lock()
+	defer b.rowGroupMinsMtx.Unlock()
+
+	if len(b.rowGroupMins) != len(x.mins) {
+		return
+	}
+	for i, min := range b.rowGroupMins {
+		if len(x.mins[i]) == 0 {
+			x.mins[i] = min
+		}
+	}
+}
+
+// storeRowGroupMins caches the mins the index read on the block. There is one per row group, so
+// the cache is bounded by the size of the block.
+func (b *backendBlock) storeRowGroupMins(x *rowGroupIndex) {
+	b.rowGroupMinsMtx.Lock()
+	defer b.rowGroupMinsMtx.Unlock()
+
+	if len(b.rowGroupMins) != len(x.mins) {
+		b.rowGroupMins = make([]common.ID, len(x.mins))
+	}
+	for i, min := range x.mins {
+		if len(min) > 0 && len(b.rowGroupMins[i]) == 0 {
+			// copied so the cache doesn't reference the page buffers
+			b.rowGroupMins[i] = append(common.ID(nil), min...)
 		}
+	}
+}
+
+func (x *rowGroupIndex) numRowGroups() int {
+	return len(x.mins) - 1
+}
 
-		min = buf[0].ByteArray()
-		rowGroupMins[rgIdx] = min
+// min gets the minimum trace ID within the row group. Since the column is sorted
+// ascending we just read the first value from the first page.
+func (x *rowGroupIndex) min(rgIdx int) (common.ID, error) {
+	min := x.mins[rgIdx]
+	if len(min) > 0 {
+		// Already loaded
 		return min, nil
 	}
 
-	rowGroup, err := binarySearch(numRowGroups, func(rgIdx int) (int, error) {
-		min, err := getRowGroupMin(rgIdx)
+	if x.rowGroups[rgIdx].NumRows() == 0 {
+		min, err := x.min(rgIdx + 1)
+		if err != nil {
+			return nil, err
+		}
+		x.mins[rgIdx] = min
+		return min, nil
+	}
+
+	pages := x.rowGroups[rgIdx].ColumnChunks()[x.colIndex].Pages()
+	defer pages.Close()
+
+	page, err := pages.ReadPage()
+	if err != nil {
+		return nil, err
+	}
+	x.reads++
+
+	c, err := page.Values().ReadValues(x.buf)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if c < 1 {
+		return nil, fmt.Errorf("failed to read value from page: blockID:%v rowGroupIdx:%d", x.meta.BlockID, rgIdx)
+	}
+
+	min = x.buf[0].ByteArray()
+	x.mins[rgIdx] = min
+	return min, nil
+}
+
+// firstRowGroupOf returns the first row group that may contain the trace ID, given the row group find
+// returned for it. A row group's rows can end with the trace ID if the next row group starts with it.
+func (x *rowGroupIndex) firstRowGroupOf(traceID common.ID, rowGroup int) (int, error) {
+	for rowGroup > 0 {
+		min, err := x.min(rowGroup)
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.Equal(min, traceID) {
+			break
+		}
+		rowGroup--
+	}
+	return rowGroup, nil
+}
+
+// find returns the index of the row group that may contain the trace ID, or -1 if it's outside
+// the bounds of every row group. Only row groups from the given index onwards are searched.
+func (x *rowGroupIndex) find(traceID common.ID, from int) (int, error) {
+	// search the non-empty row groups only
+	first := sort.SearchInts(x.nonEmpty, from)
+	numRowGroups := len(x.nonEmpty)
+
+	rowGroup, err := binarySearch(numRowGroups-first, func(i int) (int, error) {
+		pos := i + first
+		rgIdx := x.nonEmpty[pos]
+
+		min, err := x.min(rgIdx)
 		if err != nil {
 			return 0, err
 		}
@@ -134,7 +672,7 @@ func (b *backendBlock) FindTraceByID(ctx context.Context, traceID common.ID, opt
 			return check, nil
 		}
 
-		max, err := getRowGroupMin(rgIdx + 1)
+		max, err := x.min(rgIdx + 1)
 		if err != nil {
 			return 0, err
 		}
@@ -142,7 +680,7 @@ func (b *backendBlock) FindTraceByID(ctx context.Context, traceID common.ID, opt
 		// This is actually the min of the next group, so check is exclusive not inclusive like min
 		// Except for the last group, it is inclusive
 		check := bytes.Compare(traceID, max)
-		if check > 0 || (check == 0 && rgIdx < (numRowGroups-1)) {
+		if check > 0 || (check == 0 && pos < (numRowGroups-1)) {
 			// Trace is after this group
 			return 1, nil
 		}
@@ -150,56 +688,219 @@ func (b *backendBlock) FindTraceByID(ctx context.Context, traceID common.ID, opt
 		// Must be in this group
 		return 0, nil
 	})
+	if err != nil || rowGroup == -1 {
+		return rowGroup, err
+	}
 
-	if err != nil {
-		return nil, errors.Wrap(err, "error binary searching row groups")
+	return x.nonEmpty[rowGroup+first], nil
+}
+
+// readFilteredTrace reads the next row from r into tr keeping only the batches of the service and
+// at most maxSpans spans. An empty service keeps all batches and a maxSpans of 0 or less all spans.
+// The raw row is filtered before it is reconstructed so the dropped spans are never turned into Go
+// values. Returns the number of spans that were discarded by maxSpans.
+func readFilteredTrace(r *parquet.Reader, tr *Trace, service string, maxSpans int) (int, error) {
+	rows := []parquet.Row{nil}
+	n, err := r.ReadRows(rows)
+	if n == 0 {
+		if err == nil {
+			err = io.EOF
+		}
+		return 0, err
 	}
 
-	if rowGroup == -1 {
-		// Not within the bounds of any row group
-		return nil, nil
+	row := rows[0]
+	if service != "" {
+		row = filterTraceRowByService(r.Schema(), row, service)
 	}
 
-	// Now iterate the matching row group
-	iter := parquetquery.NewColumnIterator(derivedCtx, pf.RowGroups()[rowGroup:rowGroup+1], colIndex, "", 1000, parquetquery.NewStringInPredicate([]string{string(traceID)}), "")
-	defer iter.Close()
+	discarded := 0
+	if maxSpans > 0 {
+		row, discarded = truncateTraceRow(r.Schema(), row, maxSpans)
+	}
+	return discarded, r.Schema().Reconstruct(tr, row)
+}
 
-	res, err := iter.Next()
-	if err != nil {
-		return nil, err
+// filterTraceRowByService drops the batches of a trace row whose resource service name isn't
+// service. The batch of every value is tracked per column from its repetition level and the first
+// kept value of each column starts the row. Every batch has values in all of its columns, so if no
+// batch is kept the columns of the batches get an empty list instead.
+func filterTraceRowByService(schema *parquet.Schema, row parquet.Row, service string) parquet.Row {
+	serviceCol, ok := schema.Lookup("rs", "Resource", "ServiceName")
+	if !ok {
+		return row
 	}
-	if res == nil {
-		// TraceID not found in this block
-		return nil, nil
+
+	columns := schema.Columns()
+	positions := make([]traceRowPosition, len(columns))
+	seen := make([]bool, len(columns))
+
+	var keep []bool
+	for _, v := range row {
+		c := v.Column()
+		if c != serviceCol.ColumnIndex {
+			continue
+		}
+		positions[c].advance(v, !seen[c], 1)
+		seen[c] = true
+
+		if v.DefinitionLevel() == serviceCol.MaxDefinitionLevel {
+			keep = append(keep, v.String() == service)
+		}
 	}
 
-	// The row number coming out of the iterator is relative,
-	// so offset it using the num rows in all previous groups
-	rowMatch := int64(0)
-	for _, rg := range pf.RowGroups()[0:rowGroup] {
-		rowMatch += rg.NumRows()
+	for i := range seen {
+		seen[i] = false
 	}
-	rowMatch += res.RowNumber[0]
 
-	// seek to row and read
-	r := parquet.NewReader(pf)
-	err = r.SeekToRow(rowMatch)
-	if err != nil {
-		return nil, errors.Wrap(err, "seek to row")
+	filtered := make(parquet.Row, 0, len(row))
+	started := make([]bool, len(columns))
+	anyKept := false
+	for _, v := range row {
+		c := v.Column()
+		depth := traceRowDepth(columns[c])
+		positions[c].advance(v, !seen[c], depth)
+		seen[c] = true
+
+		switch {
+		case depth == 0:
+			filtered = append(filtered, v)
+		case positions[c][0] < len(keep) && keep[positions[c][0]]:
+			if !started[c] {
+				v = v.Level(0, v.DefinitionLevel(), c)
+				started[c] = true
+			}
+			anyKept = true
+			filtered = append(filtered, v)
+		}
 	}
 
-	span.LogFields(log.Message("seeked to row"), log.Int64("row", rowMatch))
+	if !anyKept {
+		for c := range columns {
+			if traceRowDepth(columns[c]) > 0 {
+				filtered = append(filtered, parquet.Value{}.Level(0, 0, c))
+			}
+		}
+		// each column has a single value now, so column order is the order of the row
+		sort.SliceStable(filtered, func(i, j int) bool {
+			return filtered[i].Column() < filtered[j].Column()
+		})
+	}
 
-	tr := new(Trace)
-	err = r.Read(tr)
-	if err != nil {
-		return nil, errors.Wrap(err, "error reading row from backend")
+	return filtered
+}
+
+// truncateTraceRow drops all spans after the first maxSpans from a trace row. Batches and scope
+// spans that only contained dropped spans are removed as well. The position of each value in the
+// rs/ils/Spans hierarchy is tracked per column from its repetition level and every value at or after
+// the first dropped span is removed. Returns the truncated row and the number of spans dropped.
+func truncateTraceRow(schema *parquet.Schema, row parquet.Row, maxSpans int) (parquet.Row, int) {
+	spanIDCol, ok := schema.Lookup("rs", "ils", "Spans", "ID")
+	if !ok {
+		return row, 0
 	}
 
-	span.LogFields(log.Message("read trace"))
+	columns := schema.Columns()
+	positions := make([]traceRowPosition, len(columns))
+	seen := make([]bool, len(columns))
+
+	// Find the position of the first span to drop
+	var (
+		cut   traceRowPosition
+		found bool
+		spans int
+	)
+	for _, v := range row {
+		c := v.Column()
+		if c != spanIDCol.ColumnIndex {
+			continue
+		}
+		positions[c].advance(v, !seen[c], 3)
+		seen[c] = true
 
-	// convert to proto trace and return
-	return parquetTraceToTempopbTrace(tr), nil
+		if v.DefinitionLevel() != spanIDCol.MaxDefinitionLevel {
+			// scope spans without any spans
+			continue
+		}
+		spans++
+		if spans == maxSpans+1 {
+			cut = positions[c]
+			found = true
+		}
+	}
+
+	if !found {
+		return row, 0
+	}
+
+	for i := range seen {
+		seen[i] = false
+	}
+
+	truncated := row[:0]
+	for _, v := range row {
+		c := v.Column()
+		depth := traceRowDepth(columns[c])
+		positions[c].advance(v, !seen[c], depth)
+		seen[c] = true
+
+		if positions[c].before(cut, depth) {
+			truncated = append(truncated, v)
+		}
+	}
+
+	return truncated, spans - maxSpans
+}
+
+// traceRowPosition is the index of a value's batch, scope spans and span within a trace row.
+type traceRowPosition [3]int
+
+// advance moves the position to the given value. Repetition levels 1 to depth map to
+// rs, ils and Spans. Deeper levels are nested within the current span and ignored.
+func (p *traceRowPosition) advance(v parquet.Value, first bool, depth int) {
+	if first {
+		*p = traceRowPosition{}
+		return
+	}
+
+	r := v.RepetitionLevel()
+	if r < 1 || r > depth {
+		return
+	}
+	p[r-1]++
+	for i := r; i < len(p); i++ {
+		p[i] = 0
+	}
+}
+
+// before returns true if a value at this position in a column nested depth levels deep comes
+// before the cut and should be kept. Containers that hold the cut are kept only if they also
+// hold something before it.
+func (p traceRowPosition) before(cut traceRowPosition, depth int) bool {
+	for i := 0; i < depth; i++ {
+		if p[i] != cut[i] {
+			return p[i] < cut[i]
+		}
+	}
+	for i := depth; i < len(cut); i++ {
+		if cut[i] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// traceRowDepth returns how many levels of the rs/ils/Spans hierarchy a column is nested in.
+func traceRowDepth(path []string) int {
+	switch {
+	case len(path) > 3 && path[0] == "rs" && path[1] == "ils" && path[2] == "Spans":
+		return 3
+	case len(path) > 2 && path[0] == "rs" && path[1] == "ils":
+		return 2
+	case len(path) > 1 && path[0] == "rs":
+		return 1
+	}
+	return 0
 }
 
 // binarySearch that finds exact matching entry. Returns non-zero index when found, or -1 when not found
@@ -0,0 +1,97 @@
+package vparquet
+
+import (
+	"context"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/grafana/tempo/pkg/boundedwaitgroup"
+	"github.com/grafana/tempo/pkg/model/trace"
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// FindTraceByIDMulti looks up the trace in all blocks concurrently and combines the partial traces
+// that are found. With opts.MultiBlockFoundLimit the lookup stops early once that many blocks
+// returned the trace, blocks that weren't searched by then are skipped. The first error fails the
+// whole lookup unless the limit was already reached. Returns nil if no block has the trace.
+func FindTraceByIDMulti(ctx context.Context, blocks []*backendBlock, traceID common.ID, opts common.SearchOptions) (*tempopb.Trace, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "parquet.FindTraceByIDMulti",
+		opentracing.Tags{
+			"blocks": len(blocks),
+		})
+	defer span.Finish()
+
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+
+	concurrency := opts.MultiBlockConcurrency
+	if concurrency <= 0 || concurrency > len(blocks) {
+		concurrency = len(blocks)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mtx      sync.Mutex
+		combiner = trace.NewCombiner()
+		found    int
+		done     bool
+		firstErr error
+	)
+
+	bg := boundedwaitgroup.New(uint(concurrency))
+	for _, b := range blocks {
+		bg.Add(1)
+		if ctx.Err() != nil {
+			bg.Done()
+			break
+		}
+
+		go func(b *backendBlock) {
+			defer bg.Done()
+
+			tr, err := b.FindTraceByID(ctx, traceID, opts)
+
+			mtx.Lock()
+			defer mtx.Unlock()
+
+			// lookups that were cancelled after the limit was reached don't count
+			if done {
+				return
+			}
+			if err != nil {
+				firstErr = err
+				done = true
+				cancel()
+				return
+			}
+			if tr == nil {
+				return
+			}
+
+			combiner.Consume(tr)
+			found++
+			if opts.MultiBlockFoundLimit > 0 && found >= opts.MultiBlockFoundLimit {
+				done = true
+				cancel()
+			}
+		}(b)
+	}
+	bg.Wait()
+
+	span.SetTag("found", found)
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if found == 0 {
+		return nil, nil
+	}
+
+	tr, _ := combiner.Result()
+	return tr, nil
+}
@@ -0,0 +1,71 @@
+package vparquet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestFindTraceByIDMulti(t *testing.T) {
+	id := test.ValidTraceID(nil)
+
+	makeTrace := func(id common.ID, spanID string, start uint64) *Trace {
+		return &Trace{
+			TraceID: id,
+			ResourceSpans: []ResourceSpans{{
+				Resource: Resource{ServiceName: "svc"},
+				ScopeSpans: []ScopeSpan{{
+					Spans: []Span{{ID: []byte(spanID), ParentSpanID: []byte{}, StartUnixNanos: start, EndUnixNanos: start + 1}},
+				}},
+			}},
+		}
+	}
+
+	// two of the blocks have a part of the trace
+	blocks := []*backendBlock{
+		makeBackendBlockWithTraces(t, []*Trace{makeTrace(id, "a", 1)}),
+		makeBackendBlockWithTraces(t, []*Trace{makeTrace(test.ValidTraceID(nil), "x", 1)}),
+		makeBackendBlockWithTraces(t, []*Trace{makeTrace(id, "b", 2)}),
+	}
+	ctx := context.Background()
+
+	spanIDs := func(opts common.SearchOptions) []string {
+		tr, err := FindTraceByIDMulti(ctx, blocks, id, opts)
+		require.NoError(t, err)
+		require.NotNil(t, tr)
+
+		var ids []string
+		for _, b := range tr.Batches {
+			for _, ss := range b.ScopeSpans {
+				for _, s := range ss.Spans {
+					ids = append(ids, string(s.SpanId))
+				}
+			}
+		}
+		return ids
+	}
+
+	require.Equal(t, []string{"a", "b"}, spanIDs(common.SearchOptions{}))
+	require.Equal(t, []string{"a", "b"}, spanIDs(common.SearchOptions{MultiBlockConcurrency: 1}))
+	require.Equal(t, []string{"a", "b"}, spanIDs(common.SearchOptions{MultiBlockFoundLimit: 2}))
+
+	// searched one block at a time, the first block is enough
+	require.Equal(t, []string{"a"}, spanIDs(common.SearchOptions{MultiBlockConcurrency: 1, MultiBlockFoundLimit: 1}))
+
+	// the first match wins
+	ids := spanIDs(common.SearchOptions{MultiBlockFoundLimit: 1})
+	require.Len(t, ids, 1)
+	require.Contains(t, []string{"a", "b"}, ids[0])
+
+	tr, err := FindTraceByIDMulti(ctx, blocks[1:2], id, common.SearchOptions{})
+	require.NoError(t, err)
+	require.Nil(t, tr)
+
+	tr, err = FindTraceByIDMulti(ctx, nil, id, common.SearchOptions{})
+	require.NoError(t, err)
+	require.Nil(t, tr)
+}
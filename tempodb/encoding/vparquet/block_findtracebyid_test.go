@@ -3,15 +3,30 @@ package vparquet
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"math"
 	"path"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/gogo/protobuf/proto"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/willf/bloom"
+
+	"github.com/segmentio/parquet-go"
 
 	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/pkg/model/trace"
+	pq "github.com/grafana/tempo/pkg/parquetquery"
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
 	"github.com/grafana/tempo/pkg/util/test"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/backend/local"
@@ -99,6 +114,609 @@ func TestBackendBlockFindTraceByID(t *testing.T) {
 		gotProto, err := b.FindTraceByID(ctx, tr.TraceID, common.SearchOptions{})
 		require.NoError(t, err)
 		require.Equal(t, wantProto, gotProto)
+
+		// read-ahead doesn't change the results
+		gotProto, err = b.FindTraceByID(ctx, tr.TraceID, common.SearchOptions{ReadAheadPages: 2})
+		require.NoError(t, err)
+		require.Equal(t, wantProto, gotProto)
+	}
+}
+
+func TestBackendBlockFindTraceByIDWithLocation(t *testing.T) {
+	var traces []*Trace
+	for i := 0; i < 150; i++ {
+		traces = append(traces, &Trace{TraceID: test.ValidTraceID(nil), RootSpanName: fmt.Sprintf("root-%d", i)})
+	}
+	sort.Slice(traces, func(i, j int) bool {
+		return bytes.Compare(traces[i].TraceID, traces[j].TraceID) == -1
+	})
+
+	// row groups of 1, 100 and 49 traces
+	b := makeBackendBlockWithTraces(t, traces)
+	ctx := context.Background()
+
+	pf, _, err := b.openForSearch(ctx, common.SearchOptions{})
+	require.NoError(t, err)
+
+	for i, tr := range traces {
+		got, loc, err := b.FindTraceByIDWithLocation(ctx, tr.TraceID, common.SearchOptions{})
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		require.Equal(t, int64(i), loc.RowNumber)
+
+		var rowGroupStart int64
+		for _, rg := range pf.RowGroups()[:loc.RowGroup] {
+			rowGroupStart += rg.NumRows()
+		}
+		require.GreaterOrEqual(t, loc.RowNumber, rowGroupStart)
+		require.Less(t, loc.RowNumber, rowGroupStart+pf.RowGroups()[loc.RowGroup].NumRows())
+
+		// the location reads the same trace again
+		r := parquet.NewReader(pf, parquet.SchemaOf(new(Trace)))
+		require.NoError(t, r.SeekToRow(loc.RowNumber))
+		reread := new(Trace)
+		require.NoError(t, r.Read(reread))
+		require.Equal(t, tr.TraceID, reread.TraceID)
+		require.Equal(t, tr.RootSpanName, reread.RootSpanName)
+	}
+
+	got, loc, err := b.FindTraceByIDWithLocation(ctx, test.ValidTraceID(nil), common.SearchOptions{})
+	require.NoError(t, err)
+	require.Nil(t, got)
+	require.Equal(t, TraceLocation{}, loc)
+}
+
+func TestBackendBlockFindTraceByIDTimeWindow(t *testing.T) {
+	tr := &Trace{
+		TraceID:           test.ValidTraceID(nil),
+		StartTimeUnixNano: uint64(100 * time.Second),
+		EndTimeUnixNano:   uint64(110 * time.Second),
+		ResourceSpans: []ResourceSpans{{
+			Resource:   Resource{ServiceName: "s"},
+			ScopeSpans: []ScopeSpan{{Spans: []Span{{Name: "hello", ID: []byte{}, ParentSpanID: []byte{}}}}},
+		}},
+	}
+	b := makeBackendBlockWithTraces(t, []*Trace{tr})
+	ctx := context.Background()
+
+	tcs := []struct {
+		name       string
+		start, end time.Duration
+		found      bool
+	}{
+		{name: "no window", found: true},
+		{name: "contains trace", start: 90 * time.Second, end: 120 * time.Second, found: true},
+		{name: "overlaps start", start: 90 * time.Second, end: 100 * time.Second, found: true},
+		{name: "overlaps end", start: 110 * time.Second, end: 120 * time.Second, found: true},
+		{name: "within trace", start: 105 * time.Second, end: 106 * time.Second, found: true},
+		{name: "open start", end: 105 * time.Second, found: true},
+		{name: "open end", start: 105 * time.Second, found: true},
+		{name: "before", start: 10 * time.Second, end: 99 * time.Second},
+		{name: "after", start: 111 * time.Second, end: 120 * time.Second},
+		{name: "open start before", end: 99 * time.Second},
+		{name: "open end after", start: 111 * time.Second},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := b.FindTraceByID(ctx, tr.TraceID, common.SearchOptions{
+				TimeWindowStartUnixNano: uint64(tc.start),
+				TimeWindowEndUnixNano:   uint64(tc.end),
+			})
+			require.NoError(t, err)
+			if tc.found {
+				require.Equal(t, parquetTraceToTempopbTrace(tr), got)
+			} else {
+				require.Nil(t, got)
+			}
+		})
+	}
+}
+
+func TestBackendBlockFindTraceByIDCompleteness(t *testing.T) {
+	makeTrace := func(id byte, spans ...Span) *Trace {
+		return &Trace{
+			TraceID: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, id},
+			ResourceSpans: []ResourceSpans{{
+				Resource:   Resource{ServiceName: "s"},
+				ScopeSpans: []ScopeSpan{{Spans: spans}},
+			}},
+		}
+	}
+
+	complete := makeTrace(1,
+		Span{ID: []byte{1}, ParentSpanID: []byte{}},
+		Span{ID: []byte{2}, ParentSpanID: []byte{1}},
+	)
+	// the root span is in another block
+	partial := makeTrace(2,
+		Span{ID: []byte{2}, ParentSpanID: []byte{1}},
+		Span{ID: []byte{3}, ParentSpanID: []byte{2}},
+	)
+	b := makeBackendBlockWithTraces(t, []*Trace{complete, partial})
+	ctx := context.Background()
+
+	find := func(id []byte, opts common.SearchOptions) map[string]bool {
+		reported := map[string]bool{}
+		opts.TraceCompleteness = func(id common.ID, complete bool) {
+			reported[string(id)] = complete
+		}
+		_, err := b.FindTraceByID(ctx, id, opts)
+		require.NoError(t, err)
+		return reported
+	}
+
+	require.Equal(t, map[string]bool{string(complete.TraceID): true}, find(complete.TraceID, common.SearchOptions{}))
+	require.Equal(t, map[string]bool{string(partial.TraceID): false}, find(partial.TraceID, common.SearchOptions{}))
+
+	// the root span was dropped
+	reversed := makeTrace(3,
+		Span{ID: []byte{2}, ParentSpanID: []byte{1}},
+		Span{ID: []byte{1}, ParentSpanID: []byte{}},
+	)
+	b = makeBackendBlockWithTraces(t, []*Trace{reversed})
+	require.Equal(t, map[string]bool{string(reversed.TraceID): false}, find(reversed.TraceID, common.SearchOptions{MaxSpansPerTrace: 1}))
+	require.Equal(t, map[string]bool{string(reversed.TraceID): true}, find(reversed.TraceID, common.SearchOptions{}))
+
+	// not found traces aren't reported
+	require.Empty(t, find([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 9}, common.SearchOptions{}))
+}
+
+func TestBackendBlockFindTraceByIDDuplicateTraceIDs(t *testing.T) {
+	makeTrace := func(id byte, spanIDs ...byte) *Trace {
+		tr := &Trace{
+			TraceID:       []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, id},
+			ResourceSpans: []ResourceSpans{{Resource: Resource{ServiceName: "s"}, ScopeSpans: []ScopeSpan{{}}}},
+		}
+		for _, spanID := range spanIDs {
+			tr.ResourceSpans[0].ScopeSpans[0].Spans = append(tr.ResourceSpans[0].ScopeSpans[0].Spans, Span{ID: []byte{spanID}})
+		}
+		return tr
+	}
+	spanIDs := func(tr *tempopb.Trace) []byte {
+		var ids []byte
+		for _, b := range tr.Batches {
+			for _, ils := range b.ScopeSpans {
+				for _, s := range ils.Spans {
+					ids = append(ids, s.SpanId...)
+				}
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		return ids
+	}
+
+	// the block has two rows for trace 1 that share span 2. The block is flushed after the first
+	// trace, so the rows are in different row groups.
+	b := makeBackendBlockWithTraces(t, []*Trace{makeTrace(1, 1, 2), makeTrace(1, 2, 3), makeTrace(2, 4)})
+	ctx := context.Background()
+	id := makeTrace(1).TraceID
+
+	// and in the same row group
+	sameRowGroup := makeBackendBlockWithTraces(t, []*Trace{makeTrace(0, 5), makeTrace(1, 1, 2), makeTrace(1, 2, 3), makeTrace(2, 4)})
+	tr, err := sameRowGroup.FindTraceByID(ctx, id, common.SearchOptions{DuplicateTraceIDs: common.DuplicateTraceIDsMerge})
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, spanIDs(tr))
+
+	// a single row by default, which one depends on the order the block was written in
+	before := testutil.ToFloat64(metricDuplicateTraceIDs)
+	tr, err = b.FindTraceByID(ctx, id, common.SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, spanIDs(tr), 2)
+	require.Equal(t, before, testutil.ToFloat64(metricDuplicateTraceIDs))
+
+	_, err = b.FindTraceByID(ctx, id, common.SearchOptions{DuplicateTraceIDs: common.DuplicateTraceIDsError})
+	require.ErrorIs(t, err, common.ErrDuplicateTraceID)
+	require.Equal(t, before+1, testutil.ToFloat64(metricDuplicateTraceIDs))
+
+	tr, err = b.FindTraceByID(ctx, id, common.SearchOptions{DuplicateTraceIDs: common.DuplicateTraceIDsMerge})
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, spanIDs(tr))
+	require.Equal(t, before+2, testutil.ToFloat64(metricDuplicateTraceIDs))
+
+	// traces with a single row aren't reported
+	for _, handling := range []common.DuplicateTraceIDs{common.DuplicateTraceIDsError, common.DuplicateTraceIDsMerge} {
+		tr, err = b.FindTraceByID(ctx, makeTrace(2).TraceID, common.SearchOptions{DuplicateTraceIDs: handling})
+		require.NoError(t, err)
+		require.Equal(t, []byte{4}, spanIDs(tr))
+	}
+	require.Equal(t, before+2, testutil.ToFloat64(metricDuplicateTraceIDs))
+}
+
+func TestRowGroupIndexEmptyRowGroups(t *testing.T) {
+	var traces []*Trace
+	for i := 0; i < 150; i++ {
+		traces = append(traces, &Trace{TraceID: test.ValidTraceID(nil)})
+	}
+	sort.Slice(traces, func(i, j int) bool {
+		return bytes.Compare(traces[i].TraceID, traces[j].TraceID) == -1
+	})
+
+	// row groups of 1, 100 and 49 traces
+	b := makeBackendBlockWithTraces(t, traces)
+	pf, _, err := b.openForSearch(context.Background(), common.SearchOptions{})
+	require.NoError(t, err)
+	colIndex, _ := pq.GetColumnIndexByPath(pf, TraceIDColumnName)
+
+	// surround the row groups with empty ones: empty, 1, empty, empty, 100, 49, empty
+	empty := func() parquet.RowGroup { return parquet.NewBuffer(pf.Schema()) }
+	rgs := pf.RowGroups()
+	withEmpty := []parquet.RowGroup{empty(), rgs[0], empty(), empty(), rgs[1], rgs[2], empty()}
+
+	index := newRowGroupIndexFor(withEmpty, colIndex, b.meta)
+
+	tcs := []struct {
+		id       common.ID
+		from     int
+		expected int
+	}{
+		{id: traces[0].TraceID, expected: 1},
+		{id: traces[1].TraceID, expected: 4}, // adjacent to the empty groups
+		{id: traces[50].TraceID, expected: 4},
+		{id: traces[101].TraceID, expected: 5},
+		{id: traces[149].TraceID, expected: 5}, // the max of the block is in the last non-empty group
+		{id: traces[50].TraceID, from: 2, expected: 4},
+		{id: traces[0].TraceID, from: 2, expected: -1},
+		{id: common.ID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, expected: -1},
+	}
+	for i, tc := range tcs {
+		rg, err := index.find(tc.id, tc.from)
+		require.NoError(t, err, "case %d", i)
+		require.Equal(t, tc.expected, rg, "case %d", i)
+	}
+
+	// empty row groups inherit the min of the next row group
+	for i, next := range map[int]int{2: 4, 3: 4} {
+		min, err := index.min(i)
+		require.NoError(t, err)
+		nextMin, err := index.min(next)
+		require.NoError(t, err)
+		require.Equal(t, nextMin, min)
+	}
+	max, err := index.min(6)
+	require.NoError(t, err)
+	require.Equal(t, common.ID(b.meta.MaxID), max)
+}
+
+func TestBackendBlockFindTraceByIDMaxInspectedBytes(t *testing.T) {
+	tr := &Trace{
+		TraceID: test.ValidTraceID(nil),
+		ResourceSpans: []ResourceSpans{{
+			Resource:   Resource{ServiceName: "s"},
+			ScopeSpans: []ScopeSpan{{Spans: []Span{{Name: "hello", ID: []byte{}, ParentSpanID: []byte{}}}}},
+		}},
+	}
+	b := makeBackendBlockWithTraces(t, []*Trace{tr})
+	ctx := context.Background()
+
+	// unlimited by default
+	got, err := b.FindTraceByID(ctx, tr.TraceID, common.SearchOptions{})
+	require.NoError(t, err)
+	require.Equal(t, parquetTraceToTempopbTrace(tr), got)
+
+	got, err = b.FindTraceByID(ctx, tr.TraceID, common.SearchOptions{MaxInspectedBytes: 1 << 30})
+	require.NoError(t, err)
+	require.Equal(t, parquetTraceToTempopbTrace(tr), got)
+
+	// the bloom alone is larger than this
+	_, err = b.FindTraceByID(ctx, tr.TraceID, common.SearchOptions{MaxInspectedBytes: 1})
+	require.ErrorIs(t, err, common.ErrInspectedBytesBudgetExceeded)
+	require.Regexp(t, `read \d+ bytes of 1 after bloom`, err.Error())
+}
+
+func TestBackendBlockFindTraceByIDMaxInspectedBytesOwnReader(t *testing.T) {
+	var traces []*Trace
+	for i := 0; i < 300; i++ {
+		traces = append(traces, &Trace{TraceID: test.ValidTraceID(nil), RootSpanName: fmt.Sprintf("root-%d", i)})
+	}
+	sort.Slice(traces, func(i, j int) bool {
+		return bytes.Compare(traces[i].TraceID, traces[j].TraceID) == -1
+	})
+	written := makeBackendBlockWithTraces(t, traces)
+	ctx := context.Background()
+	budgeted := common.SearchOptions{MaxInspectedBytes: 1 << 30}
+
+	// lookups with a budget read the file with readers of their own, so they don't count the bytes
+	// of other lookups of the block
+	b := newBackendBlock(written.meta, written.r)
+	_, err := b.FindTraceByID(ctx, traces[150].TraceID, budgeted)
+	require.NoError(t, err)
+	require.Empty(t, b.opened)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			got, err := b.FindTraceByID(ctx, traces[i*70].TraceID, budgeted)
+			assert.NoError(t, err)
+			assert.Equal(t, parquetTraceToTempopbTrace(traces[i*70]), got)
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			got, err := b.FindTraceByID(ctx, traces[i*70+1].TraceID, common.SearchOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, parquetTraceToTempopbTrace(traces[i*70+1]), got)
+		}(i)
+	}
+	wg.Wait()
+	require.Len(t, b.opened, 1)
+}
+
+func TestReadBudget(t *testing.T) {
+	var unlimited *readBudget
+	unlimited.addBloom(10)
+	unlimited.setReader(&BackendReaderAt{})
+	require.NoError(t, unlimited.check("bloom"))
+	require.Nil(t, newReadBudget(common.SearchOptions{}))
+
+	// bytes read before the lookup started aren't counted
+	rr := &BackendReaderAt{}
+	rr.TotalBytesRead.Store(100)
+
+	budget := newReadBudget(common.SearchOptions{MaxInspectedBytes: 60})
+	budget.addBloom(10)
+	budget.setReader(rr)
+	rr.TotalBytesRead.Add(50)
+	require.NoError(t, budget.check("row group mins"))
+
+	rr.TotalBytesRead.Add(1)
+	err := budget.check("trace ID scan")
+	require.ErrorIs(t, err, common.ErrInspectedBytesBudgetExceeded)
+	require.EqualError(t, err, "inspected bytes budget exceeded: read 61 bytes of 60 after trace ID scan")
+}
+
+func TestBackendBlockFindTraceByIDSpanLess(t *testing.T) {
+	// spans are written in reverse start time order, as are the batches
+	tr := &Trace{TraceID: test.ValidTraceID(nil)}
+	for b := 0; b < 2; b++ {
+		scope := ScopeSpan{}
+		for sp := 0; sp < 3; sp++ {
+			start := uint64(100*(2-b) - 10*sp)
+			scope.Spans = append(scope.Spans, Span{
+				ID:             []byte{byte(b), byte(sp)},
+				ParentSpanID:   []byte{},
+				StartUnixNanos: start,
+				EndUnixNanos:   start + 5,
+			})
+		}
+		tr.ResourceSpans = append(tr.ResourceSpans, ResourceSpans{
+			Resource:   Resource{ServiceName: fmt.Sprintf("s%d", b)},
+			ScopeSpans: []ScopeSpan{scope},
+		})
+	}
+
+	b := makeBackendBlockWithTraces(t, []*Trace{tr})
+	ctx := context.Background()
+
+	startTimes := func(tr *tempopb.Trace) []uint64 {
+		var starts []uint64
+		for _, b := range tr.Batches {
+			for _, ss := range b.ScopeSpans {
+				for _, s := range ss.Spans {
+					starts = append(starts, s.StartTimeUnixNano)
+				}
+			}
+		}
+		return starts
+	}
+
+	// storage order by default
+	actual, err := b.FindTraceByID(ctx, tr.TraceID, common.SearchOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []uint64{200, 190, 180, 100, 90, 80}, startTimes(actual))
+
+	opts := common.SearchOptions{}
+	opts.SpanLess = trace.SpansByStartTime
+	actual, err = b.FindTraceByID(ctx, tr.TraceID, opts)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{80, 90, 100, 180, 190, 200}, startTimes(actual))
+
+	opts.SpanLess = func(a, b *v1.Span) bool { return a.StartTimeUnixNano > b.StartTimeUnixNano }
+	byIDs, err := b.FindTracesByIDs(ctx, []common.ID{tr.TraceID}, opts)
+	require.NoError(t, err)
+	require.Len(t, byIDs, 1)
+	require.Equal(t, []uint64{200, 190, 180, 100, 90, 80}, startTimes(byIDs[0].Trace))
+}
+
+func TestBackendBlockFindTraceByIDServiceName(t *testing.T) {
+	batch := func(service string, spanIDs ...byte) ResourceSpans {
+		scope := ScopeSpan{}
+		for _, id := range spanIDs {
+			scope.Spans = append(scope.Spans, Span{
+				ID:           []byte{id},
+				ParentSpanID: []byte{},
+				Name:         fmt.Sprintf("%s-%d", service, id),
+				Attrs:        []Attribute{{Key: "id", ValueInt: &[]int64{int64(id)}[0]}},
+			})
+		}
+		return ResourceSpans{
+			Resource: Resource{
+				ServiceName: service,
+				Attrs:       []Attribute{{Key: "service", Value: &service}},
+			},
+			ScopeSpans: []ScopeSpan{scope},
+		}
+	}
+
+	// the batches of the services are interleaved and the first one is filtered out
+	tr := &Trace{
+		TraceID: test.ValidTraceID(nil),
+		ResourceSpans: []ResourceSpans{
+			batch("backend", 1, 2),
+			batch("frontend", 3),
+			batch("backend", 4),
+			batch("frontend", 5, 6),
+		},
+	}
+	b := makeBackendBlockWithTraces(t, []*Trace{tr})
+	ctx := context.Background()
+
+	find := func(opts common.SearchOptions) *tempopb.Trace {
+		actual, err := b.FindTraceByID(ctx, tr.TraceID, opts)
+		require.NoError(t, err)
+
+		byIDs, err := b.FindTracesByIDs(ctx, []common.ID{tr.TraceID}, opts)
+		require.NoError(t, err)
+		require.Len(t, byIDs, 1)
+		require.Equal(t, actual, byIDs[0].Trace)
+
+		return actual
+	}
+	expected := func(batches ...ResourceSpans) *tempopb.Trace {
+		return parquetTraceToTempopbTrace(&Trace{TraceID: tr.TraceID, ResourceSpans: batches})
+	}
+
+	require.Equal(t, expected(batch("frontend", 3), batch("frontend", 5, 6)), find(common.SearchOptions{ServiceName: "frontend"}))
+	require.Equal(t, expected(batch("backend", 1, 2), batch("backend", 4)), find(common.SearchOptions{ServiceName: "backend"}))
+	require.Equal(t, parquetTraceToTempopbTrace(tr), find(common.SearchOptions{}))
+
+	// the span limit applies to the spans of the service
+	require.Equal(t, expected(batch("frontend", 3), batch("frontend", 5)), find(common.SearchOptions{ServiceName: "frontend", MaxSpansPerTrace: 2}))
+
+	// the trace is still found without any spans
+	actual := find(common.SearchOptions{ServiceName: "db"})
+	require.NotNil(t, actual)
+	require.Empty(t, actual.Batches)
+}
+
+func TestBackendBlockFindTraceByIDMaxSpansPerTrace(t *testing.T) {
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+	ctx := context.Background()
+
+	cfg := &common.BlockConfig{
+		BloomFP:             0.01,
+		BloomShardSizeBytes: 100 * 1024,
+	}
+
+	// 2 batches of 2 scope spans of 3 spans each
+	makeTrace := func() *Trace {
+		tr := &Trace{
+			TraceID: test.ValidTraceID(nil),
+		}
+		for b := 0; b < 2; b++ {
+			bar := "bar"
+			rs := ResourceSpans{
+				Resource: Resource{
+					ServiceName: fmt.Sprintf("s%d", b),
+					Attrs:       []Attribute{{Key: "batch", Value: &bar}, {Key: "foo", Value: &bar}},
+				},
+			}
+			for ss := 0; ss < 2; ss++ {
+				scope := ScopeSpan{Scope: Scope{Name: fmt.Sprintf("scope%d", ss)}}
+				for sp := 0; sp < 3; sp++ {
+					scope.Spans = append(scope.Spans, Span{
+						ID:           []byte{byte(b), byte(ss), byte(sp)},
+						ParentSpanID: []byte{},
+						Name:         fmt.Sprintf("span-%d-%d-%d", b, ss, sp),
+						Attrs:        []Attribute{{Key: "a", Value: &bar}, {Key: "b", Value: &bar}},
+						Events:       []Event{{Name: "e", Attrs: []EventAttribute{{Key: "k", Value: []byte{1}}}}},
+					})
+				}
+				rs.ScopeSpans = append(rs.ScopeSpans, scope)
+			}
+			tr.ResourceSpans = append(tr.ResourceSpans, rs)
+		}
+		return tr
+	}
+
+	tr := makeTrace()
+
+	meta := backend.NewBlockMeta("fake", uuid.New(), VersionString, backend.EncNone, "")
+	meta.TotalObjects = 1
+	s := newStreamingBlock(ctx, cfg, meta, r, w, tempo_io.NewBufferedWriter)
+	require.NoError(t, s.Add(tr, 0, 0))
+	_, err = s.Complete()
+	require.NoError(t, err)
+
+	b := newBackendBlock(s.meta, r)
+
+	testCases := []struct {
+		name      string
+		maxSpans  int
+		expected  func(tr *Trace)
+		discarded int
+	}{
+		{
+			name:     "unlimited",
+			maxSpans: 0,
+			expected: func(tr *Trace) {},
+		},
+		{
+			name:     "limit equals span count",
+			maxSpans: 12,
+			expected: func(tr *Trace) {},
+		},
+		{
+			name:     "cut within scope spans",
+			maxSpans: 5,
+			expected: func(tr *Trace) {
+				tr.ResourceSpans = tr.ResourceSpans[:1]
+				tr.ResourceSpans[0].ScopeSpans[1].Spans = tr.ResourceSpans[0].ScopeSpans[1].Spans[:2]
+			},
+			discarded: 7,
+		},
+		{
+			name:     "cut at scope spans boundary",
+			maxSpans: 3,
+			expected: func(tr *Trace) {
+				tr.ResourceSpans = tr.ResourceSpans[:1]
+				tr.ResourceSpans[0].ScopeSpans = tr.ResourceSpans[0].ScopeSpans[:1]
+			},
+			discarded: 9,
+		},
+		{
+			name:     "cut at batch boundary",
+			maxSpans: 6,
+			expected: func(tr *Trace) {
+				tr.ResourceSpans = tr.ResourceSpans[:1]
+			},
+			discarded: 6,
+		},
+		{
+			name:     "single span",
+			maxSpans: 1,
+			expected: func(tr *Trace) {
+				tr.ResourceSpans = tr.ResourceSpans[:1]
+				tr.ResourceSpans[0].ScopeSpans = tr.ResourceSpans[0].ScopeSpans[:1]
+				tr.ResourceSpans[0].ScopeSpans[0].Spans = tr.ResourceSpans[0].ScopeSpans[0].Spans[:1]
+			},
+			discarded: 11,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			want := makeTrace()
+			want.TraceID = tr.TraceID
+			tc.expected(want)
+
+			var (
+				truncatedID common.ID
+				discarded   int
+			)
+			gotProto, err := b.FindTraceByID(ctx, tr.TraceID, common.SearchOptions{
+				MaxSpansPerTrace: tc.maxSpans,
+				TraceTruncated: func(id common.ID, spansDiscarded int) {
+					truncatedID = id
+					discarded = spansDiscarded
+				},
+			})
+			require.NoError(t, err)
+			require.Equal(t, parquetTraceToTempopbTrace(want), gotProto)
+			require.Equal(t, tc.discarded, discarded)
+			if tc.discarded > 0 {
+				require.Equal(t, common.ID(tr.TraceID), truncatedID)
+			} else {
+				require.Nil(t, truncatedID)
+			}
+		})
 	}
 }
 
@@ -140,6 +758,523 @@ func TestBackendBlockFindTraceByID_TestData(t *testing.T) {
 	}
 }
 
+// flakyReader fails the first attempt of every distinct read
+type flakyReader struct {
+	backend.Reader
+
+	mtx      sync.Mutex
+	attempts map[string]int
+}
+
+func (f *flakyReader) fail(key string) bool {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.attempts[key]++
+	return f.attempts[key] == 1
+}
+
+func (f *flakyReader) Read(ctx context.Context, name string, blockID uuid.UUID, tenantID string, shouldCache bool) ([]byte, error) {
+	if f.fail(name) {
+		return nil, errors.New("transient")
+	}
+	return f.Reader.Read(ctx, name, blockID, tenantID, shouldCache)
+}
+
+func (f *flakyReader) ReadRange(ctx context.Context, name string, blockID uuid.UUID, tenantID string, offset uint64, buffer []byte, shouldCache bool) error {
+	if f.fail(fmt.Sprintf("%s:%d:%d", name, offset, len(buffer))) {
+		return errors.New("transient")
+	}
+	return f.Reader.ReadRange(ctx, name, blockID, tenantID, offset, buffer, shouldCache)
+}
+
+func TestBackendBlockFindTraceByIDRetries(t *testing.T) {
+	rawR, _, _, err := local.New(&local.Config{
+		Path: "./test-data",
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	ctx := context.Background()
+
+	blocks, err := r.Blocks(ctx, "single-tenant")
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+
+	meta, err := r.BlockMeta(ctx, blocks[0], "single-tenant")
+	require.NoError(t, err)
+
+	iter, err := newBackendBlock(meta, r).Iterator(ctx)
+	require.NoError(t, err)
+	tr, err := iter.Next(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, tr)
+	iter.Close()
+
+	// No retries by default
+	b := newBackendBlock(meta, &flakyReader{Reader: r, attempts: map[string]int{}})
+	_, err = b.FindTraceByID(ctx, tr.TraceID, common.SearchOptions{})
+	require.Error(t, err)
+
+	b = newBackendBlock(meta, &flakyReader{Reader: r, attempts: map[string]int{}})
+	protoTr, err := b.FindTraceByID(ctx, tr.TraceID, common.SearchOptions{ReadRetries: 1})
+	require.NoError(t, err)
+	require.NotNil(t, protoTr)
+
+	// the file opened by a lookup is reused only by lookups with the same retries
+	b = newBackendBlock(meta, r)
+	_, noRetries, err := b.openForSearch(ctx, common.SearchOptions{})
+	require.NoError(t, err)
+	_, retries, err := b.openForSearch(ctx, common.SearchOptions{ReadRetries: 1})
+	require.NoError(t, err)
+	require.NotSame(t, noRetries, retries)
+	require.IsType(t, &retryReader{}, retries.r)
+	require.NotEqual(t, retries.r, noRetries.r)
+
+	_, again, err := b.openForSearch(ctx, common.SearchOptions{ReadRetries: 1})
+	require.NoError(t, err)
+	require.Same(t, retries, again)
+	_, again, err = b.openForSearch(ctx, common.SearchOptions{ReadRetries: 1, ReadRetryBackoff: time.Second})
+	require.NoError(t, err)
+	require.NotSame(t, retries, again)
+}
+
+// rangeReader records the reads of a backend that may not support range reads
+type rangeReader struct {
+	backend.Reader
+	unsupported bool
+
+	mtx    sync.Mutex
+	ranges [][2]uint64 // offset and length
+	reads  int         // of the data file, bloom filters are always read whole
+}
+
+func (r *rangeReader) Read(ctx context.Context, name string, blockID uuid.UUID, tenantID string, shouldCache bool) ([]byte, error) {
+	if name == DataFileName {
+		r.mtx.Lock()
+		r.reads++
+		r.mtx.Unlock()
+	}
+	return r.Reader.Read(ctx, name, blockID, tenantID, shouldCache)
+}
+
+func (r *rangeReader) ReadRange(ctx context.Context, name string, blockID uuid.UUID, tenantID string, offset uint64, buffer []byte, shouldCache bool) error {
+	if r.unsupported {
+		return backend.ErrRangeUnsupported
+	}
+	if name != DataFileName {
+		return r.Reader.ReadRange(ctx, name, blockID, tenantID, offset, buffer, shouldCache)
+	}
+	r.mtx.Lock()
+	r.ranges = append(r.ranges, [2]uint64{offset, uint64(len(buffer))})
+	r.mtx.Unlock()
+	return r.Reader.ReadRange(ctx, name, blockID, tenantID, offset, buffer, shouldCache)
+}
+
+func TestBackendBlockFindTraceByIDRangedReads(t *testing.T) {
+	var traces []*Trace
+	for i := 0; i < 150; i++ {
+		traces = append(traces, &Trace{TraceID: test.ValidTraceID(nil), RootSpanName: fmt.Sprintf("root-%d", i)})
+	}
+	sort.Slice(traces, func(i, j int) bool {
+		return bytes.Compare(traces[i].TraceID, traces[j].TraceID) == -1
+	})
+	written := makeBackendBlockWithTraces(t, traces)
+	meta := written.meta
+	ctx := context.Background()
+	want := parquetTraceToTempopbTrace(traces[120])
+
+	// only the footer and the pages of the row group with the trace are requested
+	rr := &rangeReader{Reader: written.r}
+	got, err := newBackendBlock(meta, rr).FindTraceByID(ctx, traces[120].TraceID, common.SearchOptions{})
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	require.Zero(t, rr.reads)
+	require.NotEmpty(t, rr.ranges)
+	footer := false
+	total := uint64(0)
+	for _, r := range rr.ranges {
+		require.LessOrEqual(t, r[0]+r[1], meta.Size)
+		footer = footer || r[0]+r[1] == meta.Size-8 // the footer is followed by its length and the magic number
+		total += r[1]
+	}
+	require.True(t, footer)
+	require.Less(t, total, meta.Size)
+
+	// inspectedBytes returns the bytes counted by the reader of the lookups with the options
+	inspectedBytes := func(b *backendBlock, opts common.SearchOptions) uint64 {
+		_, br, err := b.openForSearch(ctx, opts)
+		require.NoError(t, err)
+		return br.TotalBytesRead.Load()
+	}
+
+	// backends without range reads read the object once
+	rr = &rangeReader{Reader: written.r, unsupported: true}
+	b := newBackendBlock(meta, rr)
+	got, err = b.FindTraceByID(ctx, traces[120].TraceID, common.SearchOptions{})
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+	require.Equal(t, 1, rr.reads)
+	require.Equal(t, meta.Size, inspectedBytes(b, common.SearchOptions{}))
+
+	// objects larger than the limit are read for every read and not kept
+	defer func(max int) { maxWholeObjectBytes = max }(maxWholeObjectBytes)
+	maxWholeObjectBytes = int(meta.Size) - 1
+	rr = &rangeReader{Reader: written.r, unsupported: true}
+	b = newBackendBlock(meta, rr)
+	got, err = b.FindTraceByID(ctx, traces[120].TraceID, common.SearchOptions{})
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+	// each read of the object is counted once. The bytes are counted after the read, so they are
+	// loaded first in case reads continue in the background
+	inspected := inspectedBytes(b, common.SearchOptions{})
+	rr.mtx.Lock()
+	reads := rr.reads
+	rr.mtx.Unlock()
+	require.Greater(t, reads, 1)
+	require.LessOrEqual(t, inspected, uint64(reads)*meta.Size)
+	require.Zero(t, inspected%meta.Size)
+	_, br, err := b.openForSearch(ctx, common.SearchOptions{})
+	require.NoError(t, err)
+	require.Nil(t, br.wholeObject())
+	maxWholeObjectBytes = int(meta.Size)
+
+	// and aren't retried
+	rr = &rangeReader{Reader: written.r, unsupported: true}
+	b = newBackendBlock(meta, rr)
+	got, err = b.FindTraceByID(ctx, traces[120].TraceID, common.SearchOptions{ReadRetries: 2})
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+	require.Equal(t, 1, rr.reads)
+	require.Equal(t, meta.Size, inspectedBytes(b, common.SearchOptions{ReadRetries: 2}))
+}
+
+// failingRangeReader fails range reads of the data file that start in [from, to)
+type failingRangeReader struct {
+	backend.Reader
+	from, to uint64
+
+	mtx    sync.Mutex
+	failed int
+}
+
+func (r *failingRangeReader) ReadRange(ctx context.Context, name string, blockID uuid.UUID, tenantID string, offset uint64, buffer []byte, shouldCache bool) error {
+	if name == DataFileName && offset >= r.from && offset < r.to {
+		r.mtx.Lock()
+		r.failed++
+		r.mtx.Unlock()
+		return errors.New("corrupt")
+	}
+	return r.Reader.ReadRange(ctx, name, blockID, tenantID, offset, buffer, shouldCache)
+}
+
+func TestBackendBlockFindTraceByIDCorruptPageIndex(t *testing.T) {
+	var traces []*Trace
+	for i := 0; i < 150; i++ {
+		traces = append(traces, &Trace{TraceID: test.ValidTraceID(nil), RootSpanName: fmt.Sprintf("root-%d", i)})
+	}
+	sort.Slice(traces, func(i, j int) bool {
+		return bytes.Compare(traces[i].TraceID, traces[j].TraceID) == -1
+	})
+	written := makeBackendBlockWithTraces(t, traces)
+	meta := written.meta
+	ctx := context.Background()
+	want := parquetTraceToTempopbTrace(traces[120])
+
+	// the page index is written between the column chunks and the footer
+	pf, _, err := written.openForSearch(ctx, common.SearchOptions{})
+	require.NoError(t, err)
+	first := pf.Metadata().RowGroups[0].Columns[0]
+	require.NotZero(t, first.ColumnIndexOffset)
+	from := uint64(first.ColumnIndexOffset)
+	if uint64(first.OffsetIndexOffset) < from {
+		from = uint64(first.OffsetIndexOffset)
+	}
+
+	// an intact page index is read
+	b := newBackendBlock(meta, written.r)
+	pf, _, err = b.openForSearch(ctx, common.SearchOptions{ReadPageIndex: true})
+	require.NoError(t, err)
+	require.NotNil(t, pf.OffsetIndexes())
+
+	// a page index that can't be read is skipped
+	r := &failingRangeReader{Reader: written.r, from: from, to: meta.Size - uint64(meta.FooterSize) - 8}
+	b = newBackendBlock(meta, r)
+	got, err := b.FindTraceByID(ctx, traces[120].TraceID, common.SearchOptions{ReadPageIndex: true})
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+	require.Greater(t, r.failed, 0)
+	require.Nil(t, b.opened[openKeyFor(common.SearchOptions{ReadPageIndex: true})].pf.OffsetIndexes())
+
+	// the file opened without the page index isn't reused by lookups that read it, and the other
+	// way around
+	b = newBackendBlock(meta, written.r)
+	pf, _, err = b.openForSearch(ctx, common.SearchOptions{})
+	require.NoError(t, err)
+	require.Nil(t, pf.OffsetIndexes())
+	pf, _, err = b.openForSearch(ctx, common.SearchOptions{ReadPageIndex: true})
+	require.NoError(t, err)
+	require.NotNil(t, pf.OffsetIndexes())
+	pf, _, err = b.openForSearch(ctx, common.SearchOptions{})
+	require.NoError(t, err)
+	require.Nil(t, pf.OffsetIndexes())
+
+	// and isn't read by default
+	r = &failingRangeReader{Reader: written.r, from: from, to: meta.Size - uint64(meta.FooterSize) - 8}
+	got, err = newBackendBlock(meta, r).FindTraceByID(ctx, traces[120].TraceID, common.SearchOptions{})
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+	require.Zero(t, r.failed)
+}
+
+// emptyBloomReader returns bloom filters without any IDs
+type emptyBloomReader struct {
+	backend.Reader
+}
+
+func (r *emptyBloomReader) Read(ctx context.Context, name string, blockID uuid.UUID, tenantID string, shouldCache bool) ([]byte, error) {
+	if !strings.HasPrefix(name, "bloom-") {
+		return r.Reader.Read(ctx, name, blockID, tenantID, shouldCache)
+	}
+
+	buf := &bytes.Buffer{}
+	if _, err := bloom.NewWithEstimates(10, 0.01).WriteTo(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func TestBackendBlockFindTraceByIDSkipBloom(t *testing.T) {
+	traces := []*Trace{{TraceID: test.ValidTraceID(nil), RootSpanName: "root"}}
+	written := makeBackendBlockWithTraces(t, traces)
+	ctx := context.Background()
+	want := parquetTraceToTempopbTrace(traces[0])
+
+	// the bloom is a false negative for the trace
+	b := newBackendBlock(written.meta, &emptyBloomReader{Reader: written.r})
+	got, err := b.FindTraceByID(ctx, traces[0].TraceID, common.SearchOptions{})
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	got, err = b.FindTraceByID(ctx, traces[0].TraceID, common.SearchOptions{SkipBloom: true})
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	// IDs that aren't in the block still aren't found
+	got, err = b.FindTraceByID(ctx, test.ValidTraceID(nil), common.SearchOptions{SkipBloom: true})
+	require.NoError(t, err)
+	require.Nil(t, got)
+}
+
+func TestBackendBlockFindTraceByIDCacheRowGroupMins(t *testing.T) {
+	var traces []*Trace
+	for i := 0; i < 500; i++ {
+		traces = append(traces, &Trace{TraceID: test.ValidTraceID(nil), RootSpanName: fmt.Sprintf("root-%d", i)})
+	}
+	sort.Slice(traces, func(i, j int) bool {
+		return bytes.Compare(traces[i].TraceID, traces[j].TraceID) == -1
+	})
+	written := makeBackendBlockWithTraces(t, traces)
+	ctx := context.Background()
+
+	// minReads returns how many row group mins locating the trace reads from the file, like
+	// locateTrace does
+	minReads := func(b *backendBlock, tr *Trace, opts common.SearchOptions) int {
+		pf, _, err := b.openForSearch(ctx, opts)
+		require.NoError(t, err)
+		colIndex, _ := pq.GetColumnIndexByPath(pf, b.columns.traceID)
+		require.NotEqual(t, -1, colIndex)
+
+		index := newRowGroupIndex(pf, colIndex, b.meta)
+		if opts.CacheRowGroupMins {
+			b.loadRowGroupMins(index)
+			defer b.storeRowGroupMins(index)
+		}
+		rowGroup, err := index.find(tr.TraceID, 0)
+		require.NoError(t, err)
+		require.NotEqual(t, -1, rowGroup)
+		return index.reads
+	}
+	lookup := func(b *backendBlock, tr *Trace, opts common.SearchOptions) {
+		got, err := b.FindTraceByID(ctx, tr.TraceID, opts)
+		require.NoError(t, err)
+		require.Equal(t, parquetTraceToTempopbTrace(tr), got)
+	}
+
+	// without the cache every lookup reads the mins again
+	b := newBackendBlock(written.meta, written.r)
+	lookup(b, traces[250], common.SearchOptions{})
+	require.NotZero(t, minReads(b, traces[250], common.SearchOptions{}))
+	require.Nil(t, b.rowGroupMins)
+
+	opts := common.SearchOptions{CacheRowGroupMins: true}
+	b = newBackendBlock(written.meta, written.r)
+	lookup(b, traces[250], opts)
+	require.Zero(t, minReads(b, traces[250], opts))
+
+	// the mins cached by other lookups are reused and still locate every trace
+	for _, tr := range []*Trace{traces[0], traces[120], traces[499]} {
+		lookup(b, tr, opts)
+	}
+	pf, _, err := b.openForSearch(ctx, opts)
+	require.NoError(t, err)
+	require.Len(t, b.rowGroupMins, len(pf.RowGroups())+1)
+	for _, tr := range []*Trace{traces[0], traces[120], traces[250], traces[499]} {
+		require.Zero(t, minReads(b, tr, opts))
+	}
+}
+
+// readCountingReader counts all reads of the block, including the bloom filters
+type readCountingReader struct {
+	backend.Reader
+	mtx   sync.Mutex
+	reads int
+}
+
+func (r *readCountingReader) Read(ctx context.Context, name string, blockID uuid.UUID, tenantID string, shouldCache bool) ([]byte, error) {
+	r.mtx.Lock()
+	r.reads++
+	r.mtx.Unlock()
+	return r.Reader.Read(ctx, name, blockID, tenantID, shouldCache)
+}
+
+func (r *readCountingReader) ReadRange(ctx context.Context, name string, blockID uuid.UUID, tenantID string, offset uint64, buffer []byte, shouldCache bool) error {
+	r.mtx.Lock()
+	r.reads++
+	r.mtx.Unlock()
+	return r.Reader.ReadRange(ctx, name, blockID, tenantID, offset, buffer, shouldCache)
+}
+
+func TestBackendBlockFindTraceByIDTraceCache(t *testing.T) {
+	var traces []*Trace
+	for i := 0; i < 10; i++ {
+		traces = append(traces, fullyPopulatedTestTrace(test.ValidTraceID(nil)))
+	}
+	sort.Slice(traces, func(i, j int) bool {
+		return bytes.Compare(traces[i].TraceID, traces[j].TraceID) == -1
+	})
+	written := makeBackendBlockWithTraces(t, traces)
+	ctx := context.Background()
+
+	want := map[string]*tempopb.Trace{}
+	for _, tr := range traces {
+		got, err := written.FindTraceByID(ctx, tr.TraceID, common.SearchOptions{})
+		require.NoError(t, err)
+		want[string(tr.TraceID)] = got
+	}
+
+	cr := &readCountingReader{Reader: written.r}
+	b := newBackendBlock(written.meta, cr)
+	now := time.Unix(0, 0)
+	b.traces.now = func() time.Time { return now }
+
+	// lookup returns the number of backend reads of finding the trace
+	lookup := func(tr *Trace, opts common.SearchOptions) int {
+		cr.mtx.Lock()
+		start := cr.reads
+		cr.mtx.Unlock()
+
+		got, err := b.FindTraceByID(ctx, tr.TraceID, opts)
+		require.NoError(t, err)
+		// cached traces are unmarshaled, which doesn't keep empty IDs apart from nil
+		require.True(t, proto.Equal(want[string(tr.TraceID)], got))
+
+		cr.mtx.Lock()
+		defer cr.mtx.Unlock()
+		return cr.reads - start
+	}
+	hits := func() float64 { return testutil.ToFloat64(metricTraceCacheRequests.WithLabelValues(traceCacheHit)) }
+	misses := func() float64 { return testutil.ToFloat64(metricTraceCacheRequests.WithLabelValues(traceCacheMiss)) }
+
+	// off by default
+	require.NotZero(t, lookup(traces[0], common.SearchOptions{}))
+	require.NotZero(t, lookup(traces[0], common.SearchOptions{}))
+
+	opts := common.SearchOptions{TraceCacheTTL: time.Minute}
+	hit, miss := hits(), misses()
+	require.NotZero(t, lookup(traces[0], opts))
+	require.Zero(t, lookup(traces[0], opts))
+	require.Equal(t, hit+1, hits())
+	require.Equal(t, miss+1, misses())
+
+	// every lookup gets its own copy
+	got, err := b.FindTraceByID(ctx, traces[0].TraceID, opts)
+	require.NoError(t, err)
+	got.Batches = nil
+	require.Zero(t, lookup(traces[0], opts))
+
+	// the location is cached with the trace
+	_, loc, err := b.FindTraceByIDWithLocation(ctx, traces[0].TraceID, opts)
+	require.NoError(t, err)
+	_, wantLoc, err := b.FindTraceByIDWithLocation(ctx, traces[0].TraceID, common.SearchOptions{})
+	require.NoError(t, err)
+	require.Equal(t, wantLoc, loc)
+
+	// lookups that filter, limit or order the trace, or report on it, read it
+	for _, bypass := range []common.SearchOptions{
+		{TimeWindowEndUnixNano: math.MaxUint64},
+		{MaxSpansPerTrace: 1000},
+		{SpanLess: func(a, b *v1.Span) bool { return false }},
+		{DuplicateTraceIDs: common.DuplicateTraceIDsError},
+		{TraceTruncated: func(common.ID, int) {}},
+		{TraceCompleteness: func(common.ID, bool) {}},
+	} {
+		bypass.TraceCacheTTL = time.Minute
+		require.NotZero(t, lookup(traces[0], bypass))
+	}
+	require.Zero(t, lookup(traces[0], opts))
+
+	// expired traces are read again
+	now = now.Add(time.Minute)
+	require.NotZero(t, lookup(traces[0], opts))
+	require.Zero(t, lookup(traces[0], opts))
+
+	// the oldest traces are evicted first
+	bounded := common.SearchOptions{TraceCacheTTL: time.Minute, TraceCacheMaxEntries: 2}
+	for _, tr := range traces[1:4] {
+		require.NotZero(t, lookup(tr, bounded))
+	}
+	require.Len(t, b.traces.entries, 2)
+	require.NotZero(t, lookup(traces[1], bounded))
+	require.Zero(t, lookup(traces[3], bounded))
+
+	// traces larger than the cache aren't cached
+	bounded = common.SearchOptions{TraceCacheTTL: time.Minute, TraceCacheMaxBytes: 1}
+	require.NotZero(t, lookup(traces[5], bounded))
+	require.NotZero(t, lookup(traces[5], bounded))
+	require.NotContains(t, b.traces.entries, string(traces[5].TraceID))
+
+	bounded.TraceCacheMaxBytes = want[string(traces[6].TraceID)].Size() * 3 / 2
+	require.NotZero(t, lookup(traces[6], bounded))
+	require.NotZero(t, lookup(traces[7], bounded))
+	require.NotZero(t, lookup(traces[6], bounded))
+	require.LessOrEqual(t, b.traces.bytes, bounded.TraceCacheMaxBytes)
+}
+
+func TestTraceCacheConcurrency(t *testing.T) {
+	c := newTraceCache()
+	opts := common.SearchOptions{TraceCacheTTL: time.Minute, TraceCacheMaxEntries: 5}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				id := []byte{byte((i + j) % 8)}
+				if _, _, ok := c.get(id); !ok {
+					c.put(id, &tempopb.Trace{}, TraceLocation{RowNumber: int64(j)}, opts)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.LessOrEqual(t, len(c.entries), 5)
+	require.Equal(t, len(c.entries), c.order.Len())
+}
+
 func BenchmarkFindTraceByID(b *testing.B) {
 	ctx := context.TODO()
 	tenantID := "1"
@@ -0,0 +1,124 @@
+package vparquet
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+
+	pq "github.com/grafana/tempo/pkg/parquetquery"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// FindTraceIDsByPrefix returns the IDs of all traces in the block that start with prefix, in
+// ascending order. Only the trace ID column is read.
+func (b *backendBlock) FindTraceIDsByPrefix(ctx context.Context, prefix []byte, opts common.SearchOptions) ([]common.ID, error) {
+	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "parquet.backendBlock.FindTraceIDsByPrefix",
+		opentracing.Tags{
+			"blockID":   b.meta.BlockID,
+			"tenantID":  b.meta.TenantID,
+			"blockSize": b.meta.Size,
+		})
+	defer span.Finish()
+
+	pf, rr, err := b.openForSearch(derivedCtx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected error opening parquet file: %w", err)
+	}
+	defer func() {
+		span.SetTag("inspectedBytes", rr.TotalBytesRead.Load())
+	}()
+
+	colIndex, _ := pq.GetColumnIndexByPath(pf, b.columns.traceID)
+	if colIndex == -1 {
+		return nil, fmt.Errorf("unable to get index for column: %s", b.columns.traceID)
+	}
+
+	index := newRowGroupIndex(pf, colIndex, b.meta)
+
+	start, end, err := index.findPrefix(prefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "error binary searching row groups")
+	}
+	if start == end {
+		return nil, nil
+	}
+
+	iter := pq.NewColumnIterator(derivedCtx, pf.RowGroups()[start:end], colIndex, "", 1000, pq.NewPrefixPredicate(prefix), TraceIDColumnName, pq.WithReadAhead(opts.ReadAheadPages))
+	defer iter.Close()
+
+	var ids []common.ID
+	for {
+		res, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		if res == nil {
+			break
+		}
+
+		ids = append(ids, common.ID(res.Entries[0].Value.Clone().ByteArray()))
+	}
+
+	return ids, nil
+}
+
+// findPrefix returns the range of row groups [start, end) that may contain trace IDs starting with
+// prefix. The range is empty if no row group can contain a matching ID.
+func (x *rowGroupIndex) findPrefix(prefix []byte) (start, end int, err error) {
+	comparePrefix := func(id common.ID) int {
+		if len(id) > len(prefix) {
+			id = id[:len(prefix)]
+		}
+		return bytes.Compare(id, prefix)
+	}
+
+	numRowGroups := x.numRowGroups()
+
+	// The first row group whose upper bound isn't before the prefix. The upper bound of a row group
+	// is the min of the next one, or the max of the block for the last row group.
+	start, err = searchRowGroups(0, numRowGroups, func(i int) (bool, error) {
+		max, err := x.min(i + 1)
+		if err != nil {
+			return false, err
+		}
+		return comparePrefix(max) >= 0, nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// The first row group after start whose min is after the prefix.
+	end, err = searchRowGroups(start, numRowGroups, func(i int) (bool, error) {
+		min, err := x.min(i)
+		if err != nil {
+			return false, err
+		}
+		return comparePrefix(min) > 0, nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return start, end, nil
+}
+
+// searchRowGroups returns the smallest index in [from, to) for which fn is true, or to if there is
+// none. fn must be false for some prefix of the range and true for the remainder.
+func searchRowGroups(from, to int, fn func(int) (bool, error)) (int, error) {
+	for from < to {
+		mid := int(uint(from+to) >> 1)
+		ok, err := fn(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			to = mid
+		} else {
+			from = mid + 1
+		}
+	}
+	return from, nil
+}
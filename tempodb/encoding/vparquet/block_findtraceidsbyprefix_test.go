@@ -0,0 +1,125 @@
+package vparquet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestBackendBlockFindTraceIDsByPrefix(t *testing.T) {
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+	ctx := context.Background()
+
+	cfg := &common.BlockConfig{
+		BloomFP:             0.01,
+		BloomShardSizeBytes: 100 * 1024,
+	}
+
+	// Groups of 4 traces share the first byte of the ID. The IDs are
+	// generated in ascending order.
+	var ids []common.ID
+	var traces []*Trace
+	for i := 0; i < 32; i++ {
+		id := test.ValidTraceID(nil)
+		id[0] = byte(i / 4)
+		id[1] = byte(i % 4)
+		ids = append(ids, id)
+
+		traces = append(traces, &Trace{
+			TraceID: id,
+			ResourceSpans: []ResourceSpans{
+				{
+					Resource: Resource{
+						ServiceName: "s",
+					},
+					ScopeSpans: []ScopeSpan{
+						{
+							Spans: []Span{
+								{
+									Name:         "hello",
+									ID:           []byte{},
+									ParentSpanID: []byte{},
+								},
+							},
+						},
+					},
+				},
+			},
+		})
+	}
+
+	meta := backend.NewBlockMeta("fake", uuid.New(), VersionString, backend.EncNone, "")
+	meta.TotalObjects = len(traces)
+	s := newStreamingBlock(ctx, cfg, meta, r, w, tempo_io.NewBufferedWriter)
+
+	// Row groups of 3 so that traces that share a prefix are split across row groups
+	rowGroupSize := 3
+	for _, tr := range traces {
+		err := s.Add(tr, 0, 0)
+		require.NoError(t, err)
+		if s.CurrentBufferedObjects() >= rowGroupSize {
+			_, err = s.Flush()
+			require.NoError(t, err)
+		}
+	}
+	_, err = s.Complete()
+	require.NoError(t, err)
+
+	b := newBackendBlock(s.meta, r)
+
+	tcs := []struct {
+		prefix   []byte
+		expected []common.ID
+	}{
+		{
+			prefix:   []byte{0},
+			expected: ids[0:4],
+		},
+		{
+			prefix:   []byte{5},
+			expected: ids[20:24],
+		},
+		{
+			prefix:   []byte{7},
+			expected: ids[28:32],
+		},
+		{
+			prefix:   []byte{2, 3},
+			expected: ids[11:12],
+		},
+		{
+			prefix:   ids[17],
+			expected: ids[17:18],
+		},
+		{
+			prefix:   []byte{},
+			expected: ids,
+		},
+		{
+			prefix: []byte{2, 4},
+		},
+		{
+			prefix: []byte{8},
+		},
+	}
+
+	for _, tc := range tcs {
+		actual, err := b.FindTraceIDsByPrefix(ctx, tc.prefix, common.SearchOptions{})
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, actual, "prefix %x", tc.prefix)
+	}
+}
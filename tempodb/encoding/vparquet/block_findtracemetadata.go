@@ -0,0 +1,88 @@
+package vparquet
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+
+	pq "github.com/grafana/tempo/pkg/parquetquery"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// TraceMetadata is the trace-level fields of a trace, without any of its spans.
+type TraceMetadata struct {
+	TraceID           common.ID
+	RootServiceName   string
+	RootSpanName      string
+	StartTimeUnixNano uint64
+	EndTimeUnixNano   uint64
+	DurationNanos     uint64
+}
+
+// FindTraceMetadataByID locates the trace like FindTraceByID but only reads the top-level trace
+// columns. This is much cheaper than reading the full trace when only existence or the root
+// span details are needed. Returns nil if the trace isn't in the block.
+func (b *backendBlock) FindTraceMetadataByID(ctx context.Context, traceID common.ID, opts common.SearchOptions) (_ *TraceMetadata, err error) {
+	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "parquet.backendBlock.FindTraceMetadataByID",
+		opentracing.Tags{
+			"blockID":   b.meta.BlockID,
+			"tenantID":  b.meta.TenantID,
+			"blockSize": b.meta.Size,
+		})
+	defer span.Finish()
+
+	found, err := b.checkBloom(derivedCtx, traceID, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+
+	pf, rr, err := b.openForSearch(derivedCtx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected error opening parquet file: %w", err)
+	}
+	defer func() { span.SetTag("inspectedBytes", rr.TotalBytesRead.Load()) }()
+
+	loc, found, err := b.locateTrace(derivedCtx, pf, traceID, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+
+	makeIter := makeIterFunc(derivedCtx, pf.RowGroups()[loc.rowGroup:loc.rowGroup+1], pf)
+
+	iter := pq.NewJoinIterator(DefinitionLevelTrace, []pq.Iterator{
+		&rowNumberIterator{rowNumbers: []pq.RowNumber{loc.row}},
+		makeIter("TraceID", nil, "TraceID"),
+		makeIter("RootServiceName", nil, "RootServiceName"),
+		makeIter("RootSpanName", nil, "RootSpanName"),
+		makeIter("StartTimeUnixNano", nil, "StartTimeUnixNano"),
+		makeIter("EndTimeUnixNano", nil, "EndTimeUnixNano"),
+		makeIter("DurationNanos", nil, "DurationNanos"),
+	}, nil)
+	defer iter.Close()
+
+	match, err := iter.Next()
+	if err != nil {
+		return nil, errors.Wrap(err, "reading trace metadata")
+	}
+	if match == nil {
+		return nil, fmt.Errorf("trace metadata not found at row %d", loc.offset)
+	}
+
+	matchMap := match.ToMap()
+	return &TraceMetadata{
+		TraceID:           common.ID(matchMap["TraceID"][0].Clone().ByteArray()),
+		RootServiceName:   matchMap["RootServiceName"][0].String(),
+		RootSpanName:      matchMap["RootSpanName"][0].String(),
+		StartTimeUnixNano: matchMap["StartTimeUnixNano"][0].Uint64(),
+		EndTimeUnixNano:   matchMap["EndTimeUnixNano"][0].Uint64(),
+		DurationNanos:     matchMap["DurationNanos"][0].Uint64(),
+	}, nil
+}
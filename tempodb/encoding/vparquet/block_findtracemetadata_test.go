@@ -0,0 +1,127 @@
+package vparquet
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestBackendBlockFindTraceMetadataByID(t *testing.T) {
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+	ctx := context.Background()
+
+	cfg := &common.BlockConfig{
+		BloomFP:             0.01,
+		BloomShardSizeBytes: 100 * 1024,
+	}
+
+	// Traces with lots of spans so that the span columns dwarf the trace-level ones
+	var traces []*Trace
+	for i := 0; i < 8; i++ {
+		var spans []Span
+		for j := 0; j < 1000; j++ {
+			// random values don't compress, so the span columns stay large
+			v := fmt.Sprintf("%x", test.ValidTraceID(nil))
+			spans = append(spans, Span{
+				Name:           fmt.Sprintf("span-%d-%d", i, j),
+				ID:             test.ValidTraceID(nil),
+				ParentSpanID:   []byte{},
+				StartUnixNanos: uint64(i*1000 + j),
+				EndUnixNanos:   uint64(i*1000 + j + 10),
+				Attrs: []Attribute{
+					{Key: "foo", Value: &v},
+				},
+			})
+		}
+
+		traces = append(traces, &Trace{
+			TraceID:           test.ValidTraceID(nil),
+			StartTimeUnixNano: uint64(i * 1000),
+			EndTimeUnixNano:   uint64(i*1000 + 500),
+			DurationNanos:     500,
+			RootServiceName:   fmt.Sprintf("service-%d", i),
+			RootSpanName:      fmt.Sprintf("root-%d", i),
+			ResourceSpans: []ResourceSpans{
+				{
+					Resource: Resource{
+						ServiceName: fmt.Sprintf("service-%d", i),
+					},
+					ScopeSpans: []ScopeSpan{
+						{
+							Spans: spans,
+						},
+					},
+				},
+			},
+		})
+	}
+
+	sort.Slice(traces, func(i, j int) bool {
+		return bytes.Compare(traces[i].TraceID, traces[j].TraceID) == -1
+	})
+
+	meta := backend.NewBlockMeta("fake", uuid.New(), VersionString, backend.EncNone, "")
+	meta.TotalObjects = len(traces)
+	s := newStreamingBlock(ctx, cfg, meta, r, w, tempo_io.NewBufferedWriter)
+
+	for _, tr := range traces {
+		err := s.Add(tr, 0, 0)
+		require.NoError(t, err)
+		if s.CurrentBufferedObjects() >= 2 {
+			_, err = s.Flush()
+			require.NoError(t, err)
+		}
+	}
+	_, err = s.Complete()
+	require.NoError(t, err)
+
+	for _, tr := range traces {
+		// Fresh blocks so the byte counts of the two reads are independent
+		metaBlock := newBackendBlock(s.meta, r)
+		md, err := metaBlock.FindTraceMetadataByID(ctx, tr.TraceID, common.SearchOptions{})
+		require.NoError(t, err)
+		require.NotNil(t, md)
+
+		require.Equal(t, common.ID(tr.TraceID), md.TraceID)
+		require.Equal(t, tr.RootServiceName, md.RootServiceName)
+		require.Equal(t, tr.RootSpanName, md.RootSpanName)
+		require.Equal(t, tr.StartTimeUnixNano, md.StartTimeUnixNano)
+		require.Equal(t, tr.EndTimeUnixNano, md.EndTimeUnixNano)
+		require.Equal(t, tr.DurationNanos, md.DurationNanos)
+
+		fullBlock := newBackendBlock(s.meta, r)
+		full, err := fullBlock.FindTraceByID(ctx, tr.TraceID, common.SearchOptions{})
+		require.NoError(t, err)
+		require.NotNil(t, full)
+
+		_, metaRR, err := metaBlock.openForSearch(ctx, common.SearchOptions{})
+		require.NoError(t, err)
+		_, fullRR, err := fullBlock.openForSearch(ctx, common.SearchOptions{})
+		require.NoError(t, err)
+		metaBytes := metaRR.TotalBytesRead.Load()
+		fullBytes := fullRR.TotalBytesRead.Load()
+		require.Less(t, metaBytes*2, fullBytes, "metadata read %d bytes, full read %d bytes", metaBytes, fullBytes)
+	}
+
+	// Missing trace
+	md, err := newBackendBlock(s.meta, r).FindTraceMetadataByID(ctx, test.ValidTraceID(nil), common.SearchOptions{})
+	require.NoError(t, err)
+	require.Nil(t, md)
+}
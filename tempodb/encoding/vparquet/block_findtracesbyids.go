@@ -0,0 +1,261 @@
+package vparquet
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/segmentio/parquet-go"
+
+	pq "github.com/grafana/tempo/pkg/parquetquery"
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// continuationTokenVersion is bumped whenever the contents of a continuation token change. Tokens
+// from other versions are rejected instead of being misinterpreted.
+const continuationTokenVersion = 1
+
+// TraceByID is a trace found by FindTracesByIDs.
+type TraceByID struct {
+	ID    common.ID
+	Trace *tempopb.Trace
+}
+
+// continuationToken records where a paged FindTracesByIDs call left off. It is handed to callers
+// base64 encoded and must be treated as opaque.
+type continuationToken struct {
+	Version int `json:"v"`
+	// Position is the index in the sorted, deduplicated ID list of the next ID to look up.
+	Position int `json:"p"`
+	// RowGroup is the row group of the last ID looked up. The IDs are sorted so
+	// the search for the next ID can start there.
+	RowGroup int `json:"rg"`
+	// IDsHash identifies the ID list the token was issued for.
+	IDsHash uint64 `json:"h"`
+}
+
+func (t continuationToken) encode() string {
+	b, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeContinuationToken(s string) (continuationToken, error) {
+	var t continuationToken
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return t, errors.Wrap(err, "invalid continuation token")
+	}
+	err = json.Unmarshal(b, &t)
+	if err != nil {
+		return t, errors.Wrap(err, "invalid continuation token")
+	}
+	if t.Version != continuationTokenVersion {
+		return t, fmt.Errorf("unsupported continuation token version %d", t.Version)
+	}
+
+	return t, nil
+}
+
+// FindTracesByIDs looks up all of the given trace IDs in a single pass over the block. The
+// results are sorted by ID, IDs that aren't found are omitted.
+func (b *backendBlock) FindTracesByIDs(ctx context.Context, ids []common.ID, opts common.SearchOptions) ([]TraceByID, error) {
+	results, _, err := b.FindTracesByIDsPage(ctx, ids, "", 0, opts)
+	return results, err
+}
+
+// FindTracesByIDsPage looks up at most pageSize of the given trace IDs, starting at the position
+// recorded in the continuation token. An empty token starts at the beginning. The returned token
+// resumes the lookup and is empty once all IDs have been looked up. A pageSize <= 0 looks up all
+// remaining IDs. The same IDs must be passed with every page.
+func (b *backendBlock) FindTracesByIDsPage(ctx context.Context, ids []common.ID, continuation string, pageSize int, opts common.SearchOptions) ([]TraceByID, string, error) {
+	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "parquet.backendBlock.FindTracesByIDsPage",
+		opentracing.Tags{
+			"blockID":   b.meta.BlockID,
+			"tenantID":  b.meta.TenantID,
+			"blockSize": b.meta.Size,
+			"ids":       len(ids),
+		})
+	defer span.Finish()
+
+	sorted := sortedUniqueIDs(ids)
+	idsHash := hashIDs(sorted)
+
+	token := continuationToken{
+		Version: continuationTokenVersion,
+		IDsHash: idsHash,
+	}
+	if continuation != "" {
+		var err error
+		token, err = decodeContinuationToken(continuation)
+		if err != nil {
+			return nil, "", err
+		}
+		if token.IDsHash != idsHash {
+			return nil, "", fmt.Errorf("continuation token was issued for a different set of trace IDs")
+		}
+		if token.Position < 0 || token.Position > len(sorted) {
+			return nil, "", fmt.Errorf("invalid continuation token position %d", token.Position)
+		}
+	}
+
+	end := len(sorted)
+	if pageSize > 0 && token.Position+pageSize < end {
+		end = token.Position + pageSize
+	}
+	page := sorted[token.Position:end]
+
+	results, lastRowGroup, err := b.findTracesByIDs(derivedCtx, page, token.RowGroup, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if end == len(sorted) {
+		return results, "", nil
+	}
+
+	token.Position = end
+	token.RowGroup = lastRowGroup
+	return results, token.encode(), nil
+}
+
+// findTracesByIDs looks up sorted IDs starting the row group search at fromRowGroup. Returns
+// the found traces and the last row group that was searched.
+func (b *backendBlock) findTracesByIDs(ctx context.Context, ids []common.ID, fromRowGroup int, opts common.SearchOptions) ([]TraceByID, int, error) {
+	if len(ids) == 0 {
+		return nil, fromRowGroup, nil
+	}
+
+	pf, _, err := b.openForSearch(ctx, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unexpected error opening parquet file: %w", err)
+	}
+
+	colIndex, _ := pq.GetColumnIndexByPath(pf, b.columns.traceID)
+	if colIndex == -1 {
+		return nil, 0, fmt.Errorf("unable to get index for column: %s", b.columns.traceID)
+	}
+
+	index := newRowGroupIndex(pf, colIndex, b.meta)
+	if fromRowGroup < 0 || fromRowGroup >= index.numRowGroups() {
+		fromRowGroup = 0
+	}
+
+	// Group the IDs by row group. The IDs are sorted so each group is a contiguous run.
+	byRowGroup := map[int][]common.ID{}
+	var rowGroups []int
+	for _, id := range ids {
+		found, err := b.checkBloom(ctx, id, opts, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !found {
+			continue
+		}
+
+		rg, err := index.find(id, fromRowGroup)
+		if err != nil {
+			return nil, 0, errors.Wrap(err, "error binary searching row groups")
+		}
+		if rg == -1 {
+			continue
+		}
+
+		if _, ok := byRowGroup[rg]; !ok {
+			rowGroups = append(rowGroups, rg)
+		}
+		byRowGroup[rg] = append(byRowGroup[rg], id)
+		fromRowGroup = rg
+	}
+
+	r := parquet.NewReader(pf, parquet.SchemaOf(new(Trace)))
+	span := opentracing.SpanFromContext(ctx)
+
+	var (
+		results   []TraceByID
+		rowOffset int64
+		prevRG    int
+	)
+	for _, rg := range rowGroups {
+		for _, g := range pf.RowGroups()[prevRG:rg] {
+			rowOffset += g.NumRows()
+		}
+		prevRG = rg
+
+		rgIDs := byRowGroup[rg]
+		strIDs := make([]string, 0, len(rgIDs))
+		for _, id := range rgIDs {
+			strIDs = append(strIDs, string(id))
+		}
+
+		iter := pq.NewColumnIterator(ctx, pf.RowGroups()[rg:rg+1], colIndex, "", 1000, pq.NewStringInPredicate(strIDs), TraceIDColumnName, pq.WithReadAhead(opts.ReadAheadPages))
+		for {
+			res, err := iter.Next()
+			if err != nil {
+				iter.Close()
+				return nil, 0, err
+			}
+			if res == nil {
+				break
+			}
+
+			id := common.ID(res.Entries[0].Value.Clone().ByteArray())
+
+			err = r.SeekToRow(rowOffset + res.RowNumber[0])
+			if err != nil {
+				iter.Close()
+				return nil, 0, errors.Wrap(err, "seek to row")
+			}
+
+			tr, err := readTrace(r, id, opts, span)
+			if err != nil {
+				iter.Close()
+				return nil, 0, err
+			}
+
+			results = append(results, TraceByID{
+				ID:    id,
+				Trace: tempopbTrace(tr, opts),
+			})
+		}
+		iter.Close()
+	}
+
+	return results, fromRowGroup, nil
+}
+
+func sortedUniqueIDs(ids []common.ID) []common.ID {
+	sorted := make([]common.ID, len(ids))
+	copy(sorted, ids)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i], sorted[j]) < 0
+	})
+
+	unique := sorted[:0]
+	for i, id := range sorted {
+		if i > 0 && bytes.Equal(id, sorted[i-1]) {
+			continue
+		}
+		unique = append(unique, id)
+	}
+	return unique
+}
+
+func hashIDs(ids []common.ID) uint64 {
+	h := fnv.New64a()
+	var l [binary.MaxVarintLen64]byte
+	for _, id := range ids {
+		n := binary.PutUvarint(l[:], uint64(len(id)))
+		_, _ = h.Write(l[:n])
+		_, _ = h.Write(id)
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,127 @@
+package vparquet
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestBackendBlockFindTracesByIDsPage(t *testing.T) {
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+	ctx := context.Background()
+
+	cfg := &common.BlockConfig{
+		BloomFP:             0.01,
+		BloomShardSizeBytes: 100 * 1024,
+	}
+
+	var traces []*Trace
+	for i := 0; i < 100; i++ {
+		traces = append(traces, &Trace{
+			TraceID: test.ValidTraceID(nil),
+			ResourceSpans: []ResourceSpans{
+				{
+					Resource: Resource{
+						ServiceName: "s",
+					},
+					ScopeSpans: []ScopeSpan{
+						{
+							Spans: []Span{
+								{
+									Name:         "hello",
+									ID:           []byte{},
+									ParentSpanID: []byte{},
+								},
+							},
+						},
+					},
+				},
+			},
+		})
+	}
+
+	sort.Slice(traces, func(i, j int) bool {
+		return bytes.Compare(traces[i].TraceID, traces[j].TraceID) == -1
+	})
+
+	meta := backend.NewBlockMeta("fake", uuid.New(), VersionString, backend.EncNone, "")
+	meta.TotalObjects = len(traces)
+	s := newStreamingBlock(ctx, cfg, meta, r, w, tempo_io.NewBufferedWriter)
+
+	rowGroupSize := 7
+	for _, tr := range traces {
+		err := s.Add(tr, 0, 0)
+		require.NoError(t, err)
+		if s.CurrentBufferedObjects() >= rowGroupSize {
+			_, err = s.Flush()
+			require.NoError(t, err)
+		}
+	}
+	_, err = s.Complete()
+	require.NoError(t, err)
+
+	b := newBackendBlock(s.meta, r)
+
+	// Request the IDs in reverse order along with one that isn't in the block
+	var ids []common.ID
+	for i := len(traces) - 1; i >= 0; i-- {
+		ids = append(ids, traces[i].TraceID)
+	}
+	ids = append(ids, test.ValidTraceID(nil))
+
+	all, err := b.FindTracesByIDs(ctx, ids, common.SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, all, len(traces))
+	for i, tr := range traces {
+		require.Equal(t, common.ID(tr.TraceID), all[i].ID)
+		require.Equal(t, parquetTraceToTempopbTrace(tr), all[i].Trace)
+	}
+
+	var (
+		paged []TraceByID
+		token string
+		pages int
+	)
+	for {
+		results, next, err := b.FindTracesByIDsPage(ctx, ids, token, 34, common.SearchOptions{})
+		require.NoError(t, err)
+		paged = append(paged, results...)
+		pages++
+
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	require.Equal(t, 3, pages)
+	require.Equal(t, all, paged)
+
+	// Tokens are only valid for the ID set they were issued for
+	_, token, err = b.FindTracesByIDsPage(ctx, ids, "", 34, common.SearchOptions{})
+	require.NoError(t, err)
+	_, _, err = b.FindTracesByIDsPage(ctx, ids[:50], token, 34, common.SearchOptions{})
+	require.Error(t, err)
+
+	_, _, err = b.FindTracesByIDsPage(ctx, ids, "not a token", 34, common.SearchOptions{})
+	require.Error(t, err)
+
+	unknownVersion := continuationToken{Version: continuationTokenVersion + 1}.encode()
+	_, _, err = b.FindTracesByIDsPage(ctx, ids, unknownVersion, 34, common.SearchOptions{})
+	require.Error(t, err)
+}
@@ -0,0 +1,88 @@
+package vparquet
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/segmentio/parquet-go"
+
+	pq "github.com/grafana/tempo/pkg/parquetquery"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// ReadPage returns the decoded values of a single page of a column chunk. It is intended for
+// debugging and uses the offset index to seek straight to the page, so other pages of the chunk
+// are not read. The values are copied and do not reference the page buffers.
+func (b *backendBlock) ReadPage(ctx context.Context, columnPath string, rowGroup, pageIdx int) ([]parquet.Value, error) {
+	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "parquet.backendBlock.ReadPage",
+		opentracing.Tags{
+			"blockID":   b.meta.BlockID,
+			"tenantID":  b.meta.TenantID,
+			"column":    columnPath,
+			"rowGroup":  rowGroup,
+			"pageIndex": pageIdx,
+		})
+	defer span.Finish()
+
+	pf, _, err := b.openForSearch(derivedCtx, common.SearchOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unexpected error opening parquet file: %w", err)
+	}
+
+	colIndex, _ := pq.GetColumnIndexByPath(pf, columnPath)
+	if colIndex == -1 {
+		return nil, fmt.Errorf("unable to get index for column: %s", columnPath)
+	}
+
+	rowGroups := pf.RowGroups()
+	if rowGroup < 0 || rowGroup >= len(rowGroups) {
+		return nil, fmt.Errorf("row group %d out of range, block has %d row groups", rowGroup, len(rowGroups))
+	}
+
+	// search opens the file without the page index, so it is read here on demand
+	if pf.OffsetIndexes() == nil {
+		pf, err = parquet.OpenFile(pf, pf.Size(), parquet.SkipBloomFilters(true))
+		if err != nil {
+			return nil, fmt.Errorf("error reading page index: %w", err)
+		}
+		rowGroups = pf.RowGroups()
+	}
+
+	chunk := rowGroups[rowGroup].ColumnChunks()[colIndex]
+	offsets := chunk.OffsetIndex()
+	if offsets == nil {
+		return nil, fmt.Errorf("column %s has no offset index in row group %d", columnPath, rowGroup)
+	}
+	if pageIdx < 0 || pageIdx >= offsets.NumPages() {
+		return nil, fmt.Errorf("page %d out of range, column %s has %d pages in row group %d", pageIdx, columnPath, offsets.NumPages(), rowGroup)
+	}
+
+	pages := chunk.Pages()
+	defer pages.Close()
+
+	err = pages.SeekToRow(offsets.FirstRowIndex(pageIdx))
+	if err != nil {
+		return nil, fmt.Errorf("error seeking to page %d: %w", pageIdx, err)
+	}
+
+	pg, err := pages.ReadPage()
+	if err != nil {
+		return nil, fmt.Errorf("error reading page %d: %w", pageIdx, err)
+	}
+	defer parquet.Release(pg)
+
+	values := make([]parquet.Value, pg.NumValues())
+	n, err := pg.Values().ReadValues(values)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("error decoding page %d: %w", pageIdx, err)
+	}
+
+	values = values[:n]
+	for i := range values {
+		values[i] = values[i].Clone()
+	}
+
+	return values, nil
+}
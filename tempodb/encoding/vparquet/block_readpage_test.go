@@ -0,0 +1,45 @@
+package vparquet
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/util/test"
+)
+
+func TestBackendBlockReadPage(t *testing.T) {
+	var traces []*Trace
+	for i := 0; i < 150; i++ {
+		traces = append(traces, &Trace{TraceID: test.ValidTraceID(nil)})
+	}
+	sort.Slice(traces, func(i, j int) bool {
+		return bytes.Compare(traces[i].TraceID, traces[j].TraceID) == -1
+	})
+
+	// row groups of 1, 100 and 49 traces
+	b := makeBackendBlockWithTraces(t, traces)
+	ctx := context.Background()
+
+	values, err := b.ReadPage(ctx, TraceIDColumnName, 1, 0)
+	require.NoError(t, err)
+	require.Len(t, values, 100)
+	for i, v := range values {
+		require.Equal(t, []byte(traces[i+1].TraceID), v.ByteArray())
+	}
+
+	_, err = b.ReadPage(ctx, "NotAColumn", 0, 0)
+	require.EqualError(t, err, "unable to get index for column: NotAColumn")
+
+	_, err = b.ReadPage(ctx, TraceIDColumnName, 3, 0)
+	require.EqualError(t, err, "row group 3 out of range, block has 3 row groups")
+
+	_, err = b.ReadPage(ctx, TraceIDColumnName, 1, 1)
+	require.EqualError(t, err, "page 1 out of range, column TraceID has 1 pages in row group 1")
+
+	_, err = b.ReadPage(ctx, TraceIDColumnName, -1, 0)
+	require.Error(t, err)
+}
@@ -0,0 +1,57 @@
+package vparquet
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opentracing/opentracing-go"
+
+	pq "github.com/grafana/tempo/pkg/parquetquery"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// RowGroupBounds is the lowest trace ID and the number of rows of a row group.
+type RowGroupBounds struct {
+	MinID   common.ID
+	NumRows int64
+}
+
+// RowGroupIDBounds returns the bounds of every row group in the block, in order. These are the
+// same bounds FindTraceByID binary searches and only the first trace ID of each row group is read.
+func (b *backendBlock) RowGroupIDBounds(ctx context.Context) ([]RowGroupBounds, error) {
+	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "parquet.backendBlock.RowGroupIDBounds",
+		opentracing.Tags{
+			"blockID":  b.meta.BlockID,
+			"tenantID": b.meta.TenantID,
+		})
+	defer span.Finish()
+
+	pf, _, err := b.openForSearch(derivedCtx, common.SearchOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unexpected error opening parquet file: %w", err)
+	}
+
+	colIndex, _ := pq.GetColumnIndexByPath(pf, b.columns.traceID)
+	if colIndex == -1 {
+		return nil, fmt.Errorf("unable to get index for column: %s", b.columns.traceID)
+	}
+
+	index := newRowGroupIndex(pf, colIndex, b.meta)
+	rowGroups := pf.RowGroups()
+
+	bounds := make([]RowGroupBounds, 0, len(rowGroups))
+	for i, rg := range rowGroups {
+		min, err := index.min(i)
+		if err != nil {
+			return nil, fmt.Errorf("reading min trace ID of row group %d: %w", i, err)
+		}
+
+		bounds = append(bounds, RowGroupBounds{
+			// copied so the bounds don't reference the page buffers
+			MinID:   append(common.ID(nil), min...),
+			NumRows: rg.NumRows(),
+		})
+	}
+
+	return bounds, nil
+}
@@ -0,0 +1,44 @@
+package vparquet
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/util/test"
+)
+
+func TestBackendBlockRowGroupIDBounds(t *testing.T) {
+	var traces []*Trace
+	for i := 0; i < 250; i++ {
+		traces = append(traces, &Trace{TraceID: test.ValidTraceID(nil)})
+	}
+	sort.Slice(traces, func(i, j int) bool {
+		return bytes.Compare(traces[i].TraceID, traces[j].TraceID) == -1
+	})
+
+	// row groups of 1, 100, 100 and 49 traces
+	b := makeBackendBlockWithTraces(t, traces)
+
+	bounds, err := b.RowGroupIDBounds(context.Background())
+	require.NoError(t, err)
+	require.Len(t, bounds, 4)
+
+	var (
+		rows  int64
+		first int
+	)
+	for i, rg := range bounds {
+		require.Equal(t, []byte(traces[first].TraceID), []byte(rg.MinID))
+		if i > 0 {
+			require.True(t, bytes.Compare(bounds[i-1].MinID, rg.MinID) <= 0, "row group %d", i)
+		}
+
+		rows += rg.NumRows
+		first += int(rg.NumRows)
+	}
+	require.Equal(t, int64(len(traces)), rows)
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/opentracing/opentracing-go"
@@ -16,6 +17,7 @@ import (
 	pq "github.com/grafana/tempo/pkg/parquetquery"
 	"github.com/grafana/tempo/pkg/tempopb"
 	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/pkg/traceql"
 	"github.com/grafana/tempo/pkg/util"
 	"github.com/grafana/tempo/tempodb/encoding/common"
 )
@@ -36,6 +38,27 @@ var StatusCodeMapping = map[string]int{
 	StatusCodeError: int(v1.Status_STATUS_CODE_ERROR),
 }
 
+// openKey are the options an opened file depends on, the retries of its readers and whether its
+// page index was read. Lookups with other values don't share the file, they open it with their own.
+type openKey struct {
+	readRetries      int
+	readRetryBackoff time.Duration
+	readPageIndex    bool
+}
+
+type openedFile struct {
+	pf       *parquet.File
+	readerAt *BackendReaderAt
+}
+
+func openKeyFor(opts common.SearchOptions) openKey {
+	return openKey{
+		readRetries:      opts.ReadRetries,
+		readRetryBackoff: opts.ReadRetryBackoff,
+		readPageIndex:    opts.ReadPageIndex,
+	}
+}
+
 // openForSearch consolidates all the logic for opening a parquet file
 func (b *backendBlock) openForSearch(ctx context.Context, opts common.SearchOptions) (*parquet.File, *BackendReaderAt, error) {
 	b.openMtx.Lock()
@@ -44,16 +67,53 @@ func (b *backendBlock) openForSearch(ctx context.Context, opts common.SearchOpti
 	// if this backend block is repeatedly used for search/searchtags/findtracebyid/etc then this is a nice
 	// performance improvement. this does not happen currently for full backend search, but does happen
 	// if this is a complete block held on disk by the ingester
-	if b.pf != nil && b.readerAt != nil {
-		return b.pf, b.readerAt, nil
+	key := openKeyFor(opts)
+	if f, ok := b.opened[key]; ok {
+		return f.pf, f.readerAt, nil
+	}
+
+	pf, backendReaderAt, columns, err := b.openFile(ctx, opts)
+	if err == nil {
+		b.opened[key] = openedFile{pf: pf, readerAt: backendReaderAt}
+		b.setColumns(columns)
+	}
+
+	return pf, backendReaderAt, err
+}
+
+// openForLookup opens the parquet file for a single lookup. Unlike the file of openForSearch it isn't
+// shared, so the bytes its reader counts were read by the lookup only.
+func (b *backendBlock) openForLookup(ctx context.Context, opts common.SearchOptions) (*parquet.File, *BackendReaderAt, error) {
+	pf, backendReaderAt, columns, err := b.openFile(ctx, opts)
+	if err == nil {
+		b.openMtx.Lock()
+		b.setColumns(columns)
+		b.openMtx.Unlock()
+	}
+	return pf, backendReaderAt, err
+}
+
+// setColumns sets the columns of the block the first time a file is opened. It's called with
+// openMtx held, later opens don't write them again so lookups can read them without the lock.
+func (b *backendBlock) setColumns(columns schemaColumns) {
+	if b.columns == (schemaColumns{}) {
+		b.columns = columns
+	}
+}
+
+// openFile builds the readers of the file for opts and opens it.
+func (b *backendBlock) openFile(ctx context.Context, opts common.SearchOptions) (*parquet.File, *BackendReaderAt, schemaColumns, error) {
+	columns, err := columnsForVersion(b.meta.Version)
+	if err != nil {
+		return nil, nil, schemaColumns{}, err
 	}
 
-	backendReaderAt := NewBackendReaderAt(ctx, b.r, DataFileName, b.meta.BlockID, b.meta.TenantID)
+	backendReaderAt := NewBackendReaderAt(ctx, b.reader(opts), DataFileName, b.meta.BlockID, b.meta.TenantID)
 
-	// no searches currently require bloom filters or the page index. so just add them statically
+	// no searches currently require bloom filters. so just add them statically
 	o := []parquet.FileOption{
 		parquet.SkipBloomFilters(true),
-		parquet.SkipPageIndex(true),
+		parquet.SkipPageIndex(!opts.ReadPageIndex),
 	}
 
 	// backend reader
@@ -81,13 +141,14 @@ func (b *backendBlock) openForSearch(ctx context.Context, opts common.SearchOpti
 	span, _ := opentracing.StartSpanFromContext(ctx, "parquet.OpenFile")
 	defer span.Finish()
 	pf, err := parquet.OpenFile(readerAt, int64(b.meta.Size), o...)
-
-	if err == nil {
-		b.pf = pf
-		b.readerAt = backendReaderAt
+	if err != nil && opts.ReadPageIndex {
+		// a corrupt page index doesn't prevent reading the pages, they are found by scanning the
+		// column chunks instead
+		span.SetTag("pageIndexError", err.Error())
+		pf, err = parquet.OpenFile(readerAt, int64(b.meta.Size), append(o, parquet.SkipPageIndex(true))...)
 	}
 
-	return pf, backendReaderAt, err
+	return pf, backendReaderAt, columns, err
 }
 
 func (b *backendBlock) Search(ctx context.Context, req *tempopb.SearchRequest, opts common.SearchOptions) (_ *tempopb.SearchResponse, err error) {
@@ -119,7 +180,7 @@ func (b *backendBlock) Search(ctx context.Context, req *tempopb.SearchRequest, o
 		rgs = rgs[opts.StartPage : opts.StartPage+opts.TotalPages]
 	}
 
-	results, err := searchParquetFile(derivedCtx, pf, req, rgs)
+	results, err := searchParquetFile(derivedCtx, pf, req, rgs, pq.WithDecodeConcurrency(opts.DecodeConcurrency))
 	if err != nil {
 		return nil, err
 	}
@@ -131,11 +192,19 @@ func (b *backendBlock) Search(ctx context.Context, req *tempopb.SearchRequest, o
 }
 
 func (b *backendBlock) SearchTags(ctx context.Context, cb common.TagCallback, opts common.SearchOptions) error {
-	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "parquet.backendBlock.SearchTags",
+	return b.SearchTagNames(ctx, traceql.AttributeScopeNone, cb, opts)
+}
+
+// SearchTagNames reports the names of the attributes of the scope to cb. Attributes with a column of
+// their own are reported if the block has a value for them and generic attributes are read from the
+// dictionaries of the key columns. AttributeScopeNone reports the attributes of all scopes.
+func (b *backendBlock) SearchTagNames(ctx context.Context, scope traceql.AttributeScope, cb common.TagCallback, opts common.SearchOptions) error {
+	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "parquet.backendBlock.SearchTagNames",
 		opentracing.Tags{
 			"blockID":   b.meta.BlockID,
 			"tenantID":  b.meta.TenantID,
 			"blockSize": b.meta.Size,
+			"scope":     scope.String(),
 		})
 	defer span.Finish()
 
@@ -151,14 +220,20 @@ func (b *backendBlock) SearchTags(ctx context.Context, cb common.TagCallback, op
 	if resourceKeyIdx == -1 || spanKeyIdx == -1 {
 		return fmt.Errorf("resource or span attributes col not found (%d, %d)", resourceKeyIdx, spanKeyIdx)
 	}
-	standardAttrIdxs := []int{
-		resourceKeyIdx,
-		spanKeyIdx,
+	var standardAttrIdxs []int
+	if scope == traceql.AttributeScopeNone || scope == traceql.AttributeScopeResource {
+		standardAttrIdxs = append(standardAttrIdxs, resourceKeyIdx)
+	}
+	if scope == traceql.AttributeScopeNone || scope == traceql.AttributeScopeSpan {
+		standardAttrIdxs = append(standardAttrIdxs, spanKeyIdx)
 	}
 
 	// find indexes of all special columns
 	specialAttrIdxs := map[int]string{}
 	for lbl, col := range labelMappings {
+		if !columnInScope(col, scope) {
+			continue
+		}
 		idx, _ := pq.GetColumnIndexByPath(pf, col)
 		if idx == -1 {
 			continue
@@ -247,6 +322,20 @@ func (b *backendBlock) SearchTags(ctx context.Context, cb common.TagCallback, op
 	return nil
 }
 
+// columnInScope reports whether the column of a special attribute belongs to the scope. Columns
+// of the trace, like the root service name, only belong to AttributeScopeNone.
+func columnInScope(column string, scope traceql.AttributeScope) bool {
+	switch scope {
+	case traceql.AttributeScopeResource:
+		return strings.HasPrefix(column, "rs.Resource.")
+	case traceql.AttributeScopeSpan:
+		return strings.HasPrefix(column, "rs.ils.Spans.")
+	}
+	return true
+}
+
+// SearchTagValues reports the distinct values of the tag to cb, at most opts.MaxTagValues of them.
+// Columns of their own are read from their dictionaries where possible.
 func (b *backendBlock) SearchTagValues(ctx context.Context, tag string, cb common.TagCallback, opts common.SearchOptions) error {
 	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "parquet.backendBlock.SearchTagValues",
 		opentracing.Tags{
@@ -262,26 +351,28 @@ func (b *backendBlock) SearchTagValues(ctx context.Context, tag string, cb commo
 	}
 	defer func() { span.SetTag("inspectedBytes", rr.TotalBytesRead.Load()) }()
 
+	values := newDistinctValues(cb, opts.MaxTagValues)
+
 	// labelMappings will indicate whether this is a search for a special or standard
 	// column
 	column := labelMappings[tag]
 	if column == "" {
-		err = searchStandardTagValues(ctx, tag, pf, cb)
+		err = searchStandardTagValues(ctx, tag, pf, values)
 		if err != nil {
 			return fmt.Errorf("unexpected error searching standard tags: %w", err)
 		}
 		return nil
 	}
 
-	err = searchSpecialTagValues(ctx, column, pf, cb)
+	err = searchSpecialTagValues(ctx, column, pf, values)
 	if err != nil {
 		return fmt.Errorf("unexpected error searching special tags: %w", err)
 	}
 	return nil
 }
 
-func makePipelineWithRowGroups(ctx context.Context, req *tempopb.SearchRequest, pf *parquet.File, rgs []parquet.RowGroup) pq.Iterator {
-	makeIter := makeIterFunc(ctx, rgs, pf)
+func makePipelineWithRowGroups(ctx context.Context, req *tempopb.SearchRequest, pf *parquet.File, rgs []parquet.RowGroup, iterOpts ...pq.ColumnIteratorOption) pq.Iterator {
+	makeIter := makeIterFunc(ctx, rgs, pf, iterOpts...)
 
 	// Wire up iterators
 	var resourceIters []pq.Iterator
@@ -413,7 +504,9 @@ func makePipelineWithRowGroups(ctx context.Context, req *tempopb.SearchRequest,
 	}
 }
 
-func searchParquetFile(ctx context.Context, pf *parquet.File, req *tempopb.SearchRequest, rgs []parquet.RowGroup) (*tempopb.SearchResponse, error) {
+// searchParquetFile applies iterOpts to the column iterators that scan for matches. The display
+// columns are only read for the matches and always use the defaults.
+func searchParquetFile(ctx context.Context, pf *parquet.File, req *tempopb.SearchRequest, rgs []parquet.RowGroup, iterOpts ...pq.ColumnIteratorOption) (*tempopb.SearchResponse, error) {
 
 	// Search happens in 2 phases for an optimization.
 	// Phase 1 is iterate all columns involved in the request.
@@ -421,7 +514,7 @@ func searchParquetFile(ctx context.Context, pf *parquet.File, req *tempopb.Searc
 	// is to load the display-related columns.
 
 	// Find matches
-	matchingRows, err := searchRaw(ctx, pf, req, rgs)
+	matchingRows, err := searchRaw(ctx, pf, req, rgs, iterOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -441,8 +534,8 @@ func searchParquetFile(ctx context.Context, pf *parquet.File, req *tempopb.Searc
 	}, nil
 }
 
-func searchRaw(ctx context.Context, pf *parquet.File, req *tempopb.SearchRequest, rgs []parquet.RowGroup) ([]pq.RowNumber, error) {
-	iter := makePipelineWithRowGroups(ctx, req, pf, rgs)
+func searchRaw(ctx context.Context, pf *parquet.File, req *tempopb.SearchRequest, rgs []parquet.RowGroup, iterOpts ...pq.ColumnIteratorOption) ([]pq.RowNumber, error) {
+	iter := makePipelineWithRowGroups(ctx, req, pf, rgs, iterOpts...)
 	if iter == nil {
 		return nil, errors.New("make pipeline returned a nil iterator")
 	}
@@ -506,18 +599,18 @@ func rawToResults(ctx context.Context, pf *parquet.File, rgs []parquet.RowGroup,
 
 // searchStandardTagValues searches a parquet file for "standard" tags. i.e. tags that don't have unique
 // columns and are contained in labelMappings
-func searchStandardTagValues(ctx context.Context, tag string, pf *parquet.File, cb common.TagCallback) error {
+func searchStandardTagValues(ctx context.Context, tag string, pf *parquet.File, values *distinctValues) error {
 	rgs := pf.RowGroups()
 	makeIter := makeIterFunc(ctx, rgs, pf)
 
 	keyPred := pq.NewStringInPredicate([]string{tag})
 
-	err := searchKeyValues(DefinitionLevelResourceAttrs, FieldResourceAttrKey, FieldResourceAttrVal, makeIter, keyPred, cb)
+	err := searchKeyValues(DefinitionLevelResourceAttrs, FieldResourceAttrKey, FieldResourceAttrVal, makeIter, keyPred, values)
 	if err != nil {
 		return errors.Wrap(err, "search resource key values")
 	}
 
-	err = searchKeyValues(DefinitionLevelResourceSpansILSSpanAttrs, FieldSpanAttrKey, FieldSpanAttrVal, makeIter, keyPred, cb)
+	err = searchKeyValues(DefinitionLevelResourceSpansILSSpanAttrs, FieldSpanAttrKey, FieldSpanAttrVal, makeIter, keyPred, values)
 	if err != nil {
 		return errors.Wrap(err, "search span key values")
 	}
@@ -525,7 +618,10 @@ func searchStandardTagValues(ctx context.Context, tag string, pf *parquet.File,
 	return nil
 }
 
-func searchKeyValues(definitionLevel int, keyPath, valuePath string, makeIter makeIterFn, keyPred pq.Predicate, cb common.TagCallback) error {
+func searchKeyValues(definitionLevel int, keyPath, valuePath string, makeIter makeIterFn, keyPred pq.Predicate, values *distinctValues) error {
+	if values.done() {
+		return nil
+	}
 
 	iter := pq.NewJoinIterator(definitionLevel, []pq.Iterator{
 		makeIter(keyPath, keyPred, ""),
@@ -533,7 +629,7 @@ func searchKeyValues(definitionLevel int, keyPath, valuePath string, makeIter ma
 	}, nil)
 	defer iter.Close()
 
-	for {
+	for !values.done() {
 		match, err := iter.Next()
 		if err != nil {
 			return err
@@ -543,7 +639,7 @@ func searchKeyValues(definitionLevel int, keyPath, valuePath string, makeIter ma
 		}
 		for _, e := range match.Entries {
 			// We know that "values" is the only data selected above.
-			cb(e.Value.String())
+			values.report(e.Value.String())
 		}
 	}
 
@@ -552,8 +648,8 @@ func searchKeyValues(definitionLevel int, keyPath, valuePath string, makeIter ma
 
 // searchSpecialTagValues searches a parquet file for all values for the provided column. It first attempts
 // to only pull all values from the column's dictionary. If this fails it falls back to scanning the entire path.
-func searchSpecialTagValues(ctx context.Context, column string, pf *parquet.File, cb common.TagCallback) error {
-	pred := newReportValuesPredicate(cb)
+func searchSpecialTagValues(ctx context.Context, column string, pf *parquet.File, values *distinctValues) error {
+	pred := newReportValuesPredicate(values)
 	rgs := pf.RowGroups()
 
 	iter := makeIterFunc(ctx, rgs, pf)(column, pred, "")
@@ -571,14 +667,14 @@ func searchSpecialTagValues(ctx context.Context, column string, pf *parquet.File
 	return nil
 }
 
-func makeIterFunc(ctx context.Context, rgs []parquet.RowGroup, pf *parquet.File) func(name string, predicate pq.Predicate, selectAs string) pq.Iterator {
+func makeIterFunc(ctx context.Context, rgs []parquet.RowGroup, pf *parquet.File, opts ...pq.ColumnIteratorOption) func(name string, predicate pq.Predicate, selectAs string) pq.Iterator {
 	return func(name string, predicate pq.Predicate, selectAs string) pq.Iterator {
 		index, _ := pq.GetColumnIndexByPath(pf, name)
 		if index == -1 {
 			// TODO - don't panic, error instead
 			panic("column not found in parquet file:" + name)
 		}
-		return pq.NewColumnIterator(ctx, rgs, index, name, 1000, predicate, selectAs)
+		return pq.NewColumnIterator(ctx, rgs, index, name, 1000, predicate, selectAs, opts...)
 	}
 }
 
@@ -610,28 +706,59 @@ func (r *rowNumberIterator) SeekTo(to pq.RowNumber, definitionLevel int) (*pq.It
 
 func (r *rowNumberIterator) Close() {}
 
+// distinctValues reports every value to cb once. Once limit values were reported everything else is
+// dropped and done tells the search to stop. A limit of 0 is unlimited.
+type distinctValues struct {
+	cb    common.TagCallback
+	limit int
+	seen  map[string]struct{}
+}
+
+func newDistinctValues(cb common.TagCallback, limit int) *distinctValues {
+	return &distinctValues{cb: cb, limit: limit, seen: map[string]struct{}{}}
+}
+
+func (d *distinctValues) report(v string) {
+	if d.done() {
+		return
+	}
+	if _, ok := d.seen[v]; ok {
+		return
+	}
+	d.seen[v] = struct{}{}
+	d.cb(v)
+}
+
+func (d *distinctValues) done() bool {
+	return d.limit > 0 && len(d.seen) >= d.limit
+}
+
 // reportValuesPredicate is a "fake" predicate that uses existing iterator logic to find all values in a given column
 type reportValuesPredicate struct {
-	cb common.TagCallback
+	values *distinctValues
 }
 
-func newReportValuesPredicate(cb common.TagCallback) *reportValuesPredicate {
-	return &reportValuesPredicate{cb: cb}
+func newReportValuesPredicate(values *distinctValues) *reportValuesPredicate {
+	return &reportValuesPredicate{values: values}
 }
 
-// KeepColumnChunk always returns true b/c we always have to dig deeper to find all values
+// KeepColumnChunk returns true until enough values were found b/c we always have to dig deeper to find all values
 func (r *reportValuesPredicate) KeepColumnChunk(cc parquet.ColumnChunk) bool {
-	return true
+	return !r.values.done()
 }
 
 // KeepPage checks to see if the page has a dictionary. if it does then we can report the values contained in it
 // and return false b/c we don't have to go to the actual columns to retrieve values. if there is no dict we return
 // true so the iterator will call KeepValue on all values in the column
 func (r *reportValuesPredicate) KeepPage(pg parquet.Page) bool {
+	if r.values.done() {
+		return false
+	}
+
 	if dict := pg.Dictionary(); dict != nil {
 		for i := 0; i < dict.Len(); i++ {
 			s := dict.Index(int32(i)).String()
-			r.cb(s)
+			r.values.report(s)
 		}
 
 		return false
@@ -643,7 +770,7 @@ func (r *reportValuesPredicate) KeepPage(pg parquet.Page) bool {
 // KeepValue is only called if this column does not have a dictionary. Just report everything to r.cb and
 // return false so the iterator do any extra work.
 func (r *reportValuesPredicate) KeepValue(v parquet.Value) bool {
-	r.cb(v.String())
+	r.values.report(v.String())
 
 	return false
 }
@@ -2,6 +2,7 @@ package vparquet
 
 import (
 	"context"
+	"fmt"
 	"math/rand"
 	"path"
 	"testing"
@@ -11,6 +12,7 @@ import (
 	tempo_io "github.com/grafana/tempo/pkg/io"
 	"github.com/grafana/tempo/pkg/tempopb"
 	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/pkg/traceql"
 	"github.com/grafana/tempo/pkg/util"
 	"github.com/grafana/tempo/pkg/util/test"
 	"github.com/grafana/tempo/tempodb/backend"
@@ -181,6 +183,13 @@ func TestBackendBlockSearch(t *testing.T) {
 		meta := findInResults(expected.TraceID, res.Traces)
 		require.NotNil(t, meta, "search request:", req)
 		require.Equal(t, expected, meta, "search request:", req)
+
+		// decoding pages concurrently doesn't change the results
+		opts := defaultSearchOptions()
+		opts.DecodeConcurrency = 4
+		concurrentRes, err := b.Search(ctx, req, opts)
+		require.NoError(t, err)
+		require.Equal(t, res, concurrentRes, "search request:", req)
 	}
 
 	// Excludes
@@ -249,6 +258,65 @@ func TestBackendBlockSearchTags(t *testing.T) {
 	}
 }
 
+func TestBackendBlockSearchTagNames(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+	tr := &Trace{
+		TraceID:         test.ValidTraceID(nil),
+		RootServiceName: "svc",
+		ResourceSpans: []ResourceSpans{{
+			Resource: Resource{
+				ServiceName: "svc",
+				Attrs:       []Attribute{{Key: "res-attr", Value: strPtr("a")}},
+			},
+			ScopeSpans: []ScopeSpan{{
+				Spans: []Span{{
+					ID:         []byte("span"),
+					Name:       "span",
+					HttpMethod: strPtr("get"),
+					Attrs:      []Attribute{{Key: "span-attr", Value: strPtr("b")}},
+				}},
+			}},
+		}},
+	}
+	block := makeBackendBlockWithTraces(t, []*Trace{tr})
+
+	tcs := []struct {
+		scope    traceql.AttributeScope
+		expected []string
+		excluded []string
+	}{
+		{
+			scope:    traceql.AttributeScopeResource,
+			expected: []string{LabelServiceName, "res-attr"},
+			excluded: []string{LabelRootServiceName, LabelName, LabelHTTPMethod, "span-attr"},
+		},
+		{
+			scope:    traceql.AttributeScopeSpan,
+			expected: []string{LabelName, LabelHTTPMethod, "span-attr"},
+			excluded: []string{LabelRootServiceName, LabelServiceName, "res-attr"},
+		},
+		{
+			scope:    traceql.AttributeScopeNone,
+			expected: []string{LabelRootServiceName, LabelServiceName, LabelName, LabelHTTPMethod, "res-attr", "span-attr"},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.scope.String(), func(t *testing.T) {
+			found := map[string]struct{}{}
+			err := block.SearchTagNames(context.Background(), tc.scope, func(s string) { found[s] = struct{}{} }, defaultSearchOptions())
+			require.NoError(t, err)
+
+			for _, name := range tc.expected {
+				require.Contains(t, found, name)
+			}
+			for _, name := range tc.excluded {
+				require.NotContains(t, found, name)
+			}
+		})
+	}
+}
+
 func TestBackendBlockSearchTagValues(t *testing.T) {
 	traces, attrs := makeTraces()
 	block := makeBackendBlockWithTraces(t, traces)
@@ -267,6 +335,71 @@ func TestBackendBlockSearchTagValues(t *testing.T) {
 	}
 }
 
+func TestBackendBlockSearchTagValuesDistinctAndLimited(t *testing.T) {
+	var traces []*Trace
+	for i := 0; i < 20; i++ {
+		env := fmt.Sprintf("env-%d", i%4)
+		traces = append(traces, &Trace{
+			TraceID: test.ValidTraceID(nil),
+			ResourceSpans: []ResourceSpans{{
+				// service.name is a dictionary encoded column of its own
+				Resource: Resource{ServiceName: fmt.Sprintf("svc-%d", i%5)},
+				ScopeSpans: []ScopeSpan{{
+					Spans: []Span{{
+						ID:    []byte("span"),
+						Attrs: []Attribute{{Key: "env", Value: &env}},
+					}},
+				}},
+			}},
+		})
+	}
+	block := makeBackendBlockWithTraces(t, traces)
+
+	tcs := []struct {
+		tag      string
+		limit    int
+		expected []string
+	}{
+		{tag: LabelServiceName, expected: []string{"svc-0", "svc-1", "svc-2", "svc-3", "svc-4"}},
+		{tag: LabelServiceName, limit: 2, expected: []string{"svc-0", "svc-1", "svc-2", "svc-3", "svc-4"}},
+		{tag: "env", expected: []string{"env-0", "env-1", "env-2", "env-3"}},
+		{tag: "env", limit: 3, expected: []string{"env-0", "env-1", "env-2", "env-3"}},
+		{tag: "env", limit: 10, expected: []string{"env-0", "env-1", "env-2", "env-3"}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(fmt.Sprintf("%s limit %d", tc.tag, tc.limit), func(t *testing.T) {
+			var actual []string
+			opts := defaultSearchOptions()
+			opts.MaxTagValues = tc.limit
+
+			err := block.SearchTagValues(context.Background(), tc.tag, func(s string) { actual = append(actual, s) }, opts)
+			require.NoError(t, err)
+
+			expectedLen := len(tc.expected)
+			if tc.limit > 0 && tc.limit < expectedLen {
+				expectedLen = tc.limit
+			}
+			// every value is reported once and only values of the tag are reported
+			require.Len(t, actual, expectedLen)
+			require.Subset(t, tc.expected, actual)
+			require.ElementsMatch(t, actual, uniqueStrings(actual))
+		})
+	}
+}
+
+func uniqueStrings(s []string) []string {
+	seen := map[string]struct{}{}
+	var unique []string
+	for _, v := range s {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
 func makeBackendBlockWithTraces(t *testing.T, trs []*Trace) *backendBlock {
 	rawR, rawW, _, err := local.New(&local.Config{
 		Path: t.TempDir(),
@@ -0,0 +1,129 @@
+package vparquet
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+var metricTraceCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "tempodb",
+	Name:      "vparquet_trace_cache_requests_total",
+	Help:      "Total number of FindTraceByID lookups that used the trace cache of a block, by result.",
+}, []string{"result"})
+
+const (
+	traceCacheHit  = "hit"
+	traceCacheMiss = "miss"
+)
+
+// traceCache keeps the traces found by FindTraceByID for a short time. Traces are stored marshaled,
+// which sizes them and gives every lookup its own copy to modify.
+type traceCache struct {
+	mtx     sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // of *traceCacheEntry, oldest first
+	bytes   int
+
+	now func() time.Time
+}
+
+type traceCacheEntry struct {
+	id      string
+	trace   []byte
+	loc     TraceLocation
+	expires time.Time
+}
+
+func newTraceCache() *traceCache {
+	return &traceCache{
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// traceCacheEnabled reports whether the lookup may use the trace cache. The cache is keyed by trace ID
+// only, so it's used by lookups that read the whole trace as stored. Lookups that filter, limit or
+// order the trace don't, their result differs from the trace in the cache. Neither do lookups that
+// check for duplicate trace IDs or report on the trace with callbacks, a cache hit would skip them.
+func traceCacheEnabled(opts common.SearchOptions) bool {
+	return opts.TraceCacheTTL > 0 &&
+		opts.ServiceName == "" &&
+		opts.TimeWindowStartUnixNano == 0 &&
+		opts.TimeWindowEndUnixNano == 0 &&
+		opts.MaxSpansPerTrace <= 0 &&
+		opts.SpanLess == nil &&
+		opts.DuplicateTraceIDs == common.DuplicateTraceIDsIgnore &&
+		opts.TraceTruncated == nil &&
+		opts.TraceCompleteness == nil
+}
+
+// get returns the cached trace if it hasn't expired.
+func (c *traceCache) get(id common.ID) (*tempopb.Trace, TraceLocation, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	el, ok := c.entries[string(id)]
+	if !ok {
+		return nil, TraceLocation{}, false
+	}
+	e := el.Value.(*traceCacheEntry)
+	if !c.now().Before(e.expires) {
+		c.remove(el)
+		return nil, TraceLocation{}, false
+	}
+
+	tr := &tempopb.Trace{}
+	if err := tr.Unmarshal(e.trace); err != nil {
+		c.remove(el)
+		return nil, TraceLocation{}, false
+	}
+	return tr, e.loc, true
+}
+
+// put caches the trace for the TTL of the options. It evicts expired traces at the front and then the
+// oldest traces until the cache is within its bounds again. A trace larger than TraceCacheMaxBytes
+// isn't cached.
+func (c *traceCache) put(id common.ID, tr *tempopb.Trace, loc TraceLocation, opts common.SearchOptions) {
+	b, err := tr.Marshal()
+	if err != nil || (opts.TraceCacheMaxBytes > 0 && len(b) > opts.TraceCacheMaxBytes) {
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if el, ok := c.entries[string(id)]; ok {
+		c.remove(el)
+	}
+	now := c.now()
+	e := &traceCacheEntry{id: string(id), trace: b, loc: loc, expires: now.Add(opts.TraceCacheTTL)}
+	c.entries[e.id] = c.order.PushBack(e)
+	c.bytes += len(b)
+
+	for front := c.order.Front(); front != nil; front = c.order.Front() {
+		expired := !now.Before(front.Value.(*traceCacheEntry).expires)
+		if !expired && !c.overBounds(opts) {
+			break
+		}
+		c.remove(front)
+	}
+}
+
+func (c *traceCache) overBounds(opts common.SearchOptions) bool {
+	return (opts.TraceCacheMaxEntries > 0 && c.order.Len() > opts.TraceCacheMaxEntries) ||
+		(opts.TraceCacheMaxBytes > 0 && c.bytes > opts.TraceCacheMaxBytes)
+}
+
+func (c *traceCache) remove(el *list.Element) {
+	e := c.order.Remove(el).(*traceCacheEntry)
+	delete(c.entries, e.id)
+	c.bytes -= len(e.trace)
+}
@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/golang/protobuf/jsonpb" //nolint:all //deprecated
 	"github.com/pkg/errors"
 	"github.com/segmentio/parquet-go"
 
 	"github.com/grafana/tempo/pkg/parquetquery"
+	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
 	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
 	"github.com/grafana/tempo/pkg/traceql"
 	"github.com/grafana/tempo/tempodb/encoding/common"
@@ -31,6 +35,7 @@ const (
 	columnPathResourceAttrInt          = "rs.Resource.Attrs.ValueInt"
 	columnPathResourceAttrDouble       = "rs.Resource.Attrs.ValueDouble"
 	columnPathResourceAttrBool         = "rs.Resource.Attrs.ValueBool"
+	columnPathResourceAttrArray        = "rs.Resource.Attrs.ValueArray"
 	columnPathResourceServiceName      = "rs.Resource.ServiceName"
 	columnPathResourceCluster          = "rs.Resource.Cluster"
 	columnPathResourceNamespace        = "rs.Resource.Namespace"
@@ -42,6 +47,7 @@ const (
 	columnPathResourceK8sContainerName = "rs.Resource.K8sContainerName"
 
 	columnPathSpanID        = "rs.ils.Spans.ID"
+	columnPathSpanParentID  = "rs.ils.Spans.ParentSpanID"
 	columnPathSpanName      = "rs.ils.Spans.Name"
 	columnPathSpanStartTime = "rs.ils.Spans.StartUnixNanos"
 	columnPathSpanEndTime   = "rs.ils.Spans.EndUnixNanos"
@@ -52,15 +58,21 @@ const (
 	columnPathSpanAttrInt        = "rs.ils.Spans.Attrs.ValueInt"
 	columnPathSpanAttrDouble     = "rs.ils.Spans.Attrs.ValueDouble"
 	columnPathSpanAttrBool       = "rs.ils.Spans.Attrs.ValueBool"
+	columnPathSpanAttrArray      = "rs.ils.Spans.Attrs.ValueArray"
 	columnPathSpanHTTPStatusCode = "rs.ils.Spans.HttpStatusCode"
 	columnPathSpanHTTPMethod     = "rs.ils.Spans.HttpMethod"
 	columnPathSpanHTTPURL        = "rs.ils.Spans.HttpUrl"
 )
 
 var intrinsicDefaultScope = map[traceql.Intrinsic]traceql.AttributeScope{
-	traceql.IntrinsicName:     traceql.AttributeScopeSpan,
-	traceql.IntrinsicDuration: traceql.AttributeScopeSpan,
-	traceql.IntrinsicStatus:   traceql.AttributeScopeSpan,
+	traceql.IntrinsicName:       traceql.AttributeScopeSpan,
+	traceql.IntrinsicDuration:   traceql.AttributeScopeSpan,
+	traceql.IntrinsicStatus:     traceql.AttributeScopeSpan,
+	traceql.IntrinsicSelfTime:   traceql.AttributeScopeSpan,
+	traceql.IntrinsicChildCount: traceql.AttributeScopeSpan,
+	traceql.IntrinsicParent:     traceql.AttributeScopeSpan,
+	traceql.IntrinsicStartTime:  traceql.AttributeScopeSpan,
+	traceql.IntrinsicEndTime:    traceql.AttributeScopeSpan,
 }
 
 // Lookup table of all well-known attributes with dedicated columns
@@ -97,10 +109,23 @@ func (b *backendBlock) Fetch(ctx context.Context, req traceql.FetchSpansRequest)
 	}
 
 	// TODO - route global search options here
-	pf, _, err := b.openForSearch(ctx, common.SearchOptions{})
+	ok, err := b.mayMatch(ctx, req, common.SearchOptions{})
 	if err != nil {
 		return traceql.FetchSpansResponse{}, err
 	}
+	if !ok {
+		return traceql.FetchSpansResponse{
+			Results: emptySpansetIterator{},
+		}, nil
+	}
+
+	pf, rr, err := b.openForSearch(ctx, common.SearchOptions{})
+	if err != nil {
+		return traceql.FetchSpansResponse{}, err
+	}
+
+	// the reader is shared by all searches of the block, only count what this fetch reads
+	start := rr.TotalBytesRead.Load()
 
 	iter, err := fetch(ctx, req, pf)
 	if err != nil {
@@ -109,9 +134,92 @@ func (b *backendBlock) Fetch(ctx context.Context, req traceql.FetchSpansRequest)
 
 	return traceql.FetchSpansResponse{
 		Results: iter,
+		Bytes:   func() uint64 { return rr.TotalBytesRead.Load() - start },
 	}, nil
 }
 
+// mayMatch checks if the block could contain matches for the request based on the columns in its
+// schema alone. A block that has none of the columns the conditions need, or that is missing the
+// columns of any condition when all conditions must match, can be skipped.
+func (b *backendBlock) mayMatch(ctx context.Context, req traceql.FetchSpansRequest, opts common.SearchOptions) (bool, error) {
+	pf, _, err := b.openForSearch(ctx, opts)
+	if err != nil {
+		return false, err
+	}
+
+	return schemaMayMatch(pf, req), nil
+}
+
+func schemaMayMatch(pf *parquet.File, req traceql.FetchSpansRequest) bool {
+	if len(req.Conditions) == 0 {
+		return true
+	}
+
+	for _, cond := range req.Conditions {
+		present := false
+		for _, columnPath := range conditionColumns(cond) {
+			if idx, _ := parquetquery.GetColumnIndexByPath(pf, columnPath); idx != -1 {
+				present = true
+				break
+			}
+		}
+
+		if present && !req.AllConditions {
+			return true
+		}
+		if !present && req.AllConditions {
+			return false
+		}
+	}
+
+	return req.AllConditions
+}
+
+// conditionColumns returns the paths of all columns that may hold values for the condition.
+func conditionColumns(cond traceql.Condition) []string {
+	switch cond.Attribute.Intrinsic {
+	case traceql.IntrinsicName:
+		return []string{columnPathSpanName}
+	case traceql.IntrinsicDuration:
+		return []string{columnPathSpanStartTime, columnPathSpanEndTime}
+	case traceql.IntrinsicStatus:
+		return []string{columnPathSpanStatusCode}
+	case traceql.IntrinsicSelfTime:
+		return []string{columnPathSpanParentID, columnPathSpanStartTime, columnPathSpanEndTime}
+	case traceql.IntrinsicChildCount, traceql.IntrinsicParent:
+		return []string{columnPathSpanParentID}
+	case traceql.IntrinsicStartTime:
+		return []string{columnPathSpanStartTime}
+	case traceql.IntrinsicEndTime:
+		return []string{columnPathSpanEndTime}
+	}
+
+	var columns []string
+	scope := cond.Attribute.Scope
+
+	// Well-known attributes fall back to the generic columns if the operand type doesn't match
+	if entry, ok := wellKnownColumnLookups[cond.Attribute.Name]; ok && (scope == traceql.AttributeScopeNone || scope == entry.level) {
+		columns = append(columns, entry.columnPath)
+	}
+	if scope != traceql.AttributeScopeResource {
+		columns = append(columns, columnPathSpanAttrKey)
+	}
+	if scope != traceql.AttributeScopeSpan {
+		columns = append(columns, columnPathResourceAttrKey)
+	}
+
+	return columns
+}
+
+// emptySpansetIterator is returned for blocks that can't contain any matches.
+type emptySpansetIterator struct{}
+
+var _ traceql.SpansetIterator = emptySpansetIterator{}
+
+func (emptySpansetIterator) Next(context.Context) (*traceql.Spanset, error) {
+	return nil, nil
+}
+
 func checkConditions(conditions []traceql.Condition) error {
 	for _, cond := range conditions {
 		opCount := len(cond.Operands)
@@ -126,11 +234,21 @@ func checkConditions(conditions []traceql.Condition) error {
 		case traceql.OpEqual, traceql.OpNotEqual,
 			traceql.OpGreater, traceql.OpGreaterEqual,
 			traceql.OpLess, traceql.OpLessEqual,
-			traceql.OpRegex:
+			traceql.OpRegex, traceql.OpNotRegex:
 			if opCount != 1 {
 				return fmt.Errorf("operation %v must have exactly 1 argument. condition: %+v", cond.Op, cond)
 			}
 
+		case traceql.OpBetween:
+			if opCount != 2 {
+				return fmt.Errorf("operation %v must have exactly 2 arguments. condition: %+v", cond.Op, cond)
+			}
+
+		case traceql.OpIn:
+			if opCount == 0 {
+				return fmt.Errorf("operation %v must have at least 1 argument. condition: %+v", cond.Op, cond)
+			}
+
 		default:
 			return fmt.Errorf("unknown operation. condition: %+v", cond)
 		}
@@ -161,6 +279,19 @@ func operandType(operands traceql.Operands) traceql.StaticType {
 // traceql iterator.  Every row it receives is one spanset.
 type spansetIterator struct {
 	iter parquetquery.Iterator
+	errs *collectorErrors
+}
+
+// collectorErrors holds the first error of the collectors of a fetch. Collectors can't return
+// errors, so the spansetIterator returns it instead of the spanset the collector was part of.
+type collectorErrors struct {
+	err error
+}
+
+func (e *collectorErrors) store(err error) {
+	if e != nil && e.err == nil {
+		e.err = err
+	}
 }
 
 var _ traceql.SpansetIterator = (*spansetIterator)(nil)
@@ -171,6 +302,9 @@ func (i *spansetIterator) Next(ctx context.Context) (*traceql.Spanset, error) {
 	if err != nil {
 		return nil, err
 	}
+	if i.errs != nil && i.errs.err != nil {
+		return nil, i.errs.err
+	}
 	if res == nil {
 		return nil, nil
 	}
@@ -249,8 +383,22 @@ func (i *spansetIterator) Next(ctx context.Context) (*traceql.Spanset, error) {
 //                                                            |
 //                                                            V
 
+// fetch only reads the columns the conditions refer to, see conditionColumns, and the span IDs and times
+// and trace columns every spanset is built from. Attributes without a scope read both the span and the
+// resource columns.
 func fetch(ctx context.Context, req traceql.FetchSpansRequest, pf *parquet.File) (*spansetIterator, error) {
 
+	// The self time, child count and parent of a span depend on other spans, which don't have to match
+	// any condition, and = nil matches the spans without the attribute. All spans are fetched and the
+	// conditions only select the columns the engine filters on.
+	allSpans := requestsTraceStructure(req.Conditions) || requestsMissingAttributes(req.Conditions)
+	if allSpans {
+		req.Conditions = selectOnly(req.Conditions)
+		req.AllConditions = false
+	} else {
+		req.Conditions = selectNilComparisons(req.Conditions)
+	}
+
 	// Categorize conditions into span-level or resource-level
 	var (
 		mingledConditions  bool
@@ -319,25 +467,85 @@ func fetch(ctx context.Context, req traceql.FetchSpansRequest, pf *parquet.File)
 		// one either resource or span.
 		allConditions = req.AllConditions && !mingledConditions
 	)
+	if allSpans {
+		spanRequireAtLeastOneMatch = false
+		batchRequireAtLeastOneMatch = false
+		batchRequireAtLeastOneMatchOverall = false
+	}
 
-	spanIter, err := createSpanIterator(makeIter, spanConditions, req.StartTimeUnixNanos, req.EndTimeUnixNanos, spanRequireAtLeastOneMatch, allConditions)
+	errs := &collectorErrors{}
+
+	spanIter, err := createSpanIterator(makeIter, errs, spanConditions, req.StartTimeUnixNanos, req.EndTimeUnixNanos, spanRequireAtLeastOneMatch, allConditions)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating span iterator")
 	}
 
-	resourceIter, err := createResourceIterator(makeIter, spanIter, resourceConditions, batchRequireAtLeastOneMatch, batchRequireAtLeastOneMatchOverall, allConditions)
+	resourceIter, err := createResourceIterator(makeIter, errs, spanIter, resourceConditions, batchRequireAtLeastOneMatch, batchRequireAtLeastOneMatchOverall, allConditions)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating resource iterator")
 	}
 
 	traceIter := createTraceIterator(makeIter, resourceIter)
 
-	return &spansetIterator{traceIter}, nil
+	return &spansetIterator{traceIter, errs}, nil
+}
+
+// requestsTraceStructure reports whether any condition is on an intrinsic the engine computes from
+// the parents and children of spans.
+func requestsTraceStructure(conditions []traceql.Condition) bool {
+	for _, cond := range conditions {
+		switch cond.Attribute.Intrinsic {
+		case traceql.IntrinsicSelfTime, traceql.IntrinsicChildCount, traceql.IntrinsicParent:
+			return true
+		}
+	}
+	return false
+}
+
+// requestsMissingAttributes returns true if any condition matches spans without its attribute.
+func requestsMissingAttributes(conditions []traceql.Condition) bool {
+	for _, cond := range conditions {
+		if cond.Op == traceql.OpEqual && comparesWithNil(cond) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectNilComparisons turns != nil conditions into conditions that fetch the attribute, the engine
+// compares the value.
+func selectNilComparisons(conditions []traceql.Condition) []traceql.Condition {
+	selected := make([]traceql.Condition, 0, len(conditions))
+	for _, cond := range conditions {
+		if comparesWithNil(cond) {
+			cond = traceql.Condition{Attribute: cond.Attribute, Op: traceql.OpNone}
+		}
+		selected = append(selected, cond)
+	}
+	return selected
+}
+
+func comparesWithNil(cond traceql.Condition) bool {
+	return (cond.Op == traceql.OpEqual || cond.Op == traceql.OpNotEqual) &&
+		len(cond.Operands) == 1 && cond.Operands[0].Type == traceql.TypeNil
+}
+
+// selectOnly drops the operators of the conditions, so they fetch their columns without filtering.
+func selectOnly(conditions []traceql.Condition) []traceql.Condition {
+	selected := make([]traceql.Condition, 0, len(conditions))
+	for _, cond := range conditions {
+		selected = append(selected, traceql.Condition{
+			Attribute: cond.Attribute,
+			Op:        traceql.OpNone,
+			Elements:  cond.Elements,
+		})
+	}
+	return selected
 }
 
 // createSpanIterator iterates through all span-level columns, groups them into rows representing
 // one span each.  Spans are returned that match any of the given conditions.
-func createSpanIterator(makeIter makeIterFn, conditions []traceql.Condition, start, end uint64, requireAtLeastOneMatch, allConditions bool) (parquetquery.Iterator, error) {
+func createSpanIterator(makeIter makeIterFn, errs *collectorErrors, conditions []traceql.Condition, start, end uint64, requireAtLeastOneMatch, allConditions bool) (parquetquery.Iterator, error) {
 
 	var (
 		columnSelectAs     = map[string]string{}
@@ -345,6 +553,8 @@ func createSpanIterator(makeIter makeIterFn, conditions []traceql.Condition, sta
 		iters              []parquetquery.Iterator
 		genericConditions  []traceql.Condition
 		durationPredicates []*parquetquery.GenericPredicate[int64]
+		startPredicates    []*parquetquery.GenericPredicate[int64]
+		endPredicates      []*parquetquery.GenericPredicate[int64]
 	)
 
 	addPredicate := func(columnPath string, p parquetquery.Predicate) {
@@ -373,14 +583,41 @@ func createSpanIterator(makeIter makeIterFn, conditions []traceql.Condition, sta
 			durationPredicates = append(durationPredicates, pred)
 			continue
 
+		case traceql.IntrinsicStartTime:
+			pred, err := createIntPredicate(cond.Op, cond.Operands)
+			if err != nil {
+				return nil, err
+			}
+			startPredicates = append(startPredicates, pred)
+			continue
+
+		case traceql.IntrinsicEndTime:
+			pred, err := createIntPredicate(cond.Op, cond.Operands)
+			if err != nil {
+				return nil, err
+			}
+			endPredicates = append(endPredicates, pred)
+			continue
+
 		case traceql.IntrinsicStatus:
 			pred, err := createStatusPredicate(cond.Op, cond.Operands)
 			if err != nil {
 				return nil, err
 			}
-			addPredicate(columnPathSpanStatusCode, pred)
+			// a nil status predicate must be passed as a nil interface
+			if pred != nil {
+				addPredicate(columnPathSpanStatusCode, pred)
+			} else {
+				addPredicate(columnPathSpanStatusCode, nil)
+			}
 			columnSelectAs[columnPathSpanStatusCode] = columnPathSpanStatusCode
 			continue
+
+		case traceql.IntrinsicSelfTime, traceql.IntrinsicChildCount, traceql.IntrinsicParent:
+			// computed by the engine from the parent IDs, and from the span times for selfTime
+			addPredicate(columnPathSpanParentID, nil)
+			columnSelectAs[columnPathSpanParentID] = columnPathSpanParentID
+			continue
 		}
 
 		// Well-known attribute?
@@ -407,8 +644,8 @@ func createSpanIterator(makeIter makeIterFn, conditions []traceql.Condition, sta
 		genericConditions = append(genericConditions, cond)
 	}
 
-	attrIter, err := createAttributeIterator(makeIter, genericConditions, DefinitionLevelResourceSpansILSSpanAttrs,
-		columnPathSpanAttrKey, columnPathSpanAttrString, columnPathSpanAttrInt, columnPathSpanAttrDouble, columnPathSpanAttrBool)
+	attrIter, err := createAttributeIterator(makeIter, errs, genericConditions, DefinitionLevelResourceSpansILSSpanAttrs,
+		columnPathSpanAttrKey, columnPathSpanAttrString, columnPathSpanAttrInt, columnPathSpanAttrDouble, columnPathSpanAttrBool, columnPathSpanAttrArray)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating span attribute iterator")
 	}
@@ -444,8 +681,10 @@ func createSpanIterator(makeIter makeIterFn, conditions []traceql.Condition, sta
 		minCount = len(conditions)
 	}
 	spanCol := &spanCollector{
-		minCount,
-		durationPredicates,
+		minAttributes:   minCount,
+		durationFilters: durationPredicates,
+		startFilters:    startPredicates,
+		endFilters:      endPredicates,
 	}
 
 	// This is an optimization for when all of the span conditions must be met.
@@ -473,7 +712,7 @@ func createSpanIterator(makeIter makeIterFn, conditions []traceql.Condition, sta
 // createResourceIterator iterates through all resourcespans-level (batch-level) columns, groups them into rows representing
 // one batch each. It builds on top of the span iterator, and turns the groups of spans and resource-level values into
 // spansets.  Spansets are returned that match any of the given conditions.
-func createResourceIterator(makeIter makeIterFn, spanIterator parquetquery.Iterator, conditions []traceql.Condition, requireAtLeastOneMatch, requireAtLeastOneMatchOverall, allConditions bool) (parquetquery.Iterator, error) {
+func createResourceIterator(makeIter makeIterFn, errs *collectorErrors, spanIterator parquetquery.Iterator, conditions []traceql.Condition, requireAtLeastOneMatch, requireAtLeastOneMatchOverall, allConditions bool) (parquetquery.Iterator, error) {
 	var (
 		columnSelectAs    = map[string]string{}
 		columnPredicates  = map[string][]parquetquery.Predicate{}
@@ -510,8 +749,8 @@ func createResourceIterator(makeIter makeIterFn, spanIterator parquetquery.Itera
 		genericConditions = append(genericConditions, cond)
 	}
 
-	attrIter, err := createAttributeIterator(makeIter, genericConditions, DefinitionLevelResourceAttrs,
-		columnPathResourceAttrKey, columnPathResourceAttrString, columnPathResourceAttrInt, columnPathResourceAttrDouble, columnPathResourceAttrBool)
+	attrIter, err := createAttributeIterator(makeIter, errs, genericConditions, DefinitionLevelResourceAttrs,
+		columnPathResourceAttrKey, columnPathResourceAttrString, columnPathResourceAttrInt, columnPathResourceAttrDouble, columnPathResourceAttrBool, columnPathResourceAttrArray)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating span attribute iterator")
 	}
@@ -611,6 +850,13 @@ func createStringPredicate(op traceql.Operator, operands traceql.Operands) (parq
 	case traceql.OpEqual:
 		return parquetquery.NewStringInPredicate([]string{s}), nil
 
+	case traceql.OpIn:
+		ss := make([]string, len(operands))
+		for n, operand := range operands {
+			ss[n] = operand.S
+		}
+		return parquetquery.NewStringInPredicate(ss), nil
+
 	case traceql.OpNotEqual:
 		return parquetquery.NewGenericPredicate(
 			func(v string) bool {
@@ -627,6 +873,21 @@ func createStringPredicate(op traceql.Operator, operands traceql.Operands) (parq
 	case traceql.OpRegex:
 		return parquetquery.NewRegexInPredicate([]string{s})
 
+	case traceql.OpNotRegex:
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, err
+		}
+		return parquetquery.NewGenericPredicate(
+			func(v string) bool {
+				return !re.MatchString(v)
+			},
+			nil,
+			func(v parquet.Value) string {
+				return v.String()
+			},
+		), nil
+
 	default:
 		return nil, fmt.Errorf("operand not supported for strings: %+v", op)
 	}
@@ -638,20 +899,52 @@ func createIntPredicate(op traceql.Operator, operands traceql.Operands) (*parque
 		return nil, nil
 	}
 
-	var i int64
-	switch operands[0].Type {
-	case traceql.TypeInt:
-		i = int64(operands[0].N)
-	case traceql.TypeDuration:
-		i = operands[0].D.Nanoseconds()
-	default:
-		return nil, fmt.Errorf("operand is not int or duration: %+v", operands[0])
+	ints := make([]int64, len(operands))
+	for n, operand := range operands {
+		switch operand.Type {
+		case traceql.TypeInt:
+			ints[n] = int64(operand.N)
+		case traceql.TypeDuration:
+			ints[n] = operand.D.Nanoseconds()
+		case traceql.TypeTimestamp:
+			ints[n] = int64(operand.N)
+		default:
+			return nil, fmt.Errorf("operand is not int, duration or timestamp: %+v", operand)
+		}
 	}
+	return createIntComparisonPredicate(op, ints)
+}
+
+// createIntComparisonPredicate compares int64 columns to the operands, which are already converted
+// from durations, timestamps and statuses.
+func createIntComparisonPredicate(op traceql.Operator, ints []int64) (*parquetquery.GenericPredicate[int64], error) {
+	i := ints[0]
 
 	var fn func(v int64) bool
 	var rangeFn func(min, max int64) bool
 
 	switch op {
+	case traceql.OpBetween:
+		lo, hi := ints[0], ints[1]
+		fn = func(v int64) bool { return lo <= v && v <= hi }
+		rangeFn = func(min, max int64) bool { return hi >= min && lo <= max }
+	case traceql.OpIn:
+		fn = func(v int64) bool {
+			for _, n := range ints {
+				if v == n {
+					return true
+				}
+			}
+			return false
+		}
+		rangeFn = func(min, max int64) bool {
+			for _, n := range ints {
+				if min <= n && n <= max {
+					return true
+				}
+			}
+			return false
+		}
 	case traceql.OpEqual:
 		fn = func(v int64) bool { return v == i }
 		rangeFn = func(min, max int64) bool { return min <= i && i <= max }
@@ -682,43 +975,38 @@ func createStatusPredicate(op traceql.Operator, operands traceql.Operands) (*par
 		return nil, nil
 	}
 
-	var i int64
-	switch operands[0].Type {
-	case traceql.TypeInt:
-		i = int64(operands[0].N)
-	case traceql.TypeStatus:
-		i = int64(StatusCodeMapping[operands[0].Status.String()])
-	default:
-		return nil, fmt.Errorf("operand is not int or status: %+v", operands[0])
-	}
-
-	var fn func(v int64) bool
-	var rangeFn func(min, max int64) bool
+	// operands are converted the way traceql.Static.Equals compares them to a status: ints are
+	// status ordinals, not codes, and strings are status names. a code the mapping doesn't know is
+	// read as the status of the same number, see spanCollector.KeepGroup.
+	codes := make([]int64, len(operands))
+	for n, operand := range operands {
+		var name string
+		switch operand.Type {
+		case traceql.TypeInt:
+			name = traceql.Status(operand.N).String()
+		case traceql.TypeStatus:
+			name = operand.Status.String()
+		case traceql.TypeString:
+			name = operand.S
+		default:
+			return nil, fmt.Errorf("operand is not int, string or status: %+v", operand)
+		}
 
-	switch op {
-	case traceql.OpEqual:
-		fn = func(v int64) bool { return v == i }
-		rangeFn = func(min, max int64) bool { return min <= i && i <= max }
-	case traceql.OpNotEqual:
-		fn = func(v int64) bool { return v != i }
-		rangeFn = func(min, max int64) bool { return min != i || max != i }
-	case traceql.OpGreater:
-		fn = func(v int64) bool { return v > i }
-		rangeFn = func(min, max int64) bool { return max > i }
-	case traceql.OpGreaterEqual:
-		fn = func(v int64) bool { return v >= i }
-		rangeFn = func(min, max int64) bool { return max >= i }
-	case traceql.OpLess:
-		fn = func(v int64) bool { return v < i }
-		rangeFn = func(min, max int64) bool { return min < i }
-	case traceql.OpLessEqual:
-		fn = func(v int64) bool { return v <= i }
-		rangeFn = func(min, max int64) bool { return min <= i }
-	default:
-		return nil, fmt.Errorf("operand not supported for integers: %+v", op)
+		code, ok := StatusCodeMapping[name]
+		switch {
+		case ok:
+			codes[n] = int64(code)
+		case operand.Type == traceql.TypeInt:
+			codes[n] = int64(operand.N)
+		case operand.Type == traceql.TypeStatus:
+			codes[n] = int64(operand.Status)
+		default:
+			// not a status, matches no span
+			codes[n] = -1
+		}
 	}
 
-	return parquetquery.NewIntPredicate(fn, rangeFn), nil
+	return createIntComparisonPredicate(op, codes)
 }
 
 func createFloatPredicate(op traceql.Operator, operands traceql.Operands) (parquetquery.Predicate, error) {
@@ -726,9 +1014,11 @@ func createFloatPredicate(op traceql.Operator, operands traceql.Operands) (parqu
 		return nil, nil
 	}
 
-	// Ensure operand is float
-	if operands[0].Type != traceql.TypeFloat {
-		return nil, fmt.Errorf("operand is not float: %+v", operands[0])
+	// Ensure operands are float
+	for _, operand := range operands {
+		if operand.Type != traceql.TypeFloat {
+			return nil, fmt.Errorf("operand is not float: %+v", operand)
+		}
 	}
 
 	i := operands[0].F
@@ -737,6 +1027,27 @@ func createFloatPredicate(op traceql.Operator, operands traceql.Operands) (parqu
 	var rangeFn func(min, max float64) bool
 
 	switch op {
+	case traceql.OpBetween:
+		lo, hi := operands[0].F, operands[1].F
+		fn = func(v float64) bool { return lo <= v && v <= hi }
+		rangeFn = func(min, max float64) bool { return hi >= min && lo <= max }
+	case traceql.OpIn:
+		fn = func(v float64) bool {
+			for _, operand := range operands {
+				if v == operand.F {
+					return true
+				}
+			}
+			return false
+		}
+		rangeFn = func(min, max float64) bool {
+			for _, operand := range operands {
+				if min <= operand.F && operand.F <= max {
+					return true
+				}
+			}
+			return false
+		}
 	case traceql.OpEqual:
 		fn = func(v float64) bool { return v == i }
 		rangeFn = func(min, max float64) bool { return min <= i && i <= max }
@@ -784,9 +1095,9 @@ func createBoolPredicate(op traceql.Operator, operands traceql.Operands) (parque
 	}
 }
 
-func createAttributeIterator(makeIter makeIterFn, conditions []traceql.Condition,
+func createAttributeIterator(makeIter makeIterFn, errs *collectorErrors, conditions []traceql.Condition,
 	definitionLevel int,
-	keyPath, strPath, intPath, floatPath, boolPath string,
+	keyPath, strPath, intPath, floatPath, boolPath, arrayPath string,
 ) (parquetquery.Iterator, error) {
 	var (
 		attrKeys        = []string{}
@@ -794,6 +1105,7 @@ func createAttributeIterator(makeIter makeIterFn, conditions []traceql.Condition
 		attrIntPreds    = []parquetquery.Predicate{}
 		attrFltPreds    = []parquetquery.Predicate{}
 		boolPreds       = []parquetquery.Predicate{}
+		fetchArrays     bool
 	)
 	for _, cond := range conditions {
 
@@ -806,6 +1118,10 @@ func createAttributeIterator(makeIter makeIterFn, conditions []traceql.Condition
 			attrIntPreds = append(attrIntPreds, nil)
 			attrFltPreds = append(attrFltPreds, nil)
 			boolPreds = append(boolPreds, nil)
+			// arrays are only fetched for comparisons of their elements, which the engine does
+			if cond.Elements {
+				fetchArrays = true
+			}
 			continue
 		}
 
@@ -854,6 +1170,9 @@ func createAttributeIterator(makeIter makeIterFn, conditions []traceql.Condition
 	if len(boolPreds) > 0 {
 		valueIters = append(valueIters, makeIter(boolPath, parquetquery.NewOrPredicate(boolPreds...), "bool"))
 	}
+	if fetchArrays {
+		valueIters = append(valueIters, makeIter(arrayPath, nil, "array"))
+	}
 
 	if len(valueIters) > 0 {
 		// LeftJoin means only look at rows where the key is what we want.
@@ -861,7 +1180,7 @@ func createAttributeIterator(makeIter makeIterFn, conditions []traceql.Condition
 		return parquetquery.NewLeftJoinIterator(definitionLevel,
 			[]parquetquery.Iterator{makeIter(keyPath, parquetquery.NewStringInPredicate(attrKeys), "key")},
 			valueIters,
-			&attributeCollector{}), nil
+			&attributeCollector{errs: errs}), nil
 	}
 
 	return nil, nil
@@ -871,6 +1190,8 @@ func createAttributeIterator(makeIter makeIterFn, conditions []traceql.Condition
 type spanCollector struct {
 	minAttributes   int
 	durationFilters []*parquetquery.GenericPredicate[int64]
+	startFilters    []*parquetquery.GenericPredicate[int64]
+	endFilters      []*parquetquery.GenericPredicate[int64]
 }
 
 var _ parquetquery.GroupPredicate = (*spanCollector)(nil)
@@ -890,6 +1211,8 @@ func (c *spanCollector) KeepGroup(res *parquetquery.IteratorResult) bool {
 		switch kv.Key {
 		case columnPathSpanID:
 			span.ID = kv.Value.ByteArray()
+		case columnPathSpanParentID:
+			span.ParentID = kv.Value.ByteArray()
 		case columnPathSpanStartTime:
 			span.StartTimeUnixNanos = kv.Value.Uint64()
 		case columnPathSpanEndTime:
@@ -924,6 +1247,7 @@ func (c *spanCollector) KeepGroup(res *parquetquery.IteratorResult) bool {
 			case parquet.Float:
 				span.Attributes[newSpanAttr(kv.Key)] = traceql.NewStaticFloat(kv.Value.Double())
 			case parquet.ByteArray:
+				// String doesn't copy, the static shares the bytes of the cloned value
 				span.Attributes[newSpanAttr(kv.Key)] = traceql.NewStaticString(kv.Value.String())
 			}
 		}
@@ -931,19 +1255,27 @@ func (c *spanCollector) KeepGroup(res *parquetquery.IteratorResult) bool {
 
 	if len(c.durationFilters) > 0 {
 		duration := span.EndtimeUnixNanos - span.StartTimeUnixNanos
-		durationPass := false
-		for _, f := range c.durationFilters {
-			if f.Fn(int64(duration)) {
-				durationPass = true
-				break
-			}
+		if !anyIntFilterPasses(c.durationFilters, int64(duration)) {
+			return false
 		}
 
-		if !durationPass {
+		span.Attributes[traceql.NewIntrinsic(traceql.IntrinsicDuration)] = traceql.NewStaticDuration(time.Duration(duration))
+	}
+
+	if len(c.startFilters) > 0 {
+		if !anyIntFilterPasses(c.startFilters, int64(span.StartTimeUnixNanos)) {
 			return false
 		}
 
-		span.Attributes[traceql.NewIntrinsic(traceql.IntrinsicDuration)] = traceql.NewStaticDuration(time.Duration(duration))
+		span.Attributes[traceql.NewIntrinsic(traceql.IntrinsicStartTime)] = traceql.NewStaticTimestamp(time.Unix(0, int64(span.StartTimeUnixNanos)))
+	}
+
+	if len(c.endFilters) > 0 {
+		if !anyIntFilterPasses(c.endFilters, int64(span.EndtimeUnixNanos)) {
+			return false
+		}
+
+		span.Attributes[traceql.NewIntrinsic(traceql.IntrinsicEndTime)] = traceql.NewStaticTimestamp(time.Unix(0, int64(span.EndtimeUnixNanos)))
 	}
 
 	if c.minAttributes > 0 {
@@ -965,6 +1297,17 @@ func (c *spanCollector) KeepGroup(res *parquetquery.IteratorResult) bool {
 	return true
 }
 
+// anyIntFilterPasses returns true if any of the filters keeps the value. A nil filter only fetches
+// the value and keeps everything.
+func anyIntFilterPasses(filters []*parquetquery.GenericPredicate[int64], v int64) bool {
+	for _, f := range filters {
+		if f == nil || f.Fn(v) {
+			return true
+		}
+	}
+	return false
+}
+
 // batchCollector receives rows of matching resource-level
 // This turns groups of batch values and Spans into SpanSets
 type batchCollector struct {
@@ -1104,6 +1447,7 @@ func (c *traceCollector) KeepGroup(res *parquetquery.IteratorResult) bool {
 // columns and joins them together into map[key]value entries with the
 // right type.
 type attributeCollector struct {
+	errs *collectorErrors
 }
 
 var _ parquetquery.GroupPredicate = (*attributeCollector)(nil)
@@ -1131,6 +1475,12 @@ func (c *attributeCollector) KeepGroup(res *parquetquery.IteratorResult) bool {
 			val = traceql.NewStaticFloat(e.Value.Double())
 		case "bool":
 			val = traceql.NewStaticBool(e.Value.Boolean())
+		case "array":
+			var err error
+			val, err = arrayToStatic(e.Value.String())
+			if err != nil {
+				c.errs.store(fmt.Errorf("decoding array attribute %s: %w", key, err))
+			}
 		}
 	}
 
@@ -1141,6 +1491,30 @@ func (c *attributeCollector) KeepGroup(res *parquetquery.IteratorResult) bool {
 	return true
 }
 
+// arrayToStatic converts the encoded value of an array attribute to an array static. Only scalar
+// elements are kept, nested arrays and key-value lists can't be compared to a static.
+func arrayToStatic(encoded string) (traceql.Static, error) {
+	v := &v1_common.AnyValue{}
+	if err := jsonpb.Unmarshal(strings.NewReader(encoded), v); err != nil {
+		return traceql.NewStaticNil(), err
+	}
+
+	var elements []traceql.Static
+	for _, e := range v.GetArrayValue().GetValues() {
+		switch ev := e.GetValue().(type) {
+		case *v1_common.AnyValue_StringValue:
+			elements = append(elements, traceql.NewStaticString(ev.StringValue))
+		case *v1_common.AnyValue_IntValue:
+			elements = append(elements, traceql.NewStaticInt(int(ev.IntValue)))
+		case *v1_common.AnyValue_DoubleValue:
+			elements = append(elements, traceql.NewStaticFloat(ev.DoubleValue))
+		case *v1_common.AnyValue_BoolValue:
+			elements = append(elements, traceql.NewStaticBool(ev.BoolValue))
+		}
+	}
+	return traceql.NewStaticArray(elements), nil
+}
+
 func newSpanAttr(name string) traceql.Attribute {
 	return traceql.NewScopedAttribute(traceql.AttributeScopeSpan, false, name)
 }
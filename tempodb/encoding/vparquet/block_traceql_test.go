@@ -1,15 +1,26 @@
 package vparquet
 
 import (
+	"bytes"
 	"context"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/segmentio/parquet-go"
 	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/tempo/pkg/parquetquery"
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
 	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
 	"github.com/grafana/tempo/pkg/traceql"
+	"github.com/grafana/tempo/pkg/util"
 	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/encoding/common"
 )
 
@@ -33,8 +44,14 @@ func TestBackendBlockSearchTraceQL(t *testing.T) {
 		makeReq(parse(t, `{`+LabelDuration+` <  101s}`)),
 		makeReq(parse(t, `{`+LabelDuration+` <= 100s}`)),
 		makeReq(parse(t, `{`+LabelDuration+` <= 100s}`)),
+		makeReq(parse(t, `{`+LabelDuration+` >  99s && `+LabelDuration+` < 101s}`)),
 		makeReq(parse(t, `{`+LabelStatus+` = error}`)),
-		makeReq(parse(t, `{`+LabelStatus+` = 2}`)),
+		makeReq(parse(t, `{`+LabelStatus+` = 0}`)), // status ordinal, as the engine compares it
+		makeReq(parse(t, `{`+LabelStatus+` = "error"}`)),
+		makeReq(parse(t, `{startTime = 1970-01-01T00:01:40Z}`)),
+		makeReq(parse(t, `{startTime < 1970-01-01T00:01:40.000000001Z}`)),
+		makeReq(parse(t, `{endTime >= 1970-01-01T00:03:20Z}`)),
+		makeReq(parse(t, `{endTime < 1970-01-01T01:04:00+01:00}`)),
 		// Resource well-known attributes
 		makeReq(parse(t, `{.`+LabelServiceName+` = "spanservicename"}`)), // Overridden at span
 		makeReq(parse(t, `{.`+LabelCluster+` = "cluster"}`)),
@@ -61,6 +78,8 @@ func TestBackendBlockSearchTraceQL(t *testing.T) {
 		makeReq(parse(t, `{span.`+LabelHTTPStatusCode+` = 500}`)),
 		makeReq(parse(t, `{span.`+LabelHTTPMethod+` = "get"}`)),
 		makeReq(parse(t, `{span.`+LabelHTTPUrl+` = "url/hello/world"}`)),
+		makeReq(parse(t, `{span."`+LabelHTTPMethod+`" = "get"}`)), // Quoted names
+		makeReq(parse(t, `{resource."`+LabelServiceName+`" = "myservice"}`)),
 		// Basic data types and operations
 		makeReq(parse(t, `{.float = 456.78}`)),      // Float ==
 		makeReq(parse(t, `{.float != 456.79}`)),     // Float !=
@@ -78,9 +97,22 @@ func TestBackendBlockSearchTraceQL(t *testing.T) {
 		makeReq(parse(t, `{.foo = "def"}`)),         // String ==
 		makeReq(parse(t, `{.foo != "deg"}`)),        // String !=
 		makeReq(parse(t, `{.foo =~ "d.*"}`)),        // String Regex
+		makeReq(parse(t, `{.foo !~ "a.*"}`)),        // String not Regex
 		makeReq(parse(t, `{resource.foo = "abc"}`)), // Resource-level only
 		makeReq(parse(t, `{span.foo = "def"}`)),     // Span-level only
 		makeReq(parse(t, `{.foo}`)),                 // Projection only
+		// Ranges combined into a single predicate
+		makeReq(parse(t, `{.bar > 122 && .bar < 124}`)),
+		makeReq(parse(t, `{.float >= 456.78 && .float <= 456.78}`)),
+		makeReq(parse(t, `{.`+LabelHTTPStatusCode+` > 499 && .`+LabelHTTPStatusCode+` < 501}`)),
+		// Equalities of the same attribute combined into a set
+		makeReq(parse(t, `{`+LabelName+` = "bye" || `+LabelName+` = "hello"}`)),
+		makeReq(parse(t, `{`+LabelDuration+` = 1s || `+LabelDuration+` = 100s}`)),
+		makeReq(parse(t, `{`+LabelStatus+` = ok || `+LabelStatus+` = error}`)),
+		makeReq(parse(t, `{.bar = 1 || .bar = 123}`)),
+		makeReq(parse(t, `{.float = 1.5 || .float = 456.78}`)),
+		makeReq(parse(t, `{.foo = "abc" || .foo = "def"}`)),
+		makeReq(parse(t, `{.`+LabelHTTPStatusCode+` = 200 || .`+LabelHTTPStatusCode+` = 500}`)),
 		makeReq(
 			// Matches either condition
 			parse(t, `{.foo = "baz"}`),
@@ -119,19 +151,35 @@ func TestBackendBlockSearchTraceQL(t *testing.T) {
 		require.NotNil(t, spanSet, "search request:", req)
 		require.Equal(t, wantTr.TraceID, spanSet.TraceID, "search request:", req)
 		require.Equal(t, []byte("spanid"), spanSet.Spans[0].ID, "search request:", req)
+		require.NotZero(t, resp.BytesRead(), "search request:", req)
 	}
 
 	searchesThatDontMatch := []traceql.FetchSpansRequest{
 		// TODO - Should the below query return data or not?  It does match the resource
 		// makeReq(parse(t, `{.foo = "abc"}`)),                           // This should not return results because the span has overridden this attribute to "def".
 		makeReq(parse(t, `{.foo =~ "xyz.*"}`)),                        // Regex IN
+		makeReq(parse(t, `{span.foo !~ "d.*"}`)),                      // Not regex
 		makeReq(parse(t, `{span.bool = true}`)),                       // Bool not match
 		makeReq(parse(t, `{`+LabelDuration+` >  100s}`)),              // Intrinsic: duration
 		makeReq(parse(t, `{`+LabelStatus+` = ok}`)),                   // Intrinsic: status
+		makeReq(parse(t, `{`+LabelStatus+` = "ok"}`)),                 // Intrinsic: status name
+		makeReq(parse(t, `{`+LabelStatus+` = "failed"}`)),             // Intrinsic: not a status
+		makeReq(parse(t, `{startTime > 1970-01-01T00:01:40Z}`)),       // Intrinsic: startTime
+		makeReq(parse(t, `{endTime > 1970-01-01T00:03:20Z}`)),         // Intrinsic: endTime
+		makeReq(parse(t, `{link:traceID = "abc"}`)),                   // Intrinsic: links aren't stored in this format
 		makeReq(parse(t, `{`+LabelName+` = "nothello"}`)),             // Intrinsic: name
 		makeReq(parse(t, `{.`+LabelServiceName+` = "notmyservice"}`)), // Well-known attribute: service.name not match
 		makeReq(parse(t, `{.`+LabelHTTPStatusCode+` = 200}`)),         // Well-known attribute: http.status_code not match
 		makeReq(parse(t, `{.`+LabelHTTPStatusCode+` > 600}`)),         // Well-known attribute: http.status_code not match
+		// Ranges combined into a single predicate
+		makeReq(parse(t, `{`+LabelDuration+` >  100s && `+LabelDuration+` < 200s}`)),
+		makeReq(parse(t, `{.bar > 123 && .bar < 200}`)),
+		// Equalities of the same attribute combined into a set
+		makeReq(parse(t, `{`+LabelName+` = "bye" || `+LabelName+` = "ciao"}`)),
+		makeReq(parse(t, `{.bar = 1 || .bar = 2}`)),
+		makeReq(parse(t, `{.float = 1.5 || .float = 2.5}`)),
+		makeReq(parse(t, `{span.foo = "abc" || span.foo = "xyz"}`)),
+		makeReq(parse(t, `{.`+LabelHTTPStatusCode+` = 200 || .`+LabelHTTPStatusCode+` = 404}`)),
 		makeReq(
 			// Matches neither condition
 			parse(t, `{.foo = "xyz"}`),
@@ -451,6 +499,418 @@ func parse(t *testing.T, q string) traceql.Condition {
 	return cond
 }
 
+func TestBackendBlockMayMatch(t *testing.T) {
+	// a block written with only the trace ID and resource service name columns
+	type resource struct {
+		ServiceName string
+	}
+	type resourceSpans struct {
+		Resource resource
+	}
+	type reducedTrace struct {
+		TraceID       []byte
+		ResourceSpans []resourceSpans `parquet:"rs"`
+	}
+
+	buf := &bytes.Buffer{}
+	w := parquet.NewWriter(buf)
+	require.NoError(t, w.Write(&reducedTrace{
+		TraceID:       test.ValidTraceID(nil),
+		ResourceSpans: []resourceSpans{{Resource: resource{ServiceName: "svc"}}},
+	}))
+	require.NoError(t, w.Close())
+
+	pf, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	reduced := newBackendBlock(&backend.BlockMeta{Version: VersionString}, nil)
+	reduced.opened[openKey{}] = openedFile{pf: pf, readerAt: &BackendReaderAt{}}
+
+	full := makeBackendBlockWithTraces(t, []*Trace{fullyPopulatedTestTrace(nil)})
+
+	tcs := []struct {
+		req         traceql.FetchSpansRequest
+		fullMatch   bool
+		reduceMatch bool
+	}{
+		{req: traceql.FetchSpansRequest{}, fullMatch: true, reduceMatch: true},
+		{req: makeReq(parse(t, `{resource.`+LabelServiceName+` = "svc"}`)), fullMatch: true, reduceMatch: true},
+		{req: makeReq(parse(t, `{.`+LabelServiceName+` = "svc"}`)), fullMatch: true, reduceMatch: true},
+		{req: makeReq(parse(t, `{.foo = "bar"}`)), fullMatch: true, reduceMatch: false},
+		{req: makeReq(parse(t, `{span.foo = "bar"}`)), fullMatch: true, reduceMatch: false},
+		{req: makeReq(parse(t, `{`+LabelName+` = "hello"}`)), fullMatch: true, reduceMatch: false},
+		{
+			req: traceql.FetchSpansRequest{
+				AllConditions: true,
+				Conditions:    []traceql.Condition{parse(t, `{resource.`+LabelServiceName+` = "svc"}`), parse(t, `{.foo = "bar"}`)},
+			},
+			fullMatch:   true,
+			reduceMatch: false,
+		},
+		{
+			req:         makeReq(parse(t, `{resource.`+LabelServiceName+` = "svc"}`), parse(t, `{.foo = "bar"}`)),
+			fullMatch:   true,
+			reduceMatch: true,
+		},
+	}
+
+	ctx := context.Background()
+	for _, tc := range tcs {
+		ok, err := full.mayMatch(ctx, tc.req, common.SearchOptions{})
+		require.NoError(t, err)
+		require.Equal(t, tc.fullMatch, ok, "full: %+v", tc.req)
+
+		ok, err = reduced.mayMatch(ctx, tc.req, common.SearchOptions{})
+		require.NoError(t, err)
+		require.Equal(t, tc.reduceMatch, ok, "reduced: %+v", tc.req)
+	}
+
+	// blocks that can't match are skipped without building the iterators
+	resp, err := reduced.Fetch(ctx, makeReq(parse(t, `{.foo = "bar"}`)))
+	require.NoError(t, err)
+	spanset, err := resp.Results.Next(ctx)
+	require.NoError(t, err)
+	require.Nil(t, spanset)
+}
+
+func TestConditionColumns(t *testing.T) {
+	tcs := []struct {
+		query    string
+		expected []string
+	}{
+		{query: `{span.` + LabelHTTPMethod + ` = "get"}`, expected: []string{columnPathSpanHTTPMethod, columnPathSpanAttrKey}},
+		{query: `{span."` + LabelHTTPMethod + `" = "get"}`, expected: []string{columnPathSpanHTTPMethod, columnPathSpanAttrKey}},
+		{query: `{resource."` + LabelK8sPodName + `" = "pod"}`, expected: []string{columnPathResourceK8sPodName, columnPathResourceAttrKey}},
+		{query: `{."foo.bar baz" = "x"}`, expected: []string{columnPathSpanAttrKey, columnPathResourceAttrKey}},
+		{query: `{` + LabelName + ` = "x"}`, expected: []string{columnPathSpanName}},
+		{query: `{selfTime > 1s}`, expected: []string{columnPathSpanParentID, columnPathSpanStartTime, columnPathSpanEndTime}},
+		{query: `{childCount > 1}`, expected: []string{columnPathSpanParentID}},
+		{query: `{parent = nil}`, expected: []string{columnPathSpanParentID}},
+		{query: `{startTime > 2023-01-01T00:00:00Z}`, expected: []string{columnPathSpanStartTime}},
+		{query: `{endTime > 2023-01-01T00:00:00Z}`, expected: []string{columnPathSpanEndTime}},
+	}
+
+	for _, tc := range tcs {
+		require.Equal(t, tc.expected, conditionColumns(parse(t, tc.query)), tc.query)
+	}
+}
+
+// chunkReader records the column chunks of the data file that range reads touch
+type chunkReader struct {
+	backend.Reader
+
+	mtx   sync.Mutex
+	reads [][2]int64
+}
+
+func (r *chunkReader) ReadRange(ctx context.Context, name string, blockID uuid.UUID, tenantID string, offset uint64, buffer []byte, shouldCache bool) error {
+	if name == DataFileName {
+		r.mtx.Lock()
+		r.reads = append(r.reads, [2]int64{int64(offset), int64(offset) + int64(len(buffer))})
+		r.mtx.Unlock()
+	}
+	return r.Reader.ReadRange(ctx, name, blockID, tenantID, offset, buffer, shouldCache)
+}
+
+// columns returns the paths of the columns with a chunk that was read
+func (r *chunkReader) columns(pf *parquet.File) []string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	read := map[string]struct{}{}
+	for _, rg := range pf.Metadata().RowGroups {
+		for _, c := range rg.Columns {
+			start := c.MetaData.DataPageOffset
+			if c.MetaData.DictionaryPageOffset > 0 && c.MetaData.DictionaryPageOffset < start {
+				start = c.MetaData.DictionaryPageOffset
+			}
+			end := start + c.MetaData.TotalCompressedSize
+
+			for _, rd := range r.reads {
+				if rd[0] < end && rd[1] > start {
+					read[strings.Join(c.MetaData.PathInSchema, ".")] = struct{}{}
+				}
+			}
+		}
+	}
+
+	columns := make([]string, 0, len(read))
+	for c := range read {
+		columns = append(columns, c)
+	}
+	return columns
+}
+
+func TestBackendBlockFetchColumns(t *testing.T) {
+	var traces []*Trace
+	for i := 0; i < 3; i++ {
+		traces = append(traces, fullyPopulatedTestTrace(nil))
+	}
+	sort.Slice(traces, func(i, j int) bool {
+		return bytes.Compare(traces[i].TraceID, traces[j].TraceID) == -1
+	})
+	written := makeBackendBlockWithTraces(t, traces)
+	ctx := context.Background()
+
+	// every spanset is built from these
+	structural := []string{
+		columnPathTraceID, columnPathStartTimeUnixNano, columnPathDurationNanos, columnPathRootSpanName, columnPathRootServiceName,
+		columnPathSpanID, columnPathSpanStartTime, columnPathSpanEndTime,
+	}
+
+	tcs := []struct {
+		query    string
+		expected []string
+	}{
+		{query: `{ name = "hello" }`, expected: []string{columnPathSpanName}},
+		{query: `{ span.foo = "def" }`, expected: []string{columnPathSpanAttrKey, columnPathSpanAttrString}},
+		{query: `{ resource.foo = "abc" }`, expected: []string{columnPathResourceAttrKey, columnPathResourceAttrString}},
+		// attributes without a scope can be on the span or the resource
+		{query: `{ .foo = "abc" }`, expected: []string{columnPathSpanAttrKey, columnPathSpanAttrString, columnPathResourceAttrKey, columnPathResourceAttrString}},
+		{query: `{ .bar = 123 }`, expected: []string{columnPathSpanAttrKey, columnPathSpanAttrInt, columnPathResourceAttrKey, columnPathResourceAttrInt}},
+	}
+
+	for _, tc := range tcs {
+		r := &chunkReader{Reader: written.r}
+		b := newBackendBlock(written.meta, r)
+
+		res, err := traceql.NewEngine().Execute(ctx, &tempopb.SearchRequest{Query: tc.query}, b)
+		require.NoError(t, err, tc.query)
+		require.Len(t, res.Traces, len(traces), tc.query)
+
+		pf, rr, err := b.openForSearch(ctx, common.SearchOptions{})
+		require.NoError(t, err)
+		require.ElementsMatch(t, append(tc.expected, structural...), r.columns(pf), tc.query)
+		require.Less(t, rr.TotalBytesRead.Load(), written.meta.Size, tc.query)
+	}
+}
+
+func TestBackendBlockSearchTraceQLSelfTime(t *testing.T) {
+	span := func(id, parent, name string, start, end time.Duration) Span {
+		return Span{
+			ID:             []byte(id),
+			ParentSpanID:   []byte(parent),
+			Name:           name,
+			StartUnixNanos: uint64(start),
+			EndUnixNanos:   uint64(end),
+		}
+	}
+
+	tr := &Trace{
+		TraceID: test.ValidTraceID(nil),
+		ResourceSpans: []ResourceSpans{{
+			Resource: Resource{ServiceName: "svc"},
+			ScopeSpans: []ScopeSpan{{
+				Spans: []Span{
+					span("parent", "", "parent", 100*time.Second, 200*time.Second),
+					// overlapping children cover 110s to 180s
+					span("child1", "parent", "child", 110*time.Second, 160*time.Second),
+					span("child2", "parent", "child", 120*time.Second, 180*time.Second),
+				},
+			}},
+		}},
+	}
+	b := makeBackendBlockWithTraces(t, []*Trace{tr})
+	ctx := context.Background()
+
+	tcs := []struct {
+		query         string
+		expectedSpans []string
+	}{
+		// the children don't match the name but are still needed for the self time of the parent
+		{query: `{ name = "parent" && selfTime = 30s }`, expectedSpans: []string{"parent"}},
+		{query: `{ name = "parent" && selfTime > 30s }`},
+		{query: `{ selfTime >= 50s }`, expectedSpans: []string{"child1", "child2"}},
+		// the child count is computed from the same parent IDs
+		{query: `{ childCount = 2 }`, expectedSpans: []string{"parent"}},
+		{query: `{ name = "child" && childCount = 0 }`, expectedSpans: []string{"child1", "child2"}},
+		{query: `{ name = "child" && childCount > 0 }`},
+		// and whether it has a parent
+		{query: `{ parent = nil }`, expectedSpans: []string{"parent"}},
+		{query: `{ parent != nil }`, expectedSpans: []string{"child1", "child2"}},
+		{query: `{ name = "parent" && parent != nil }`},
+	}
+
+	for _, tc := range tcs {
+		res, err := traceql.NewEngine().Execute(ctx, &tempopb.SearchRequest{Query: tc.query}, b)
+		require.NoError(t, err, tc.query)
+
+		if len(tc.expectedSpans) == 0 {
+			require.Empty(t, res.Traces, tc.query)
+			continue
+		}
+
+		require.Len(t, res.Traces, 1, tc.query)
+		var actual []string
+		for _, s := range res.Traces[0].SpanSet.Spans {
+			actual = append(actual, s.SpanID)
+		}
+		var expected []string
+		for _, id := range tc.expectedSpans {
+			expected = append(expected, util.TraceIDToHexString([]byte(id)))
+		}
+		require.ElementsMatch(t, expected, actual, tc.query)
+	}
+}
+
+func TestBackendBlockSearchTraceQLNil(t *testing.T) {
+	value := "v"
+	tr := &Trace{
+		TraceID: test.ValidTraceID(nil),
+		ResourceSpans: []ResourceSpans{{
+			Resource: Resource{ServiceName: "svc"},
+			ScopeSpans: []ScopeSpan{{
+				Spans: []Span{
+					{ID: []byte("with"), Name: "with", Attrs: []Attribute{{Key: "x", Value: &value}}},
+					{ID: []byte("without"), Name: "without"},
+				},
+			}},
+		}},
+	}
+	b := makeBackendBlockWithTraces(t, []*Trace{tr})
+	ctx := context.Background()
+
+	tcs := []struct {
+		query         string
+		expectedSpans []string
+	}{
+		{query: `{ .x != nil }`, expectedSpans: []string{"with"}},
+		{query: `{ nil != span.x }`, expectedSpans: []string{"with"}},
+		// spans without the attribute don't match any condition but are fetched
+		{query: `{ .x = nil }`, expectedSpans: []string{"without"}},
+		{query: `{ .x = nil && name = "with" }`},
+		{query: `{ name != nil }`, expectedSpans: []string{"with", "without"}},
+		{query: `{ duration = nil }`},
+	}
+
+	for _, tc := range tcs {
+		res, err := traceql.NewEngine().Execute(ctx, &tempopb.SearchRequest{Query: tc.query}, b)
+		require.NoError(t, err, tc.query)
+
+		var actual []string
+		for _, tr := range res.Traces {
+			for _, s := range tr.SpanSet.Spans {
+				actual = append(actual, s.SpanID)
+			}
+		}
+		var expected []string
+		for _, id := range tc.expectedSpans {
+			expected = append(expected, util.TraceIDToHexString([]byte(id)))
+		}
+		require.ElementsMatch(t, expected, actual, tc.query)
+	}
+}
+
+func TestBackendBlockSearchTraceQLArrays(t *testing.T) {
+	span := func(id string, values ...*v1_common.AnyValue) Span {
+		var tags Attribute
+		attrToParquet(&v1_common.KeyValue{
+			Key:   "tags",
+			Value: &v1_common.AnyValue{Value: &v1_common.AnyValue_ArrayValue{ArrayValue: &v1_common.ArrayValue{Values: values}}},
+		}, &tags)
+		return Span{ID: []byte(id), Attrs: []Attribute{tags}}
+	}
+	str := func(s string) *v1_common.AnyValue {
+		return &v1_common.AnyValue{Value: &v1_common.AnyValue_StringValue{StringValue: s}}
+	}
+	num := func(n int64) *v1_common.AnyValue {
+		return &v1_common.AnyValue{Value: &v1_common.AnyValue_IntValue{IntValue: n}}
+	}
+
+	tr := &Trace{
+		TraceID: test.ValidTraceID(nil),
+		ResourceSpans: []ResourceSpans{{
+			Resource: Resource{ServiceName: "svc"},
+			ScopeSpans: []ScopeSpan{{
+				Spans: []Span{
+					span("prod", str("prod")),
+					span("mixed", str("prod"), str("dev")),
+					span("ports", num(80), num(8080)),
+					span("empty"),
+				},
+			}},
+		}},
+	}
+	b := makeBackendBlockWithTraces(t, []*Trace{tr})
+	ctx := context.Background()
+
+	tcs := []struct {
+		query         string
+		expectedSpans []string
+	}{
+		{query: `{ span.tags[] = "prod" }`, expectedSpans: []string{"prod", "mixed"}},
+		{query: `{ all(span.tags[]) = "prod" }`, expectedSpans: []string{"prod", "empty"}},
+		{query: `{ .tags[] = "staging" }`},
+		{query: `{ .tags[] > 1024 }`, expectedSpans: []string{"ports"}},
+		{query: `{ all(.tags[]) > 1024 }`, expectedSpans: []string{"empty"}},
+	}
+
+	for _, tc := range tcs {
+		res, err := traceql.NewEngine().Execute(ctx, &tempopb.SearchRequest{Query: tc.query}, b)
+		require.NoError(t, err, tc.query)
+
+		if len(tc.expectedSpans) == 0 {
+			require.Empty(t, res.Traces, tc.query)
+			continue
+		}
+
+		require.Len(t, res.Traces, 1, tc.query)
+		var actual []string
+		for _, s := range res.Traces[0].SpanSet.Spans {
+			actual = append(actual, s.SpanID)
+		}
+		var expected []string
+		for _, id := range tc.expectedSpans {
+			expected = append(expected, util.TraceIDToHexString([]byte(id)))
+		}
+		require.ElementsMatch(t, expected, actual, tc.query)
+	}
+}
+
+func TestCreateAttributeIteratorArrays(t *testing.T) {
+	columns := func(conditions ...traceql.Condition) []string {
+		var paths []string
+		makeIter := func(columnName string, _ parquetquery.Predicate, _ string) parquetquery.Iterator {
+			paths = append(paths, columnName)
+			return nil
+		}
+		_, err := createAttributeIterator(makeIter, nil, conditions, DefinitionLevelResourceSpansILSSpanAttrs,
+			columnPathSpanAttrKey, columnPathSpanAttrString, columnPathSpanAttrInt, columnPathSpanAttrDouble, columnPathSpanAttrBool, columnPathSpanAttrArray)
+		require.NoError(t, err)
+		return paths
+	}
+	attr := traceql.NewScopedAttribute(traceql.AttributeScopeSpan, false, "tags")
+
+	// fetching the attribute for the engine doesn't read its array values
+	require.NotContains(t, columns(traceql.Condition{Attribute: attr, Op: traceql.OpNone}), columnPathSpanAttrArray)
+	require.Contains(t, columns(traceql.Condition{Attribute: attr, Op: traceql.OpNone, Elements: true}), columnPathSpanAttrArray)
+}
+
+func TestAttributeCollectorInvalidArray(t *testing.T) {
+	errs := &collectorErrors{}
+	c := &attributeCollector{errs: errs}
+
+	res := &parquetquery.IteratorResult{}
+	res.AppendValue("key", parquet.ValueOf("tags"))
+	res.AppendValue("array", parquet.ValueOf("{not json"))
+	require.True(t, c.KeepGroup(res))
+	require.ErrorContains(t, errs.err, "decoding array attribute tags")
+
+	// the fetch fails instead of returning spansets without the attribute
+	iter := &spansetIterator{iter: doneIterator{}, errs: errs}
+	_, err := iter.Next(context.Background())
+	require.Equal(t, errs.err, err)
+}
+
+// doneIterator is a parquetquery.Iterator without any results.
+type doneIterator struct{}
+
+func (doneIterator) Next() (*parquetquery.IteratorResult, error) { return nil, nil }
+func (doneIterator) SeekTo(parquetquery.RowNumber, int) (*parquetquery.IteratorResult, error) {
+	return nil, nil
+}
+func (doneIterator) Close() {}
+
 func fullyPopulatedTestTrace(id common.ID) *Trace {
 	// Helper functions to make pointers
 	strPtr := func(s string) *string { return &s }
@@ -542,3 +1002,45 @@ func fullyPopulatedTestTrace(id common.ID) *Trace {
 		},
 	}
 }
+
+// TestStringStaticsShareValueBytes guards that string attributes are created from the fetched
+// values without copying. Value.String returns a string backed by the value's bytes, which are owned
+// by the value since the column iterators clone them.
+func TestStringStaticsShareValueBytes(t *testing.T) {
+	v := parquet.ValueOf("some-high-cardinality-value").Clone()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		s := traceql.NewStaticString(v.String())
+		if s.S == "" {
+			t.Fatal("unexpected empty string")
+		}
+	})
+	require.Zero(t, allocs)
+}
+
+func BenchmarkStringAttributeRejectingFilter(b *testing.B) {
+	expr, err := traceql.Parse(`{ .foo = "nomatch" }`)
+	require.NoError(b, err)
+	evaluator := traceql.NewEvaluator(traceql.EvalOptions{}, nil)
+	ctx := context.Background()
+
+	values := make([]parquet.Value, 1000)
+	for i := range values {
+		values[i] = parquet.ValueOf(test.ValidTraceID(nil)).Clone()
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		spans := make([]traceql.Span, len(values))
+		for j, v := range values {
+			spans[j].Attributes = map[traceql.Attribute]traceql.Static{
+				traceql.NewAttribute("foo"): traceql.NewStaticString(v.String()),
+			}
+		}
+
+		_, err := evaluator.Evaluate(ctx, expr.Pipeline, []traceql.Spanset{{Spans: spans}})
+		require.NoError(b, err)
+	}
+}
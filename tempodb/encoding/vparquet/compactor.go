@@ -14,6 +14,7 @@ import (
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/segmentio/parquet-go"
+	"golang.org/x/exp/slices"
 
 	tempo_io "github.com/grafana/tempo/pkg/io"
 	"github.com/grafana/tempo/tempodb/backend"
@@ -300,6 +301,49 @@ func (b *bookmark) close() {
 	b.iter.Close()
 }
 
+// MergeSortedBuffers merges buffers that are each sorted into rows that are sorted across all of
+// them. Rows are read from the buffers as the merged rows are read, so nothing is materialized. The
+// buffers must share their schema and sorting columns and mustn't be written while merging.
+func MergeSortedBuffers(bufs []*parquet.Buffer) (parquet.Rows, error) {
+	if len(bufs) == 0 {
+		return nil, errors.New("no buffers to merge")
+	}
+
+	sorting := bufs[0].SortingColumns()
+	if len(sorting) == 0 {
+		return nil, errors.New("buffers to merge must be sorted")
+	}
+
+	rowGroups := make([]parquet.RowGroup, 0, len(bufs))
+	for i, buf := range bufs {
+		if !equalSortingColumns(sorting, buf.SortingColumns()) {
+			return nil, fmt.Errorf("buffer %d is sorted by other columns than buffer 0", i)
+		}
+		rowGroups = append(rowGroups, buf)
+	}
+
+	// fails with parquet.ErrRowGroupSchemaMismatch if the schemas differ
+	merged, err := parquet.MergeRowGroups(rowGroups, parquet.SortingColumns(sorting...))
+	if err != nil {
+		return nil, err
+	}
+	return merged.Rows(), nil
+}
+
+func equalSortingColumns(a, b []parquet.SortingColumn) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !slices.Equal(a[i].Path(), b[i].Path()) ||
+			a[i].Descending() != b[i].Descending() ||
+			a[i].NullsFirst() != b[i].NullsFirst() {
+			return false
+		}
+	}
+	return true
+}
+
 type rowPool struct {
 	pool sync.Pool
 }
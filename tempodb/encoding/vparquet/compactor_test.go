@@ -3,6 +3,8 @@ package vparquet
 import (
 	"context"
 	"encoding/binary"
+	"io"
+	"sort"
 	"time"
 
 	"testing"
@@ -142,3 +144,60 @@ func createTestBlock(t testing.TB, ctx context.Context, cfg *common.BlockConfig,
 func TestValueAlloc(t *testing.T) {
 	_ = make([]parquet.Value, 1_000_000)
 }
+
+func TestMergeSortedBuffers(t *testing.T) {
+	type row struct {
+		N int64
+	}
+	schema := parquet.SchemaOf(row{})
+	sorted := parquet.SortingColumns(parquet.Ascending("N"))
+
+	newBuffer := func(values ...int64) *parquet.Buffer {
+		buf := parquet.NewBuffer(schema, sorted)
+		for _, v := range values {
+			require.NoError(t, buf.Write(row{N: v}))
+		}
+		sort.Sort(buf)
+		return buf
+	}
+
+	rows, err := MergeSortedBuffers([]*parquet.Buffer{
+		newBuffer(1, 4, 7, 10),
+		newBuffer(2, 5, 8),
+		newBuffer(0, 3, 6, 9, 11),
+	})
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var actual []int64
+	buf := make([]parquet.Row, 2)
+	for {
+		n, err := rows.ReadRows(buf)
+		for _, r := range buf[:n] {
+			actual = append(actual, r[0].Int64())
+		}
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	require.Equal(t, []int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, actual)
+
+	_, err = MergeSortedBuffers(nil)
+	require.Error(t, err)
+
+	_, err = MergeSortedBuffers([]*parquet.Buffer{parquet.NewBuffer(schema)})
+	require.Error(t, err)
+
+	descending := parquet.NewBuffer(schema, parquet.SortingColumns(parquet.Descending("N")))
+	_, err = MergeSortedBuffers([]*parquet.Buffer{newBuffer(1), descending})
+	require.Error(t, err)
+
+	type otherRow struct {
+		N int64
+		S string
+	}
+	other := parquet.NewBuffer(parquet.SchemaOf(otherRow{}), sorted)
+	_, err = MergeSortedBuffers([]*parquet.Buffer{newBuffer(1), other})
+	require.ErrorIs(t, err, parquet.ErrRowGroupSchemaMismatch)
+}
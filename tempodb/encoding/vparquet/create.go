@@ -281,6 +281,89 @@ func estimateAttrSize(attrs []Attribute) (size int) {
 	return
 }
 
+// estimatedEncodedSizeSampleRows is the number of rows EstimatedEncodedSize writes to estimate the
+// size of a buffer.
+const estimatedEncodedSizeSampleRows = 100
+
+// EstimatedEncodedSize estimates the size of the buffer once it's written as a parquet file with the
+// encodings and compression of its schema. Buffer.Size is the size in memory, which is a poor
+// predictor of the size of a block. A sample of evenly spaced rows is written and the size of
+// their row group is scaled to all rows, so how well dictionaries and compression do on the whole
+// buffer is only approximated.
+func EstimatedEncodedSize(buf *parquet.Buffer) (int64, error) {
+	numRows := buf.NumRows()
+	if numRows == 0 {
+		return 0, nil
+	}
+
+	samples := int64(estimatedEncodedSizeSampleRows)
+	if samples > numRows {
+		samples = numRows
+	}
+	step := numRows / samples
+
+	// the file overhead of an empty file isn't scaled
+	overhead, err := encodedSize(buf.Schema(), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := buf.Rows()
+	defer rows.Close()
+
+	sample := make([]parquet.Row, 0, samples)
+	for i := int64(0); i < samples; i++ {
+		if err := rows.SeekToRow(i * step); err != nil {
+			return 0, err
+		}
+		row := make([]parquet.Row, 1)
+		if _, err := rows.ReadRows(row); err != nil && err != io.EOF {
+			return 0, err
+		}
+		sample = append(sample, row[0])
+	}
+
+	size, err := encodedSize(buf.Schema(), sample)
+	if err != nil {
+		return 0, err
+	}
+	if samples == numRows {
+		return size - overhead, nil
+	}
+
+	// values repeat across rows, so each row costs less than the average of the sample once there
+	// are more of them. The rows beyond the sample are scaled by what the second half of the
+	// sample added to the first half.
+	half, err := encodedSize(buf.Schema(), sample[:samples/2])
+	if err != nil {
+		return 0, err
+	}
+	marginal := float64(size-half) / float64(samples-samples/2)
+	return size - overhead + int64(marginal*float64(numRows-samples)), nil
+}
+
+// encodedSize is the size of a parquet file of the rows.
+func encodedSize(schema *parquet.Schema, rows []parquet.Row) (int64, error) {
+	w := &countingWriter{}
+	pw := parquet.NewWriter(w, schema)
+	if _, err := pw.WriteRows(rows); err != nil {
+		return 0, err
+	}
+	if err := pw.Close(); err != nil {
+		return 0, err
+	}
+	return w.n, nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
 func estimateEventsSize(events []Event) (size int) {
 	for _, e := range events {
 		size += 8 + 4 // time/dropped attributes
@@ -7,11 +7,14 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	pq "github.com/grafana/tempo/pkg/parquetquery"
 	"github.com/grafana/tempo/pkg/tempopb"
 	"github.com/grafana/tempo/pkg/util/test"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/backend/local"
 	"github.com/grafana/tempo/tempodb/encoding/common"
+	"github.com/segmentio/parquet-go"
 	"github.com/stretchr/testify/require"
 )
 
@@ -48,6 +51,82 @@ func TestCreateBlockHonorsTraceStartEndTimesFromWalMeta(t *testing.T) {
 	require.Equal(t, 305, int(outMeta.EndTime.Unix()))
 }
 
+func TestStreamingBlockBloomMatchesScan(t *testing.T) {
+	// The bloom filter is filled as traces are added to the block. It must be the same filter
+	// that a second pass over the written trace IDs would build.
+	ctx := context.Background()
+
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+
+	cfg := &common.BlockConfig{
+		BloomFP:             0.01,
+		BloomShardSizeBytes: 128,
+	}
+
+	meta := backend.NewBlockMeta("fake", uuid.New(), VersionString, backend.EncNone, "")
+	meta.TotalObjects = 500
+
+	s := newStreamingBlock(ctx, cfg, meta, r, w, tempo_io.NewBufferedWriter)
+	sch := parquet.SchemaOf(new(Trace))
+	for i := 0; i < meta.TotalObjects; i++ {
+		id := test.ValidTraceID(nil)
+		tr := traceToParquet(id, test.MakeTrace(1, id), nil)
+
+		// both ways of adding traces used by block creation and compaction
+		if i%2 == 0 {
+			require.NoError(t, s.Add(tr, 0, 0))
+		} else {
+			require.NoError(t, s.AddRaw(id, sch.Deconstruct(nil, tr), 0, 0))
+		}
+
+		if s.CurrentBufferedObjects() >= 100 {
+			_, err := s.Flush()
+			require.NoError(t, err)
+		}
+	}
+	_, err = s.Complete()
+	require.NoError(t, err)
+
+	// Scan the trace IDs of the written block
+	b := newBackendBlock(s.meta, r)
+	pf, _, err := b.openForSearch(ctx, common.SearchOptions{})
+	require.NoError(t, err)
+
+	colIndex, _ := pq.GetColumnIndexByPath(pf, TraceIDColumnName)
+	iter := pq.NewColumnIterator(ctx, pf.RowGroups(), colIndex, "", 1000, nil, TraceIDColumnName)
+	defer iter.Close()
+
+	scanned := common.NewBloom(cfg.BloomFP, uint(cfg.BloomShardSizeBytes), uint(meta.TotalObjects))
+	count := 0
+	for {
+		res, err := iter.Next()
+		require.NoError(t, err)
+		if res == nil {
+			break
+		}
+		scanned.Add(res.Entries[0].Value.ByteArray())
+		count++
+	}
+	require.Equal(t, meta.TotalObjects, count)
+
+	expected, err := scanned.Marshal()
+	require.NoError(t, err)
+	require.Equal(t, len(expected), int(s.meta.BloomShardCount))
+	require.Greater(t, len(expected), 1)
+
+	for shard, want := range expected {
+		got, err := r.Read(ctx, common.BloomName(shard), s.meta.BlockID, s.meta.TenantID, false)
+		require.NoError(t, err)
+		require.Equal(t, want, got, "shard %d", shard)
+	}
+}
+
 // func TestEstimateTraceSize(t *testing.T) {
 // 	f := "<put data.parquet file here>"
 // 	file, err := os.OpenFile(f, os.O_RDONLY, 0644)
@@ -115,3 +194,37 @@ func (i *testIterator) Next(ctx context.Context) (common.ID, *tempopb.Trace, err
 
 func (i *testIterator) Close() {
 }
+
+func TestEstimatedEncodedSize(t *testing.T) {
+	sch := parquet.SchemaOf(&Trace{})
+	buf := parquet.NewBuffer(sch)
+
+	empty, err := EstimatedEncodedSize(buf)
+	require.NoError(t, err)
+	require.Zero(t, empty)
+
+	for i := 0; i < 1000; i++ {
+		id := test.ValidTraceID(nil)
+		require.NoError(t, buf.Write(traceToParquet(id, test.MakeTraceWithSpanCount(2, 5, id), nil)))
+	}
+
+	estimate, err := EstimatedEncodedSize(buf)
+	require.NoError(t, err)
+
+	var rows []parquet.Row
+	r := buf.Rows()
+	defer r.Close()
+	for {
+		row := make([]parquet.Row, 1)
+		n, err := r.ReadRows(row)
+		rows = append(rows, row[:n]...)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	actual, err := encodedSize(sch, rows)
+	require.NoError(t, err)
+
+	require.InEpsilon(t, actual, estimate, 0.25, "in memory size is %d", buf.Size())
+}
@@ -3,9 +3,13 @@ package vparquet
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"io"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/grafana/dskit/backoff"
 	"go.uber.org/atomic"
 
 	"github.com/grafana/tempo/tempodb/backend"
@@ -18,8 +22,14 @@ import (
 //                                \                                                         /
 //                                  <------------------------------------------------------
 
+// maxWholeObjectBytes is the largest object a BackendReaderAt keeps in memory if the backend can't
+// read ranges. The reader lives as long as the block it is opened for.
+var maxWholeObjectBytes = 64 * 1024 * 1024
+
 // BackendReaderAt is used to track backend requests and present a io.ReaderAt interface backed
-// by a backend.Reader
+// by a backend.Reader. Only the requested ranges are read, unless the backend doesn't support
+// range reads. Then the whole object is read and ranges are served from memory. Objects of up to
+// maxWholeObjectBytes are read once, larger ones are read again for every read and not kept.
 type BackendReaderAt struct {
 	ctx      context.Context
 	r        backend.Reader
@@ -28,29 +38,165 @@ type BackendReaderAt struct {
 	tenantID string
 
 	TotalBytesRead atomic.Uint64
+
+	objectMtx      sync.Mutex
+	object         []byte
+	maxObjectBytes int
 }
 
 var _ io.ReaderAt = (*BackendReaderAt)(nil)
 
 func NewBackendReaderAt(ctx context.Context, r backend.Reader, name string, blockID uuid.UUID, tenantID string) *BackendReaderAt {
-	return &BackendReaderAt{ctx, r, name, blockID, tenantID, atomic.Uint64{}}
+	return &BackendReaderAt{
+		ctx:            ctx,
+		r:              r,
+		name:           name,
+		blockID:        blockID,
+		tenantID:       tenantID,
+		maxObjectBytes: maxWholeObjectBytes,
+	}
 }
 
 func (b *BackendReaderAt) ReadAt(p []byte, off int64) (int, error) {
-	b.TotalBytesRead.Add(uint64(len(p)))
-	err := b.r.ReadRange(b.ctx, b.name, b.blockID, b.tenantID, uint64(off), p, false)
+	return b.readAt(p, off, false)
+}
+
+func (b *BackendReaderAt) ReadAtWithCache(p []byte, off int64) (int, error) {
+	return b.readAt(p, off, true)
+}
+
+func (b *BackendReaderAt) readAt(p []byte, off int64, shouldCache bool) (int, error) {
+	if object := b.wholeObject(); object != nil {
+		return readObjectAt(object, p, off)
+	}
+
+	err := b.r.ReadRange(b.ctx, b.name, b.blockID, b.tenantID, uint64(off), p, shouldCache)
+	if errors.Is(err, backend.ErrRangeUnsupported) {
+		// readObject counts the bytes of the object instead
+		object, err := b.readObject(shouldCache)
+		if err != nil {
+			return 0, err
+		}
+		return readObjectAt(object, p, off)
+	}
 	if err != nil {
 		return 0, err
 	}
-	return len(p), err
+	if !shouldCache {
+		b.TotalBytesRead.Add(uint64(len(p)))
+	}
+	return len(p), nil
 }
 
-func (b *BackendReaderAt) ReadAtWithCache(p []byte, off int64) (int, error) {
-	err := b.r.ReadRange(b.ctx, b.name, b.blockID, b.tenantID, uint64(off), p, true)
+func (b *BackendReaderAt) wholeObject() []byte {
+	b.objectMtx.Lock()
+	defer b.objectMtx.Unlock()
+	return b.object
+}
+
+// readObject reads the whole object the first time it is called and returns the same bytes after,
+// unless the object is larger than maxObjectBytes.
+func (b *BackendReaderAt) readObject(shouldCache bool) ([]byte, error) {
+	b.objectMtx.Lock()
+	defer b.objectMtx.Unlock()
+
+	if b.object != nil {
+		return b.object, nil
+	}
+	object, err := b.r.Read(b.ctx, b.name, b.blockID, b.tenantID, shouldCache)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	b.TotalBytesRead.Add(uint64(len(object)))
+	if len(object) <= b.maxObjectBytes {
+		b.object = object
+	}
+	return object, nil
+}
+
+func readObjectAt(object []byte, p []byte, off int64) (int, error) {
+	if off < 0 || off > int64(len(object)) {
+		return 0, io.EOF
 	}
-	return len(p), err
+	n := copy(p, object[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// maxReadRetryBackoff caps the wait between retries of a backend read
+const maxReadRetryBackoff = 10 * time.Second
+
+// retryReader retries Read and ReadRange calls to the wrapped reader that fail with a transient
+// error. Not found errors and errors of a done context are returned immediately.
+type retryReader struct {
+	backend.Reader
+	cfg backoff.Config
+}
+
+var _ backend.Reader = (*retryReader)(nil)
+
+// newRetryReader wraps r to make up to retries additional attempts of failed reads. Returns r if
+// retries is 0.
+func newRetryReader(r backend.Reader, retries int, minBackoff time.Duration) backend.Reader {
+	if retries <= 0 {
+		return r
+	}
+
+	maxBackoff := maxReadRetryBackoff
+	if minBackoff > maxBackoff {
+		maxBackoff = minBackoff
+	}
+
+	return &retryReader{
+		Reader: r,
+		cfg: backoff.Config{
+			MinBackoff: minBackoff,
+			MaxBackoff: maxBackoff,
+			MaxRetries: retries + 1,
+		},
+	}
+}
+
+func (r *retryReader) Read(ctx context.Context, name string, blockID uuid.UUID, tenantID string, shouldCache bool) ([]byte, error) {
+	var b []byte
+	err := r.retry(ctx, func() error {
+		var err error
+		b, err = r.Reader.Read(ctx, name, blockID, tenantID, shouldCache)
+		return err
+	})
+	return b, err
+}
+
+func (r *retryReader) ReadRange(ctx context.Context, name string, blockID uuid.UUID, tenantID string, offset uint64, buffer []byte, shouldCache bool) error {
+	return r.retry(ctx, func() error {
+		return r.Reader.ReadRange(ctx, name, blockID, tenantID, offset, buffer, shouldCache)
+	})
+}
+
+func (r *retryReader) retry(ctx context.Context, fn func() error) error {
+	var err error
+	b := backoff.New(ctx, r.cfg)
+	for b.Ongoing() {
+		err = fn()
+		if err == nil || !isTransientReadError(err) {
+			return err
+		}
+		b.Wait()
+	}
+	if err == nil {
+		// the context was done before the first attempt
+		return b.Err()
+	}
+	return err
+}
+
+func isTransientReadError(err error) bool {
+	return !errors.Is(err, backend.ErrDoesNotExist) &&
+		!errors.Is(err, backend.ErrRangeUnsupported) &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded)
 }
 
 // parquetOptimizedReaderAt is used to cheat a few parquet calls. By default when opening a
@@ -2,9 +2,13 @@ package vparquet
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/segmentio/parquet-go"
 	"github.com/stretchr/testify/require"
 
@@ -155,3 +159,41 @@ func (r *recordingReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
 
 	return len(p), nil
 }
+
+type countingReader struct {
+	backend.Reader
+	err   error
+	calls int
+}
+
+func (c *countingReader) Read(context.Context, string, uuid.UUID, string, bool) ([]byte, error) {
+	c.calls++
+	return nil, c.err
+}
+
+func TestRetryReader(t *testing.T) {
+	ctx := context.Background()
+
+	// not found isn't retried
+	cr := &countingReader{err: fmt.Errorf("wrapped: %w", backend.ErrDoesNotExist)}
+	_, err := newRetryReader(cr, 3, 0).Read(ctx, "foo", uuid.New(), tenantID, false)
+	require.ErrorIs(t, err, backend.ErrDoesNotExist)
+	require.Equal(t, 1, cr.calls)
+
+	// transient errors are retried until attempts run out
+	cr = &countingReader{err: errors.New("transient")}
+	_, err = newRetryReader(cr, 3, 0).Read(ctx, "foo", uuid.New(), tenantID, false)
+	require.EqualError(t, err, "transient")
+	require.Equal(t, 4, cr.calls)
+
+	// a done context stops retrying
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	cr = &countingReader{err: errors.New("transient")}
+	_, err = newRetryReader(cr, 3, 0).Read(cctx, "foo", uuid.New(), tenantID, false)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 0, cr.calls)
+
+	// no retries returns the reader unwrapped
+	require.Equal(t, backend.Reader(cr), newRetryReader(cr, 0, time.Second))
+}
@@ -2,6 +2,7 @@ package vparquet
 
 import (
 	"bytes"
+	"fmt"
 
 	"github.com/golang/protobuf/jsonpb" //nolint:all //deprecated
 	"github.com/grafana/tempo/pkg/tempopb"
@@ -73,6 +74,27 @@ var (
 	}
 )
 
+// schemaColumns are the paths of columns that are searched directly by name. They are resolved
+// from the version of a block so blocks written with different schema generations can be
+// searched by the same code.
+type schemaColumns struct {
+	traceID string
+}
+
+var schemaColumnsByVersion = map[string]schemaColumns{
+	VersionString: {
+		traceID: TraceIDColumnName,
+	},
+}
+
+func columnsForVersion(version string) (schemaColumns, error) {
+	c, ok := schemaColumnsByVersion[version]
+	if !ok {
+		return schemaColumns{}, fmt.Errorf("unsupported block version for search: %s", version)
+	}
+	return c, nil
+}
+
 type Attribute struct {
 	Key string `parquet:",snappy,dict"`
 
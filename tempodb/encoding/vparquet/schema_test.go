@@ -1,6 +1,8 @@
 package vparquet
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"math/rand"
 	"testing"
@@ -10,11 +12,14 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	pq "github.com/grafana/tempo/pkg/parquetquery"
 	"github.com/grafana/tempo/pkg/tempopb"
 	v1 "github.com/grafana/tempo/pkg/tempopb/common/v1"
 	v1_resource "github.com/grafana/tempo/pkg/tempopb/resource/v1"
 	v1_trace "github.com/grafana/tempo/pkg/tempopb/trace/v1"
 	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
 )
 
 func TestProtoParquetRoundTrip(t *testing.T) {
@@ -199,6 +204,47 @@ func BenchmarkDeconstruct(b *testing.B) {
 	}
 }
 
+func BenchmarkBufferWrite(b *testing.B) {
+	id := test.ValidTraceID(nil)
+	tr := traceToParquet(id, test.MakeTraceWithSpanCount(10, 10, id), nil)
+	sch := parquet.SchemaOf(tr)
+
+	// resetting keeps the column buffers from growing for the whole run
+	const rowsPerReset = 1000
+
+	b.Run("Write", func(b *testing.B) {
+		buf := parquet.NewBuffer(sch)
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			if i%rowsPerReset == 0 {
+				buf.Reset()
+			}
+			if err := buf.Write(tr); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	// deconstructing into a new row each call is the cost Write avoids by reusing its row
+	b.Run("WriteRowsNewRow", func(b *testing.B) {
+		buf := parquet.NewBuffer(sch)
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			if i%rowsPerReset == 0 {
+				buf.Reset()
+			}
+			row := sch.Deconstruct(nil, tr)
+			if _, err := buf.WriteRows([]parquet.Row{row}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
 func TestParquetRowSizeEstimate(t *testing.T) {
 
 	batchCount := 100
@@ -225,3 +271,55 @@ func TestParquetRowSizeEstimate(t *testing.T) {
 		})
 	}
 }
+
+func TestColumnsForVersion(t *testing.T) {
+	// a mock second schema generation that renamed the trace ID column
+	const otherVersion = "vParquetTest"
+	schemaColumnsByVersion[otherVersion] = schemaColumns{traceID: "ID"}
+	defer delete(schemaColumnsByVersion, otherVersion)
+
+	type schemaA struct {
+		StartTime uint64
+		TraceID   []byte
+	}
+	type schemaB struct {
+		ID        []byte
+		StartTime uint64
+		TraceID   []byte // unused by this generation
+	}
+
+	tcs := []struct {
+		version  string
+		row      interface{}
+		expected int
+	}{
+		{version: VersionString, row: &schemaA{}, expected: 1},
+		{version: otherVersion, row: &schemaB{}, expected: 0},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.version, func(t *testing.T) {
+			columns, err := columnsForVersion(tc.version)
+			require.NoError(t, err)
+
+			buf := &bytes.Buffer{}
+			w := parquet.NewWriter(buf)
+			require.NoError(t, w.Write(tc.row))
+			require.NoError(t, w.Close())
+
+			pf, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			require.NoError(t, err)
+
+			idx, _ := pq.GetColumnIndexByPath(pf, columns.traceID)
+			require.Equal(t, tc.expected, idx)
+		})
+	}
+
+	_, err := columnsForVersion("vUnknown")
+	require.EqualError(t, err, "unsupported block version for search: vUnknown")
+
+	// searching a block with an unknown version fails before reading anything
+	b := newBackendBlock(&backend.BlockMeta{Version: "vUnknown"}, nil)
+	_, _, err = b.openForSearch(context.Background(), common.SearchOptions{})
+	require.EqualError(t, err, "unsupported block version for search: vUnknown")
+}